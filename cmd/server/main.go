@@ -2,49 +2,51 @@ package main
 
 import (
     "context"
+    "flag"
+    "fmt"
+    "io/fs"
     "log"
+    "net"
     "net/http"
     "os"
     "os/signal"
-    "path/filepath"
     "strings"
+    "sync/atomic"
     "syscall"
     "time"
 
     "github.com/Cdaprod/registry-service/internal/api"
+    "github.com/Cdaprod/registry-service/internal/audit"
+    registrygrpc "github.com/Cdaprod/registry-service/internal/grpc"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/reconcile"
     "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/Cdaprod/registry-service/internal/tracing"
     "github.com/Cdaprod/registry-service/pkg/builtins"
+    "github.com/Cdaprod/registry-service/pkg/config"
     "github.com/Cdaprod/registry-service/pkg/logger"
+    "github.com/Cdaprod/registry-service/pkg/plugins"
+    pluginrpc "github.com/Cdaprod/registry-service/pkg/plugins/rpc"
+    "github.com/Cdaprod/registry-service/web"
     "github.com/gorilla/mux"
-    "github.com/rs/cors"
     "go.uber.org/zap"
+    "google.golang.org/grpc"
 )
 
-func setCorrectMIMEType(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        // Set correct MIME type
-        switch {
-        case strings.HasSuffix(r.URL.Path, ".js"):
-            w.Header().Set("Content-Type", "application/javascript")
-        case strings.HasSuffix(r.URL.Path, ".css"):
-            w.Header().Set("Content-Type", "text/css")
-        case strings.HasSuffix(r.URL.Path, ".html"):
-            w.Header().Set("Content-Type", "text/html")
-        case strings.HasSuffix(r.URL.Path, ".json"):
-            w.Header().Set("Content-Type", "application/json")
-        case strings.HasSuffix(r.URL.Path, ".png"):
-            w.Header().Set("Content-Type", "image/png")
-        case strings.HasSuffix(r.URL.Path, ".jpg"), strings.HasSuffix(r.URL.Path, ".jpeg"):
-            w.Header().Set("Content-Type", "image/jpeg")
-        case strings.HasSuffix(r.URL.Path, ".gif"):
-            w.Header().Set("Content-Type", "image/gif")
-        case strings.HasSuffix(r.URL.Path, ".svg"):
-            w.Header().Set("Content-Type", "image/svg+xml")
-        default:
-            w.Header().Set("Content-Type", "text/plain") // Default MIME type
-        }
-        next.ServeHTTP(w, r)
-    })
+// webAssets returns the web frontend to serve: the assets embedded with the
+// embedweb build tag if any, else the contents of staticDir. A missing
+// staticDir is logged and serves no frontend, so only the API answers.
+func webAssets(staticDir string, l *zap.Logger) fs.FS {
+    if web.Assets != nil {
+        l.Info("Serving embedded web assets")
+        return web.Assets
+    }
+    if info, err := os.Stat(staticDir); err != nil || !info.IsDir() {
+        l.Warn("Static directory not found; the web frontend is not served", zap.String("static_dir", staticDir))
+        return nil
+    }
+    l.Info("Serving web assets", zap.String("static_dir", staticDir))
+    return os.DirFS(staticDir)
 }
 
 // initializeServer sets up and starts the HTTP server with all configurations.
@@ -66,74 +68,314 @@ func initializeServer(router http.Handler, bindAddr string, l *zap.Logger) *http
     return server
 }
 
+// initializeGRPCServer sets up and starts the gRPC server alongside the HTTP
+// server. Calls are checked by auth like API requests, when it is not nil,
+// and their changes are announced on notifier.
+func initializeGRPCServer(store storage.Store, auditLog *audit.Logger, notifier *notify.Notifier, auth *api.Authenticator, bindAddr string, l *zap.Logger) (*grpc.Server, error) {
+    l.Info("Starting gRPC server", zap.String("bind_address", bindAddr))
+
+    lis, err := net.Listen("tcp", bindAddr)
+    if err != nil {
+        return nil, err
+    }
+
+    server := registrygrpc.NewServer(store, l, auditLog, notifier, auth).Register()
+
+    go func() {
+        if err := server.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+            l.Fatal("Failed to start gRPC server", zap.Error(err))
+        }
+    }()
+
+    return server, nil
+}
+
+// initializeStorage opens the storage backend selected by the config.
+func initializeStorage(cfg config.StorageConfig, l *zap.Logger) (storage.Store, error) {
+    switch cfg.Backend {
+    case "memory":
+        var ms *storage.MemoryStorage
+        switch {
+        case cfg.DeletedRetention > 0:
+            // Retention is enforced by the expiry sweeper, so it runs even
+            // without an interval of its own
+            interval := cfg.ExpirySweepInterval
+            if interval == 0 {
+                interval = storage.DefaultRetentionSweepInterval
+            }
+            l.Info("Using in-memory storage with deleted item retention",
+                zap.Duration("retention", cfg.DeletedRetention),
+                zap.Duration("sweep_interval", interval))
+            ms = storage.NewMemoryStorageWithRetention(cfg.DeletedRetention, interval)
+        case cfg.ExpirySweepInterval > 0:
+            l.Info("Using in-memory storage with expiry", zap.Duration("sweep_interval", cfg.ExpirySweepInterval))
+            ms = storage.NewMemoryStorageWithExpiry(cfg.ExpirySweepInterval)
+        default:
+            l.Info("Using in-memory storage")
+            ms = storage.NewMemoryStorage()
+        }
+        ms.SetHistoryLimit(cfg.HistoryLimit)
+        if len(cfg.NaturalKey) > 0 {
+            l.Info("Deduplicating items by natural key", zap.Strings("fields", cfg.NaturalKey))
+            if err := ms.SetNaturalKey(cfg.NaturalKey); err != nil {
+                return nil, err
+            }
+        }
+        if len(cfg.IndexedMetadataKeys) > 0 {
+            l.Info("Indexing metadata keys", zap.Strings("keys", cfg.IndexedMetadataKeys))
+            if err := ms.SetIndexedMetadataKeys(cfg.IndexedMetadataKeys); err != nil {
+                return nil, err
+            }
+        }
+        return ms, nil
+    case "bolt":
+        l.Info("Using bolt storage", zap.String("path", cfg.BoltPath))
+        return storage.NewBoltStorage(cfg.BoltPath)
+    case "sqlite":
+        l.Info("Using sqlite storage", zap.String("dsn", cfg.SQLiteDSN))
+        return storage.NewSQLiteStorage(cfg.SQLiteDSN)
+    case "postgres":
+        l.Info("Using postgres storage")
+        return storage.NewPostgresStorage(cfg.DatabaseURL)
+    case "redis":
+        l.Info("Using redis storage", zap.String("addr", cfg.RedisAddr), zap.Int("db", cfg.RedisDB))
+        return storage.NewRedisStorage(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+    default:
+        return nil, fmt.Errorf("unknown storage backend: %s", cfg.Backend)
+    }
+}
+
+// initializePlugins loads plugins from the configured directory using the
+// configured loader: "so" (default) for Go plugins, which Watch hot-reloads,
+// or "rpc" for out-of-process gRPC plugins. Plugins that fail to load are
+// logged and skipped. Go plugins exporting a Lifecycle are called for the
+// item events published to broker, and those exporting a Reconciler are
+// added to scheduler. The returned reloader loads Go plugins
+// added later and is nil for rpc plugins. The returned function stops any
+// directory watcher and the Lifecycle hooks, and runs the Shutdown hooks of
+// the loaded Go plugins.
+func initializePlugins(store storage.Store, cfg config.PluginsConfig, broker *notify.Broker, scheduler *reconcile.Scheduler, l *zap.Logger) (api.PluginReloader, func() error, error) {
+    pluginsDir := cfg.Dir
+    stop := func() error { return nil }
+    var reloader api.PluginReloader
+
+    var loader interface{ LoadAll() error }
+    switch cfg.Loader {
+    case "so":
+        builtinLoader := builtins.NewBuiltinLoader(store, pluginsDir, l)
+        if cfg.Watch {
+            var err error
+            builtinLoader, err = builtins.NewBuiltinLoaderWithWatch(store, pluginsDir, l)
+            if err != nil {
+                return nil, nil, fmt.Errorf("failed to watch plugins directory: %w", err)
+            }
+        }
+        builtinLoader.SetScheduler(scheduler)
+        builtinLoader.Lifecycle().Start(broker)
+        stop = func() error {
+            watchErr := builtinLoader.Stop()
+            builtinLoader.Lifecycle().Stop()
+            if err := builtinLoader.Shutdown(); err != nil {
+                return err
+            }
+            return watchErr
+        }
+        loader = builtinLoader
+        reloader = builtinLoader
+    case "rpc":
+        l.Info("Using out-of-process gRPC plugins")
+        loader = pluginrpc.NewLoader(store, pluginsDir, l)
+    default:
+        return nil, nil, fmt.Errorf("unknown plugin loader: %s", cfg.Loader)
+    }
+
+    if err := loader.LoadAll(); err != nil {
+        loadErrs, ok := err.(plugins.LoadErrors)
+        if !ok {
+            stop()
+            return nil, nil, err
+        }
+        // Broken plugins are skipped so the remaining ones stay available
+        for _, loadErr := range loadErrs {
+            l.Error("Failed to load plugin", zap.String("path", loadErr.Path), zap.Error(loadErr.Err))
+        }
+    }
+    if builtinLoader, ok := loader.(*builtins.BuiltinLoader); ok && builtinLoader.Lifecycle().Len() > 0 {
+        l.Info("Calling plugin lifecycle hooks", zap.Int("plugins", builtinLoader.Lifecycle().Len()))
+    }
+
+    return reloader, stop, nil
+}
+
 // handleGracefulShutdown gracefully shuts down the server on receiving a termination signal.
-func handleGracefulShutdown(server *http.Server, l *zap.Logger) {
+// In-flight requests get up to timeout to finish. It returns once the HTTP
+// and gRPC servers have stopped, leaving plugins and storage for the caller
+// to close.
+func handleGracefulShutdown(server *http.Server, grpcServer *grpc.Server, inFlight *int64, timeout time.Duration, l *zap.Logger) {
     quit := make(chan os.Signal, 1)
     signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
     <-quit
 
-    l.Info("Server is shutting down...")
+    // Idle keep-alive connections are closed rather than handed new requests
+    server.SetKeepAlivesEnabled(false)
+    l.Info("Server is shutting down...", zap.Int64("in_flight_requests", atomic.LoadInt64(inFlight)), zap.Duration("timeout", timeout))
 
-    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
     defer cancel()
 
+    // Stop the gRPC server gracefully, forcing it down if the deadline passes
+    grpcStopped := make(chan struct{})
+    go func() {
+        grpcServer.GracefulStop()
+        close(grpcStopped)
+    }()
+
+    // Report the drain while it lasts, so slow shutdowns are visible
+    drained := make(chan struct{})
+    defer close(drained)
+    go func() {
+        ticker := time.NewTicker(5 * time.Second)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ticker.C:
+                l.Info("Waiting for in-flight requests", zap.Int64("in_flight_requests", atomic.LoadInt64(inFlight)))
+            case <-drained:
+                return
+            }
+        }
+    }()
+
+    // Shutdown closes the listeners at once, then waits for in-flight
+    // requests to finish until the deadline
     if err := server.Shutdown(ctx); err != nil {
-        l.Fatal("Server forced to shutdown", zap.Error(err))
+        l.Error("Server forced to shutdown", zap.Error(err), zap.Int64("in_flight_requests", atomic.LoadInt64(inFlight)))
     }
 
-    l.Info("Server has shut down gracefully")
+    select {
+    case <-grpcStopped:
+    case <-ctx.Done():
+        l.Warn("gRPC server forced to shutdown")
+        grpcServer.Stop()
+    }
+}
+
+// countInFlight tracks the number of HTTP requests being served in inFlight
+func countInFlight(next http.Handler, inFlight *int64) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt64(inFlight, 1)
+        defer atomic.AddInt64(inFlight, -1)
+        next.ServeHTTP(w, r)
+    })
 }
 
 // main is the entry point for the application.
 func main() {
+    configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a JSON or YAML config file")
+    flag.Parse()
+
+    // Load the config file and environment overrides
+    cfg, err := config.Load(*configPath)
+    if err != nil {
+        log.Fatalf("Failed to load config: %v", err)
+    }
+
     // Initialize logger
-    l, err := logger.NewLogger()
+    l, err := logger.New(logger.Options{
+        Level:              cfg.LogLevel,
+        Format:             cfg.LogFormat,
+        SamplingInitial:    cfg.LogSampling.Initial,
+        SamplingThereafter: cfg.LogSampling.Thereafter,
+        File:               cfg.LogFile.Path,
+        MaxSizeMB:          cfg.LogFile.MaxSizeMB,
+        MaxAgeDays:         cfg.LogFile.MaxAgeDays,
+        MaxBackups:         cfg.LogFile.MaxBackups,
+    })
     if err != nil {
         log.Fatalf("Failed to initialize logger: %v", err)
     }
     defer l.Sync()
 
-    // Initialize in-memory storage
-    memoryStorage := storage.NewMemoryStorage()
-
-    // Load built-in plugins
-    builtinLoader := builtins.NewBuiltinLoader(memoryStorage, "pkg/plugins/")
-    if err := builtinLoader.LoadAll(); err != nil {
-        l.Fatal("Error loading built-ins", zap.Error(err))
+    // Initialize tracing; spans are exported only when OTEL_EXPORTER_OTLP_ENDPOINT is set
+    shutdownTracing, err := tracing.Init(context.Background(), "registry-service")
+    if err != nil {
+        l.Fatal("Failed to initialize tracing", zap.Error(err))
     }
+    defer func() {
+        if err := shutdownTracing(context.Background()); err != nil {
+            l.Error("Failed to flush traces", zap.Error(err))
+        }
+    }()
 
-    // Set up router using mux
-    r := mux.NewRouter()
-    api.SetupRoutes(r, memoryStorage, l)
+    // Initialize storage
+    store, err := initializeStorage(cfg.Storage, l)
+    if err != nil {
+        l.Fatal("Failed to initialize storage", zap.Error(err))
+    }
+    // The memory backend is already an in-process map, so only persistent
+    // backends are fronted by the cache
+    if cfg.Storage.Backend != "memory" && cfg.Storage.Cache.Size > 0 {
+        l.Info("Caching item reads", zap.Int("size", cfg.Storage.Cache.Size), zap.Duration("ttl", cfg.Storage.Cache.TTL))
+        store = storage.NewCachingStorage(store, cfg.Storage.Cache.Size, cfg.Storage.Cache.TTL)
+    }
 
-    // Serve static files from the web/build directory with correct MIME types
-    fs := http.FileServer(http.Dir("./web/build"))
-    r.PathPrefix("/static/").Handler(setCorrectMIMEType(http.StripPrefix("/static", fs)))
+    // Open the audit trail of item mutations
+    auditLog, err := audit.Open(cfg.Audit.Sink, cfg.Audit.Retain, l)
+    if err != nil {
+        l.Fatal("Failed to open audit log", zap.Error(err))
+    }
 
-    // Serve index.html for any non-static file requests (React Router fallback)
-    r.PathPrefix("/").Handler(setCorrectMIMEType(http.StripPrefix("/", http.FileServer(http.Dir("./web/build")))))
+    // Item events go to webhooks, streaming clients and plugin hooks
+    notifier := notify.NewNotifier(l, strings.Split(os.Getenv("WEBHOOK_URLS"), ",")...)
 
-    // Determine port and bind address
-    port := os.Getenv("PORT")
-    if port == "" {
-        port = "7777"
+    // Load plugins, and keep the items of those that mirror an external
+    // system in sync with it
+    scheduler := reconcile.NewScheduler(store, notifier, auditLog, reconcile.Intervals{
+        Default: cfg.Plugins.ReconcileInterval,
+        Sources: cfg.Plugins.ReconcileIntervals,
+    }, l)
+    reloader, stopPlugins, err := initializePlugins(store, cfg.Plugins, notifier.Broker(), scheduler, l)
+    if err != nil {
+        l.Fatal("Failed to load plugins", zap.Error(err))
+    }
+    if scheduler.Len() > 0 {
+        l.Info("Reconciling plugin items", zap.Int("plugins", scheduler.Len()), zap.Duration("interval", cfg.Plugins.ReconcileInterval))
     }
-    bindAddr := "0.0.0.0:" + port
+    scheduler.Start()
 
-    // Set up CORS
-    c := cors.New(cors.Options{
-        AllowedOrigins:   []string{"*"},  // Allow all origins for staged environment
-        AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-        AllowedHeaders:   []string{"Content-Type", "Authorization"},
-        AllowCredentials: true,
-    })
+    // Set up router using mux
+    r := mux.NewRouter()
+    auth := api.SetupRoutes(r, store, l, webAssets(cfg.StaticDir, l), auditLog, reloader, notifier)
 
     // Wrap router with CORS handler
-    handler := c.Handler(r)
+    c := api.NewCORS(cfg.CORS.AllowedOrigins, cfg.CORS.AllowedMethods, cfg.CORS.AllowedHeaders, cfg.CORS.AllowCredentials)
+    var inFlight int64
+    handler := countInFlight(c.Handler(r), &inFlight)
 
     // Start the HTTP server
-    server := initializeServer(handler, bindAddr, l)
+    server := initializeServer(handler, cfg.BindAddress, l)
+    l.Info("Graceful shutdown configured", zap.Duration("shutdown_timeout", cfg.ShutdownTimeout))
 
-    // Handle graceful shutdown
-    handleGracefulShutdown(server, l)
-}
\ No newline at end of file
+    // Start the gRPC server on its own port
+    grpcServer, err := initializeGRPCServer(store, auditLog, notifier, auth, cfg.GRPCAddress, l)
+    if err != nil {
+        l.Fatal("Failed to start gRPC server", zap.Error(err))
+    }
+
+    // Handle graceful shutdown, then release plugins and storage once no
+    // request can use them anymore
+    handleGracefulShutdown(server, grpcServer, &inFlight, cfg.ShutdownTimeout, l)
+
+    scheduler.Stop()
+    if err := stopPlugins(); err != nil {
+        l.Error("Failed to shut down plugins", zap.Error(err))
+    }
+    if err := store.Close(); err != nil {
+        l.Error("Failed to close storage", zap.Error(err))
+    }
+    if err := auditLog.Close(); err != nil {
+        l.Error("Failed to close audit log", zap.Error(err))
+    }
+
+    l.Info("Server has shut down gracefully")
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// actorHeader names the caller of an unauthenticated request, such as a
+// service behind a trusted proxy
+const actorHeader = "X-Actor"
+
+// maxActorLength bounds the X-Actor names stored on items
+const maxActorLength = 256
+
+// requestActor names the caller of r on items and in the audit log.
+// Authenticated callers are named after their credentials, and X-Actor is
+// only trusted when authentication is disabled.
+func requestActor(r *http.Request) string {
+    if p := principalFrom(r.Context()); p != nil {
+        return p.actor()
+    }
+    if actor := strings.TrimSpace(r.Header.Get(actorHeader)); actor != "" && len(actor) <= maxActorLength {
+        return actor
+    }
+    return principalFrom(r.Context()).actor()
+}
+
+// stampCreatedBy records the caller of r as the creator and last updater of
+// item. Storage keeps the creator when the write turns out to be an update.
+func stampCreatedBy(r *http.Request, item *registry.Item) {
+    actor := requestActor(r)
+    item.CreatedBy = actor
+    item.UpdatedBy = actor
+}
+
+// stampUpdatedBy records the caller of r as the last updater of item
+func stampUpdatedBy(r *http.Request, item *registry.Item) {
+    item.UpdatedBy = requestActor(r)
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "mime"
+    "net/http"
+    "os"
+    "strconv"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+)
+
+// DefaultMaxAttachmentBytes caps attachments when MAX_ATTACHMENT_BYTES is unset
+const DefaultMaxAttachmentBytes = 10 << 20
+
+// Metadata keys describing an item's attachment, set on every upload
+const (
+    AttachmentSizeKey        = "attachmentSize"
+    AttachmentContentTypeKey = "attachmentContentType"
+)
+
+// attachmentRoute names the upload route, whose body is limited by
+// MAX_ATTACHMENT_BYTES instead of MAX_BODY_BYTES
+const attachmentRoute = "attachment"
+
+// maxAttachmentBytesFromEnv reads the attachment size limit from
+// MAX_ATTACHMENT_BYTES
+func maxAttachmentBytesFromEnv() (int64, error) {
+    value := os.Getenv("MAX_ATTACHMENT_BYTES")
+    if value == "" {
+        return DefaultMaxAttachmentBytes, nil
+    }
+    limit, err := strconv.ParseInt(value, 10, 64)
+    if err != nil || limit < 1 {
+        return 0, fmt.Errorf("invalid MAX_ATTACHMENT_BYTES: %q", value)
+    }
+    return limit, nil
+}
+
+// PutAttachment stores the request body as the item's attachment, replacing
+// any previous one, and records its size and content type in the item's
+// metadata
+func (h *Handler) PutAttachment(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+
+    current, err := h.store.GetItemCtx(r.Context(), id)
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+    if h.outOfScope(w, r, current.RegistryName) || h.notOwner(w, r, current) || h.locked(w, r, id) {
+        return
+    }
+
+    contentType := r.Header.Get("Content-Type")
+    if contentType == "" {
+        contentType = "application/octet-stream"
+    } else if _, _, err := mime.ParseMediaType(contentType); err != nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid Content-Type header")
+        return
+    }
+
+    if r.ContentLength > h.blobLimit {
+        h.respondWithError(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "Attachment exceeds "+strconv.FormatInt(h.blobLimit, 10)+" bytes")
+        return
+    }
+    data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.blobLimit))
+    if err != nil {
+        h.respondPayloadError(w, err, "Failed to read attachment")
+        return
+    }
+
+    item, err := applyMergePatch(current, map[string]interface{}{
+        "metadata": map[string]interface{}{
+            AttachmentSizeKey:        len(data),
+            AttachmentContentTypeKey: contentType,
+        },
+    })
+    if err != nil {
+        h.log(r).Error("Failed to record attachment metadata", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to store attachment")
+        return
+    }
+    before := current.Version
+    item.Version = before + 1
+    stampUpdatedBy(r, item)
+
+    updatedItem, err := h.store.UpdateItemCtx(r.Context(), item)
+    if err == registry.ErrStaleVersion {
+        h.respondVersionConflict(w, r, id)
+        return
+    }
+    if err != nil {
+        h.log(r).Error("Failed to record attachment metadata", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to store attachment")
+        return
+    }
+    if _, err := h.blobs.PutBlob(id, contentType, data); err != nil {
+        h.log(r).Error("Failed to store attachment", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to store attachment")
+        return
+    }
+
+    setItemAttributes(r.Context(), updatedItem)
+    h.metrics.itemUpdated(updatedItem)
+    h.notifier.Notify(notify.EventItemUpdated, updatedItem.ID, updatedItem.Type, updatedItem.RegistryName)
+    h.recordAudit(r, audit.ActionUpdate, updatedItem.ID, before, updatedItem.Version)
+
+    w.Header().Set("ETag", versionETag(updatedItem.Version))
+    h.respondWithItem(w, r, http.StatusOK, updatedItem)
+}
+
+// GetAttachment serves the item's attachment with the content type it was
+// uploaded with. Range and If-Modified-Since requests are supported.
+func (h *Handler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+
+    if _, err := h.store.GetItemCtx(r.Context(), id); err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+    blob, err := h.blobs.GetBlob(id)
+    if err == storage.ErrBlobNotFound {
+        h.respondWithError(w, http.StatusNotFound, CodeAttachmentNotFound, "Item has no attachment")
+        return
+    }
+    if err != nil {
+        h.log(r).Error("Failed to read attachment", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to read attachment")
+        return
+    }
+
+    w.Header().Set("Content-Type", blob.ContentType)
+    http.ServeContent(w, r, "", blob.UpdatedAt, bytes.NewReader(blob.Data))
+}
+
+// dropAttachment discards the attachment of a deleted item. Failures are
+// logged, since the item itself is already gone.
+func (h *Handler) dropAttachment(r *http.Request, id string) {
+    if err := h.blobs.DeleteBlob(id); err != nil {
+        h.log(r).Warn("Failed to drop attachment", zap.String("id", id), zap.Error(err))
+    }
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+    "net/http"
+    "strconv"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/storage"
+)
+
+// recordAudit adds a mutation made by the caller of r to the audit trail
+func (h *Handler) recordAudit(r *http.Request, action, itemID string, before, after int64) {
+    h.auditLog.Record(audit.Entry{
+        Actor:         requestActor(r),
+        Action:        action,
+        ItemID:        itemID,
+        BeforeVersion: before,
+        AfterVersion:  after,
+        RequestID:     requestIDFrom(r.Context()),
+    })
+}
+
+// sweepActor names the storage sweeper in the audit log
+const sweepActor = "system"
+
+// announceSweep publishes the items the storage sweeper expired or purged
+// and adds them to the audit trail
+func (h *Handler) announceSweep(result storage.SweepResult) {
+    for _, item := range result.Expired {
+        h.notifier.Notify(notify.EventItemDeleted, item.ID, item.Type, item.RegistryName)
+        h.auditLog.Record(audit.Entry{
+            Actor:         sweepActor,
+            Action:        audit.ActionDelete,
+            ItemID:        item.ID,
+            BeforeVersion: item.Version,
+            AfterVersion:  item.Version,
+        })
+    }
+    for _, item := range result.Purged {
+        h.notifier.Notify(notify.EventItemPurged, item.ID, item.Type, item.RegistryName)
+        h.auditLog.Record(audit.Entry{
+            Actor:         sweepActor,
+            Action:        audit.ActionPurge,
+            ItemID:        item.ID,
+            BeforeVersion: item.Version,
+        })
+    }
+}
+
+// storedVersion returns the current version of the item with the given ID,
+// or zero when there is none
+func (h *Handler) storedVersion(r *http.Request, id string) int64 {
+    item, err := h.store.GetItemCtx(r.Context(), id)
+    if err != nil {
+        return 0
+    }
+    return item.Version
+}
+
+// GetAudit returns the most recent audit entries, newest first, optionally
+// only those of ?itemId= and at most ?limit= of them. Only the retained
+// entries can be queried; the sink holds the full trail.
+func (h *Handler) GetAudit(w http.ResponseWriter, r *http.Request) {
+    if p := principalFrom(r.Context()); p == nil || p.role != RoleAdmin {
+        h.respondWithError(w, http.StatusForbidden, CodeForbidden, "Admin role required")
+        return
+    }
+
+    limit := 100
+    if value := r.URL.Query().Get("limit"); value != "" {
+        n, err := strconv.Atoi(value)
+        if err != nil || n < 1 {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid limit")
+            return
+        }
+        limit = n
+    }
+
+    h.respondWithJSON(w, http.StatusOK, h.auditLog.Recent(r.URL.Query().Get("itemId"), limit))
+}
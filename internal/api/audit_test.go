@@ -0,0 +1,151 @@
+package api
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+)
+
+// waitForAudit polls the audit log until itemID has want entries
+func waitForAudit(t *testing.T, log *audit.Logger, itemID string, want int) []audit.Entry {
+    t.Helper()
+    deadline := time.Now().Add(2 * time.Second)
+    for {
+        entries := log.Recent(itemID, 10)
+        if len(entries) >= want || time.Now().After(deadline) {
+            return entries
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+}
+
+func TestAuditPurgeDeleted(t *testing.T) {
+    s := newTestServer(t)
+    for _, id := range []string{"kept", "gone"} {
+        if rec := s.do(t, "POST", "/api/v1/items", item(id, id, "team-a")); rec.Code != http.StatusCreated {
+            t.Fatalf("create %s: %d %s", id, rec.Code, rec.Body)
+        }
+    }
+    if rec := s.do(t, "DELETE", "/api/v1/items/gone", nil); rec.Code != http.StatusNoContent && rec.Code != http.StatusOK {
+        t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+    }
+
+    if rec := s.do(t, "POST", "/api/v1/items/purge-deleted", nil); rec.Code != http.StatusOK {
+        t.Fatalf("purge: %d %s", rec.Code, rec.Body)
+    }
+
+    tests := []struct {
+        id         string
+        wantAction string
+    }{
+        {id: "gone", wantAction: audit.ActionPurge},
+        {id: "kept", wantAction: audit.ActionCreate},
+    }
+    for _, tt := range tests {
+        t.Run(tt.id, func(t *testing.T) {
+            entries := s.auditLog.Recent(tt.id, 1)
+            if len(entries) != 1 || entries[0].Action != tt.wantAction {
+                t.Errorf("latest entry = %+v, want %s", entries, tt.wantAction)
+            }
+        })
+    }
+}
+
+func TestAuditRestoreSnapshot(t *testing.T) {
+    s := newAdminTestServer(t)
+    for _, id := range []string{"replaced", "removed"} {
+        if rec := s.do(t, "POST", "/api/v1/items", item(id, id, "team-a")); rec.Code != http.StatusCreated {
+            t.Fatalf("create %s: %d %s", id, rec.Code, rec.Body)
+        }
+    }
+
+    snapshot := storage.Snapshot{Format: storage.SnapshotFormat, TakenAt: time.Now()}
+    for _, id := range []string{"replaced", "added"} {
+        snapshot.Items = append(snapshot.Items, storage.NewSnapshotItem(&registry.Item{
+            ID: id, Type: "service", Name: id + "-restored", RegistryName: "team-a", Version: 7,
+        }))
+    }
+    rec := s.do(t, "POST", "/api/v1/admin/restore?mode=replace", snapshot)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("restore: %d %s", rec.Code, rec.Body)
+    }
+
+    tests := []struct {
+        id         string
+        wantAction string
+        wantBefore int64
+        wantAfter  int64
+    }{
+        {id: "replaced", wantAction: audit.ActionUpdate, wantBefore: 1, wantAfter: 7},
+        {id: "added", wantAction: audit.ActionCreate, wantBefore: 0, wantAfter: 7},
+        {id: "removed", wantAction: audit.ActionPurge, wantBefore: 1, wantAfter: 0},
+    }
+    for _, tt := range tests {
+        t.Run(tt.id, func(t *testing.T) {
+            entries := s.auditLog.Recent(tt.id, 1)
+            if len(entries) != 1 {
+                t.Fatalf("got no audit entry")
+            }
+            got := entries[0]
+            if got.Action != tt.wantAction || got.BeforeVersion != tt.wantBefore || got.AfterVersion != tt.wantAfter {
+                t.Errorf("entry = %s %d->%d, want %s %d->%d", got.Action, got.BeforeVersion, got.AfterVersion,
+                    tt.wantAction, tt.wantBefore, tt.wantAfter)
+            }
+        })
+    }
+}
+
+func TestAuditSweep(t *testing.T) {
+    s := newTestServerWith(t, storage.NewMemoryStorageWithRetention(time.Millisecond, 10*time.Millisecond))
+    if _, err := s.store.CreateItem(&registry.Item{ID: "temp", Type: "service", Name: "temp", RegistryName: "team-a",
+        ExpiresAt: time.Now().Add(20 * time.Millisecond)}); err != nil {
+        t.Fatal(err)
+    }
+
+    // The item expires on one sweep and is purged on a later one
+    entries := waitForAudit(t, s.auditLog, "temp", 2)
+    if len(entries) != 2 {
+        t.Fatalf("got %d audit entries, want 2: %+v", len(entries), entries)
+    }
+    for i, wantAction := range []string{audit.ActionPurge, audit.ActionDelete} {
+        if entries[i].Actor != sweepActor || entries[i].Action != wantAction {
+            t.Errorf("entry %d = %s by %s, want %s by %s", i, entries[i].Action, entries[i].Actor, wantAction, sweepActor)
+        }
+    }
+}
+
+func TestAuditRequiresAdmin(t *testing.T) {
+    tests := []struct {
+        name     string
+        env      map[string]string
+        apiKey   string
+        wantCode int
+    }{
+        {name: "without authentication", wantCode: http.StatusForbidden},
+        {name: "anonymous with optional authentication", env: map[string]string{"JWT_SECRET": "secret", "AUTH_OPTIONAL": "true"},
+            wantCode: http.StatusForbidden},
+        {name: "admin", env: map[string]string{"ADMIN_API_KEY": testAdminKey, "AUTH_OPTIONAL": "true"}, apiKey: testAdminKey,
+            wantCode: http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            for name, value := range tt.env {
+                t.Setenv(name, value)
+            }
+            s := newTestServer(t)
+            s.apiKey = tt.apiKey
+            if _, err := s.store.CreateItem(&registry.Item{ID: "svc", Type: "service", Name: "api", RegistryName: "team-a"}); err != nil {
+                t.Fatal(err)
+            }
+
+            rec := s.do(t, "GET", "/api/v1/audit", nil)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+        })
+    }
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+    "context"
+    "crypto/subtle"
+    "errors"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/golang-jwt/jwt/v4"
+)
+
+// RoleAdmin is the role required to delete items and mint API keys
+const RoleAdmin = "admin"
+
+// authClaims are the JWT claims the API understands
+type authClaims struct {
+    Role string `json:"role"`
+    jwt.RegisteredClaims
+}
+
+// principal is the authenticated caller of a request
+type principal struct {
+    role string
+    // subject identifies the caller in the audit log
+    subject string
+    // apiKey restricts the caller to the key's registries; nil means unrestricted
+    apiKey *storage.APIKey
+    // ownerOnly restricts the caller's updates to items it owns, unless it is an admin
+    ownerOnly bool
+}
+
+// allowsRegistry reports whether the caller may access registryName
+func (p *principal) allowsRegistry(registryName string) bool {
+    return p == nil || p.apiKey == nil || p.apiKey.AllowsRegistry(registryName)
+}
+
+// actor names the caller in the audit log
+func (p *principal) actor() string {
+    if p == nil {
+        return "anonymous"
+    }
+    return p.subject
+}
+
+type principalKey struct{}
+
+// principalFrom returns the authenticated caller of a request, if any
+func principalFrom(ctx context.Context) *principal {
+    p, _ := ctx.Value(principalKey{}).(*principal)
+    return p
+}
+
+// Caller is the authenticated caller of a request that did not come over
+// HTTP. A nil Caller is an anonymous reader.
+type Caller struct {
+    p *principal
+}
+
+// principal returns the principal of c, nil for an anonymous reader
+func (c *Caller) principal() *principal {
+    if c == nil {
+        return nil
+    }
+    return c.p
+}
+
+// Actor names the caller on items and in the audit log
+func (c *Caller) Actor() string {
+    return c.principal().actor()
+}
+
+// IsAdmin reports whether the caller has the admin role
+func (c *Caller) IsAdmin() bool {
+    p := c.principal()
+    return p != nil && p.role == RoleAdmin
+}
+
+// AllowsRegistry reports whether the caller may access registryName
+func (c *Caller) AllowsRegistry(registryName string) bool {
+    return c.principal().allowsRegistry(registryName)
+}
+
+// MayUpdate reports whether the caller may update an item owned by owner
+// when updates are restricted to owners
+func (c *Caller) MayUpdate(owner string) bool {
+    p := c.principal()
+    return p == nil || !p.ownerOnly || p.role == RoleAdmin || owner == "" || owner == p.subject
+}
+
+// Authenticator validates bearer JWTs and X-API-Key headers on API requests
+type Authenticator struct {
+    keyfunc   jwt.Keyfunc
+    methods   []string
+    keys      storage.KeyStore
+    adminKey  string
+    optional  bool
+    ownerOnly bool
+}
+
+// NewAuthenticatorFromEnv configures authentication from the environment.
+// JWT_SECRET enables HS256 tokens and JWT_JWKS_URL enables RS256 tokens signed
+// by a key in that JWKS. ADMIN_API_KEY enables API keys resolved through keys,
+// with itself as the admin key used to mint them. AUTH_OPTIONAL=true leaves
+// GET requests public, and OWNER_ONLY_UPDATES=true lets only admins and the
+// owner of an item update it. It returns nil when no authentication is
+// configured.
+func NewAuthenticatorFromEnv(keys storage.KeyStore) *Authenticator {
+    a := &Authenticator{}
+    a.optional, _ = strconv.ParseBool(os.Getenv("AUTH_OPTIONAL"))
+    a.ownerOnly, _ = strconv.ParseBool(os.Getenv("OWNER_ONLY_UPDATES"))
+
+    if secret := os.Getenv("JWT_SECRET"); secret != "" {
+        a.keyfunc = func(*jwt.Token) (interface{}, error) {
+            return []byte(secret), nil
+        }
+        a.methods = []string{jwt.SigningMethodHS256.Alg()}
+    } else if url := os.Getenv("JWT_JWKS_URL"); url != "" {
+        a.keyfunc = newJWKSKeySet(url).keyfunc
+        a.methods = []string{jwt.SigningMethodRS256.Alg()}
+    }
+
+    if adminKey := os.Getenv("ADMIN_API_KEY"); adminKey != "" {
+        a.keys = keys
+        a.adminKey = adminKey
+    }
+
+    if a.keyfunc == nil && a.keys == nil {
+        return nil
+    }
+    return a
+}
+
+// Middleware rejects requests without valid credentials with 401. GET
+// requests pass without credentials when authentication is optional, and
+// DELETE requests additionally require the admin role, failing with 403.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method == http.MethodOptions {
+            next.ServeHTTP(w, r)
+            return
+        }
+
+        p, err := a.authenticate(r.Header)
+        if err == errNoCredentials && a.optional && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+            next.ServeHTTP(w, r)
+            return
+        }
+        if err != nil {
+            w.Header().Set("WWW-Authenticate", `Bearer realm="registry-service"`)
+            writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized: "+err.Error(), nil)
+            return
+        }
+
+        // Lease holders release their own locks, proven by the lock token
+        if r.Method == http.MethodDelete && p.role != RoleAdmin && !strings.HasSuffix(r.URL.Path, "/lock") {
+            writeError(w, http.StatusForbidden, CodeForbidden, "Admin role required", nil)
+            return
+        }
+        p.ownerOnly = a.ownerOnly
+
+        next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+    })
+}
+
+var errNoCredentials = errors.New("missing credentials")
+
+// Authenticate resolves the caller of a request that did not come over HTTP,
+// such as a gRPC call, from the credentials in header, with the rules of
+// Middleware. Reads without credentials return a nil Caller when
+// authentication is optional.
+func (a *Authenticator) Authenticate(header http.Header, read bool) (*Caller, error) {
+    p, err := a.authenticate(header)
+    if err == errNoCredentials && a.optional && read {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    p.ownerOnly = a.ownerOnly
+    return &Caller{p: p}, nil
+}
+
+// authenticate resolves the caller from the X-API-Key or Authorization header
+func (a *Authenticator) authenticate(header http.Header) (*principal, error) {
+    if key := header.Get("X-API-Key"); key != "" && a.keys != nil {
+        if subtle.ConstantTimeCompare([]byte(key), []byte(a.adminKey)) == 1 {
+            return &principal{role: RoleAdmin, subject: "admin-key"}, nil
+        }
+        apiKey, ok := a.keys.LookupKey(key)
+        if !ok {
+            return nil, errors.New("invalid api key")
+        }
+        return &principal{apiKey: apiKey, subject: "apikey:" + apiKey.ID}, nil
+    }
+
+    authorization := header.Get("Authorization")
+    if authorization == "" {
+        return nil, errNoCredentials
+    }
+    if a.keyfunc == nil {
+        return nil, errors.New("bearer tokens are not accepted")
+    }
+
+    tokenString := strings.TrimPrefix(authorization, "Bearer ")
+    if tokenString == authorization {
+        return nil, errors.New("missing bearer token")
+    }
+
+    claims := &authClaims{}
+    token, err := jwt.ParseWithClaims(tokenString, claims, a.keyfunc, jwt.WithValidMethods(a.methods))
+    if err != nil || !token.Valid {
+        return nil, errors.New("invalid bearer token")
+    }
+
+    subject := claims.Subject
+    if subject == "" {
+        subject = "jwt"
+    }
+    return &principal{role: claims.Role, subject: subject}, nil
+}
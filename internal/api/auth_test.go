@@ -0,0 +1,147 @@
+package api
+
+import (
+    "encoding/base64"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/golang-jwt/jwt/v4"
+)
+
+const testJWTSecret = "test-jwt-secret"
+
+// signToken returns an HS256 token for role, signed with secret and expiring
+// after ttl
+func signToken(t *testing.T, secret, role string, ttl time.Duration) string {
+    t.Helper()
+    claims := authClaims{
+        Role: role,
+        RegisteredClaims: jwt.RegisteredClaims{
+            Subject:   "user-" + role,
+            ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+        },
+    }
+    token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+    if err != nil {
+        t.Fatalf("sign token: %v", err)
+    }
+    return token
+}
+
+// tamperToken swaps the claims of a token for an admin role, keeping its
+// original signature
+func tamperToken(t *testing.T, token string) string {
+    t.Helper()
+    parts := strings.Split(token, ".")
+    parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"role":"admin","sub":"intruder"}`))
+    return strings.Join(parts, ".")
+}
+
+// unsignedToken returns a token with the "none" algorithm
+func unsignedToken(t *testing.T) string {
+    t.Helper()
+    token, err := jwt.NewWithClaims(jwt.SigningMethodNone, authClaims{Role: RoleAdmin}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+    if err != nil {
+        t.Fatalf("sign token: %v", err)
+    }
+    return token
+}
+
+// hs384Token returns a token signed with the right secret but HS384
+func hs384Token(t *testing.T) string {
+    t.Helper()
+    token, err := jwt.NewWithClaims(jwt.SigningMethodHS384, authClaims{Role: RoleAdmin}).SignedString([]byte(testJWTSecret))
+    if err != nil {
+        t.Fatalf("sign token: %v", err)
+    }
+    return token
+}
+
+func TestAuthentication(t *testing.T) {
+    editor := signToken(t, testJWTSecret, "editor", time.Hour)
+    admin := signToken(t, testJWTSecret, RoleAdmin, time.Hour)
+
+    tests := []struct {
+        name          string
+        optional      bool
+        method        string
+        target        string
+        authorization string
+        wantCode      int
+    }{
+        {name: "get without token", method: "GET", target: "/api/v1/items/svc", wantCode: http.StatusUnauthorized},
+        {name: "optional get without token", optional: true, method: "GET", target: "/api/v1/items/svc", wantCode: http.StatusOK},
+        {name: "optional get with tampered token", optional: true, method: "GET", target: "/api/v1/items/svc",
+            authorization: "Bearer " + tamperToken(t, editor), wantCode: http.StatusUnauthorized},
+        {name: "get with token", method: "GET", target: "/api/v1/items/svc", authorization: "Bearer " + editor, wantCode: http.StatusOK},
+        {name: "post without token", optional: true, method: "POST", target: "/api/v1/items", wantCode: http.StatusUnauthorized},
+        {name: "put without token", optional: true, method: "PUT", target: "/api/v1/items/svc", wantCode: http.StatusUnauthorized},
+        {name: "delete without token", optional: true, method: "DELETE", target: "/api/v1/items/svc", wantCode: http.StatusUnauthorized},
+        {name: "post with token", method: "POST", target: "/api/v1/items", authorization: "Bearer " + editor, wantCode: http.StatusCreated},
+        {name: "put with token", method: "PUT", target: "/api/v1/items/svc", authorization: "Bearer " + editor, wantCode: http.StatusOK},
+        {name: "tampered signature", method: "POST", target: "/api/v1/items", authorization: "Bearer " + tamperToken(t, editor),
+            wantCode: http.StatusUnauthorized},
+        {name: "other secret", method: "POST", target: "/api/v1/items", authorization: "Bearer " + signToken(t, "other-secret", "editor", time.Hour),
+            wantCode: http.StatusUnauthorized},
+        {name: "expired token", method: "POST", target: "/api/v1/items", authorization: "Bearer " + signToken(t, testJWTSecret, "editor", -time.Minute),
+            wantCode: http.StatusUnauthorized},
+        {name: "none algorithm", method: "POST", target: "/api/v1/items", authorization: "Bearer " + unsignedToken(t), wantCode: http.StatusUnauthorized},
+        {name: "wrong algorithm", method: "POST", target: "/api/v1/items", authorization: "Bearer " + hs384Token(t), wantCode: http.StatusUnauthorized},
+        {name: "not a bearer token", method: "POST", target: "/api/v1/items", authorization: "Token " + editor, wantCode: http.StatusUnauthorized},
+        {name: "delete without admin role", method: "DELETE", target: "/api/v1/items/svc", authorization: "Bearer " + editor, wantCode: http.StatusForbidden},
+        {name: "delete as admin", method: "DELETE", target: "/api/v1/items/svc", authorization: "Bearer " + admin, wantCode: http.StatusNoContent},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            t.Setenv("JWT_SECRET", testJWTSecret)
+            if tt.optional {
+                t.Setenv("AUTH_OPTIONAL", "true")
+            }
+            s := newTestServer(t)
+            if _, err := s.store.CreateItem(&registry.Item{ID: "svc", Type: "service", Name: "api", RegistryName: "team-a"}); err != nil {
+                t.Fatal(err)
+            }
+
+            var body interface{}
+            switch tt.method {
+            case "POST":
+                body = item("new", "new", "team-a")
+            case "PUT":
+                body = item("svc", "renamed", "team-a")
+            }
+            var header []string
+            if tt.authorization != "" {
+                header = []string{"Authorization", tt.authorization}
+            }
+            rec := s.do(t, tt.method, tt.target, body, header...)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if rec.Code == http.StatusUnauthorized {
+                if code := errorCode(t, rec); code != CodeUnauthorized {
+                    t.Errorf("code = %s, want %s", code, CodeUnauthorized)
+                }
+                if rec.Header().Get("WWW-Authenticate") == "" {
+                    t.Error("401 without WWW-Authenticate")
+                }
+            }
+        })
+    }
+}
+
+func TestAuthenticationActor(t *testing.T) {
+    t.Setenv("JWT_SECRET", testJWTSecret)
+    s := newTestServer(t)
+
+    rec := s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a"), "Authorization", "Bearer "+signToken(t, testJWTSecret, "editor", time.Hour))
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+    }
+    entries := s.auditLog.Recent("svc", 1)
+    if len(entries) != 1 || entries[0].Actor != "user-editor" {
+        t.Errorf("audit entries = %+v, want one by user-editor", entries)
+    }
+}
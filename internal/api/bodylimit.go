@@ -0,0 +1,70 @@
+package api
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+    "strconv"
+
+    "github.com/gorilla/mux"
+)
+
+// DefaultMaxBodyBytes caps request bodies when MAX_BODY_BYTES is unset
+const DefaultMaxBodyBytes = 1 << 20
+
+// maxBodyBytesFromEnv reads the request body limit from MAX_BODY_BYTES
+func maxBodyBytesFromEnv() (int64, error) {
+    value := os.Getenv("MAX_BODY_BYTES")
+    if value == "" {
+        return DefaultMaxBodyBytes, nil
+    }
+    limit, err := strconv.ParseInt(value, 10, 64)
+    if err != nil || limit < 1 {
+        return 0, fmt.Errorf("invalid MAX_BODY_BYTES: %q", value)
+    }
+    return limit, nil
+}
+
+// bodyLimitMiddleware caps the bodies of POST, PUT, PATCH and DELETE
+// requests at limit bytes. Requests declaring a longer body are rejected with
+// 413 up front; longer chunked bodies fail when read past the limit.
+func bodyLimitMiddleware(limit int64) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            switch r.Method {
+            case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+            default:
+                next.ServeHTTP(w, r)
+                return
+            }
+            // Attachments are limited by their own handler
+            if route := mux.CurrentRoute(r); route != nil && route.GetName() == attachmentRoute {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            if r.ContentLength > limit {
+                writeError(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, payloadTooLargeMessage(limit), nil)
+                return
+            }
+            r.Body = http.MaxBytesReader(w, r.Body, limit)
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+func payloadTooLargeMessage(limit int64) string {
+    return "Request body exceeds " + strconv.FormatInt(limit, 10) + " bytes"
+}
+
+// respondPayloadError rejects a request body that could not be read or
+// decoded, with 413 when reading it hit the body size limit and 400 otherwise
+func (h *Handler) respondPayloadError(w http.ResponseWriter, err error, message string) {
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) {
+        h.respondWithError(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, payloadTooLargeMessage(tooLarge.Limit))
+        return
+    }
+    h.respondWithError(w, http.StatusBadRequest, CodeInvalidPayload, message)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+    t.Setenv("MAX_BODY_BYTES", "200")
+    small := item("svc", "api", "team-a")
+    large := item("svc", "api", "team-a")
+    large["metadata"] = map[string]interface{}{"padding": strings.Repeat("x", 300)}
+
+    tests := []struct {
+        name     string
+        method   string
+        target   string
+        body     map[string]interface{}
+        chunked  bool
+        wantCode int
+    }{
+        {name: "create under the limit", method: "POST", target: "/api/v1/items", body: small, wantCode: http.StatusCreated},
+        {name: "create over the limit", method: "POST", target: "/api/v1/items", body: large, wantCode: http.StatusRequestEntityTooLarge},
+        {name: "chunked create over the limit", method: "POST", target: "/api/v1/items", body: large, chunked: true, wantCode: http.StatusRequestEntityTooLarge},
+        {name: "update over the limit", method: "PUT", target: "/api/v1/items/svc", body: large, wantCode: http.StatusRequestEntityTooLarge},
+        {name: "chunked update over the limit", method: "PUT", target: "/api/v1/items/svc", body: large, chunked: true, wantCode: http.StatusRequestEntityTooLarge},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+            raw, err := json.Marshal(tt.body)
+            if err != nil {
+                t.Fatal(err)
+            }
+            // A reader without a known length is sent without Content-Length
+            var body io.Reader = bytes.NewReader(raw)
+            if tt.chunked {
+                body = io.MultiReader(body)
+            }
+            req := httptest.NewRequest(tt.method, tt.target, body)
+            req.Header.Set("Content-Type", "application/json")
+            rec := httptest.NewRecorder()
+            s.handler.ServeHTTP(rec, req)
+
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if tt.wantCode == http.StatusRequestEntityTooLarge {
+                if code := errorCode(t, rec); code != CodePayloadTooLarge {
+                    t.Errorf("code = %s, want %s", code, CodePayloadTooLarge)
+                }
+                if _, err := s.store.GetItem("svc"); err == nil {
+                    t.Error("oversized item was stored")
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "go.uber.org/zap"
+)
+
+// BulkDeleteResponse reports how many items a bulk delete soft-deleted, or
+// would soft-delete in a dry run
+type BulkDeleteResponse struct {
+    DryRun  bool `json:"dryRun,omitempty"`
+    Deleted int  `json:"deleted"`
+}
+
+// DeleteItems soft-deletes every non-deleted item matching the
+// {"ids": [...], "type": "...", "registryName": "..."} criteria in the body.
+// At least one criterion is required. Scoped API keys must name one of their
+// registries.
+func (h *Handler) DeleteItems(w http.ResponseWriter, r *http.Request) {
+    var filter storage.DeleteFilter
+    if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+        h.log(r).Error("Failed to decode request body", zap.Error(err))
+        h.respondPayloadError(w, err, "Invalid request payload")
+        return
+    }
+    filter.Type = h.types.Normalize(filter.Type)
+    if filter.IsEmpty() {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "At least one of ids, type or registryName is required")
+        return
+    }
+
+    if p := principalFrom(r.Context()); p != nil && p.apiKey != nil {
+        if filter.RegistryName == "" {
+            h.respondWithError(w, http.StatusForbidden, CodeKeyOutOfScope, "Scoped API keys must set registryName")
+            return
+        }
+        if h.outOfScope(w, r, filter.RegistryName) {
+            return
+        }
+    }
+
+    // Locked items must be released first, rather than silently skipped
+    locked, err := h.lockedMatches(r, filter)
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+    if len(locked) > 0 {
+        h.respondWithErrorDetails(w, http.StatusLocked, CodeItemLocked, "Some matching items are locked",
+            map[string][]string{"locked": locked})
+        return
+    }
+
+    if dryRun(r) {
+        matched, err := h.matchItems(r, filter)
+        if err != nil {
+            h.scanFailed(w, r, err)
+            return
+        }
+        h.respondWithJSON(w, http.StatusOK, BulkDeleteResponse{DryRun: true, Deleted: len(matched)})
+        return
+    }
+
+    var deleted []*registry.Item
+    if bulk, ok := h.store.(storage.BulkDeleteStore); ok {
+        deleted, err = bulk.DeleteByFilter(filter)
+    } else {
+        deleted, err = h.deleteEach(r, filter)
+    }
+    if err != nil {
+        h.log(r).Error("Failed to delete items", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete items")
+        return
+    }
+
+    for _, item := range deleted {
+        h.dropAttachment(r, item.ID)
+        h.metrics.itemsDeleted.Inc()
+        h.notifier.Notify(notify.EventItemDeleted, item.ID, item.Type, item.RegistryName)
+        h.recordAudit(r, audit.ActionDelete, item.ID, item.Version, item.Version)
+    }
+    h.log(r).Info("Bulk deleted items", zap.Int("count", len(deleted)))
+
+    h.respondWithJSON(w, http.StatusOK, BulkDeleteResponse{Deleted: len(deleted)})
+}
+
+// matchItems lists the non-deleted items matching filter
+func (h *Handler) matchItems(r *http.Request, filter storage.DeleteFilter) ([]*registry.Item, error) {
+    items, err := h.store.ListCtx(r.Context())
+    if err != nil {
+        return nil, err
+    }
+
+    var matched []*registry.Item
+    for _, item := range items {
+        if itemObj, ok := item.(*registry.Item); ok && filter.Matches(itemObj) {
+            matched = append(matched, itemObj)
+        }
+    }
+    return matched, nil
+}
+
+// deleteEach soft-deletes the items matching filter one at a time, for stores
+// without BulkDeleteStore. Items deleted concurrently are skipped.
+func (h *Handler) deleteEach(r *http.Request, filter storage.DeleteFilter) ([]*registry.Item, error) {
+    matched, err := h.matchItems(r, filter)
+    if err != nil {
+        return nil, err
+    }
+
+    deleted := make([]*registry.Item, 0, len(matched))
+    for _, item := range matched {
+        if err := h.store.DeleteItemCtx(r.Context(), item.ID); err != nil {
+            h.log(r).Warn("Failed to delete item", zap.String("id", item.ID), zap.Error(err))
+            continue
+        }
+        deleted = append(deleted, item)
+    }
+    return deleted, nil
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+    "compress/gzip"
+    "compress/zlib"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// compressMinSize is the smallest response body worth compressing; smaller
+// responses are sent as-is
+const compressMinSize = 1024
+
+// compressor is implemented by gzip.Writer and zlib.Writer
+type compressor interface {
+    io.WriteCloser
+    Flush() error
+    Reset(w io.Writer)
+}
+
+// compressorPools reuse compressors per content coding
+var compressorPools = map[string]*sync.Pool{
+    "gzip": {New: func() interface{} { return gzip.NewWriter(io.Discard) }},
+    // The HTTP "deflate" coding is the zlib format
+    "deflate": {New: func() interface{} { return zlib.NewWriter(io.Discard) }},
+}
+
+// incompressibleTypes are content type prefixes that are already compressed
+// or must be streamed unbuffered
+var incompressibleTypes = []string{
+    "text/event-stream",
+    "image/png", "image/jpeg", "image/gif", "image/webp",
+    "video/", "audio/", "font/woff",
+    "application/zip", "application/gzip", "application/x-gzip",
+}
+
+// compressionMiddleware compresses response bodies of at least
+// compressMinSize bytes with gzip or deflate, as accepted by the client.
+// Responses that already carry a Content-Encoding, event streams and
+// already-compressed content types are passed through.
+func compressionMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Add("Vary", "Accept-Encoding")
+
+        encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+        if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" || r.Header.Get("Upgrade") != "" {
+            next.ServeHTTP(w, r)
+            return
+        }
+
+        cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+        defer cw.close()
+        next.ServeHTTP(cw, r)
+    })
+}
+
+// acceptedEncoding picks gzip, then deflate, from an Accept-Encoding header,
+// skipping codings the client refuses with q=0
+func acceptedEncoding(header string) string {
+    accepted := make(map[string]bool)
+    for _, part := range strings.Split(header, ",") {
+        coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        coding = strings.ToLower(strings.TrimSpace(coding))
+        if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+            if q, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64); err == nil && q == 0 {
+                continue
+            }
+        }
+        accepted[coding] = true
+    }
+
+    for _, coding := range []string{"gzip", "deflate"} {
+        if accepted[coding] {
+            return coding
+        }
+    }
+    return ""
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// body is large enough to compress, then writes the headers and either
+// compresses or passes through the rest
+type compressWriter struct {
+    http.ResponseWriter
+    encoding string
+    status   int
+    buf      []byte
+    started  bool
+    enc      compressor
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+    if cw.status == 0 {
+        cw.status = status
+    }
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+    if cw.status == 0 {
+        cw.status = http.StatusOK
+    }
+    if !cw.started {
+        if !cw.compressible() {
+            cw.start(false)
+        } else {
+            cw.buf = append(cw.buf, p...)
+            if len(cw.buf) >= compressMinSize {
+                cw.start(true)
+            }
+            return len(p), nil
+        }
+    }
+
+    if cw.enc != nil {
+        return cw.enc.Write(p)
+    }
+    return cw.ResponseWriter.Write(p)
+}
+
+// Flush commits to compressing a streamed response, whatever its size so far
+func (cw *compressWriter) Flush() {
+    if !cw.started {
+        if cw.status == 0 {
+            cw.status = http.StatusOK
+        }
+        cw.start(cw.compressible())
+    }
+    if cw.enc != nil {
+        cw.enc.Flush()
+    }
+    // The writer beneath may itself wrap the connection's writer
+    http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+    return cw.ResponseWriter
+}
+
+// compressible reports whether the response status and headers allow
+// compressing its body
+func (cw *compressWriter) compressible() bool {
+    if cw.status < http.StatusOK || cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+        return false
+    }
+    header := cw.Header()
+    if header.Get("Content-Encoding") != "" {
+        return false
+    }
+    contentType := header.Get("Content-Type")
+    for _, prefix := range incompressibleTypes {
+        if strings.HasPrefix(contentType, prefix) {
+            return false
+        }
+    }
+    return true
+}
+
+// start writes the headers and any buffered body, compressing it and the
+// rest of the response when compress is set
+func (cw *compressWriter) start(compress bool) {
+    cw.started = true
+    header := cw.Header()
+
+    // Sniff the type from the uncompressed body, as net/http would
+    if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+        header.Set("Content-Type", http.DetectContentType(cw.buf))
+    }
+    if compress {
+        header.Set("Content-Encoding", cw.encoding)
+        header.Del("Content-Length")
+        cw.enc = compressorPools[cw.encoding].Get().(compressor)
+        cw.enc.Reset(cw.ResponseWriter)
+    }
+
+    cw.ResponseWriter.WriteHeader(cw.status)
+    if len(cw.buf) > 0 {
+        if cw.enc != nil {
+            cw.enc.Write(cw.buf)
+        } else {
+            cw.ResponseWriter.Write(cw.buf)
+        }
+        cw.buf = nil
+    }
+}
+
+// close sends a response that stayed below compressMinSize and finishes the
+// compressed stream
+func (cw *compressWriter) close() {
+    if !cw.started {
+        if cw.status == 0 {
+            cw.status = http.StatusOK
+        }
+        cw.start(false)
+    }
+    if cw.enc != nil {
+        cw.enc.Close()
+        compressorPools[cw.encoding].Put(cw.enc)
+        cw.enc = nil
+    }
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+    "github.com/rs/cors"
+)
+
+// corsExposedHeaders are the response headers browsers may read from the API
+var corsExposedHeaders = []string{"X-Total-Count", "Retry-After", "X-Request-ID", "ETag"}
+
+// NewCORS builds the CORS handler for the given allowed origins, methods and
+// request headers. A request's Origin is checked against the allowed origins
+// and echoed back when it matches. Callers must not combine credentials with
+// the "*" origin, which browsers reject.
+func NewCORS(origins, methods, headers []string, credentials bool) *cors.Cors {
+    return cors.New(cors.Options{
+        AllowedOrigins:   origins,
+        AllowedMethods:   methods,
+        AllowedHeaders:   headers,
+        ExposedHeaders:   corsExposedHeaders,
+        AllowCredentials: credentials,
+    })
+}
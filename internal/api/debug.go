@@ -0,0 +1,63 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/pprof"
+    "os"
+    "runtime"
+    "strconv"
+
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/gorilla/mux"
+)
+
+// debugEndpointsFromEnv reports whether DEBUG_ENDPOINTS enables the
+// /debug/pprof/ profiles and /debug/vars
+func debugEndpointsFromEnv() (bool, error) {
+    value := os.Getenv("DEBUG_ENDPOINTS")
+    if value == "" {
+        return false, nil
+    }
+    enabled, err := strconv.ParseBool(value)
+    if err != nil {
+        return false, fmt.Errorf("invalid DEBUG_ENDPOINTS: %q", value)
+    }
+    return enabled, nil
+}
+
+// registerDebugRoutes mounts the net/http/pprof handlers under /debug/pprof/
+// and the runtime variables under /debug/vars. They are not authenticated.
+func registerDebugRoutes(r *mux.Router, h *Handler) {
+    r.HandleFunc("/debug/vars", h.DebugVars).Methods("GET")
+    r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+    r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+    r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+    r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+    // Index also serves the named profiles, such as heap and goroutine
+    r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+}
+
+// DebugVars reports the item counts, goroutine count and memory use of the
+// process
+func (h *Handler) DebugVars(w http.ResponseWriter, r *http.Request) {
+    var mem runtime.MemStats
+    runtime.ReadMemStats(&mem)
+
+    total := h.store.Count(storage.CountFilter{IncludeDeleted: true})
+    live := h.store.Count(storage.CountFilter{})
+    h.respondWithJSON(w, http.StatusOK, map[string]interface{}{
+        "items": map[string]int{
+            "total":   total,
+            "live":    live,
+            "deleted": total - live,
+        },
+        "goroutines": runtime.NumGoroutine(),
+        "memory": map[string]uint64{
+            "heapAlloc":   mem.HeapAlloc,
+            "heapObjects": mem.HeapObjects,
+            "sys":         mem.Sys,
+            "numGC":       uint64(mem.NumGC),
+        },
+    })
+}
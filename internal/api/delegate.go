@@ -0,0 +1,66 @@
+package api
+
+import (
+    "bytes"
+    "io"
+    "net/http"
+    "net/url"
+
+    "github.com/gorilla/mux"
+)
+
+// serveDelegated serves a request derived from r with handler and records the
+// response. The request keeps the caller's context and headers, such as
+// X-Actor and X-Lock-Token, but not their preconditions or Idempotency-Key;
+// header sets those it should carry. The response is always JSON, so that
+// callers can read it back.
+func serveDelegated(handler http.HandlerFunc, r *http.Request, method, target, id string, header http.Header, contentType string, body []byte) *delegatedResponse {
+    req := r.Clone(r.Context())
+    req.Method = method
+    req.URL, _ = url.Parse(target)
+    req.RequestURI = target
+    req.Body = io.NopCloser(bytes.NewReader(body))
+    req.ContentLength = int64(len(body))
+    for _, name := range []string{"If-Match", "If-None-Match", idempotencyKeyHeader, "Content-Type"} {
+        req.Header.Del(name)
+    }
+    for name, values := range header {
+        req.Header[name] = values
+    }
+    req.Header.Set("Accept", "application/json")
+    if contentType != "" {
+        req.Header.Set("Content-Type", contentType)
+    }
+    if id != "" {
+        req = mux.SetURLVars(req, map[string]string{"id": id})
+    }
+
+    rec := &delegatedResponse{header: make(http.Header), code: http.StatusOK}
+    handler(rec, req)
+    return rec
+}
+
+// delegatedResponse keeps the response of a delegated request in memory
+type delegatedResponse struct {
+    header      http.Header
+    code        int
+    wroteHeader bool
+    body        bytes.Buffer
+}
+
+func (d *delegatedResponse) Header() http.Header {
+    return d.header
+}
+
+func (d *delegatedResponse) WriteHeader(code int) {
+    if d.wroteHeader {
+        return
+    }
+    d.code = code
+    d.wroteHeader = true
+}
+
+func (d *delegatedResponse) Write(b []byte) (int, error) {
+    d.wroteHeader = true
+    return d.body.Write(b)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+    _ "embed"
+    "encoding/json"
+    "net/http"
+    "sync"
+
+    "go.uber.org/zap"
+    "gopkg.in/yaml.v3"
+)
+
+// openAPISpec is the hand-maintained OpenAPI 3 description of /api/v1. Keep
+// it in step with the routes in SetupRoutes.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// openAPIV2Spec describes /api/v2 in the same way
+//
+//go:embed openapi-v2.yaml
+var openAPIV2Spec []byte
+
+var (
+    v1Spec = &embeddedSpec{yaml: openAPISpec}
+    v2Spec = &embeddedSpec{yaml: openAPIV2Spec}
+)
+
+// embeddedSpec is an embedded YAML spec and its JSON form
+type embeddedSpec struct {
+    yaml []byte
+    once sync.Once
+    json []byte
+    err  error
+}
+
+// swaggerUIPage renders Swagger UI against /openapi.json
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Registry Service API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// JSON converts the embedded YAML spec to JSON once
+func (s *embeddedSpec) JSON() ([]byte, error) {
+    s.once.Do(func() {
+        var spec map[string]interface{}
+        if s.err = yaml.Unmarshal(s.yaml, &spec); s.err != nil {
+            return
+        }
+        s.json, s.err = json.Marshal(spec)
+    })
+    return s.json, s.err
+}
+
+func (h *Handler) ServeDocs(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    w.Write([]byte(swaggerUIPage))
+}
+
+func (h *Handler) ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+    h.serveSpec(w, r, v1Spec)
+}
+
+// ServeOpenAPIV2 returns the OpenAPI spec of /api/v2
+func (h *Handler) ServeOpenAPIV2(w http.ResponseWriter, r *http.Request) {
+    h.serveSpec(w, r, v2Spec)
+}
+
+func (h *Handler) serveSpec(w http.ResponseWriter, r *http.Request, s *embeddedSpec) {
+    spec, err := s.JSON()
+    if err != nil {
+        h.log(r).Error("Failed to load OpenAPI spec", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to load OpenAPI spec")
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.Write(spec)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// DryRunResponse is the body of a create or update sent with ?dryRun=true.
+// Item is the item as it would be stored; nothing is written.
+type DryRunResponse struct {
+    DryRun bool           `json:"dryRun"`
+    Item   *registry.Item `json:"item"`
+}
+
+// dryRun reports whether the request asks to be validated without being persisted
+func dryRun(r *http.Request) bool {
+    enabled, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+    return enabled
+}
+
+// previewUpdate fills in the fields storage maintains, as an update of
+// current would set them. A nil current previews a newly created item.
+func previewUpdate(item, current *registry.Item) {
+    now := time.Now().UTC()
+    if current == nil {
+        item.Version = 1
+        if item.CreatedAt.IsZero() {
+            item.CreatedAt = now
+        }
+        item.UpdatedAt = item.CreatedAt
+        return
+    }
+
+    item.Version = current.Version + 1
+    item.Owner = current.Owner
+    item.CreatedBy = current.CreatedBy
+    item.CreatedAt = current.CreatedAt
+    item.UpdatedAt = now
+    // Updates that omit links keep the existing ones
+    if item.Links == nil {
+        item.Links = current.Links
+    }
+}
+
+// respondDryRun answers a dry run with the previewed item
+func (h *Handler) respondDryRun(w http.ResponseWriter, item *registry.Item) {
+    h.respondWithJSON(w, http.StatusOK, DryRunResponse{DryRun: true, Item: item})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+)
+
+// Error codes identify the kind of failure in an error response. They are
+// part of the API contract and must not change once released.
+const (
+    CodeInvalidPayload       = "INVALID_PAYLOAD"
+    CodeInvalidRequest       = "INVALID_REQUEST"
+    CodeInvalidCursor        = "INVALID_CURSOR"
+    CodeValidationFailed     = "VALIDATION_FAILED"
+    CodeRegistryNameRequired = "REGISTRY_NAME_REQUIRED"
+    CodeInvalidLinkTargets   = "INVALID_LINK_TARGETS"
+    CodePatchFailed          = "PATCH_FAILED"
+    CodeItemNotFound         = "ITEM_NOT_FOUND"
+    CodeDuplicateItem        = "DUPLICATE_ITEM"
+    CodeItemExists           = "ITEM_EXISTS"
+    CodeIdempotencyKeyInUse  = "IDEMPOTENCY_KEY_IN_USE"
+    CodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+    CodeVersionConflict      = "VERSION_CONFLICT" // API v2 only; v1 answers with the current item
+    CodeItemLocked           = "ITEM_LOCKED"
+    CodeLockNotHeld          = "LOCK_NOT_HELD"
+    CodeRevisionNotFound     = "REVISION_NOT_FOUND"
+    CodeAttachmentNotFound   = "ATTACHMENT_NOT_FOUND"
+    CodeUnauthorized         = "UNAUTHORIZED"
+    CodeForbidden            = "FORBIDDEN"
+    CodeKeyOutOfScope        = "KEY_OUT_OF_SCOPE"
+    CodeNotOwner             = "NOT_OWNER"
+    CodeRateLimited          = "RATE_LIMITED"
+    CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+    CodePayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+    CodeNotImplemented       = "NOT_IMPLEMENTED"
+    CodeStorageUnavailable   = "STORAGE_UNAVAILABLE"
+    CodeRequestTimeout       = "REQUEST_TIMEOUT"
+    CodeInternal             = "INTERNAL_ERROR"
+)
+
+// ErrorResponse is the JSON body of every API error
+type ErrorResponse struct {
+    Code    string      `json:"code"`
+    Message string      `json:"message"`
+    Details interface{} `json:"details,omitempty"`
+}
+
+// writeError writes an ErrorResponse with the given HTTP status
+func writeError(w http.ResponseWriter, status int, code, message string, details interface{}) {
+    response, _ := json.Marshal(ErrorResponse{Code: code, Message: message, Details: details})
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    w.Write(response)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+)
+
+func TestErrorResponses(t *testing.T) {
+    tests := []struct {
+        name     string
+        method   string
+        target   string
+        body     interface{}
+        wantCode int
+        wantErr  string
+    }{
+        {name: "create invalid payload", method: "POST", target: "/api/v1/items", body: "{", wantCode: http.StatusBadRequest, wantErr: CodeInvalidPayload},
+        {name: "get missing item", method: "GET", target: "/api/v1/items/missing", wantCode: http.StatusNotFound, wantErr: CodeItemNotFound},
+        {name: "update invalid payload", method: "PUT", target: "/api/v1/items/svc", body: "{", wantCode: http.StatusBadRequest, wantErr: CodeInvalidPayload},
+        {name: "update without registry name", method: "PUT", target: "/api/v1/items/svc", body: item("svc", "api", ""),
+            wantCode: http.StatusUnprocessableEntity, wantErr: CodeRegistryNameRequired},
+        {name: "delete missing item", method: "DELETE", target: "/api/v1/items/missing", wantCode: http.StatusNotFound, wantErr: CodeItemNotFound},
+        {name: "list invalid sort", method: "GET", target: "/api/v1/items?sort=colour", wantCode: http.StatusBadRequest, wantErr: CodeInvalidRequest},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+            if rec := s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a")); rec.Code != http.StatusCreated {
+                t.Fatalf("create: %d %s", rec.Code, rec.Body)
+            }
+
+            rec := s.do(t, tt.method, tt.target, tt.body)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+                t.Errorf("Content-Type = %q, want application/json", ct)
+            }
+            var body map[string]interface{}
+            if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+                t.Fatalf("decode %q: %v", rec.Body, err)
+            }
+            if body["code"] != tt.wantErr {
+                t.Errorf("code = %v, want %s", body["code"], tt.wantErr)
+            }
+            if message, _ := body["message"].(string); message == "" {
+                t.Errorf("message is empty in %s", rec.Body)
+            }
+            for key := range body {
+                if key != "code" && key != "message" && key != "details" {
+                    t.Errorf("unexpected field %q in %s", key, rec.Body)
+                }
+            }
+        })
+    }
+}
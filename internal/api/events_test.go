@@ -0,0 +1,120 @@
+package api
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/Cdaprod/registry-service/pkg/plugins"
+    "go.uber.org/zap"
+)
+
+// recordingHooks is a plugins.LifecyclePlugin that reports each call as
+// "registered:<id>" or "deleted:<id>"
+type recordingHooks chan string
+
+func (h recordingHooks) OnItemRegistered(item registry.Registerable) {
+    h <- "registered:" + item.GetID()
+}
+
+func (h recordingHooks) OnItemDeleted(id string) {
+    h <- "deleted:" + id
+}
+
+// startHooks dispatches the events of s to a recordingHooks
+func startHooks(t *testing.T, s *testServer) recordingHooks {
+    t.Helper()
+    hooks := make(recordingHooks, 10)
+    dispatcher := plugins.NewLifecycleDispatcher(s.store, zap.NewNop())
+    dispatcher.Add("test", hooks)
+    dispatcher.Start(s.notifier.Broker())
+    t.Cleanup(dispatcher.Stop)
+    return hooks
+}
+
+// expectHooks fails unless hooks are called with want, in any order
+func expectHooks(t *testing.T, hooks recordingHooks, want ...string) {
+    t.Helper()
+    pending := make(map[string]bool)
+    for _, call := range want {
+        pending[call] = true
+    }
+    for len(pending) > 0 {
+        select {
+        case got := <-hooks:
+            if !pending[got] {
+                t.Errorf("unexpected hook call %s", got)
+            }
+            delete(pending, got)
+        case <-time.After(2 * time.Second):
+            t.Fatalf("missing hook calls %v", pending)
+        }
+    }
+}
+
+func TestLifecycleHooksSweep(t *testing.T) {
+    s := newTestServerWith(t, storage.NewMemoryStorageWithRetention(time.Millisecond, 10*time.Millisecond))
+    hooks := startHooks(t, s)
+    if _, err := s.store.CreateItem(&registry.Item{ID: "temp", Type: "service", Name: "temp", RegistryName: "team-a",
+        ExpiresAt: time.Now().Add(20 * time.Millisecond)}); err != nil {
+        t.Fatal(err)
+    }
+
+    // Expiry and the later retention purge both delete the item for hooks
+    expectHooks(t, hooks, "deleted:temp")
+    expectHooks(t, hooks, "deleted:temp")
+}
+
+func TestLifecycleHooksAdmin(t *testing.T) {
+    tests := []struct {
+        name   string
+        method string
+        target string
+        body   func() interface{}
+        want   []string
+    }{
+        {
+            name:   "purge deleted",
+            method: "POST",
+            target: "/api/v1/items/purge-deleted",
+            want:   []string{"deleted:b"},
+        },
+        {
+            name:   "restore snapshot",
+            method: "POST",
+            target: "/api/v1/admin/restore?mode=replace",
+            body: func() interface{} {
+                return storage.Snapshot{Format: storage.SnapshotFormat, TakenAt: time.Now(), Items: []storage.SnapshotItem{
+                    storage.NewSnapshotItem(&registry.Item{ID: "a", Type: "service", Name: "a", RegistryName: "team-a", Version: 5}),
+                    storage.NewSnapshotItem(&registry.Item{ID: "c", Type: "service", Name: "c", RegistryName: "team-a", Version: 1}),
+                }}
+            },
+            want: []string{"registered:a", "registered:c", "deleted:b"},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newAdminTestServer(t)
+            for _, id := range []string{"a", "b"} {
+                if _, err := s.store.CreateItem(&registry.Item{ID: id, Type: "service", Name: id, RegistryName: "team-a"}); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            if err := s.store.DeleteItem("b"); err != nil {
+                t.Fatal(err)
+            }
+            hooks := startHooks(t, s)
+
+            var body interface{}
+            if tt.body != nil {
+                body = tt.body()
+            }
+            if rec := s.do(t, tt.method, tt.target, body); rec.Code != http.StatusOK {
+                t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+            }
+            expectHooks(t, hooks, tt.want...)
+        })
+    }
+}
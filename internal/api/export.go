@@ -0,0 +1,129 @@
+package api
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "net/http"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "go.uber.org/zap"
+)
+
+// exportColumns are the fixed leading columns of a CSV export
+var exportColumns = []string{"id", "type", "name", "registryName", "version", "createdAt", "updatedAt"}
+
+// exportFlushEvery is the number of CSV rows written between flushes
+const exportFlushEvery = 100
+
+func (h *Handler) ExportItems(w http.ResponseWriter, r *http.Request) {
+    all, err := h.store.ListCtx(r.Context())
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+
+    items := make([]*registry.Item, 0, len(all))
+    for _, item := range all {
+        items = append(items, item.(*registry.Item))
+    }
+    sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+    switch format := r.URL.Query().Get("format"); format {
+    case "", "json":
+        h.respondWithJSON(w, http.StatusOK, items)
+    case "csv":
+        h.writeItemsCSV(w, r, items)
+    default:
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Unsupported export format "+strconv.Quote(format))
+    }
+}
+
+// writeItemsCSV streams items as CSV, with one column per top-level metadata
+// key found on any item, flushing to the client as rows are written
+func (h *Handler) writeItemsCSV(w http.ResponseWriter, r *http.Request, items []*registry.Item) {
+    keys := metadataKeys(items)
+
+    header := append([]string{}, exportColumns...)
+    for _, key := range keys {
+        header = append(header, "metadata."+key)
+    }
+
+    w.Header().Set("Content-Type", "text/csv")
+    w.Header().Set("Content-Disposition", `attachment; filename="items.csv"`)
+
+    cw := csv.NewWriter(w)
+    if err := cw.Write(header); err != nil {
+        h.log(r).Error("Failed to write CSV export", zap.Error(err))
+        return
+    }
+
+    rc := http.NewResponseController(w)
+    row := make([]string, 0, len(header))
+    for i, item := range items {
+        row = row[:0]
+        row = append(row,
+            item.ID,
+            item.Type,
+            item.Name,
+            item.RegistryName,
+            strconv.FormatInt(item.Version, 10),
+            item.CreatedAt.Format(time.RFC3339),
+            item.UpdatedAt.Format(time.RFC3339),
+        )
+        for _, key := range keys {
+            row = append(row, metadataCell(item.Metadata[key]))
+        }
+
+        if err := cw.Write(row); err != nil {
+            h.log(r).Error("Failed to write CSV export", zap.Error(err))
+            return
+        }
+        if (i+1)%exportFlushEvery == 0 {
+            cw.Flush()
+            rc.Flush()
+        }
+    }
+
+    cw.Flush()
+    if err := cw.Error(); err != nil {
+        h.log(r).Error("Failed to write CSV export", zap.Error(err))
+    }
+}
+
+// metadataKeys returns every top-level metadata key used by items, sorted so
+// the CSV header is stable
+func metadataKeys(items []*registry.Item) []string {
+    seen := make(map[string]bool)
+    for _, item := range items {
+        for key := range item.Metadata {
+            seen[key] = true
+        }
+    }
+
+    keys := make([]string, 0, len(seen))
+    for key := range seen {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// metadataCell formats a metadata value for CSV. Strings are written as-is and
+// other values, including nested objects, as JSON.
+func metadataCell(value interface{}) string {
+    switch v := value.(type) {
+    case nil:
+        return ""
+    case string:
+        return v
+    default:
+        data, err := json.Marshal(v)
+        if err != nil {
+            return ""
+        }
+        return string(data)
+    }
+}
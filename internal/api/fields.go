@@ -0,0 +1,86 @@
+package api
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "strings"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// fieldSelection is the set of top-level fields, and of metadata keys named
+// as metadata.<key>, that a ?fields= parameter keeps in item responses
+type fieldSelection struct {
+    fields   map[string]bool
+    metadata map[string]bool
+}
+
+// parseFields reads the comma-separated ?fields= parameter of r. It reports
+// false when the parameter is absent or names no field, so the whole item is
+// returned.
+func parseFields(r *http.Request) (fieldSelection, bool) {
+    s := fieldSelection{fields: make(map[string]bool), metadata: make(map[string]bool)}
+    for _, field := range strings.Split(r.URL.Query().Get("fields"), ",") {
+        field = strings.TrimSpace(field)
+        if key := strings.TrimPrefix(field, "metadata."); key != field && key != "" {
+            s.metadata[key] = true
+        } else if field != "" {
+            s.fields[field] = true
+        }
+    }
+    return s, len(s.fields) > 0 || len(s.metadata) > 0
+}
+
+// marshal encodes v as JSON and keeps only the selected fields. Fields that
+// v does not have are ignored, and a selected metadata key without a value
+// is left out of the metadata. Values that are not JSON objects are kept
+// whole.
+func (s fieldSelection) marshal(v interface{}) ([]byte, error) {
+    data, err := json.Marshal(v)
+    if err != nil {
+        return nil, err
+    }
+    var doc map[string]interface{}
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    if err := dec.Decode(&doc); err != nil {
+        return data, nil
+    }
+
+    projected := make(map[string]interface{}, len(s.fields)+1)
+    for field := range s.fields {
+        if value, ok := doc[field]; ok {
+            projected[field] = value
+        }
+    }
+    if metadata, ok := doc["metadata"].(map[string]interface{}); ok && len(s.metadata) > 0 && !s.fields["metadata"] {
+        selected := make(map[string]interface{}, len(s.metadata))
+        for key := range s.metadata {
+            if value, ok := metadata[key]; ok {
+                selected[key] = value
+            }
+        }
+        projected["metadata"] = selected
+    }
+    return json.Marshal(projected)
+}
+
+// apply wraps items so that they marshal with only the selected fields
+func (s fieldSelection) apply(items []registry.Registerable) []registry.Registerable {
+    projected := make([]registry.Registerable, len(items))
+    for i, item := range items {
+        projected[i] = projectedItem{Registerable: item, fields: s}
+    }
+    return projected
+}
+
+// projectedItem is an item that marshals with only the selected fields
+type projectedItem struct {
+    registry.Registerable
+    fields fieldSelection
+}
+
+func (p projectedItem) MarshalJSON() ([]byte, error) {
+    return p.fields.marshal(p.Registerable)
+}
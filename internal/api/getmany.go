@@ -0,0 +1,72 @@
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "go.uber.org/zap"
+)
+
+// maxGetManyIDs caps the number of IDs one bulk get may ask for
+const maxGetManyIDs = 1000
+
+// GetManyResponse holds the items a bulk get found, in the order they were
+// asked for, and the IDs it did not find
+type GetManyResponse struct {
+    Items    []*registry.Item `json:"items"`
+    NotFound []string         `json:"notFound"`
+}
+
+// GetItems returns the items whose IDs are listed in the {"ids": [...]}
+// body. Missing and deleted items are reported in notFound rather than
+// failing the request.
+func (h *Handler) GetItems(w http.ResponseWriter, r *http.Request) {
+    var req struct {
+        IDs []string `json:"ids"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        h.log(r).Error("Failed to decode request body", zap.Error(err))
+        h.respondPayloadError(w, err, "Invalid request payload")
+        return
+    }
+    if len(req.IDs) == 0 {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "ids is required")
+        return
+    }
+    if len(req.IDs) > maxGetManyIDs {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "At most "+strconv.Itoa(maxGetManyIDs)+" ids may be requested at once")
+        return
+    }
+
+    var resp GetManyResponse
+    if many, ok := h.store.(storage.GetManyStore); ok {
+        resp.Items, resp.NotFound = many.GetMany(req.IDs)
+    } else {
+        resp = h.getEach(r, req.IDs)
+    }
+
+    h.respondWithJSON(w, http.StatusOK, resp)
+}
+
+// getEach looks up ids one at a time, for stores without GetManyStore
+func (h *Handler) getEach(r *http.Request, ids []string) GetManyResponse {
+    resp := GetManyResponse{Items: []*registry.Item{}, NotFound: []string{}}
+    seen := make(map[string]bool, len(ids))
+    for _, id := range ids {
+        if seen[id] {
+            continue
+        }
+        seen[id] = true
+
+        item, err := h.store.GetItemCtx(r.Context(), id)
+        if err != nil {
+            resp.NotFound = append(resp.NotFound, id)
+            continue
+        }
+        resp.Items = append(resp.Items, item)
+    }
+    return resp
+}
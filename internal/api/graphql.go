@@ -0,0 +1,727 @@
+package api
+
+import (
+    "encoding/json"
+    "io"
+    "mime"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/graphql"
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "go.uber.org/zap"
+)
+
+// GraphQLSchema describes the GraphQL API in SDL. It is served at
+// /api/v1/graphql/schema for client code generators.
+const GraphQLSchema = `"Arbitrary JSON, used for item metadata and metadata filters"
+scalar JSON
+
+"An RFC 3339 timestamp"
+scalar Time
+
+type Query {
+  "The item with the ID, or null if there is none or it was deleted"
+  item(id: ID!): Item
+  """
+  Non-deleted items matching every given criterion, oldest first. filter maps
+  metadata keys to the expressions of the REST meta.{key} parameters, such as
+  {env: "prod", count: "gt:5"}.
+  """
+  items(type: String, registryName: String, tags: [String!], filter: JSON, limit: Int, offset: Int): [Item!]!
+}
+
+type Mutation {
+  "Creates an item, as POST /api/v1/items"
+  createItem(input: ItemInput!): Item
+  """
+  Changes the given fields of an item, as a JSON merge patch with
+  PATCH /api/v1/items/{id}. With expectedVersion, the update only applies to
+  that version.
+  """
+  updateItem(id: ID!, input: ItemInput!, expectedVersion: Int): Item
+  "Soft-deletes an item, or removes it for good with purge; admin only"
+  deleteItem(id: ID!, purge: Boolean): Boolean
+}
+
+type Item {
+  id: ID!
+  type: String!
+  name: String!
+  registryName: String!
+  owner: String
+  createdBy: String
+  updatedBy: String
+  "The metadata, or only the value of key"
+  metadata(key: String): JSON
+  tags: [String!]!
+  "The item's links, or only those of relation"
+  links(relation: String): [Link!]!
+  version: Int!
+  createdAt: Time!
+  updatedAt: Time!
+  expiresAt: Time
+  deletedAt: Time
+  deleted: Boolean!
+}
+
+type Link {
+  relation: String!
+  ids: [ID!]!
+  "The linked items that exist and are not deleted"
+  items: [Item!]!
+}
+
+input ItemInput {
+  id: ID
+  type: String
+  name: String
+  registryName: String
+  metadata: JSON
+  tags: [String!]
+  links: JSON
+  expiresAt: Time
+}
+`
+
+// maxGraphQLLinkDepth bounds how deeply links may be followed in one query,
+// since every level multiplies the items looked up
+const maxGraphQLLinkDepth = 5
+
+// GraphQLRequest is the body of a GraphQL request
+type GraphQLRequest struct {
+    Query         string                 `json:"query"`
+    OperationName string                 `json:"operationName"`
+    Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQLResponse is the body of a GraphQL response. Data is absent when the
+// request could not be executed at all.
+type GraphQLResponse struct {
+    Data   *graphql.Object  `json:"data,omitempty"`
+    Errors []*graphql.Error `json:"errors,omitempty"`
+}
+
+// GraphQLSchemaSDL serves GraphQLSchema
+func (h *Handler) GraphQLSchemaSDL(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/graphql; charset=utf-8")
+    w.Write([]byte(GraphQLSchema))
+}
+
+// GraphQL executes a GraphQL query or mutation against the store. Queries
+// may be sent with GET as the query, operationName and variables parameters,
+// mutations only with POST, as JSON or as a raw application/graphql body.
+func (h *Handler) GraphQL(w http.ResponseWriter, r *http.Request) {
+    var req GraphQLRequest
+    if r.Method == http.MethodGet {
+        query := r.URL.Query()
+        req.Query = query.Get("query")
+        req.OperationName = query.Get("operationName")
+        if variables := query.Get("variables"); variables != "" {
+            if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+                h.respondGraphQLError(w, http.StatusBadRequest, "variables must be a JSON object")
+                return
+            }
+        }
+    } else if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/graphql" {
+        query, err := io.ReadAll(r.Body)
+        if err != nil {
+            h.respondPayloadError(w, err, "Failed to read request body")
+            return
+        }
+        req.Query = string(query)
+    } else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        h.respondPayloadError(w, err, "Invalid GraphQL request")
+        return
+    }
+    if strings.TrimSpace(req.Query) == "" {
+        h.respondGraphQLError(w, http.StatusBadRequest, "query is required")
+        return
+    }
+
+    doc, err := graphql.Parse(req.Query)
+    if err != nil {
+        h.respondGraphQLErrors(w, http.StatusBadRequest, err)
+        return
+    }
+    op, err := doc.Operation(req.OperationName)
+    if err != nil {
+        h.respondGraphQLErrors(w, http.StatusBadRequest, err)
+        return
+    }
+    switch {
+    case op.Type == graphql.OperationSubscription:
+        h.respondGraphQLError(w, http.StatusBadRequest, "Subscriptions are not supported; use the /api/v1/ws event stream")
+        return
+    case op.Type == graphql.OperationMutation && r.Method == http.MethodGet:
+        w.Header().Set("Allow", http.MethodPost)
+        h.respondGraphQLError(w, http.StatusMethodNotAllowed, "Mutations must be sent with POST")
+        return
+    }
+    variables, err := graphql.CoerceVariables(op, req.Variables)
+    if err != nil {
+        h.respondGraphQLErrors(w, http.StatusBadRequest, err)
+        return
+    }
+
+    exec := &graphqlExecution{h: h, r: r, doc: doc, variables: variables}
+    data, err := exec.operation(op)
+    if err != nil {
+        h.respondGraphQLErrors(w, http.StatusBadRequest, err)
+        return
+    }
+    h.respondWithJSON(w, http.StatusOK, GraphQLResponse{Data: data, Errors: exec.errors})
+}
+
+// respondGraphQLError rejects a GraphQL request that cannot be executed
+func (h *Handler) respondGraphQLError(w http.ResponseWriter, status int, message string) {
+    h.respondGraphQLErrors(w, status, &graphql.Error{Message: message})
+}
+
+func (h *Handler) respondGraphQLErrors(w http.ResponseWriter, status int, err error) {
+    gqlErr, ok := err.(*graphql.Error)
+    if !ok {
+        gqlErr = &graphql.Error{Message: err.Error()}
+    }
+    h.respondWithJSON(w, status, GraphQLResponse{Errors: []*graphql.Error{gqlErr}})
+}
+
+// graphqlFieldError fails a single field, which resolves to null, rather
+// than the whole request. Code and status are those of the REST API.
+type graphqlFieldError struct {
+    message string
+    code    string
+    status  int
+}
+
+func (e *graphqlFieldError) Error() string {
+    return e.message
+}
+
+// graphqlExecution runs one operation. Errors returned by resolvers abort it,
+// except graphqlFieldErrors, which are collected in errors.
+type graphqlExecution struct {
+    h         *Handler
+    r         *http.Request
+    doc       *graphql.Document
+    variables map[string]interface{}
+    errors    []*graphql.Error
+}
+
+// graphqlResolver resolves one field of an object
+type graphqlResolver func(field *graphql.Field, path []interface{}) (interface{}, error)
+
+func (e *graphqlExecution) operation(op *graphql.Operation) (*graphql.Object, error) {
+    if op.Type == graphql.OperationMutation {
+        return e.object("Mutation", op.SelectionSet, nil, e.mutationField)
+    }
+    return e.object("Query", op.SelectionSet, nil, e.queryField)
+}
+
+// object resolves the fields selected on an object of typeName in order
+func (e *graphqlExecution) object(typeName string, selections []graphql.Selection, path []interface{}, resolve graphqlResolver) (*graphql.Object, error) {
+    fields, err := graphql.CollectFields(e.doc, selections, typeName, e.variables)
+    if err != nil {
+        return nil, err
+    }
+
+    result := graphql.NewObject()
+    for _, field := range fields {
+        fieldPath := graphql.Path(path, field.ResponseKey())
+        if field.Name == "__typename" {
+            result.Set(field.ResponseKey(), typeName)
+            continue
+        }
+        value, err := resolve(field, fieldPath)
+        if fieldErr, ok := err.(*graphqlFieldError); ok {
+            gqlErr := graphql.FieldError(field, fieldPath, fieldErr.message)
+            if fieldErr.status != 0 {
+                gqlErr.Extensions = map[string]interface{}{"status": fieldErr.status}
+                if fieldErr.code != "" {
+                    gqlErr.Extensions["code"] = fieldErr.code
+                }
+            }
+            e.errors = append(e.errors, gqlErr)
+            value = nil
+        } else if err != nil {
+            return nil, err
+        }
+        result.Set(field.ResponseKey(), value)
+    }
+    return result, nil
+}
+
+// unknownField rejects a field the schema does not have
+func unknownField(typeName string, field *graphql.Field) error {
+    return &graphql.Error{Message: "Cannot query field " + strconv.Quote(field.Name) + " on type " + typeName, Locations: []graphql.Location{field.Loc}}
+}
+
+// leaf rejects a selection set on a field that has none
+func leaf(field *graphql.Field, value interface{}) (interface{}, error) {
+    if len(field.SelectionSet) > 0 {
+        return nil, &graphql.Error{Message: "Field " + strconv.Quote(field.Name) + " cannot have a selection set", Locations: []graphql.Location{field.Loc}}
+    }
+    return value, nil
+}
+
+// composite requires a selection set on a field returning objects
+func composite(field *graphql.Field) error {
+    if len(field.SelectionSet) == 0 {
+        return &graphql.Error{Message: "Field " + strconv.Quote(field.Name) + " must have a selection set", Locations: []graphql.Location{field.Loc}}
+    }
+    return nil
+}
+
+func (e *graphqlExecution) queryField(field *graphql.Field, path []interface{}) (interface{}, error) {
+    switch field.Name {
+    case "item":
+        args, err := e.arguments(field, "id")
+        if err != nil {
+            return nil, err
+        }
+        id, err := stringArgument(field, args, "id", true)
+        if err != nil {
+            return nil, err
+        }
+        item, err := e.h.store.GetItemCtx(e.r.Context(), id)
+        if err != nil {
+            return nil, nil
+        }
+        return e.item(field, path, item, 0)
+    case "items":
+        return e.items(field, path)
+    }
+    return nil, unknownField("Query", field)
+}
+
+// items lists the items matching the arguments of field
+func (e *graphqlExecution) items(field *graphql.Field, path []interface{}) (interface{}, error) {
+    args, err := e.arguments(field, "type", "registryName", "tags", "filter", "limit", "offset")
+    if err != nil {
+        return nil, err
+    }
+    itemType, err := stringArgument(field, args, "type", false)
+    if err != nil {
+        return nil, err
+    }
+    registryName, err := stringArgument(field, args, "registryName", false)
+    if err != nil {
+        return nil, err
+    }
+    tags, err := stringListArgument(field, args, "tags")
+    if err != nil {
+        return nil, err
+    }
+    limit, err := intArgument(field, args, "limit")
+    if err != nil {
+        return nil, err
+    }
+    offset, err := intArgument(field, args, "offset")
+    if err != nil {
+        return nil, err
+    }
+
+    filters := make(map[string]string)
+    if raw, ok := args["filter"]; ok && raw != nil {
+        object, ok := raw.(map[string]interface{})
+        if !ok {
+            return nil, argumentError(field, "filter", "must be an object of metadata filter expressions")
+        }
+        for key, expr := range object {
+            text, ok := expr.(string)
+            if !ok {
+                return nil, argumentError(field, "filter", "expression of "+strconv.Quote(key)+" must be a string")
+            }
+            filters[key] = text
+        }
+    }
+    criteria, err := e.h.newItemCriteria(itemType, registryName, tags, filters)
+    if err != nil {
+        return nil, argumentError(field, "filter", "is invalid: "+strings.TrimPrefix(err.Error(), storage.ErrInvalidMetadataFilter.Error()+": "))
+    }
+    p := principalFrom(e.r.Context())
+    if registryName != "" && !p.allowsRegistry(registryName) {
+        return nil, &graphqlFieldError{message: "API key is not scoped to registry " + strconv.Quote(registryName), code: CodeKeyOutOfScope, status: http.StatusForbidden}
+    }
+
+    found, err := e.h.findItems(e.r.Context(), criteria)
+    if err != nil {
+        e.h.log(e.r).Error("GraphQL items scan failed", zap.Error(err))
+        return nil, &graphqlFieldError{message: "Failed to list items", code: CodeInternal, status: http.StatusInternalServerError}
+    }
+    // Without a registryName, scoped API keys only see their registries
+    var matched []*registry.Item
+    for _, item := range found {
+        if p.allowsRegistry(item.RegistryName) {
+            matched = append(matched, item)
+        }
+    }
+    if offset > len(matched) {
+        offset = len(matched)
+    }
+    matched = matched[offset:]
+    if limit > 0 && limit < len(matched) {
+        matched = matched[:limit]
+    }
+    return e.itemList(field, path, matched, 0)
+}
+
+func (e *graphqlExecution) itemList(field *graphql.Field, path []interface{}, items []*registry.Item, depth int) (interface{}, error) {
+    if err := composite(field); err != nil {
+        return nil, err
+    }
+    result := make([]interface{}, 0, len(items))
+    for i, item := range items {
+        object, err := e.object("Item", field.SelectionSet, graphql.Path(path, i), e.itemResolver(item, depth))
+        if err != nil {
+            return nil, err
+        }
+        result = append(result, object)
+    }
+    return result, nil
+}
+
+func (e *graphqlExecution) item(field *graphql.Field, path []interface{}, item *registry.Item, depth int) (interface{}, error) {
+    if err := composite(field); err != nil {
+        return nil, err
+    }
+    return e.object("Item", field.SelectionSet, path, e.itemResolver(item, depth))
+}
+
+// itemResolver resolves the fields of item, which was reached by following
+// depth levels of links
+func (e *graphqlExecution) itemResolver(item *registry.Item, depth int) graphqlResolver {
+    return func(field *graphql.Field, path []interface{}) (interface{}, error) {
+        if field.Name != "metadata" && field.Name != "links" && len(field.Arguments) > 0 {
+            return nil, &graphql.Error{Message: "Unknown argument " + strconv.Quote(field.Arguments[0].Name) + " on field " + strconv.Quote(field.Name), Locations: []graphql.Location{field.Arguments[0].Loc}}
+        }
+        switch field.Name {
+        case "id":
+            return leaf(field, item.ID)
+        case "type":
+            return leaf(field, item.Type)
+        case "name":
+            return leaf(field, item.Name)
+        case "registryName":
+            return leaf(field, item.RegistryName)
+        case "owner":
+            return leaf(field, item.Owner)
+        case "createdBy":
+            return leaf(field, item.CreatedBy)
+        case "updatedBy":
+            return leaf(field, item.UpdatedBy)
+        case "metadata":
+            args, err := e.arguments(field, "key")
+            if err != nil {
+                return nil, err
+            }
+            key, err := stringArgument(field, args, "key", false)
+            if err != nil {
+                return nil, err
+            }
+            if _, ok := args["key"]; ok {
+                return leaf(field, item.Metadata[key])
+            }
+            return leaf(field, item.Metadata)
+        case "tags":
+            tags := item.Tags
+            if tags == nil {
+                tags = []string{}
+            }
+            return leaf(field, tags)
+        case "links":
+            return e.links(field, path, item, depth)
+        case "version":
+            return leaf(field, item.Version)
+        case "createdAt":
+            return leaf(field, item.CreatedAt.UTC().Format(time.RFC3339))
+        case "updatedAt":
+            return leaf(field, item.UpdatedAt.UTC().Format(time.RFC3339))
+        case "expiresAt":
+            return leaf(field, graphqlTime(item.ExpiresAt))
+        case "deletedAt":
+            return leaf(field, graphqlTime(item.DeletedAt))
+        case "deleted":
+            return leaf(field, item.IsDeleted())
+        }
+        return nil, unknownField("Item", field)
+    }
+}
+
+// graphqlTime formats an optional timestamp, returning nil for the zero time
+func graphqlTime(t time.Time) interface{} {
+    if t.IsZero() {
+        return nil
+    }
+    return t.UTC().Format(time.RFC3339)
+}
+
+// links resolves the links of item, ordered by relation
+func (e *graphqlExecution) links(field *graphql.Field, path []interface{}, item *registry.Item, depth int) (interface{}, error) {
+    if err := composite(field); err != nil {
+        return nil, err
+    }
+    args, err := e.arguments(field, "relation")
+    if err != nil {
+        return nil, err
+    }
+    only, err := stringArgument(field, args, "relation", false)
+    if err != nil {
+        return nil, err
+    }
+
+    relations := make([]string, 0, len(item.Links))
+    for relation := range item.Links {
+        if only == "" || relation == only {
+            relations = append(relations, relation)
+        }
+    }
+    sort.Strings(relations)
+
+    result := make([]interface{}, 0, len(relations))
+    for i, relation := range relations {
+        ids := item.Links[relation]
+        object, err := e.object("Link", field.SelectionSet, graphql.Path(path, i), func(field *graphql.Field, path []interface{}) (interface{}, error) {
+            switch field.Name {
+            case "relation":
+                return leaf(field, relation)
+            case "ids":
+                return leaf(field, append([]string{}, ids...))
+            case "items":
+                if depth+1 > maxGraphQLLinkDepth {
+                    return nil, &graphqlFieldError{message: "Links may be followed at most " + strconv.Itoa(maxGraphQLLinkDepth) + " levels deep", code: CodeInvalidRequest, status: http.StatusBadRequest}
+                }
+                var targets []*registry.Item
+                for _, id := range ids {
+                    if target, err := e.h.store.GetItemCtx(e.r.Context(), id); err == nil {
+                        targets = append(targets, target)
+                    }
+                }
+                return e.itemList(field, path, targets, depth+1)
+            }
+            return nil, unknownField("Link", field)
+        })
+        if err != nil {
+            return nil, err
+        }
+        result = append(result, object)
+    }
+    return result, nil
+}
+
+func (e *graphqlExecution) mutationField(field *graphql.Field, path []interface{}) (interface{}, error) {
+    switch field.Name {
+    case "createItem":
+        args, err := e.arguments(field, "input")
+        if err != nil {
+            return nil, err
+        }
+        if err := composite(field); err != nil {
+            return nil, err
+        }
+        input, err := inputArgument(field, args)
+        if err != nil {
+            return nil, err
+        }
+        item, err := e.delegate(e.h.CreateItem, http.MethodPost, "/api/v1/items", "", nil, "application/json", input)
+        if err != nil {
+            return nil, err
+        }
+        return e.item(field, path, item, 0)
+    case "updateItem":
+        args, err := e.arguments(field, "id", "input", "expectedVersion")
+        if err != nil {
+            return nil, err
+        }
+        if err := composite(field); err != nil {
+            return nil, err
+        }
+        id, err := stringArgument(field, args, "id", true)
+        if err != nil {
+            return nil, err
+        }
+        input, err := inputArgument(field, args)
+        if err != nil {
+            return nil, err
+        }
+        header := http.Header{}
+        if _, ok := args["expectedVersion"]; ok {
+            version, err := intArgument(field, args, "expectedVersion")
+            if err != nil {
+                return nil, err
+            }
+            header.Set("If-Match", versionETag(int64(version)))
+        }
+        item, err := e.delegate(e.h.PatchItem, http.MethodPatch, "/api/v1/items/"+url.PathEscape(id), id, header, mergePatchContentType, input)
+        if err != nil {
+            return nil, err
+        }
+        return e.item(field, path, item, 0)
+    case "deleteItem":
+        args, err := e.arguments(field, "id", "purge")
+        if err != nil {
+            return nil, err
+        }
+        if _, err := leaf(field, nil); err != nil {
+            return nil, err
+        }
+        id, err := stringArgument(field, args, "id", true)
+        if err != nil {
+            return nil, err
+        }
+        purge, _ := args["purge"].(bool)
+        // The auth middleware only checks the role of DELETE requests
+        if p := principalFrom(e.r.Context()); p != nil && p.role != RoleAdmin {
+            return nil, &graphqlFieldError{message: "Admin role required", code: CodeForbidden, status: http.StatusForbidden}
+        }
+        if _, err := e.h.store.GetItemCtx(e.r.Context(), id); err != nil && !purge {
+            return nil, &graphqlFieldError{message: "Item not found", code: CodeItemNotFound, status: http.StatusNotFound}
+        }
+        target := "/api/v1/items/" + url.PathEscape(id)
+        if purge {
+            target += "?purge=true"
+        }
+        if _, err := e.delegate(e.h.DeleteItem, http.MethodDelete, target, id, nil, "", nil); err != nil {
+            return nil, err
+        }
+        return true, nil
+    }
+    return nil, unknownField("Mutation", field)
+}
+
+// delegate serves a mutation with the REST handler for the same change, so
+// that it is validated, checked, audited and announced exactly like one. It
+// returns the stored item, or nil for a response without one.
+func (e *graphqlExecution) delegate(handler http.HandlerFunc, method, target, id string, header http.Header, contentType string, body interface{}) (*registry.Item, error) {
+    var data []byte
+    if body != nil {
+        var err error
+        if data, err = json.Marshal(body); err != nil {
+            return nil, &graphqlFieldError{message: "Invalid input: " + err.Error(), code: CodeInvalidPayload, status: http.StatusBadRequest}
+        }
+    }
+
+    rec := serveDelegated(handler, e.r, method, target, id, header, contentType, data)
+
+    if rec.code >= http.StatusBadRequest {
+        var response struct {
+            ErrorResponse
+            Version int64 `json:"version"`
+        }
+        json.Unmarshal(rec.body.Bytes(), &response)
+        fieldErr := &graphqlFieldError{message: response.Message, code: response.Code, status: rec.code}
+        if fieldErr.message == "" {
+            fieldErr.message = http.StatusText(rec.code)
+        }
+        // Version conflicts answer with the current item instead of an error
+        if rec.code == http.StatusConflict && response.Code == "" {
+            fieldErr.message = "The item is at version " + strconv.FormatInt(response.Version, 10)
+        }
+        return nil, fieldErr
+    }
+    if rec.code == http.StatusNoContent {
+        return nil, nil
+    }
+
+    var created struct {
+        ID string `json:"id"`
+    }
+    if err := json.Unmarshal(rec.body.Bytes(), &created); err != nil || created.ID == "" {
+        return nil, &graphqlFieldError{message: "Unexpected response from " + method + " " + target, code: CodeInternal, status: http.StatusInternalServerError}
+    }
+    // The stored item is read back, since responses carry the fields of
+    // typed kinds at the top level instead of in the metadata
+    item, err := e.h.store.GetItemCtx(e.r.Context(), created.ID)
+    if err != nil {
+        return nil, &graphqlFieldError{message: "Item not found", code: CodeItemNotFound, status: http.StatusNotFound}
+    }
+    return item, nil
+}
+
+func (e *graphqlExecution) arguments(field *graphql.Field, allowed ...string) (map[string]interface{}, error) {
+    return graphql.Arguments(field, e.variables, allowed...)
+}
+
+// argumentError rejects the value of an argument, failing the request
+func argumentError(field *graphql.Field, name, message string) error {
+    loc := field.Loc
+    if arg := field.Argument(name); arg != nil {
+        loc = arg.Loc
+    }
+    return &graphql.Error{Message: "Argument " + strconv.Quote(name) + " of " + strconv.Quote(field.Name) + " " + message, Locations: []graphql.Location{loc}}
+}
+
+func stringArgument(field *graphql.Field, args map[string]interface{}, name string, required bool) (string, error) {
+    value, ok := args[name]
+    if !ok || value == nil {
+        if required {
+            return "", argumentError(field, name, "is required")
+        }
+        return "", nil
+    }
+    text, ok := value.(string)
+    if !ok {
+        return "", argumentError(field, name, "must be a string")
+    }
+    return text, nil
+}
+
+func stringListArgument(field *graphql.Field, args map[string]interface{}, name string) ([]string, error) {
+    value, ok := args[name]
+    if !ok || value == nil {
+        return nil, nil
+    }
+    // A single value is accepted where a list is expected, as GraphQL does
+    list, ok := value.([]interface{})
+    if !ok {
+        list = []interface{}{value}
+    }
+    result := make([]string, 0, len(list))
+    for _, elem := range list {
+        text, ok := elem.(string)
+        if !ok {
+            return nil, argumentError(field, name, "must be a list of strings")
+        }
+        result = append(result, text)
+    }
+    return result, nil
+}
+
+// intArgument reads an optional non-negative integer argument. Variables
+// decoded from JSON hold float64s.
+func intArgument(field *graphql.Field, args map[string]interface{}, name string) (int, error) {
+    switch value := args[name].(type) {
+    case nil:
+        return 0, nil
+    case int64:
+        if value >= 0 && value <= 1<<31-1 {
+            return int(value), nil
+        }
+    case float64:
+        if value >= 0 && value <= 1<<31-1 && value == float64(int(value)) {
+            return int(value), nil
+        }
+    }
+    return 0, argumentError(field, name, "must be a non-negative Int")
+}
+
+// inputArgument reads the input argument as the JSON object of an item
+func inputArgument(field *graphql.Field, args map[string]interface{}) (map[string]interface{}, error) {
+    input, ok := args["input"].(map[string]interface{})
+    if !ok {
+        return nil, argumentError(field, "input", "must be an ItemInput object")
+    }
+    for key := range input {
+        switch key {
+        case "id", "type", "name", "registryName", "metadata", "tags", "links", "expiresAt":
+        default:
+            return nil, argumentError(field, "input", "has unknown field "+strconv.Quote(key))
+        }
+    }
+    return input, nil
+}
@@ -0,0 +1,333 @@
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "reflect"
+    "testing"
+    "time"
+)
+
+// graphqlResult is a decoded GraphQL response
+type graphqlResult struct {
+    Data   map[string]interface{} `json:"data"`
+    Errors []struct {
+        Message    string                 `json:"message"`
+        Path       []interface{}          `json:"path"`
+        Extensions map[string]interface{} `json:"extensions"`
+    } `json:"errors"`
+}
+
+// graphql posts query with variables and decodes the response, which must
+// have wantCode
+func (s *testServer) graphql(t *testing.T, wantCode int, query string, variables map[string]interface{}, header ...string) graphqlResult {
+    t.Helper()
+    rec := s.do(t, "POST", "/api/v1/graphql", GraphQLRequest{Query: query, Variables: variables}, header...)
+    if rec.Code != wantCode {
+        t.Fatalf("status = %d, want %d: %s", rec.Code, wantCode, rec.Body)
+    }
+    var result graphqlResult
+    if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+        t.Fatalf("decode response: %v: %s", err, rec.Body)
+    }
+    return result
+}
+
+// fieldStatus returns the REST status of the only error of result
+func (r graphqlResult) fieldStatus(t *testing.T) int {
+    t.Helper()
+    if len(r.Errors) != 1 {
+        t.Fatalf("got %d errors, want 1: %+v", len(r.Errors), r.Errors)
+    }
+    status, _ := r.Errors[0].Extensions["status"].(float64)
+    return int(status)
+}
+
+// ids returns the IDs of the items of a list field
+func ids(t *testing.T, value interface{}) []string {
+    t.Helper()
+    list, ok := value.([]interface{})
+    if !ok {
+        t.Fatalf("value = %#v, want a list", value)
+    }
+    result := []string{}
+    for _, elem := range list {
+        result = append(result, elem.(map[string]interface{})["id"].(string))
+    }
+    return result
+}
+
+// seedGraphQLItems creates items of two types in two registries
+func seedGraphQLItems(t *testing.T, s *testServer) {
+    t.Helper()
+    items := []map[string]interface{}{
+        {"id": "api", "type": "service", "name": "api", "registryName": "team-a", "tags": []string{"go"},
+            "metadata": map[string]interface{}{"env": "prod", "replicas": 6}},
+        {"id": "web", "type": "service", "name": "web", "registryName": "team-a",
+            "metadata": map[string]interface{}{"env": "dev", "replicas": 2}},
+        {"id": "db", "type": "database", "name": "db", "registryName": "team-a", "tags": []string{"go"},
+            "metadata": map[string]interface{}{"env": "prod"}},
+        {"id": "worker", "type": "service", "name": "worker", "registryName": "team-b",
+            "metadata": map[string]interface{}{"env": "prod", "replicas": 3}},
+    }
+    for _, it := range items {
+        if rec := s.do(t, "POST", "/api/v1/items", it); rec.Code != http.StatusCreated {
+            t.Fatalf("create %s: %d %s", it["id"], rec.Code, rec.Body)
+        }
+        // Items are listed oldest first
+        time.Sleep(time.Millisecond)
+    }
+}
+
+func TestGraphQLItem(t *testing.T) {
+    s := newTestServer(t)
+    seedGraphQLItems(t, s)
+
+    tests := []struct {
+        name string
+        id   string
+        want interface{}
+    }{
+        {name: "existing item", id: "api", want: map[string]interface{}{
+            "id": "api", "kind": "service", "registryName": "team-a", "env": "prod", "tags": []interface{}{"go"}, "version": 1.0,
+        }},
+        {name: "unknown item", id: "missing", want: nil},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result := s.graphql(t, http.StatusOK, `query($id: ID!) {
+                item(id: $id) { id kind: type registryName env: metadata(key: "env") tags version }
+            }`, map[string]interface{}{"id": tt.id})
+            if len(result.Errors) > 0 {
+                t.Fatalf("errors = %+v", result.Errors)
+            }
+            if !reflect.DeepEqual(result.Data["item"], tt.want) {
+                t.Errorf("item = %#v, want %#v", result.Data["item"], tt.want)
+            }
+        })
+    }
+}
+
+func TestGraphQLItems(t *testing.T) {
+    s := newTestServer(t)
+    seedGraphQLItems(t, s)
+
+    tests := []struct {
+        name    string
+        args    string
+        want    []string
+        wantErr bool
+    }{
+        {name: "all", args: ``, want: []string{"api", "web", "db", "worker"}},
+        {name: "type", args: `(type: "service")`, want: []string{"api", "web", "worker"}},
+        {name: "registryName", args: `(registryName: "team-a")`, want: []string{"api", "web", "db"}},
+        {name: "type and registryName", args: `(type: "service", registryName: "team-b")`, want: []string{"worker"}},
+        {name: "tags", args: `(tags: ["go"])`, want: []string{"api", "db"}},
+        {name: "filter", args: `(filter: {env: "prod"})`, want: []string{"api", "db", "worker"}},
+        {name: "filter expression", args: `(type: "service", filter: {env: "prod", replicas: "gt:4"})`, want: []string{"api"}},
+        {name: "limit and offset", args: `(limit: 2, offset: 1)`, want: []string{"web", "db"}},
+        {name: "offset past the end", args: `(offset: 10)`, want: []string{}},
+        {name: "no match", args: `(registryName: "team-c")`, want: []string{}},
+        {name: "filter expression not a string", args: `(filter: {replicas: 4})`, wantErr: true},
+        {name: "negative limit", args: `(limit: -1)`, wantErr: true},
+        {name: "unknown argument", args: `(owner: "me")`, wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            query := `{ items` + tt.args + ` { id } }`
+            if tt.wantErr {
+                result := s.graphql(t, http.StatusBadRequest, query, nil)
+                if len(result.Errors) != 1 || result.Data != nil {
+                    t.Errorf("response = %+v, want one error and no data", result)
+                }
+                return
+            }
+            result := s.graphql(t, http.StatusOK, query, nil)
+            if len(result.Errors) > 0 {
+                t.Fatalf("errors = %+v", result.Errors)
+            }
+            if got := ids(t, result.Data["items"]); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("items = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestGraphQLMutations(t *testing.T) {
+    s := newTestServer(t)
+    const fields = `{ id name version metadata }`
+
+    result := s.graphql(t, http.StatusOK, `mutation($input: ItemInput!) { createItem(input: $input) `+fields+` }`,
+        map[string]interface{}{"input": map[string]interface{}{
+            "id": "api", "type": "service", "name": "api", "registryName": "team-a", "metadata": map[string]interface{}{"env": "dev"},
+        }})
+    want := map[string]interface{}{"id": "api", "name": "api", "version": 1.0, "metadata": map[string]interface{}{"env": "dev"}}
+    if len(result.Errors) > 0 || !reflect.DeepEqual(result.Data["createItem"], want) {
+        t.Fatalf("createItem = %#v, errors %+v", result.Data["createItem"], result.Errors)
+    }
+    if _, err := s.store.GetItem("api"); err != nil {
+        t.Fatalf("created item was not stored: %v", err)
+    }
+
+    // The same ID cannot be taken twice
+    result = s.graphql(t, http.StatusOK, `mutation { createItem(input: {id: "api", type: "service", name: "api", registryName: "team-a"}) { id } }`, nil)
+    if status := result.fieldStatus(t); status != http.StatusConflict || result.Data["createItem"] != nil {
+        t.Errorf("duplicate createItem = %#v with status %d, want null with %d", result.Data["createItem"], status, http.StatusConflict)
+    }
+
+    tests := []struct {
+        name       string
+        query      string
+        want       interface{}
+        wantStatus int
+    }{
+        {name: "update", query: `mutation { updateItem(id: "api", input: {name: "api-v2", metadata: {replicas: 3}}) ` + fields + ` }`,
+            want: map[string]interface{}{"id": "api", "name": "api-v2", "version": 2.0,
+                "metadata": map[string]interface{}{"env": "dev", "replicas": 3.0}}},
+        {name: "update at expected version", query: `mutation { updateItem(id: "api", input: {name: "api-v3"}, expectedVersion: 2) { version } }`,
+            want: map[string]interface{}{"version": 3.0}},
+        {name: "update at stale version", query: `mutation { updateItem(id: "api", input: {name: "api-v4"}, expectedVersion: 2) { version } }`,
+            wantStatus: http.StatusConflict},
+        {name: "update unknown item", query: `mutation { updateItem(id: "missing", input: {name: "x"}) { id } }`,
+            wantStatus: http.StatusNotFound},
+        {name: "delete", query: `mutation { deleteItem(id: "api") }`, want: true},
+        {name: "delete unknown item", query: `mutation { deleteItem(id: "missing") }`, wantStatus: http.StatusNotFound},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result := s.graphql(t, http.StatusOK, tt.query, nil)
+            var got interface{}
+            for _, value := range result.Data {
+                got = value
+            }
+            if tt.wantStatus != 0 {
+                if status := result.fieldStatus(t); status != tt.wantStatus || got != nil {
+                    t.Errorf("result = %#v with status %d, want null with %d", got, status, tt.wantStatus)
+                }
+                return
+            }
+            if len(result.Errors) > 0 {
+                t.Fatalf("errors = %+v", result.Errors)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("result = %#v, want %#v", got, tt.want)
+            }
+        })
+    }
+
+    stored, err := s.store.GetItem("api")
+    if err == nil && !stored.IsDeleted() {
+        t.Errorf("deleted item is still stored: %+v", stored)
+    }
+}
+
+func TestGraphQLDeleteRequiresAdmin(t *testing.T) {
+    t.Setenv("JWT_SECRET", testJWTSecret)
+    s := newTestServer(t)
+    if rec := s.do(t, "POST", "/api/v1/items", item("api", "api", "team-a"), "Authorization", "Bearer "+signToken(t, testJWTSecret, "editor", time.Hour)); rec.Code != http.StatusCreated {
+        t.Fatalf("create: %d %s", rec.Code, rec.Body)
+    }
+
+    tests := []struct {
+        name       string
+        role       string
+        want       interface{}
+        wantStatus int
+    }{
+        {name: "editor", role: "editor", wantStatus: http.StatusForbidden},
+        {name: "admin", role: RoleAdmin, want: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result := s.graphql(t, http.StatusOK, `mutation { deleteItem(id: "api", purge: true) }`, nil,
+                "Authorization", "Bearer "+signToken(t, testJWTSecret, tt.role, time.Hour))
+            if tt.wantStatus != 0 {
+                if status := result.fieldStatus(t); status != tt.wantStatus {
+                    t.Errorf("status = %d, want %d", status, tt.wantStatus)
+                }
+                if _, err := s.store.GetItem("api"); err != nil {
+                    t.Errorf("item was deleted: %v", err)
+                }
+                return
+            }
+            if len(result.Errors) > 0 || result.Data["deleteItem"] != tt.want {
+                t.Errorf("deleteItem = %#v, errors %+v", result.Data["deleteItem"], result.Errors)
+            }
+            if _, err := s.store.GetItem("api"); err == nil {
+                t.Errorf("item was not purged")
+            }
+        })
+    }
+}
+
+func TestGraphQLMutationOverGET(t *testing.T) {
+    s := newTestServer(t)
+    seedGraphQLItems(t, s)
+
+    tests := []struct {
+        name     string
+        query    string
+        wantCode int
+    }{
+        {name: "query", query: `{ item(id: "api") { name } }`, wantCode: http.StatusOK},
+        {name: "create", query: `mutation { createItem(input: {id: "new", type: "service", name: "new", registryName: "team-a"}) { id } }`,
+            wantCode: http.StatusMethodNotAllowed},
+        {name: "delete", query: `mutation { deleteItem(id: "api") }`, wantCode: http.StatusMethodNotAllowed},
+        {name: "named mutation", query: `query Read { item(id: "api") { id } } mutation Write { deleteItem(id: "api") }`,
+            wantCode: http.StatusMethodNotAllowed},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            target := "/api/v1/graphql?query=" + url.QueryEscape(tt.query)
+            if tt.name == "named mutation" {
+                target += "&operationName=Write"
+            }
+            rec := s.do(t, "GET", target, nil)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if tt.wantCode == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != http.MethodPost {
+                t.Errorf("Allow = %q, want POST", rec.Header().Get("Allow"))
+            }
+        })
+    }
+
+    if _, err := s.store.GetItem("new"); err == nil {
+        t.Errorf("item was created over GET")
+    }
+    if stored, err := s.store.GetItem("api"); err != nil || stored.IsDeleted() {
+        t.Errorf("item was deleted over GET")
+    }
+}
+
+func TestGraphQLScopedKey(t *testing.T) {
+    s := newAdminTestServer(t)
+    seedGraphQLItems(t, s)
+    rec := s.do(t, "POST", "/api/v1/keys", map[string]interface{}{"registries": []string{"team-b"}})
+    if rec.Code != http.StatusCreated {
+        t.Fatalf("create key: %d %s", rec.Code, rec.Body)
+    }
+    var created struct {
+        Key string `json:"key"`
+    }
+    if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+        t.Fatal(err)
+    }
+    s.apiKey = created.Key
+
+    result := s.graphql(t, http.StatusOK, `{ items { id } }`, nil)
+    if got := ids(t, result.Data["items"]); !reflect.DeepEqual(got, []string{"worker"}) {
+        t.Errorf("items = %v, want [worker]", got)
+    }
+
+    result = s.graphql(t, http.StatusOK, `{ items(registryName: "team-a") { id } }`, nil)
+    if status := result.fieldStatus(t); status != http.StatusForbidden || result.Data["items"] != nil {
+        t.Errorf("out of scope items = %#v with status %d, want null with %d", result.Data["items"], status, http.StatusForbidden)
+    }
+
+    result = s.graphql(t, http.StatusOK, `mutation { createItem(input: {id: "new", type: "service", name: "new", registryName: "team-a"}) { id } }`, nil)
+    if status := result.fieldStatus(t); status != http.StatusForbidden {
+        t.Errorf("out of scope createItem status = %d, want %d", status, http.StatusForbidden)
+    }
+}
@@ -1,25 +1,62 @@
 package api
 
 import (
+    "context"
     "encoding/json"
+    "errors"
+    "fmt"
     "net/http"
+    "net/url"
     "strconv"
+    "strings"
+    "time"
 
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
     "github.com/Cdaprod/registry-service/internal/registry"
     "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/Cdaprod/registry-service/pkg/plugins"
+    "github.com/google/uuid"
     "github.com/gorilla/mux"
     "go.uber.org/zap"
 )
 
+// readinessTimeout bounds how long ReadinessCheck waits on the storage backend
+const readinessTimeout = 2 * time.Second
+
 type Handler struct {
-    store  *storage.MemoryStorage
-    logger *zap.Logger
+    store         storage.Store
+    logger        *zap.Logger
+    notifier      *notify.Notifier
+    metrics       *Metrics
+    keys          storage.KeyStore
+    auditLog      *audit.Logger
+    // reloader is nil when the plugin loader cannot reload
+    reloader      PluginReloader
+    types         TypePolicy
+    // idempotency is nil when Idempotency-Key is not supported
+    idempotency   *IdempotencyStore
+    blobs         storage.BlobStore
+    blobLimit     int64
+    pageSize      int
+    maxPageSize   int
+    // metadataLimit caps the metadata size of items; 0 sets no limit
+    metadataLimit int
 }
 
-func NewHandler(store *storage.MemoryStorage, logger *zap.Logger) *Handler {
+func NewHandler(store storage.Store, logger *zap.Logger, notifier *notify.Notifier, metrics *Metrics, keys storage.KeyStore, auditLog *audit.Logger, reloader PluginReloader) *Handler {
     return &Handler{
-        store:  store,
-        logger: logger,
+        store:       store,
+        logger:      logger,
+        notifier:    notifier,
+        metrics:     metrics,
+        keys:        keys,
+        auditLog:    auditLog,
+        reloader:    reloader,
+        blobs:       storage.NewMemoryBlobStore(),
+        blobLimit:   DefaultMaxAttachmentBytes,
+        pageSize:    DefaultPageSize,
+        maxPageSize: DefaultMaxPageSize,
     }
 }
 
@@ -27,109 +64,1035 @@ func (h *Handler) HomeHandler(w http.ResponseWriter, r *http.Request) {
     w.Write([]byte("Welcome to the Registry Service!"))
 }
 
+// HealthCheck reports that the process is up, without checking its dependencies
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
     w.WriteHeader(http.StatusOK)
     w.Write([]byte("OK"))
 }
 
-func (h *Handler) ServeDocs(w http.ResponseWriter, r *http.Request) {
-    // Implement API documentation serving (e.g., Swagger UI)
-    w.Write([]byte("API Documentation (To be implemented)"))
-}
+// ReadinessCheck reports whether the storage backend can serve requests, so
+// load balancers stop routing to an instance that cannot reach it
+func (h *Handler) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
+    ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+    defer cancel()
+
+    if err := h.store.Ping(ctx); err != nil {
+        h.log(r).Warn("Storage is not ready", zap.Error(err))
+        h.respondWithError(w, http.StatusServiceUnavailable, CodeStorageUnavailable, "Storage is unreachable: "+err.Error())
+        return
+    }
 
+    h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
 
 func (h *Handler) CreateItem(w http.ResponseWriter, r *http.Request) {
-    var item registry.Item
-    if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
-        h.logger.Error("Failed to decode request body", zap.Error(err))
-        http.Error(w, "Invalid request payload", http.StatusBadRequest)
+    item, ok := h.decodeItem(w, r)
+    if !ok {
+        return
+    }
+
+    if err := item.Validate(); err != nil {
+        h.log(r).Info("Rejected invalid item", zap.Error(err))
+        h.respondWithErrorDetails(w, http.StatusUnprocessableEntity, CodeValidationFailed, "Item is missing required fields",
+            map[string]interface{}{"fields": err.(*registry.ValidationError).Fields})
+        return
+    }
+    if !h.checkType(w, item) || h.metadataTooLarge(w, item) {
         return
     }
 
-    createdItem, err := h.store.CreateItem(&item)
+    if h.outOfScope(w, r, item.RegistryName) {
+        return
+    }
+
+    // Items without an ID are assigned one, as in CreateItems
+    if item.ID == "" {
+        item.ID = uuid.New().String()
+    }
+    stampOwner(r, item)
+    stampCreatedBy(r, item)
+
+    if existing, found := h.findDuplicate(item); found {
+        if upsert(r) {
+            h.upsertItem(w, r, item, existing)
+        } else {
+            h.respondDuplicate(w, item)
+        }
+        return
+    }
+
+    // Creates never overwrite a stored item; PUT replaces items. If-None-Match: *
+    // asks for this explicitly and fails with 412 instead of 409.
+    status := http.StatusConflict
+    if r.Header.Get("If-None-Match") == "*" {
+        status = http.StatusPreconditionFailed
+    }
+
+    if dryRun(r) {
+        if h.storedVersion(r, item.ID) != 0 {
+            h.respondItemExists(w, status, item.ID)
+            return
+        }
+        previewUpdate(item, nil)
+        h.respondDryRun(w, item)
+        return
+    }
+
+    createdItem, err := h.createIfAbsent(r, item)
+    if err == errItemExists {
+        h.respondItemExists(w, status, item.ID)
+        return
+    }
+    if err == storage.ErrDuplicateItem {
+        h.respondDuplicate(w, item)
+        return
+    }
+    if err == registry.ErrRegistryNameRequired {
+        h.respondWithError(w, http.StatusUnprocessableEntity, CodeRegistryNameRequired, "Registry name is required")
+        return
+    }
     if err != nil {
-        h.logger.Error("Failed to create item", zap.Error(err))
-        http.Error(w, "Failed to create item", http.StatusInternalServerError)
+        h.log(r).Error("Failed to create item", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to create item")
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(http.StatusCreated)
-    json.NewEncoder(w).Encode(createdItem)
+    setItemAttributes(r.Context(), createdItem)
+    h.metrics.itemCreated(createdItem)
+    h.notifier.Notify(notify.EventItemCreated, createdItem.ID, createdItem.Type, createdItem.RegistryName)
+    h.recordAudit(r, audit.ActionCreate, createdItem.ID, 0, createdItem.Version)
+
+    h.respondWithItem(w, r, http.StatusCreated, createdItem)
+}
+
+// errItemExists is returned by createIfAbsent when the ID is taken
+var errItemExists = errors.New("item already exists")
+
+// createIfAbsent creates item unless a stored item already has its ID. Stores
+// with compare-and-swap check and create atomically, and also count
+// soft-deleted items as taking their ID.
+func (h *Handler) createIfAbsent(r *http.Request, item *registry.Item) (*registry.Item, error) {
+    if cas, ok := h.store.(storage.CASStore); ok {
+        created, err := cas.CompareAndSwap(item.ID, 0, item)
+        if err == storage.ErrVersionConflict {
+            return nil, errItemExists
+        }
+        return created, err
+    }
+    if h.storedVersion(r, item.ID) != 0 {
+        return nil, errItemExists
+    }
+    return h.store.CreateItemCtx(r.Context(), item)
+}
+
+// respondItemExists rejects a create for a taken ID with status
+func (h *Handler) respondItemExists(w http.ResponseWriter, status int, id string) {
+    h.respondWithError(w, status, CodeItemExists, "An item with ID "+strconv.Quote(id)+" already exists")
+}
+
+// createItems stores items with CreateItems, except those whose ID is taken
+// by a stored item or an earlier item of the batch, which fail instead of
+// overwriting it
+func (h *Handler) createItems(r *http.Request, items []*registry.Item) []storage.BatchResult {
+    results := make([]storage.BatchResult, len(items))
+    var fresh []*registry.Item
+    var indexes []int
+    seen := make(map[string]bool)
+    for i, item := range items {
+        if item != nil && item.ID != "" {
+            if seen[item.ID] || h.storedVersion(r, item.ID) != 0 {
+                results[i] = storage.BatchResult{Index: i, ID: item.ID, Error: errItemExists.Error()}
+                continue
+            }
+            seen[item.ID] = true
+        }
+        fresh = append(fresh, item)
+        indexes = append(indexes, i)
+    }
+
+    for j, result := range h.store.CreateItems(fresh) {
+        result.Index = indexes[j]
+        results[indexes[j]] = result
+    }
+    return results
+}
+
+func (h *Handler) CreateItems(w http.ResponseWriter, r *http.Request) {
+    var items []*registry.Item
+    if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+        h.log(r).Error("Failed to decode request body", zap.Error(err))
+        h.respondPayloadError(w, err, "Invalid request payload")
+        return
+    }
+
+    var typeErrors, sizeErrors []registry.FieldError
+    for i, item := range items {
+        if item == nil {
+            continue
+        }
+        if h.outOfScope(w, r, item.RegistryName) {
+            return
+        }
+        if fieldErr := h.types.check(item); fieldErr != nil {
+            fieldErr.Field = "[" + strconv.Itoa(i) + "]." + fieldErr.Field
+            typeErrors = append(typeErrors, *fieldErr)
+        }
+        if fieldErr := h.checkMetadataSize(item); fieldErr != nil {
+            fieldErr.Field = "[" + strconv.Itoa(i) + "]." + fieldErr.Field
+            sizeErrors = append(sizeErrors, *fieldErr)
+        }
+        stampOwner(r, item)
+        stampCreatedBy(r, item)
+    }
+    if len(typeErrors) > 0 {
+        h.respondWithErrorDetails(w, http.StatusUnprocessableEntity, CodeValidationFailed, "Some item types are not allowed",
+            map[string]interface{}{"fields": typeErrors})
+        return
+    }
+    if len(sizeErrors) > 0 {
+        h.respondWithErrorDetails(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "Some items have metadata over the size limit",
+            map[string]interface{}{"fields": sizeErrors})
+        return
+    }
+
+    results := h.createItems(r, items)
+    for i, result := range results {
+        if result.Success {
+            h.metrics.itemCreated(items[i])
+            h.notifier.Notify(notify.EventItemCreated, result.ID, items[i].Type, items[i].RegistryName)
+            h.recordAudit(r, audit.ActionCreate, result.ID, 0, items[i].Version)
+        }
+    }
+
+    h.respondWithJSON(w, http.StatusOK, results)
 }
 
 func (h *Handler) GetItem(w http.ResponseWriter, r *http.Request) {
     params := mux.Vars(r)
     id := params["id"]
 
-    item, err := h.store.GetItem(id)
+    item, err := h.store.GetItemCtx(r.Context(), id)
     if err != nil {
-        h.logger.Error("Failed to get item", zap.Error(err))
-        http.Error(w, "Item not found", http.StatusNotFound)
+        h.log(r).Error("Failed to get item", zap.Error(err))
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(item)
+    setItemAttributes(r.Context(), item)
+    w.Header().Add("Vary", "Accept")
+
+    // The version identifies the item's state, so clients polling an
+    // unchanged item get 304 without a body. If-Modified-Since is only
+    // consulted without If-None-Match, as RFC 9110 requires.
+    w.Header().Set("ETag", weakVersionETag(item.Version))
+    if !item.UpdatedAt.IsZero() {
+        w.Header().Set("Last-Modified", item.UpdatedAt.UTC().Format(http.TimeFormat))
+    }
+    if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+        if etagListMatches(ifNoneMatch, item.Version) {
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+    } else if notModifiedSince(r.Header.Get("If-Modified-Since"), item.UpdatedAt) {
+        w.WriteHeader(http.StatusNotModified)
+        return
+    }
+
+    if fields, ok := parseFields(r); ok {
+        body, err := fields.marshal(h.itemPayload(r, item))
+        if err != nil {
+            h.log(r).Error("Failed to encode item", zap.Error(err))
+            h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode item")
+            return
+        }
+        h.respondNegotiated(w, r, http.StatusOK, json.RawMessage(body))
+        return
+    }
+    h.respondWithItem(w, r, http.StatusOK, item)
 }
 
 func (h *Handler) UpdateItem(w http.ResponseWriter, r *http.Request) {
     params := mux.Vars(r)
     id := params["id"]
 
-    var item registry.Item
-    if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
-        h.logger.Error("Failed to decode request body", zap.Error(err))
-        http.Error(w, "Invalid request payload", http.StatusBadRequest)
+    item, ok := h.decodeItem(w, r)
+    if !ok {
         return
     }
 
     item.ID = id
-    updatedItem, err := h.store.UpdateItem(&item)
+    if !h.checkType(w, item) || h.metadataTooLarge(w, item) {
+        return
+    }
+
+    // Scoped API keys may neither edit nor move items outside their registries
+    if p := principalFrom(r.Context()); p != nil && p.apiKey != nil {
+        current, err := h.store.GetItemCtx(r.Context(), id)
+        if err == nil && h.outOfScope(w, r, current.RegistryName) {
+            return
+        }
+        if h.outOfScope(w, r, item.RegistryName) {
+            return
+        }
+    }
+
+    if h.locked(w, r, id) {
+        return
+    }
+
+    // Updates keep the owner, which only applies when the PUT creates the item
+    if p := principalFrom(r.Context()); p != nil && p.ownerOnly {
+        if current, err := h.store.GetItemCtx(r.Context(), id); err == nil && h.notOwner(w, r, current) {
+            return
+        }
+    }
+    stampOwner(r, item)
+    stampCreatedBy(r, item)
+
+    // ?expectedVersion=N and If-Match both make the update conditional on
+    // the stored version
+    var expected int64
+    conditional := false
+    if value := r.URL.Query().Get("expectedVersion"); value != "" {
+        version, err := strconv.ParseInt(value, 10, 64)
+        if err != nil || version < 0 {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid expectedVersion")
+            return
+        }
+        expected, conditional = version, true
+    } else if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+        version, err := parseVersionETag(ifMatch)
+        if err != nil {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid If-Match header")
+            return
+        }
+        expected, conditional = version, true
+    }
+
+    // Stores with compare-and-swap check the version atomically with the
+    // write; others are checked here first
+    cas, atomic := h.store.(storage.CASStore)
+    atomic = atomic && conditional && !dryRun(r)
+    if conditional && !atomic {
+        current, err := h.store.GetItemCtx(r.Context(), id)
+        switch {
+        case err != nil && expected != 0:
+            h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+            return
+        case err == nil && current.Version != expected:
+            w.Header().Set("ETag", versionETag(current.Version))
+            h.respondWithJSON(w, http.StatusConflict, current)
+            return
+        case err == nil:
+            item.Version = current.Version + 1
+        }
+    }
+
+    if dryRun(r) {
+        if item.RegistryName == "" {
+            h.respondWithError(w, http.StatusUnprocessableEntity, CodeRegistryNameRequired, "Registry name is required")
+            return
+        }
+        // PUT stores the item even when the ID is new, so a missing item is
+        // previewed as created
+        current, _ := h.store.GetItemCtx(r.Context(), id)
+        previewUpdate(item, current)
+        h.respondDryRun(w, item)
+        return
+    }
+
+    // The audit trail records the version this update replaces
+    before := expected
+    if !atomic {
+        before = h.storedVersion(r, id)
+    }
+
+    var updatedItem *registry.Item
+    var err error
+    if atomic {
+        updatedItem, err = cas.CompareAndSwap(id, expected, item)
+    } else {
+        updatedItem, err = h.store.UpdateItemCtx(r.Context(), item)
+    }
+    // Backends that drop updates not newer than the stored version report
+    // that as ErrStaleVersion, which is a conflict like a failed CAS
+    if err == storage.ErrVersionConflict || err == registry.ErrStaleVersion {
+        h.respondVersionConflict(w, r, id)
+        return
+    }
+    if err == storage.ErrDuplicateItem {
+        h.respondDuplicate(w, item)
+        return
+    }
+    if err == registry.ErrRegistryNameRequired {
+        h.respondWithError(w, http.StatusUnprocessableEntity, CodeRegistryNameRequired, "Registry name is required")
+        return
+    }
     if err != nil {
-        h.logger.Error("Failed to update item", zap.Error(err))
-        http.Error(w, "Failed to update item", http.StatusInternalServerError)
+        h.log(r).Error("Failed to update item", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to update item")
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(updatedItem)
+    setItemAttributes(r.Context(), updatedItem)
+    h.metrics.itemUpdated(updatedItem)
+    h.notifier.Notify(notify.EventItemUpdated, updatedItem.ID, updatedItem.Type, updatedItem.RegistryName)
+    action := audit.ActionUpdate
+    if before == 0 {
+        action = audit.ActionCreate
+    }
+    h.recordAudit(r, action, updatedItem.ID, before, updatedItem.Version)
+
+    w.Header().Set("ETag", versionETag(updatedItem.Version))
+    h.respondWithItem(w, r, http.StatusOK, updatedItem)
 }
 
 func (h *Handler) DeleteItem(w http.ResponseWriter, r *http.Request) {
     params := mux.Vars(r)
     id := params["id"]
 
-    if err := h.store.DeleteItem(id); err != nil {
-        h.logger.Error("Failed to delete item", zap.Error(err))
-        http.Error(w, "Failed to delete item", http.StatusInternalServerError)
+    if h.locked(w, r, id) {
+        return
+    }
+
+    var itemType, registryName string
+    var version int64
+    item, lookupErr := h.store.GetItemCtx(r.Context(), id)
+    if lookupErr == nil {
+        itemType = item.Type
+        registryName = item.RegistryName
+        version = item.Version
+        setItemAttributes(r.Context(), item)
+    }
+
+    if purge, _ := strconv.ParseBool(r.URL.Query().Get("purge")); purge {
+        h.purgeItem(w, r, id, itemType, registryName, version)
         return
     }
 
+    // Soft-deleted items are not found above but may be deleted again, so
+    // only a failed delete of an item that was not found is a 404
+    if err := h.store.DeleteItemCtx(r.Context(), id); err != nil {
+        if lookupErr != nil {
+            h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+            return
+        }
+        h.log(r).Error("Failed to delete item", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete item")
+        return
+    }
+
+    h.dropAttachment(r, id)
+    h.metrics.itemsDeleted.Inc()
+    h.notifier.Notify(notify.EventItemDeleted, id, itemType, registryName)
+    // Soft deletes keep the version
+    h.recordAudit(r, audit.ActionDelete, id, version, version)
+
     w.WriteHeader(http.StatusNoContent)
 }
 
+// purgeItem permanently removes an item, whether or not it was soft-deleted
+func (h *Handler) purgeItem(w http.ResponseWriter, r *http.Request, id, itemType, registryName string, version int64) {
+    if err := h.store.PurgeItem(id); err != nil {
+        h.log(r).Error("Failed to purge item", zap.Error(err))
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+
+    h.dropAttachment(r, id)
+    h.metrics.itemsDeleted.Inc()
+    h.notifier.Notify(notify.EventItemPurged, id, itemType, registryName)
+    h.recordAudit(r, audit.ActionPurge, id, version, 0)
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PurgeDeletedItems(w http.ResponseWriter, r *http.Request) {
+    // Only DELETE requests are checked for the admin role by the auth middleware
+    if p := principalFrom(r.Context()); p != nil && p.role != RoleAdmin {
+        h.respondWithError(w, http.StatusForbidden, CodeForbidden, "Admin role required")
+        return
+    }
+
+    purged, err := h.purgeDeleted(r)
+    if err != nil {
+        h.log(r).Error("Failed to purge deleted items", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to purge deleted items")
+        return
+    }
+    for _, item := range purged {
+        h.notifier.Notify(notify.EventItemPurged, item.ID, item.Type, item.RegistryName)
+        h.recordAudit(r, audit.ActionPurge, item.ID, item.Version, 0)
+    }
+
+    h.log(r).Info("Purged deleted items", zap.Int("count", len(purged)))
+    h.respondWithJSON(w, http.StatusOK, map[string]int{"purged": len(purged)})
+}
+
+// purgeDeleted purges every soft-deleted item and returns them. Stores that
+// cannot report the purged items have their deleted items listed first, so
+// an item deleted while the purge runs may be missing from the result.
+func (h *Handler) purgeDeleted(r *http.Request) ([]*registry.Item, error) {
+    if store, ok := h.store.(storage.DeletedPurger); ok {
+        return store.PurgeDeletedItems()
+    }
+
+    items, err := h.store.ListIncludingDeletedCtx(r.Context())
+    if err != nil {
+        return nil, err
+    }
+    var deleted []*registry.Item
+    for _, reg := range items {
+        if item, ok := reg.(*registry.Item); ok && item.IsDeleted() {
+            deleted = append(deleted, item)
+        }
+    }
+    if _, err := h.store.PurgeDeleted(); err != nil {
+        return nil, err
+    }
+    return deleted, nil
+}
+
+func (h *Handler) RestoreItem(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id := params["id"]
+
+    if h.locked(w, r, id) {
+        return
+    }
+
+    item, err := h.store.RestoreItem(id)
+    if err == storage.ErrDuplicateItem {
+        h.respondWithError(w, http.StatusConflict, CodeDuplicateItem, "Another item with the same natural key exists")
+        return
+    }
+    if err != nil {
+        h.log(r).Error("Failed to restore item", zap.Error(err))
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+
+    h.notifier.Notify(notify.EventItemRestored, item.ID, item.Type, item.RegistryName)
+    h.recordAudit(r, audit.ActionRestore, item.ID, item.Version, item.Version)
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(item)
+}
+
+// respondVersionConflict answers a conditional update that lost to another
+// write with the current item and its ETag, or 404 if there is none
+func (h *Handler) respondVersionConflict(w http.ResponseWriter, r *http.Request, id string) {
+    current, err := h.store.GetItemCtx(r.Context(), id)
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+    w.Header().Set("ETag", versionETag(current.Version))
+    h.respondWithJSON(w, http.StatusConflict, current)
+}
+
+func (h *Handler) GetItemHistory(w http.ResponseWriter, r *http.Request) {
+    history, ok := h.store.(storage.HistoryStore)
+    if !ok {
+        h.respondWithError(w, http.StatusNotImplemented, CodeNotImplemented, "Item history is not supported by this storage backend")
+        return
+    }
+
+    revisions, err := history.History(mux.Vars(r)["id"])
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+
+    h.respondWithJSON(w, http.StatusOK, revisions)
+}
+
+func (h *Handler) GetItemVersion(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+
+    history, ok := h.store.(storage.HistoryStore)
+    if !ok {
+        h.respondWithError(w, http.StatusNotImplemented, CodeNotImplemented, "Item history is not supported by this storage backend")
+        return
+    }
+
+    version, err := strconv.ParseInt(params["version"], 10, 64)
+    if err != nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid version")
+        return
+    }
+
+    revision, err := history.Revision(params["id"], version)
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeRevisionNotFound, "Revision not found")
+        return
+    }
+
+    h.respondWithJSON(w, http.StatusOK, revision)
+}
+
+// GetItemDiff compares the revisions from and to of an item, to defaulting
+// to the latest one
+func (h *Handler) GetItemDiff(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+    query := r.URL.Query()
+
+    history, ok := h.store.(storage.HistoryStore)
+    if !ok {
+        h.respondWithError(w, http.StatusNotImplemented, CodeNotImplemented, "Item history is not supported by this storage backend")
+        return
+    }
+
+    from, err := strconv.ParseInt(query.Get("from"), 10, 64)
+    if err != nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "from must be a version number")
+        return
+    }
+    var to int64
+    if query.Get("to") != "" {
+        if to, err = strconv.ParseInt(query.Get("to"), 10, 64); err != nil {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "to must be a version number")
+            return
+        }
+    }
+
+    revisions, err := history.History(id)
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+    if len(revisions) == 0 {
+        h.respondWithError(w, http.StatusNotFound, CodeRevisionNotFound, "Revision not found")
+        return
+    }
+    if to == 0 {
+        to = revisions[len(revisions)-1].Version
+    }
+
+    // The latest revision of a version wins, as with GetItemVersion
+    var fromRevision, toRevision *registry.ItemRevision
+    for i := range revisions {
+        if revisions[i].Version == from {
+            fromRevision = &revisions[i]
+        }
+        if revisions[i].Version == to {
+            toRevision = &revisions[i]
+        }
+    }
+    if fromRevision == nil || toRevision == nil {
+        missing := from
+        if fromRevision != nil {
+            missing = to
+        }
+        h.respondWithError(w, http.StatusNotFound, CodeRevisionNotFound, fmt.Sprintf("Version %d is not in the item's history", missing))
+        return
+    }
+
+    h.respondWithJSON(w, http.StatusOK, registry.DiffRevisions(id, fromRevision, toRevision))
+}
+
 func (h *Handler) ListItems(w http.ResponseWriter, r *http.Request) {
     limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
     offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+    includeDeleted, _ := strconv.ParseBool(r.URL.Query().Get("includeDeleted"))
 
+    sortField := r.URL.Query().Get("sort")
+    // Incremental syncs read pages in update order, so that the latest
+    // updatedAt of a page is where the next one starts
+    if sortField == "" && r.URL.Query().Get("updatedSince") != "" && !r.URL.Query().Has("cursor") {
+        sortField = storage.SortUpdatedAt
+    }
+    sortBy, err := storage.ParseSort(sortField, r.URL.Query().Get("order"))
+    if err != nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+        return
+    }
+    limit = h.pageLimit(w, limit)
+
+    // A cursor parameter, even an empty one for the first page, switches to
+    // stable cursor pagination
+    if r.URL.Query().Has("cursor") {
+        if wantsNDJSON(r) {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Cursor pagination returns a Page and cannot be streamed as NDJSON")
+            return
+        }
+        if sortBy != storage.DefaultSort {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Cursor pagination only supports the default sort order")
+            return
+        }
+        h.listItemsPage(w, r, limit)
+        return
+    }
+    if r.URL.Query().Has("offset") {
+        w.Header().Set("Deprecation", "true")
+        w.Header().Add("Warning", `299 - "offset pagination is deprecated; use cursor"`)
+    }
+
+    filters, err := metadataFilters(r.URL.Query())
+    if err != nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+        return
+    }
+
+    ctx := r.Context()
     var items []registry.Registerable
+    var total int
+    paginated := false
 
-    if limit > 0 || offset > 0 {
-        // Use ListPaginated if limit or offset is specified
+    if value := r.URL.Query().Get("updatedSince"); value != "" {
+        since, perr := time.Parse(time.RFC3339, value)
+        if perr != nil {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "updatedSince must be an RFC 3339 timestamp")
+            return
+        }
+        items, err = h.listUpdatedSince(ctx, since)
+    } else if owner := r.URL.Query().Get("owner"); owner != "" {
+        items, err = h.listByOwner(ctx, owner)
+    } else if tags := r.URL.Query()["tag"]; len(tags) > 0 {
+        items, err = h.store.ListByTagCtx(ctx, tags...)
+    } else if len(filters) > 0 {
+        items, err = h.store.ListByMetadataCtx(ctx, filters)
+    } else if itemType := h.types.Normalize(r.URL.Query().Get("type")); itemType != "" {
+        items = h.store.ListByType(itemType)
+    } else if includeDeleted {
+        // Soft-deleted items are only reachable through ListIncludingDeleted
+        items, err = h.store.ListIncludingDeletedCtx(ctx)
+    } else if sortBy == storage.DefaultSort {
+        // ListPaginated already returns items in the default order
         items = h.store.ListPaginated(limit, offset)
+        total = h.store.Count(storage.CountFilter{})
+        paginated = true
     } else {
-        // Use List if no pagination is specified
-        items = h.store.List()
+        items, err = h.store.ListCtx(ctx)
+    }
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+
+    if !paginated {
+        // Storage already lists items in the default order
+        if sortBy != storage.DefaultSort {
+            storage.SortItems(items, sortBy)
+        }
+        total = len(items)
+        items = paginate(items, limit, offset)
+    }
+    if fields, ok := parseFields(r); ok {
+        items = fields.apply(items)
+    }
+
+    w.Header().Set("X-Total-Count", strconv.Itoa(total))
+    w.Header().Add("Vary", "Accept")
+    if wantsNDJSON(r) {
+        h.writeItemsNDJSON(w, r, items)
+        return
+    }
+    if wantsYAML(r) {
+        h.respondNegotiated(w, r, http.StatusOK, items)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(items)
+}
+
+// listUpdatedSince lists the items changed after since, with deleted items
+// reduced to tombstones
+func (h *Handler) listUpdatedSince(ctx context.Context, since time.Time) ([]registry.Registerable, error) {
+    items, err := h.store.ListUpdatedSinceCtx(ctx, since)
+    if err != nil {
+        return nil, err
+    }
+    for i, item := range items {
+        if itemObj, ok := item.(*registry.Item); ok && itemObj.IsDeleted() {
+            items[i] = itemObj.Tombstone()
+        }
+    }
+    return items, nil
+}
+
+// listItemsPage serves one page of non-deleted items in creation order. The
+// response carries the items and, unless this is the last page, the
+// nextCursor to pass back for the following one.
+func (h *Handler) listItemsPage(w http.ResponseWriter, r *http.Request, limit int) {
+    query := r.URL.Query()
+    if filters, _ := metadataFilters(query); len(query["tag"]) > 0 || len(filters) > 0 || query.Get("includeDeleted") != "" || query.Get("updatedSince") != "" || query.Get("owner") != "" {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Cursor pagination cannot be combined with tag, metadata, owner, includeDeleted or updatedSince filters")
+        return
+    }
+
+    page, err := h.store.ListPage(r.Context(), limit, query.Get("cursor"))
+    if err == storage.ErrInvalidCursor {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidCursor, "Invalid cursor")
+        return
+    }
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+
+    if fields, ok := parseFields(r); ok {
+        page.Items = fields.apply(page.Items)
+    }
+    w.Header().Set("X-Total-Count", strconv.Itoa(h.store.Count(storage.CountFilter{})))
+    w.Header().Add("Vary", "Accept")
+    h.respondNegotiated(w, r, http.StatusOK, page)
+}
+
+func (h *Handler) CountItems(w http.ResponseWriter, r *http.Request) {
+    includeDeleted, _ := strconv.ParseBool(r.URL.Query().Get("includeDeleted"))
+    filter := storage.CountFilter{
+        Type:           h.types.Normalize(r.URL.Query().Get("type")),
+        RegistryName:   r.URL.Query().Get("registryName"),
+        IncludeDeleted: includeDeleted,
+    }
+
+    if filter.RegistryName != "" && h.outOfScope(w, r, filter.RegistryName) {
+        return
+    }
+
+    h.respondWithJSON(w, http.StatusOK, map[string]int{"count": h.store.Count(filter)})
+}
+
+// GetStats summarizes the stored items for dashboards and capacity planning
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+    if stats, ok := h.store.(storage.StatsStore); ok {
+        h.respondWithJSON(w, http.StatusOK, stats.Stats())
+        return
+    }
+
+    items, err := h.store.ListIncludingDeletedCtx(r.Context())
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+    h.respondWithJSON(w, http.StatusOK, storage.ComputeStats(items))
+}
+
+func (h *Handler) SearchItems(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query().Get("q")
+    if query == "" {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Missing search query")
+        return
+    }
+
+    results, err := h.store.SearchItemsCtx(r.Context(), query)
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+    if limit, _ := strconv.Atoi(r.URL.Query().Get("limit")); limit > 0 && limit < len(results) {
+        results = results[:limit]
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(results)
+}
+
+func (h *Handler) ListDeletedItems(w http.ResponseWriter, r *http.Request) {
+    all, err := h.store.ListIncludingDeletedCtx(r.Context())
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+
+    items := []registry.Registerable{}
+    for _, item := range all {
+        if d, ok := item.(interface{ IsDeleted() bool }); ok && d.IsDeleted() {
+            items = append(items, item)
+        }
     }
 
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(items)
 }
 
-// Add a new method for error responses
-func (h *Handler) respondWithError(w http.ResponseWriter, code int, message string) {
-    h.respondWithJSON(w, code, map[string]string{"error": message})
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+    h.respondWithJSON(w, http.StatusOK, h.store.ListTags())
+}
+
+func (h *Handler) ListPlugins(w http.ResponseWriter, r *http.Request) {
+    items := h.store.ListByType(plugins.PluginItemType)
+    if items == nil {
+        items = []registry.Registerable{}
+    }
+    h.respondWithJSON(w, http.StatusOK, items)
+}
+
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+    h.respondWithJSON(w, http.StatusOK, h.notifier.URLs())
+}
+
+func (h *Handler) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
+    var req struct {
+        URL string `json:"url"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        h.log(r).Error("Failed to decode request body", zap.Error(err))
+        h.respondPayloadError(w, err, "Invalid request payload")
+        return
+    }
+
+    if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidPayload, "Webhook URL must be an absolute http(s) URL")
+        return
+    }
+
+    h.notifier.AddURL(req.URL)
+    h.respondWithJSON(w, http.StatusCreated, h.notifier.URLs())
+}
+
+func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
+    if p := principalFrom(r.Context()); p == nil || p.role != RoleAdmin {
+        h.respondWithError(w, http.StatusForbidden, CodeForbidden, "Admin role required")
+        return
+    }
+
+    var req struct {
+        Registries []string `json:"registries"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        h.log(r).Error("Failed to decode request body", zap.Error(err))
+        h.respondPayloadError(w, err, "Invalid request payload")
+        return
+    }
+
+    key, apiKey, err := h.keys.CreateKey(req.Registries)
+    if err == storage.ErrKeyScopeRequired {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidPayload, err.Error())
+        return
+    }
+    if err != nil {
+        h.log(r).Error("Failed to create api key", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to create api key")
+        return
+    }
+
+    // The plaintext key is only ever returned here
+    h.respondWithJSON(w, http.StatusCreated, struct {
+        *storage.APIKey
+        Key string `json:"key"`
+    }{apiKey, key})
+}
+
+// log returns the logger for r, tagged with its request ID
+func (h *Handler) log(r *http.Request) *zap.Logger {
+    return loggerFrom(r.Context(), h.logger)
+}
+
+// outOfScope writes a 403 and reports true when the caller's API key does not
+// cover every one of registryNames
+func (h *Handler) outOfScope(w http.ResponseWriter, r *http.Request, registryNames ...string) bool {
+    p := principalFrom(r.Context())
+    for _, name := range registryNames {
+        if !p.allowsRegistry(name) {
+            h.respondWithError(w, http.StatusForbidden, CodeKeyOutOfScope, "API key is not scoped to registry "+strconv.Quote(name))
+            return true
+        }
+    }
+    return false
+}
+
+// scanFailed reports a storage scan that returned err. A scan abandoned
+// because the client went away gets no response, since nobody is reading it.
+func (h *Handler) scanFailed(w http.ResponseWriter, r *http.Request, err error) {
+    if r.Context().Err() != nil {
+        h.log(r).Debug("Request cancelled during storage scan", zap.String("path", r.URL.Path), zap.Error(err))
+        return
+    }
+    h.log(r).Error("Failed to list items", zap.Error(err))
+    h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to list items")
+}
+
+// versionETag formats an item version as an entity tag
+func versionETag(version int64) string {
+    return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// weakVersionETag formats an item version as a weak entity tag, for
+// representations that may differ in encoding but not in content
+func weakVersionETag(version int64) string {
+    return "W/" + versionETag(version)
+}
+
+// etagListMatches reports whether the comma-separated If-None-Match style
+// list contains "*" or an entity tag for version, using weak comparison
+func etagListMatches(list string, version int64) bool {
+    for _, tag := range strings.Split(list, ",") {
+        if strings.TrimSpace(tag) == "*" {
+            return true
+        }
+        if v, err := parseVersionETag(tag); err == nil && v == version {
+            return true
+        }
+    }
+    return false
+}
+
+// notModifiedSince reports whether an If-Modified-Since header value names a
+// time no earlier than modified, compared at the second precision of HTTP
+// dates. Invalid dates and unknown modification times never match.
+func notModifiedSince(ifModifiedSince string, modified time.Time) bool {
+    if ifModifiedSince == "" || modified.IsZero() {
+        return false
+    }
+    since, err := http.ParseTime(ifModifiedSince)
+    if err != nil {
+        return false
+    }
+    return !modified.Truncate(time.Second).After(since)
+}
+
+// parseVersionETag reads an item version from an If-Match style header value,
+// accepting both bare versions and quoted (optionally weak) entity tags
+func parseVersionETag(value string) (int64, error) {
+    value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+    return strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+}
+
+// metadataFilters collects ?meta.<key>=<expr> query params into a filter map,
+// reporting a key given twice or an expression storage cannot parse
+func metadataFilters(query url.Values) (map[string]string, error) {
+    filters := make(map[string]string)
+    for param, values := range query {
+        if key := strings.TrimPrefix(param, "meta."); key != param && key != "" && len(values) > 0 {
+            if len(values) > 1 {
+                return filters, fmt.Errorf("%w: %s is given more than once", storage.ErrInvalidMetadataFilter, param)
+            }
+            filters[key] = values[0]
+        }
+    }
+    if _, err := storage.ParseMetadataFilters(filters); err != nil {
+        return filters, err
+    }
+    return filters, nil
+}
+
+// paginate applies limit/offset to an already materialized list
+func paginate(items []registry.Registerable, limit, offset int) []registry.Registerable {
+    if limit <= 0 && offset <= 0 {
+        return items
+    }
+    if offset > len(items) {
+        return []registry.Registerable{}
+    }
+
+    end := offset + limit
+    if limit <= 0 || end > len(items) {
+        end = len(items)
+    }
+
+    return items[offset:end]
+}
+
+// respondWithError writes an ErrorResponse without details
+func (h *Handler) respondWithError(w http.ResponseWriter, status int, code, message string) {
+    writeError(w, status, code, message, nil)
+}
+
+// respondWithErrorDetails writes an ErrorResponse carrying details
+func (h *Handler) respondWithErrorDetails(w http.ResponseWriter, status int, code, message string, details interface{}) {
+    writeError(w, status, code, message, details)
 }
 
 // Add a new method for JSON responses
@@ -0,0 +1,497 @@
+package api
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+)
+
+// testServer is the API on a MemoryStorage, configured by the environment
+// set before newTestServer
+type testServer struct {
+    store    *storage.MemoryStorage
+    auditLog *audit.Logger
+    notifier *notify.Notifier
+    handler  http.Handler
+    // apiKey is sent as X-API-Key on every request, unless empty
+    apiKey string
+}
+
+const testAdminKey = "test-admin-key"
+
+// newAdminTestServer returns a testServer that requires API keys and sends
+// the admin key on every request
+func newAdminTestServer(t *testing.T) *testServer {
+    t.Helper()
+    t.Setenv("ADMIN_API_KEY", testAdminKey)
+    s := newTestServer(t)
+    s.apiKey = testAdminKey
+    return s
+}
+
+func newTestServer(t *testing.T) *testServer {
+    t.Helper()
+    return newTestServerWith(t, storage.NewMemoryStorage())
+}
+
+func newTestServerWith(t *testing.T, store *storage.MemoryStorage) *testServer {
+    t.Helper()
+    auditLog, err := audit.Open("", 100, zap.NewNop())
+    if err != nil {
+        t.Fatalf("open audit log: %v", err)
+    }
+    notifier := notify.NewNotifier(zap.NewNop())
+    r := mux.NewRouter()
+    SetupRoutes(r, store, zap.NewNop(), nil, auditLog, nil, notifier)
+    t.Cleanup(func() { store.Close() })
+    return &testServer{store: store, auditLog: auditLog, notifier: notifier, handler: r}
+}
+
+// do serves a request with an optional JSON body and returns the recorded
+// response
+func (s *testServer) do(t *testing.T, method, target string, body interface{}, header ...string) *httptest.ResponseRecorder {
+    t.Helper()
+    var buf bytes.Buffer
+    if body != nil {
+        if raw, ok := body.(string); ok {
+            buf.WriteString(raw)
+        } else if err := json.NewEncoder(&buf).Encode(body); err != nil {
+            t.Fatalf("encode body: %v", err)
+        }
+    }
+    req := httptest.NewRequest(method, target, &buf)
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    if s.apiKey != "" {
+        req.Header.Set("X-API-Key", s.apiKey)
+    }
+    for i := 0; i+1 < len(header); i += 2 {
+        req.Header.Set(header[i], header[i+1])
+    }
+    rec := httptest.NewRecorder()
+    s.handler.ServeHTTP(rec, req)
+    return rec
+}
+
+// errorCode returns the code of an error response
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+    t.Helper()
+    var body struct {
+        Code  string `json:"code"`
+        Error struct {
+            Code string `json:"code"`
+        } `json:"error"`
+    }
+    if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+        t.Fatalf("decode error response %q: %v", rec.Body.String(), err)
+    }
+    if body.Code == "" {
+        return body.Error.Code
+    }
+    return body.Code
+}
+
+func item(id, name, registryName string) map[string]interface{} {
+    return map[string]interface{}{"id": id, "type": "service", "name": name, "registryName": registryName}
+}
+
+func TestCreateItemTakenID(t *testing.T) {
+    tests := []struct {
+        name     string
+        target   string
+        header   []string
+        wantCode int
+    }{
+        {name: "v1", target: "/api/v1/items", wantCode: http.StatusConflict},
+        {name: "v1 if-none-match", target: "/api/v1/items", header: []string{"If-None-Match", "*"}, wantCode: http.StatusPreconditionFailed},
+        {name: "v1 dry run", target: "/api/v1/items?dryRun=true", wantCode: http.StatusConflict},
+        {name: "v2", target: "/api/v2/items", wantCode: http.StatusConflict},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+            if rec := s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a")); rec.Code != http.StatusCreated {
+                t.Fatalf("create: %d %s", rec.Code, rec.Body)
+            }
+
+            rec := s.do(t, "POST", tt.target, item("svc", "other", "team-b"), tt.header...)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if code := errorCode(t, rec); code != CodeItemExists {
+                t.Errorf("code = %s, want %s", code, CodeItemExists)
+            }
+            stored, err := s.store.GetItem("svc")
+            if err != nil || stored.Name != "api" || stored.Version != 1 {
+                t.Errorf("stored item changed: %+v, %v", stored, err)
+            }
+            if entries := s.auditLog.Recent("svc", 10); len(entries) != 1 {
+                t.Errorf("got %d audit entries, want 1", len(entries))
+            }
+        })
+    }
+}
+
+func TestCreateItemsTakenID(t *testing.T) {
+    s := newTestServer(t)
+    if rec := s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a")); rec.Code != http.StatusCreated {
+        t.Fatalf("create: %d %s", rec.Code, rec.Body)
+    }
+
+    rec := s.do(t, "POST", "/api/v1/items/batch", []interface{}{
+        item("svc", "replaced", "team-a"),
+        item("new", "new", "team-a"),
+        item("new", "again", "team-a"),
+    })
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+    }
+    var results []storage.BatchResult
+    if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+        t.Fatal(err)
+    }
+    want := []bool{false, true, false}
+    if len(results) != len(want) {
+        t.Fatalf("got %d results, want %d", len(results), len(want))
+    }
+    for i, result := range results {
+        if result.Index != i || result.Success != want[i] {
+            t.Errorf("result %d = %+v, want success %v", i, result, want[i])
+        }
+    }
+    if stored, _ := s.store.GetItem("svc"); stored.Name != "api" {
+        t.Errorf("stored item was replaced by %q", stored.Name)
+    }
+    if stored, _ := s.store.GetItem("new"); stored.Name != "new" {
+        t.Errorf("later item of the batch replaced the first: %q", stored.Name)
+    }
+}
+
+func TestRestoreItem(t *testing.T) {
+    tests := []struct {
+        name     string
+        id       string
+        delete   bool
+        wantCode int
+    }{
+        {name: "deleted item", id: "svc", delete: true, wantCode: http.StatusOK},
+        {name: "item not deleted", id: "svc", wantCode: http.StatusOK},
+        {name: "unknown item", id: "missing", wantCode: http.StatusNotFound},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+            if rec := s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a")); rec.Code != http.StatusCreated {
+                t.Fatalf("create: %d %s", rec.Code, rec.Body)
+            }
+            if tt.delete {
+                if rec := s.do(t, "DELETE", "/api/v1/items/svc", nil); rec.Code != http.StatusNoContent {
+                    t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+                }
+            }
+
+            rec := s.do(t, "POST", "/api/v1/items/"+tt.id+"/restore", nil)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if tt.wantCode != http.StatusOK {
+                if code := errorCode(t, rec); code != CodeItemNotFound {
+                    t.Errorf("code = %s, want %s", code, CodeItemNotFound)
+                }
+                return
+            }
+            var restored struct {
+                ID      string `json:"id"`
+                Deleted bool   `json:"deleted"`
+            }
+            if err := json.Unmarshal(rec.Body.Bytes(), &restored); err != nil {
+                t.Fatalf("decode response: %v", err)
+            }
+            if restored.ID != tt.id || restored.Deleted {
+                t.Errorf("response = %+v, want the restored item", restored)
+            }
+            if _, err := s.store.GetItem(tt.id); err != nil {
+                t.Errorf("get restored item: %v", err)
+            }
+        })
+    }
+}
+
+func TestUpdateItemIfMatch(t *testing.T) {
+    tests := []struct {
+        name        string
+        header      []string
+        wantCode    int
+        wantVersion int64
+    }{
+        {name: "matching version", header: []string{"If-Match", `"1"`}, wantCode: http.StatusOK, wantVersion: 2},
+        {name: "bare version", header: []string{"If-Match", "1"}, wantCode: http.StatusOK, wantVersion: 2},
+        {name: "mismatching version", header: []string{"If-Match", `"5"`}, wantCode: http.StatusConflict, wantVersion: 1},
+        {name: "missing header", wantCode: http.StatusOK, wantVersion: 2},
+        {name: "invalid header", header: []string{"If-Match", "latest"}, wantCode: http.StatusBadRequest, wantVersion: 1},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+            if rec := s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a")); rec.Code != http.StatusCreated {
+                t.Fatalf("create: %d %s", rec.Code, rec.Body)
+            }
+
+            rec := s.do(t, "PUT", "/api/v1/items/svc", item("svc", "renamed", "team-a"), tt.header...)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if rec.Code != http.StatusBadRequest {
+                if etag := rec.Header().Get("ETag"); etag != versionETag(tt.wantVersion) {
+                    t.Errorf("ETag = %s, want %s", etag, versionETag(tt.wantVersion))
+                }
+                var body struct {
+                    Version int64 `json:"version"`
+                }
+                if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Version != tt.wantVersion {
+                    t.Errorf("response version = %d (%v), want %d", body.Version, err, tt.wantVersion)
+                }
+            }
+            if stored, _ := s.store.GetItem("svc"); stored.Version != tt.wantVersion {
+                t.Errorf("stored version = %d, want %d", stored.Version, tt.wantVersion)
+            }
+        })
+    }
+}
+
+func TestCreateItemRequiredFields(t *testing.T) {
+    tests := []struct {
+        name       string
+        itemType   string
+        itemName   string
+        registry   string
+        wantFields []string
+    }{
+        {name: "all set", itemType: "service", itemName: "api", registry: "team-a"},
+        {name: "no type", itemName: "api", registry: "team-a", wantFields: []string{"type"}},
+        {name: "no name", itemType: "service", registry: "team-a", wantFields: []string{"name"}},
+        {name: "no registry name", itemType: "service", itemName: "api", wantFields: []string{"registryName"}},
+        {name: "no type or name", registry: "team-a", wantFields: []string{"type", "name"}},
+        {name: "nothing", wantFields: []string{"type", "name", "registryName"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+            rec := s.do(t, "POST", "/api/v1/items", map[string]interface{}{
+                "id": "svc", "type": tt.itemType, "name": tt.itemName, "registryName": tt.registry,
+            })
+            if len(tt.wantFields) == 0 {
+                if rec.Code != http.StatusCreated {
+                    t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+                }
+                return
+            }
+
+            if rec.Code != http.StatusUnprocessableEntity {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+            }
+            var body struct {
+                Code    string `json:"code"`
+                Details struct {
+                    Fields []struct {
+                        Field   string `json:"field"`
+                        Message string `json:"message"`
+                    } `json:"fields"`
+                } `json:"details"`
+            }
+            if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+                t.Fatalf("decode %q: %v", rec.Body, err)
+            }
+            if body.Code != CodeValidationFailed {
+                t.Errorf("code = %s, want %s", body.Code, CodeValidationFailed)
+            }
+            var fields []string
+            for _, field := range body.Details.Fields {
+                fields = append(fields, field.Field)
+            }
+            if len(fields) != len(tt.wantFields) {
+                t.Fatalf("fields = %v, want %v", fields, tt.wantFields)
+            }
+            for i := range fields {
+                if fields[i] != tt.wantFields[i] {
+                    t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+                    break
+                }
+            }
+            if _, err := s.store.GetItem("svc"); err == nil {
+                t.Error("invalid item was stored")
+            }
+        })
+    }
+}
+
+func TestGetItemIfNoneMatch(t *testing.T) {
+    tests := []struct {
+        name        string
+        ifNoneMatch string
+        wantCode    int
+    }{
+        {name: "no header", wantCode: http.StatusOK},
+        {name: "current version", ifNoneMatch: `W/"1"`, wantCode: http.StatusNotModified},
+        {name: "strong tag", ifNoneMatch: `"1"`, wantCode: http.StatusNotModified},
+        {name: "list with current version", ifNoneMatch: `"3", W/"1"`, wantCode: http.StatusNotModified},
+        {name: "any version", ifNoneMatch: "*", wantCode: http.StatusNotModified},
+        {name: "stale version", ifNoneMatch: `W/"0"`, wantCode: http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+            if rec := s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a")); rec.Code != http.StatusCreated {
+                t.Fatalf("create: %d %s", rec.Code, rec.Body)
+            }
+
+            var header []string
+            if tt.ifNoneMatch != "" {
+                header = []string{"If-None-Match", tt.ifNoneMatch}
+            }
+            rec := s.do(t, "GET", "/api/v1/items/svc", nil, header...)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if etag := rec.Header().Get("ETag"); etag != `W/"1"` {
+                t.Errorf("ETag = %s, want W/\"1\"", etag)
+            }
+            if tt.wantCode == http.StatusNotModified && rec.Body.Len() != 0 {
+                t.Errorf("304 response has a body: %s", rec.Body)
+            }
+        })
+    }
+}
+
+func TestUpdateItemExpectedVersionConcurrent(t *testing.T) {
+    s := newTestServer(t)
+    if rec := s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a")); rec.Code != http.StatusCreated {
+        t.Fatalf("create: %d %s", rec.Code, rec.Body)
+    }
+
+    const callers = 10
+    var wg sync.WaitGroup
+    codes := make(chan int, callers)
+    for i := 0; i < callers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            codes <- s.do(t, "PUT", "/api/v1/items/svc?expectedVersion=1", item("svc", "renamed", "team-a")).Code
+        }()
+    }
+    wg.Wait()
+    close(codes)
+
+    counts := make(map[int]int)
+    for code := range codes {
+        counts[code]++
+    }
+    if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != callers-1 {
+        t.Errorf("status counts = %v, want one 200 and %d 409", counts, callers-1)
+    }
+    if stored, _ := s.store.GetItem("svc"); stored.Version != 2 {
+        t.Errorf("stored version = %d, want 2", stored.Version)
+    }
+}
+
+func TestCreateItemIfNoneMatch(t *testing.T) {
+    tests := []struct {
+        name   string
+        target string
+    }{
+        {name: "v1", target: "/api/v1/items"},
+        {name: "v2", target: "/api/v2/items"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+
+            rec := s.do(t, "POST", tt.target, item("svc", "first", "team-a"), "If-None-Match", "*")
+            if rec.Code != http.StatusCreated {
+                t.Fatalf("first create: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+            }
+            rec = s.do(t, "POST", tt.target, item("svc", "retry", "team-a"), "If-None-Match", "*")
+            if rec.Code != http.StatusPreconditionFailed {
+                t.Fatalf("second create: status = %d, want %d: %s", rec.Code, http.StatusPreconditionFailed, rec.Body)
+            }
+            if code := errorCode(t, rec); code != CodeItemExists {
+                t.Errorf("code = %s, want %s", code, CodeItemExists)
+            }
+            if stored, _ := s.store.GetItem("svc"); stored.Name != "first" || stored.Version != 1 {
+                t.Errorf("stored item = %s at version %d, want first at version 1", stored.Name, stored.Version)
+            }
+        })
+    }
+}
+
+func TestCreateItemIfNoneMatchConcurrent(t *testing.T) {
+    s := newTestServer(t)
+
+    const callers = 10
+    var wg sync.WaitGroup
+    codes := make(chan int, callers)
+    for i := 0; i < callers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            codes <- s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a"), "If-None-Match", "*").Code
+        }()
+    }
+    wg.Wait()
+    close(codes)
+
+    counts := make(map[int]int)
+    for code := range codes {
+        counts[code]++
+    }
+    if counts[http.StatusCreated] != 1 || counts[http.StatusPreconditionFailed] != callers-1 {
+        t.Errorf("status counts = %v, want one 201 and %d 412", counts, callers-1)
+    }
+}
+
+func TestGetItemIfModifiedSince(t *testing.T) {
+    modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    tests := []struct {
+        name     string
+        header   []string
+        wantCode int
+    }{
+        {name: "no header", wantCode: http.StatusOK},
+        {name: "same second", header: []string{"If-Modified-Since", modified.Format(http.TimeFormat)}, wantCode: http.StatusNotModified},
+        {name: "later", header: []string{"If-Modified-Since", modified.Add(time.Hour).Format(http.TimeFormat)}, wantCode: http.StatusNotModified},
+        {name: "earlier", header: []string{"If-Modified-Since", modified.Add(-time.Second).Format(http.TimeFormat)}, wantCode: http.StatusOK},
+        {name: "invalid date", header: []string{"If-Modified-Since", "yesterday"}, wantCode: http.StatusOK},
+        {name: "if-none-match takes precedence", header: []string{"If-Modified-Since", modified.Format(http.TimeFormat), "If-None-Match", `W/"0"`},
+            wantCode: http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+            if rec := s.do(t, "POST", "/api/v1/items", item("svc", "api", "team-a")); rec.Code != http.StatusCreated {
+                t.Fatalf("create: %d %s", rec.Code, rec.Body)
+            }
+            // The fraction of a second is below the precision of HTTP dates
+            stored, _ := s.store.GetItem("svc")
+            stored.UpdatedAt = modified.Add(500 * time.Millisecond)
+
+            rec := s.do(t, "GET", "/api/v1/items/svc", nil, tt.header...)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if lastModified := rec.Header().Get("Last-Modified"); lastModified != modified.Format(http.TimeFormat) {
+                t.Errorf("Last-Modified = %s, want %s", lastModified, modified.Format(http.TimeFormat))
+            }
+        })
+    }
+}
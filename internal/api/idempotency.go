@@ -0,0 +1,214 @@
+package api
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "reflect"
+    "sync"
+    "time"
+
+    "go.uber.org/zap"
+)
+
+// idempotencyKeyHeader carries the client-chosen key of a retriable request
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyTTL is how long responses are kept for replay when
+// IDEMPOTENCY_TTL is unset
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength bounds the keys clients may send
+const maxIdempotencyKeyLength = 255
+
+// idempotencySweepInterval is how often expired keys are dropped
+const idempotencySweepInterval = time.Minute
+
+// IdempotencyStore remembers the responses to requests sent with an
+// Idempotency-Key for a TTL, in memory, so that a retried request gets the
+// original response instead of being carried out twice
+type IdempotencyStore struct {
+    ttl time.Duration
+
+    mu        sync.Mutex
+    entries   map[string]*idempotencyEntry
+    nextSweep time.Time
+}
+
+// idempotencyEntry is a key's request and, once it completed, its response
+type idempotencyEntry struct {
+    // fingerprint identifies the request, so that a key cannot be reused
+    // for a different one
+    fingerprint [sha256.Size]byte
+    done        bool
+    status      int
+    header      http.Header
+    body        []byte
+    expires     time.Time
+}
+
+// NewIdempotencyStore creates an IdempotencyStore keeping responses for ttl
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+    return &IdempotencyStore{ttl: ttl, entries: make(map[string]*idempotencyEntry)}
+}
+
+// NewIdempotencyStoreFromEnv creates an IdempotencyStore keeping responses
+// for IDEMPOTENCY_TTL, or DefaultIdempotencyTTL when it is unset. It returns
+// nil when IDEMPOTENCY_TTL is 0, leaving Idempotency-Key unsupported.
+func NewIdempotencyStoreFromEnv() (*IdempotencyStore, error) {
+    ttl := DefaultIdempotencyTTL
+    if value := os.Getenv("IDEMPOTENCY_TTL"); value != "" {
+        var err error
+        if ttl, err = time.ParseDuration(value); err != nil || ttl < 0 {
+            return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL: %q", value)
+        }
+    }
+    if ttl == 0 {
+        return nil, nil
+    }
+    return NewIdempotencyStore(ttl), nil
+}
+
+// begin claims key for the request identified by fingerprint. It returns the
+// entry already held by the key, if any, which the caller must replay or
+// reject; otherwise the caller must finish or abandon the key.
+func (s *IdempotencyStore) begin(key string, fingerprint [sha256.Size]byte) *idempotencyEntry {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    if now.After(s.nextSweep) {
+        for k, entry := range s.entries {
+            if entry.done && now.After(entry.expires) {
+                delete(s.entries, k)
+            }
+        }
+        s.nextSweep = now.Add(idempotencySweepInterval)
+    }
+
+    if entry, ok := s.entries[key]; ok && (!entry.done || now.Before(entry.expires)) {
+        return entry
+    }
+    s.entries[key] = &idempotencyEntry{fingerprint: fingerprint}
+    return nil
+}
+
+// finish records the response to the request holding key
+func (s *IdempotencyStore) finish(key string, status int, header http.Header, body []byte) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if entry, ok := s.entries[key]; ok {
+        entry.done = true
+        entry.status = status
+        entry.header = header
+        entry.body = body
+        entry.expires = time.Now().Add(s.ttl)
+    }
+}
+
+// abandon releases key without a response, so the request may be retried
+func (s *IdempotencyStore) abandon(key string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.entries, key)
+}
+
+// idempotent makes next replay its response to requests that repeat an
+// Idempotency-Key. Keys are scoped to the authenticated caller, if any, and
+// bound to the request body, so reusing one for a different body is rejected
+// with 422. A repeat that arrives while the first request is still running is
+// rejected with 409. Server errors are not kept, so such requests can be
+// retried with the same key.
+func (h *Handler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        key := r.Header.Get(idempotencyKeyHeader)
+        if h.idempotency == nil || key == "" {
+            next(w, r)
+            return
+        }
+        if len(key) > maxIdempotencyKeyLength {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Idempotency-Key must be at most 255 characters")
+            return
+        }
+
+        body, err := io.ReadAll(r.Body)
+        if err != nil {
+            h.respondPayloadError(w, err, "Failed to read request body")
+            return
+        }
+        r.Body = io.NopCloser(bytes.NewReader(body))
+
+        if p := principalFrom(r.Context()); p != nil {
+            key = p.actor() + "\x00" + key
+        }
+        fingerprint := sha256.Sum256(append([]byte(r.Method+" "+r.URL.RequestURI()+"\n"), body...))
+
+        if entry := h.idempotency.begin(key, fingerprint); entry != nil {
+            switch {
+            case entry.fingerprint != fingerprint:
+                h.respondWithError(w, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "Idempotency-Key was already used for a different request")
+            case !entry.done:
+                h.respondWithError(w, http.StatusConflict, CodeIdempotencyKeyInUse, "A request with this Idempotency-Key is still in progress")
+            default:
+                h.log(r).Info("Replayed idempotent request", zap.Int("status", entry.status))
+                for name, values := range entry.header {
+                    w.Header()[name] = values
+                }
+                w.Header().Set("Idempotent-Replayed", "true")
+                w.WriteHeader(entry.status)
+                w.Write(entry.body)
+            }
+            return
+        }
+
+        rec := &idempotencyRecorder{ResponseWriter: w, before: w.Header().Clone()}
+        defer func() {
+            if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+                h.idempotency.abandon(key)
+                return
+            }
+            h.idempotency.finish(key, rec.status, rec.header, rec.body.Bytes())
+        }()
+        next(rec, r)
+    }
+}
+
+// idempotencyRecorder passes a response through while keeping a copy of its
+// status, body and the headers the handler set
+type idempotencyRecorder struct {
+    http.ResponseWriter
+    before http.Header
+    status int
+    header http.Header
+    body   bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+    if rec.status == 0 {
+        rec.status = status
+        rec.header = make(http.Header)
+        for name, values := range rec.Header() {
+            if !reflect.DeepEqual(values, rec.before[name]) {
+                rec.header[name] = append([]string(nil), values...)
+            }
+        }
+    }
+    rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(data []byte) (int, error) {
+    if rec.status == 0 {
+        rec.WriteHeader(http.StatusOK)
+    }
+    rec.body.Write(data)
+    return rec.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+    return rec.ResponseWriter
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "go.uber.org/zap"
+)
+
+// importBatchSize is the number of decoded items stored per CreateItems call
+const importBatchSize = 100
+
+// importMaxLine bounds the length of a single NDJSON line
+const importMaxLine = 10 << 20
+
+// ImportError reports why a single item of an import was skipped. Line is
+// only set for NDJSON uploads.
+type ImportError struct {
+    Index int    `json:"index"`
+    Line  int    `json:"line,omitempty"`
+    Error string `json:"error"`
+}
+
+// ImportSummary is the result of an import
+type ImportSummary struct {
+    Imported int           `json:"imported"`
+    Skipped  int           `json:"skipped"`
+    Errors   []ImportError `json:"errors"`
+}
+
+// importer stores decoded items in batches and tallies the outcome
+type importer struct {
+    h       *Handler
+    r       *http.Request
+    summary ImportSummary
+
+    pending []*registry.Item
+    origins []ImportError // index and line of each pending item
+}
+
+func (h *Handler) ImportItems(w http.ResponseWriter, r *http.Request) {
+    mr, err := r.MultipartReader()
+    if err != nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidPayload, "Expected a multipart/form-data upload")
+        return
+    }
+
+    var file io.Reader
+    for {
+        part, err := mr.NextPart()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            h.respondPayloadError(w, err, "Invalid multipart upload")
+            return
+        }
+        if part.FormName() == "file" {
+            file = part
+            break
+        }
+    }
+    if file == nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidPayload, `Missing "file" field`)
+        return
+    }
+
+    br := bufio.NewReader(file)
+    format := r.URL.Query().Get("format")
+    if format == "" {
+        format = detectImportFormat(br)
+    }
+
+    imp := &importer{h: h, r: r, summary: ImportSummary{Errors: []ImportError{}}}
+    switch format {
+    case "json":
+        err = imp.decodeJSONArray(br)
+    case "ndjson":
+        err = imp.decodeNDJSON(br)
+    default:
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Unsupported import format "+strconv.Quote(format))
+        return
+    }
+    if err != nil {
+        h.log(r).Error("Failed to import items", zap.Error(err))
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidPayload, err.Error())
+        return
+    }
+    imp.flush()
+
+    // Items that failed in storage are reported after decoding errors; restore upload order
+    sort.SliceStable(imp.summary.Errors, func(i, j int) bool {
+        return imp.summary.Errors[i].Index < imp.summary.Errors[j].Index
+    })
+
+    h.respondWithJSON(w, http.StatusOK, imp.summary)
+}
+
+// detectImportFormat treats uploads starting with '[' as a JSON array and
+// anything else as NDJSON
+func detectImportFormat(br *bufio.Reader) string {
+    for {
+        b, err := br.Peek(1)
+        if err != nil {
+            return "ndjson"
+        }
+        switch b[0] {
+        case ' ', '\t', '\r', '\n':
+            br.ReadByte()
+        case '[':
+            return "json"
+        default:
+            return "ndjson"
+        }
+    }
+}
+
+// decodeJSONArray streams the elements of a JSON array. Invalid elements are
+// skipped; malformed JSON ends the import since the rest cannot be located,
+// but items decoded before it are still stored.
+func (imp *importer) decodeJSONArray(r io.Reader) error {
+    dec := json.NewDecoder(r)
+    if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+        return errors.New("upload is not a JSON array")
+    }
+
+    for index := 0; dec.More(); index++ {
+        var item registry.Item
+        if err := dec.Decode(&item); err != nil {
+            var syntaxErr *json.SyntaxError
+            if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+                imp.stop(ImportError{Index: index, Error: "malformed JSON, import stopped: " + err.Error()})
+                return nil
+            }
+            imp.skip(ImportError{Index: index, Error: err.Error()})
+            continue
+        }
+        imp.add(&item, ImportError{Index: index})
+    }
+
+    return nil
+}
+
+// decodeNDJSON reads one item per line; blank lines are ignored. A line that
+// cannot be read ends the import, but items before it are still stored.
+func (imp *importer) decodeNDJSON(r io.Reader) error {
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 0, 64*1024), importMaxLine)
+
+    index, line := 0, 1
+    for ; scanner.Scan(); line++ {
+        data := bytes.TrimSpace(scanner.Bytes())
+        if len(data) == 0 {
+            continue
+        }
+
+        var item registry.Item
+        if err := json.Unmarshal(data, &item); err != nil {
+            imp.skip(ImportError{Index: index, Line: line, Error: err.Error()})
+        } else {
+            imp.add(&item, ImportError{Index: index, Line: line})
+        }
+        index++
+    }
+
+    if err := scanner.Err(); err != nil {
+        imp.stop(ImportError{Index: index, Line: line, Error: "unreadable line, import stopped: " + err.Error()})
+    }
+    return nil
+}
+
+// add queues item for storage, storing a batch once it is full
+func (imp *importer) add(item *registry.Item, origin ImportError) {
+    if p := principalFrom(imp.r.Context()); !p.allowsRegistry(item.RegistryName) {
+        origin.Error = "API key is not scoped to registry " + strconv.Quote(item.RegistryName)
+        imp.skip(origin)
+        return
+    }
+    if fieldErr := imp.h.types.check(item); fieldErr != nil {
+        origin.Error = fieldErr.Field + ": " + fieldErr.Message
+        imp.skip(origin)
+        return
+    }
+    if fieldErr := imp.h.checkMetadataSize(item); fieldErr != nil {
+        origin.Error = fieldErr.Field + " " + fieldErr.Message
+        imp.skip(origin)
+        return
+    }
+
+    // Exported items keep the actors they carry
+    if item.CreatedBy == "" {
+        stampCreatedBy(imp.r, item)
+    }
+
+    imp.pending = append(imp.pending, item)
+    imp.origins = append(imp.origins, origin)
+    if len(imp.pending) >= importBatchSize {
+        imp.flush()
+    }
+}
+
+// skip records an item that will not be imported
+func (imp *importer) skip(origin ImportError) {
+    imp.summary.Skipped++
+    imp.summary.Errors = append(imp.summary.Errors, origin)
+}
+
+// stop records why the rest of an upload could not be read
+func (imp *importer) stop(origin ImportError) {
+    imp.summary.Errors = append(imp.summary.Errors, origin)
+}
+
+// flush stores the pending items
+func (imp *importer) flush() {
+    if len(imp.pending) == 0 {
+        return
+    }
+
+    results := imp.h.createItems(imp.r, imp.pending)
+    for i, result := range results {
+        if !result.Success {
+            origin := imp.origins[i]
+            origin.Error = result.Error
+            imp.skip(origin)
+            continue
+        }
+
+        imp.summary.Imported++
+        imp.h.metrics.itemCreated(imp.pending[i])
+        imp.h.notifier.Notify(notify.EventItemCreated, result.ID, imp.pending[i].Type, imp.pending[i].RegistryName)
+        imp.h.recordAudit(imp.r, audit.ActionCreate, result.ID, 0, imp.pending[i].Version)
+    }
+
+    imp.pending = imp.pending[:0]
+    imp.origins = imp.origins[:0]
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// jsonPatchContentType is the media type of an RFC 6902 JSON Patch
+const jsonPatchContentType = "application/json-patch+json"
+
+// jsonPatchOp is one operation of a JSON Patch. Value is only meaningful
+// when HasValue is set, since a null value is still a value.
+type jsonPatchOp struct {
+    Op       string
+    Path     string
+    Value    interface{}
+    HasValue bool
+}
+
+// jsonPatchError reports the operation of a JSON Patch that could not be
+// applied, either because its path does not resolve or its test failed
+type jsonPatchError struct {
+    Index   int    `json:"index"`
+    Op      string `json:"op"`
+    Path    string `json:"path"`
+    Message string `json:"-"`
+}
+
+func (e *jsonPatchError) Error() string {
+    return fmt.Sprintf("operation %d (%s %s): %s", e.Index, e.Op, e.Path, e.Message)
+}
+
+// parseJSONPatch checks the shape of the decoded operations of a JSON Patch.
+// Only add, remove, replace and test are supported, and only test may touch
+// the fields the service maintains.
+func parseJSONPatch(raw []map[string]interface{}) ([]jsonPatchOp, error) {
+    ops := make([]jsonPatchOp, 0, len(raw))
+    for i, entry := range raw {
+        var op jsonPatchOp
+        op.Op, _ = entry["op"].(string)
+        path, ok := entry["path"].(string)
+        if !ok {
+            return nil, fmt.Errorf("operation %d has no path", i)
+        }
+        op.Path = path
+        op.Value, op.HasValue = entry["value"]
+
+        switch op.Op {
+        case "add", "replace", "test":
+            if !op.HasValue {
+                return nil, fmt.Errorf("operation %d (%s) has no value", i, op.Op)
+            }
+        case "remove":
+        default:
+            return nil, fmt.Errorf("operation %d has unsupported op %q; expected add, remove, replace or test", i, op.Op)
+        }
+
+        tokens, err := parsePointer(op.Path)
+        if err != nil {
+            return nil, fmt.Errorf("operation %d: %v", i, err)
+        }
+        if op.Op != "test" {
+            if len(tokens) == 0 {
+                return nil, fmt.Errorf("operation %d cannot %s the whole item", i, op.Op)
+            }
+            for _, field := range readOnlyItemFields {
+                if tokens[0] == field {
+                    return nil, fmt.Errorf("field %q cannot be patched", field)
+                }
+            }
+        }
+        ops = append(ops, op)
+    }
+    return ops, nil
+}
+
+// applyJSONPatch returns a copy of item with ops applied in order to its JSON
+// form. Nothing is applied unless every operation succeeds.
+func applyJSONPatch(item *registry.Item, ops []jsonPatchOp) (*registry.Item, error) {
+    doc, err := itemDocument(item)
+    if err != nil {
+        return nil, err
+    }
+    // Unset metadata, tags and links encode as null, but are patched as
+    // empty so that entries can be added to them
+    if fields, ok := doc.(map[string]interface{}); ok {
+        for field, empty := range map[string]interface{}{"metadata": map[string]interface{}{}, "tags": []interface{}{}, "links": map[string]interface{}{}} {
+            if fields[field] == nil {
+                fields[field] = empty
+            }
+        }
+    }
+
+    for i, op := range ops {
+        tokens, _ := parsePointer(op.Path)
+        if doc, err = applyJSONPatchOp(doc, tokens, op); err != nil {
+            return nil, &jsonPatchError{Index: i, Op: op.Op, Path: op.Path, Message: err.Error()}
+        }
+    }
+    return itemFromDocument(item, doc)
+}
+
+// applyJSONPatchOp applies op at the location tokens point to in doc and
+// returns the resulting document
+func applyJSONPatchOp(doc interface{}, tokens []string, op jsonPatchOp) (interface{}, error) {
+    if len(tokens) == 0 {
+        // Only test reaches the root, as parseJSONPatch rejects the others
+        if !reflect.DeepEqual(doc, op.Value) {
+            return nil, fmt.Errorf("test failed")
+        }
+        return doc, nil
+    }
+
+    parent, err := resolvePointer(doc, tokens[:len(tokens)-1])
+    if err != nil {
+        return nil, err
+    }
+    key := tokens[len(tokens)-1]
+
+    switch container := parent.(type) {
+    case map[string]interface{}:
+        current, exists := container[key]
+        switch op.Op {
+        case "add":
+            container[key] = op.Value
+        case "remove", "replace":
+            if !exists {
+                return nil, fmt.Errorf("path does not exist")
+            }
+            if op.Op == "remove" {
+                delete(container, key)
+            } else {
+                container[key] = op.Value
+            }
+        case "test":
+            if !exists || !reflect.DeepEqual(current, op.Value) {
+                return nil, fmt.Errorf("test failed")
+            }
+        }
+        return doc, nil
+
+    case []interface{}:
+        // "-" names the position after the last element, which only add uses
+        if key == "-" && op.Op == "add" {
+            return setPointer(doc, tokens[:len(tokens)-1], append(container, op.Value))
+        }
+        index, err := arrayIndex(key, len(container), op.Op == "add")
+        if err != nil {
+            return nil, err
+        }
+        switch op.Op {
+        case "add":
+            grown := append(container[:index:index], op.Value)
+            return setPointer(doc, tokens[:len(tokens)-1], append(grown, container[index:]...))
+        case "remove":
+            shrunk := append(container[:index:index], container[index+1:]...)
+            return setPointer(doc, tokens[:len(tokens)-1], shrunk)
+        case "replace":
+            container[index] = op.Value
+        case "test":
+            if !reflect.DeepEqual(container[index], op.Value) {
+                return nil, fmt.Errorf("test failed")
+            }
+        }
+        return doc, nil
+    }
+    return nil, fmt.Errorf("path does not exist")
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+    if pointer == "" {
+        return nil, nil
+    }
+    if !strings.HasPrefix(pointer, "/") {
+        return nil, fmt.Errorf("path %q must be empty or start with /", pointer)
+    }
+    tokens := strings.Split(pointer[1:], "/")
+    for i, token := range tokens {
+        tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+    }
+    return tokens, nil
+}
+
+// resolvePointer returns the value tokens point to in doc
+func resolvePointer(doc interface{}, tokens []string) (interface{}, error) {
+    current := doc
+    for _, token := range tokens {
+        switch container := current.(type) {
+        case map[string]interface{}:
+            value, ok := container[token]
+            if !ok {
+                return nil, fmt.Errorf("path does not exist")
+            }
+            current = value
+        case []interface{}:
+            index, err := arrayIndex(token, len(container), false)
+            if err != nil {
+                return nil, err
+            }
+            current = container[index]
+        default:
+            return nil, fmt.Errorf("path does not exist")
+        }
+    }
+    return current, nil
+}
+
+// setPointer replaces the value tokens point to in doc, which must exist,
+// and returns the resulting document. It is needed for arrays, which change
+// length in place of being modified.
+func setPointer(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+    if len(tokens) == 0 {
+        return value, nil
+    }
+    parent, err := resolvePointer(doc, tokens[:len(tokens)-1])
+    if err != nil {
+        return nil, err
+    }
+    key := tokens[len(tokens)-1]
+    switch container := parent.(type) {
+    case map[string]interface{}:
+        container[key] = value
+    case []interface{}:
+        index, err := arrayIndex(key, len(container), false)
+        if err != nil {
+            return nil, err
+        }
+        container[index] = value
+    }
+    return doc, nil
+}
+
+// arrayIndex parses an array index token for an array of length n. Adding
+// may also use n itself, to append.
+func arrayIndex(token string, n int, adding bool) (int, error) {
+    // Indexes are plain decimal numbers without signs or leading zeros
+    index, err := strconv.Atoi(token)
+    if err != nil || strings.Trim(token, "0123456789") != "" || (token != "0" && strings.HasPrefix(token, "0")) {
+        return 0, fmt.Errorf("invalid array index %q", token)
+    }
+    if index > n || (index == n && !adding) {
+        return 0, fmt.Errorf("array index %d is out of range", index)
+    }
+    return index, nil
+}
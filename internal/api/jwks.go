@@ -0,0 +1,126 @@
+package api
+
+import (
+    "crypto/rsa"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math/big"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/golang-jwt/jwt/v4"
+)
+
+// jwksRefreshInterval limits how often an unknown key ID triggers a refetch
+const jwksRefreshInterval = time.Minute
+
+// jwksKeySet resolves RSA signing keys by key ID from a JWKS endpoint. Keys
+// are fetched lazily and refetched when a token names an unknown key.
+type jwksKeySet struct {
+    url    string
+    client *http.Client
+
+    mu          sync.Mutex
+    keys        map[string]*rsa.PublicKey
+    lastFetched time.Time
+}
+
+func newJWKSKeySet(url string) *jwksKeySet {
+    return &jwksKeySet{
+        url:    url,
+        client: &http.Client{Timeout: 10 * time.Second},
+        keys:   make(map[string]*rsa.PublicKey),
+    }
+}
+
+// keyfunc implements jwt.Keyfunc using the token's kid header
+func (s *jwksKeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+    kid, _ := token.Header["kid"].(string)
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if key, ok := s.keys[kid]; ok {
+        return key, nil
+    }
+
+    if time.Since(s.lastFetched) < jwksRefreshInterval {
+        return nil, fmt.Errorf("unknown signing key %q", kid)
+    }
+    if err := s.fetch(); err != nil {
+        return nil, err
+    }
+
+    if key, ok := s.keys[kid]; ok {
+        return key, nil
+    }
+    return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// fetch replaces the cached keys with the RSA keys served at the JWKS URL;
+// the caller must hold the lock
+func (s *jwksKeySet) fetch() error {
+    s.lastFetched = time.Now()
+
+    resp, err := s.client.Get(s.url)
+    if err != nil {
+        return fmt.Errorf("failed to fetch JWKS: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+    }
+
+    var set struct {
+        Keys []struct {
+            Kty string `json:"kty"`
+            Kid string `json:"kid"`
+            N   string `json:"n"`
+            E   string `json:"e"`
+        } `json:"keys"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+        return fmt.Errorf("failed to decode JWKS: %w", err)
+    }
+
+    keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+    for _, k := range set.Keys {
+        if k.Kty != "RSA" {
+            continue
+        }
+        key, err := parseRSAPublicKey(k.N, k.E)
+        if err != nil {
+            return fmt.Errorf("invalid JWKS key %q: %w", k.Kid, err)
+        }
+        keys[k.Kid] = key
+    }
+
+    s.keys = keys
+    return nil
+}
+
+// parseRSAPublicKey decodes the base64url modulus and exponent of a JWK
+func parseRSAPublicKey(n, e string) (*rsa.PublicKey, error) {
+    modulus, err := base64.RawURLEncoding.DecodeString(n)
+    if err != nil {
+        return nil, err
+    }
+    exponent, err := base64.RawURLEncoding.DecodeString(e)
+    if err != nil {
+        return nil, err
+    }
+
+    exp := new(big.Int).SetBytes(exponent)
+    if !exp.IsInt64() || exp.Int64() < 3 {
+        return nil, errors.New("invalid exponent")
+    }
+
+    return &rsa.PublicKey{
+        N: new(big.Int).SetBytes(modulus),
+        E: int(exp.Int64()),
+    }, nil
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+    "io"
+    "net/http"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "go.uber.org/zap"
+)
+
+// decodeItem reads an item from the JSON or YAML request body, through its
+// typed kind when one is registered for its type. It writes the error
+// response and returns false when the body is malformed or the kind rejects
+// it.
+func (h *Handler) decodeItem(w http.ResponseWriter, r *http.Request) (*registry.Item, bool) {
+    data, err := io.ReadAll(r.Body)
+    if err == nil && isYAMLRequest(r) {
+        data, err = jsonFromYAML(data)
+    }
+    if err == nil {
+        var item *registry.Item
+        if item, err = registry.DecodeItem(data); err == nil {
+            return item, true
+        }
+    }
+
+    if verr, ok := err.(*registry.ValidationError); ok {
+        h.log(r).Info("Rejected invalid item", zap.Error(err))
+        h.respondWithErrorDetails(w, http.StatusUnprocessableEntity, CodeValidationFailed, "Item has invalid fields",
+            map[string]interface{}{"fields": verr.Fields})
+        return nil, false
+    }
+    h.log(r).Error("Failed to decode request body", zap.Error(err))
+    h.respondPayloadError(w, err, "Invalid request payload")
+    return nil, false
+}
+
+// respondWithItem writes item in the JSON or YAML form of its typed kind, or
+// as a plain item when it has none or no longer matches it
+func (h *Handler) respondWithItem(w http.ResponseWriter, r *http.Request, code int, item *registry.Item) {
+    h.respondNegotiated(w, r, code, h.itemPayload(r, item))
+}
+
+// itemPayload returns item encoded as its kind, or item itself when that
+// fails
+func (h *Handler) itemPayload(r *http.Request, item *registry.Item) interface{} {
+    payload, err := registry.EncodeItem(item)
+    if err != nil {
+        h.log(r).Warn("Failed to encode item as its kind", zap.String("id", item.ID), zap.Error(err))
+        return item
+    }
+    return payload
+}
+
+// ListKinds returns the item types that have a typed kind
+func (h *Handler) ListKinds(w http.ResponseWriter, r *http.Request) {
+    h.respondWithJSON(w, http.StatusOK, registry.Kinds())
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+)
+
+// maxLinkDepth bounds how many hops GetItemLinks expands
+const maxLinkDepth = 5
+
+// resolvedLink is a link target expanded to the full item, along with the
+// target's own links when more hops were requested
+type resolvedLink struct {
+    Item  *registry.Item            `json:"item"`
+    Links map[string][]resolvedLink `json:"links,omitempty"`
+}
+
+func (h *Handler) AddItemLinks(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+
+    var req struct {
+        Relation string   `json:"relation"`
+        Targets  []string `json:"targets"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        h.log(r).Error("Failed to decode request body", zap.Error(err))
+        h.respondPayloadError(w, err, "Invalid request payload")
+        return
+    }
+    if req.Relation == "" || len(req.Targets) == 0 {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidPayload, "A relation and at least one target are required")
+        return
+    }
+
+    item, err := h.store.GetItemCtx(r.Context(), id)
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+    if h.outOfScope(w, r, item.RegistryName) || h.notOwner(w, r, item) || h.locked(w, r, id) {
+        return
+    }
+
+    // Every target must be a live item other than the source
+    var invalid []string
+    for _, target := range req.Targets {
+        if target == id {
+            invalid = append(invalid, target)
+            continue
+        }
+        if _, err := h.store.GetItemCtx(r.Context(), target); err != nil {
+            invalid = append(invalid, target)
+        }
+    }
+    if len(invalid) > 0 {
+        h.respondWithErrorDetails(w, http.StatusUnprocessableEntity, CodeInvalidLinkTargets,
+            "Link targets must be existing, non-deleted items", map[string][]string{"invalid": invalid})
+        return
+    }
+
+    before := item.Version
+    updated, err := h.store.AddLinks(id, req.Relation, req.Targets)
+    if err != nil {
+        h.log(r).Error("Failed to add item links", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to add item links")
+        return
+    }
+
+    h.metrics.itemUpdated(updated)
+    h.notifier.Notify(notify.EventItemUpdated, updated.ID, updated.Type, updated.RegistryName)
+    h.recordAudit(r, audit.ActionUpdate, updated.ID, before, updated.Version)
+
+    w.Header().Set("ETag", versionETag(updated.Version))
+    h.respondWithJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handler) GetItemLinks(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+
+    depth := 1
+    if value := r.URL.Query().Get("depth"); value != "" {
+        n, err := strconv.Atoi(value)
+        if err != nil || n < 1 || n > maxLinkDepth {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "depth must be between 1 and "+strconv.Itoa(maxLinkDepth))
+            return
+        }
+        depth = n
+    }
+
+    item, err := h.store.GetItemCtx(r.Context(), id)
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+
+    h.respondWithJSON(w, http.StatusOK, h.resolveLinks(r, item, depth, map[string]bool{id: true}))
+}
+
+// resolveLinks expands the links of item to full items, following them for
+// depth hops. Targets deleted since they were linked are left out, and items
+// already on the current path are not expanded again so cycles terminate.
+func (h *Handler) resolveLinks(r *http.Request, item *registry.Item, depth int, path map[string]bool) map[string][]resolvedLink {
+    resolved := make(map[string][]resolvedLink, len(item.Links))
+    for relation, targets := range item.Links {
+        links := []resolvedLink{}
+        for _, targetID := range targets {
+            target, err := h.store.GetItemCtx(r.Context(), targetID)
+            if err != nil {
+                continue
+            }
+
+            link := resolvedLink{Item: target}
+            if depth > 1 && !path[targetID] {
+                path[targetID] = true
+                link.Links = h.resolveLinks(r, target, depth-1, path)
+                delete(path, targetID)
+            }
+            links = append(links, link)
+        }
+        resolved[relation] = links
+    }
+    return resolved
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+)
+
+// lockTokenHeader carries the token of the lease held on an item
+const lockTokenHeader = "X-Lock-Token"
+
+// DefaultLockTTL and MaxLockTTL bound how long a lease lasts before it must
+// be renewed
+const (
+    DefaultLockTTL = time.Minute
+    MaxLockTTL     = time.Hour
+)
+
+// LockItem leases an item to the caller for the ?ttl= or {"ttl": "..."}
+// duration, one minute by default. Sending the current token in X-Lock-Token
+// renews the lease; otherwise a held lease fails with 423.
+func (h *Handler) LockItem(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+
+    locks, ok := h.store.(storage.LockStore)
+    if !ok {
+        h.respondWithError(w, http.StatusNotImplemented, CodeNotImplemented, "The storage backend does not support item locks")
+        return
+    }
+
+    var req struct {
+        TTL string `json:"ttl"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+        h.log(r).Info("Failed to decode request body", zap.Error(err))
+        h.respondPayloadError(w, err, "Invalid request payload")
+        return
+    }
+    if value := r.URL.Query().Get("ttl"); value != "" {
+        req.TTL = value
+    }
+    ttl := DefaultLockTTL
+    if req.TTL != "" {
+        d, err := time.ParseDuration(req.TTL)
+        if err != nil || d <= 0 || d > MaxLockTTL {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "ttl must be a positive duration of at most "+MaxLockTTL.String())
+            return
+        }
+        ttl = d
+    }
+
+    item, err := h.store.GetItemCtx(r.Context(), id)
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+    if h.outOfScope(w, r, item.RegistryName) {
+        return
+    }
+
+    lease, err := locks.AcquireLock(id, requestActor(r), r.Header.Get(lockTokenHeader), ttl)
+    if err == storage.ErrItemLocked {
+        h.respondLocked(w, lease)
+        return
+    }
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+
+    h.respondWithJSON(w, http.StatusOK, lease)
+}
+
+// UnlockItem releases the lease whose token is sent in X-Lock-Token. Admins
+// may break a lease without its token.
+func (h *Handler) UnlockItem(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+
+    locks, ok := h.store.(storage.LockStore)
+    if !ok {
+        h.respondWithError(w, http.StatusNotImplemented, CodeNotImplemented, "The storage backend does not support item locks")
+        return
+    }
+
+    token := r.Header.Get(lockTokenHeader)
+    if p := principalFrom(r.Context()); p != nil && p.role == RoleAdmin {
+        if lease, err := locks.CheckLock(id, token); err == storage.ErrItemLocked {
+            token = lease.Token
+        }
+    }
+
+    err := locks.ReleaseLock(id, token)
+    if err == storage.ErrLockNotHeld {
+        h.respondWithError(w, http.StatusConflict, CodeLockNotHeld, "Item is not locked")
+        return
+    }
+    if err == storage.ErrItemLocked {
+        lease, _ := locks.CheckLock(id, token)
+        h.respondLocked(w, lease)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// locked writes a 423 and reports true when another caller's lease covers
+// the item with the given ID, unless the request carries its token
+func (h *Handler) locked(w http.ResponseWriter, r *http.Request, id string) bool {
+    locks, ok := h.store.(storage.LockStore)
+    if !ok {
+        return false
+    }
+    lease, err := locks.CheckLock(id, r.Header.Get(lockTokenHeader))
+    if !errors.Is(err, storage.ErrItemLocked) {
+        return false
+    }
+    h.respondLocked(w, lease)
+    return true
+}
+
+// lockedMatches returns the IDs of the items matching filter that another
+// caller's lease covers
+func (h *Handler) lockedMatches(r *http.Request, filter storage.DeleteFilter) ([]string, error) {
+    locks, ok := h.store.(storage.LockStore)
+    if !ok {
+        return nil, nil
+    }
+    matched, err := h.matchItems(r, filter)
+    if err != nil {
+        return nil, err
+    }
+
+    var ids []string
+    for _, item := range matched {
+        if _, err := locks.CheckLock(item.ID, r.Header.Get(lockTokenHeader)); err == storage.ErrItemLocked {
+            ids = append(ids, item.ID)
+        }
+    }
+    return ids, nil
+}
+
+// respondLocked answers 423 with the holder and expiry of lease, but not its token
+func (h *Handler) respondLocked(w http.ResponseWriter, lease storage.Lease) {
+    h.respondWithErrorDetails(w, http.StatusLocked, CodeItemLocked, "Item is locked by "+lease.Holder,
+        map[string]interface{}{"holder": lease.Holder, "expiresAt": lease.ExpiresAt})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "os"
+    "strconv"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// metadataLimitFromEnv reads the item metadata size limit in bytes from
+// MAX_METADATA_BYTES. Unset or 0 sets no limit.
+func metadataLimitFromEnv() (int, error) {
+    value := os.Getenv("MAX_METADATA_BYTES")
+    if value == "" {
+        return 0, nil
+    }
+    limit, err := strconv.Atoi(value)
+    if err != nil || limit < 0 {
+        return 0, fmt.Errorf("invalid MAX_METADATA_BYTES: %q", value)
+    }
+    return limit, nil
+}
+
+// checkMetadataSize returns the field error of an item whose metadata is
+// larger than the limit, as measured by Item.MetadataSize
+func (h *Handler) checkMetadataSize(item *registry.Item) *registry.FieldError {
+    if h.metadataLimit <= 0 {
+        return nil
+    }
+    if size := item.MetadataSize(); size > h.metadataLimit {
+        return &registry.FieldError{
+            Field:   "metadata",
+            Message: fmt.Sprintf("is %d bytes, over the limit of %d", size, h.metadataLimit),
+        }
+    }
+    return nil
+}
+
+// metadataTooLarge answers 413 when the metadata of item is over the limit
+func (h *Handler) metadataTooLarge(w http.ResponseWriter, item *registry.Item) bool {
+    fieldErr := h.checkMetadataSize(item)
+    if fieldErr == nil {
+        return false
+    }
+    h.respondWithErrorDetails(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "Item metadata is too large",
+        map[string]interface{}{"fields": []registry.FieldError{*fieldErr}})
+    return true
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+    "bufio"
+    "errors"
+    "net"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/collectors"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the Prometheus collectors for registry operations
+type Metrics struct {
+    registry *prometheus.Registry
+
+    itemsCreated    prometheus.Counter
+    itemsUpdated    prometheus.Counter
+    itemsDeleted    prometheus.Counter
+    metadataSize    *prometheus.HistogramVec
+    requestDuration *prometheus.HistogramVec
+}
+
+// NewMetrics creates the registry metrics and registers them, along with an
+// item count gauge computed from store on every scrape
+func NewMetrics(store storage.Store) *Metrics {
+    m := &Metrics{
+        registry: prometheus.NewRegistry(),
+        itemsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+            Name: "registry_items_created_total",
+            Help: "Total number of items created.",
+        }),
+        itemsUpdated: prometheus.NewCounter(prometheus.CounterOpts{
+            Name: "registry_items_updated_total",
+            Help: "Total number of items updated.",
+        }),
+        itemsDeleted: prometheus.NewCounter(prometheus.CounterOpts{
+            Name: "registry_items_deleted_total",
+            Help: "Total number of items deleted.",
+        }),
+        metadataSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Name:    "registry_item_metadata_bytes",
+            Help:    "Approximate JSON size of item metadata on create and update.",
+            Buckets: prometheus.ExponentialBuckets(64, 4, 9),
+        }, []string{"operation"}),
+        requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Name:    "registry_http_request_duration_seconds",
+            Help:    "Latency of HTTP handlers.",
+            Buckets: prometheus.DefBuckets,
+        }, []string{"method", "route", "status"}),
+    }
+
+    m.registry.MustRegister(
+        m.itemsCreated,
+        m.itemsUpdated,
+        m.itemsDeleted,
+        m.metadataSize,
+        m.requestDuration,
+        &itemCountCollector{store: store},
+        collectors.NewGoCollector(),
+        collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+    )
+
+    return m
+}
+
+// itemCreated counts a created item and records its metadata size
+func (m *Metrics) itemCreated(item *registry.Item) {
+    m.itemsCreated.Inc()
+    m.metadataSize.WithLabelValues("create").Observe(float64(item.MetadataSize()))
+}
+
+// itemUpdated counts an updated item and records its metadata size
+func (m *Metrics) itemUpdated(item *registry.Item) {
+    m.itemsUpdated.Inc()
+    m.metadataSize.WithLabelValues("update").Observe(float64(item.MetadataSize()))
+}
+
+// Handler serves the collected metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+    return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records the latency of every request routed through mux
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        start := time.Now()
+        rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+        next.ServeHTTP(rec, r)
+
+        m.requestDuration.
+            WithLabelValues(r.Method, routeTemplate(r), strconv.Itoa(rec.status)).
+            Observe(time.Since(start).Seconds())
+    })
+}
+
+// itemCountCollector reports the number of non-deleted items per type. The
+// count is recomputed from storage on every scrape so it can never drift.
+type itemCountCollector struct {
+    store storage.Store
+}
+
+var itemCountDesc = prometheus.NewDesc(
+    "registry_items",
+    "Current number of non-deleted items by type.",
+    []string{"type"}, nil,
+)
+
+func (c *itemCountCollector) Describe(ch chan<- *prometheus.Desc) {
+    ch <- itemCountDesc
+}
+
+func (c *itemCountCollector) Collect(ch chan<- prometheus.Metric) {
+    counts := make(map[string]int)
+    for _, item := range c.store.List() {
+        counts[item.GetType()]++
+    }
+
+    for itemType, count := range counts {
+        ch <- prometheus.MustNewConstMetric(itemCountDesc, prometheus.GaugeValue, float64(count), itemType)
+    }
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+    r.status = code
+    r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush sends buffered data of streamed responses to the client
+func (r *statusRecorder) Flush() {
+    http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+    return r.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades take over the connection
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    hijacker, ok := r.ResponseWriter.(http.Hijacker)
+    if !ok {
+        return nil, nil, errors.New("response writer does not support hijacking")
+    }
+    r.status = http.StatusSwitchingProtocols
+    return hijacker.Hijack()
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+    "net/http"
+    "strconv"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "go.uber.org/zap"
+)
+
+// upsert reports whether a create should update the item with the same
+// natural key instead of failing
+func upsert(r *http.Request) bool {
+    enabled, _ := strconv.ParseBool(r.URL.Query().Get("upsert"))
+    return enabled
+}
+
+// findDuplicate returns the stored item, other than item itself, that has
+// the natural key of item. Stores without natural keys find nothing.
+func (h *Handler) findDuplicate(item *registry.Item) (*registry.Item, bool) {
+    store, ok := h.store.(storage.NaturalKeyStore)
+    if !ok {
+        return nil, false
+    }
+    existing, found := store.FindByNaturalKey(item)
+    if !found || existing.ID == item.ID {
+        return nil, false
+    }
+    return existing, true
+}
+
+// respondDuplicate rejects a write that would duplicate the natural key of
+// another item, which is returned in the details when it can be found
+func (h *Handler) respondDuplicate(w http.ResponseWriter, item *registry.Item) {
+    var details map[string]interface{}
+    if existing, found := h.findDuplicate(item); found {
+        details = map[string]interface{}{"existing": existing}
+    }
+    h.respondWithErrorDetails(w, http.StatusConflict, CodeDuplicateItem, "An item with the same natural key already exists", details)
+}
+
+// upsertItem applies a create sent with ?upsert=true to existing, the item
+// with the same natural key
+func (h *Handler) upsertItem(w http.ResponseWriter, r *http.Request, item, existing *registry.Item) {
+    if h.outOfScope(w, r, existing.RegistryName) || h.notOwner(w, r, existing) || h.locked(w, r, existing.ID) {
+        return
+    }
+    item.ID = existing.ID
+    before := existing.Version
+
+    if dryRun(r) {
+        previewUpdate(item, existing)
+        h.respondDryRun(w, item)
+        return
+    }
+
+    updatedItem, err := h.store.UpdateItemCtx(r.Context(), item)
+    if err == registry.ErrStaleVersion {
+        h.respondVersionConflict(w, r, item.ID)
+        return
+    }
+    if err == storage.ErrDuplicateItem {
+        h.respondDuplicate(w, item)
+        return
+    }
+    if err != nil {
+        h.log(r).Error("Failed to upsert item", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to update item")
+        return
+    }
+
+    setItemAttributes(r.Context(), updatedItem)
+    h.metrics.itemUpdated(updatedItem)
+    h.notifier.Notify(notify.EventItemUpdated, updatedItem.ID, updatedItem.Type, updatedItem.RegistryName)
+    h.recordAudit(r, audit.ActionUpdate, updatedItem.ID, before, updatedItem.Version)
+
+    w.Header().Set("ETag", versionETag(updatedItem.Version))
+    h.respondWithItem(w, r, http.StatusOK, updatedItem)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+    "encoding/json"
+    "mime"
+    "net/http"
+    "strings"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "go.uber.org/zap"
+)
+
+// ndjsonContentType is the media type of newline-delimited JSON
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonFlushEvery is the number of lines written between flushes. The first
+// line is flushed on its own, so clients see results right away.
+const ndjsonFlushEvery = 100
+
+// wantsNDJSON reports whether the client asked for newline-delimited JSON,
+// with ?format=ndjson or an Accept header listing application/x-ndjson
+func wantsNDJSON(r *http.Request) bool {
+    if r.URL.Query().Get("format") == "ndjson" {
+        return true
+    }
+    for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+        if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == ndjsonContentType {
+            return true
+        }
+    }
+    return false
+}
+
+// writeItemsNDJSON streams items one JSON object per line, flushing to the
+// client as lines are written so it can process them incrementally
+func (h *Handler) writeItemsNDJSON(w http.ResponseWriter, r *http.Request, items []registry.Registerable) {
+    w.Header().Set("Content-Type", ndjsonContentType)
+
+    enc := json.NewEncoder(w)
+    rc := http.NewResponseController(w)
+    for i, item := range items {
+        if err := enc.Encode(item); err != nil {
+            h.log(r).Warn("Failed to stream items", zap.Error(err))
+            return
+        }
+        if i == 0 || (i+1)%ndjsonFlushEvery == 0 {
+            rc.Flush()
+        }
+    }
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+    "bufio"
+    "compress/gzip"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// blockingValue is a metadata value whose encoding waits until it is closed,
+// holding a streaming handler in the middle of its response
+type blockingValue chan struct{}
+
+func (b blockingValue) MarshalJSON() ([]byte, error) {
+    <-b
+    return []byte(`"released"`), nil
+}
+
+func TestNDJSONStreamsBeforeHandlerFinishes(t *testing.T) {
+    tests := []struct {
+        name     string
+        encoding string
+    }{
+        {name: "identity"},
+        {name: "gzip", encoding: "gzip"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := newTestServer(t)
+            srv := httptest.NewServer(s.handler)
+            defer srv.Close()
+
+            // The first line is flushed on its own, and the handler then
+            // blocks on the second item
+            if _, err := s.store.CreateItem(&registry.Item{ID: "item-000", Type: "service", Name: "first", RegistryName: "team-a"}); err != nil {
+                t.Fatal(err)
+            }
+            time.Sleep(time.Millisecond)
+            second, err := s.store.CreateItem(&registry.Item{ID: "item-001", Type: "service", Name: "second", RegistryName: "team-a"})
+            if err != nil {
+                t.Fatal(err)
+            }
+            release := make(blockingValue)
+            defer close(release)
+            second.Metadata = map[string]interface{}{"block": release}
+
+            req, _ := http.NewRequest("GET", srv.URL+"/api/v1/items", nil)
+            req.Header.Set("Accept", ndjsonContentType)
+            if tt.encoding != "" {
+                req.Header.Set("Accept-Encoding", tt.encoding)
+            }
+
+            lines := make(chan string, 1)
+            errs := make(chan error, 1)
+            go func() {
+                resp, err := http.DefaultClient.Do(req)
+                if err != nil {
+                    errs <- err
+                    return
+                }
+                defer resp.Body.Close()
+                var body io.Reader = resp.Body
+                if resp.Header.Get("Content-Encoding") == "gzip" {
+                    if body, err = gzip.NewReader(resp.Body); err != nil {
+                        errs <- err
+                        return
+                    }
+                } else if tt.encoding != "" {
+                    errs <- fmt.Errorf("response is not compressed: %v", resp.Header)
+                    return
+                }
+                line, err := bufio.NewReader(body).ReadString('\n')
+                if err != nil {
+                    errs <- err
+                    return
+                }
+                lines <- line
+            }()
+
+            select {
+            case line := <-lines:
+                var got registry.Item
+                if err := json.Unmarshal([]byte(line), &got); err != nil || got.ID != "item-000" {
+                    t.Errorf("first line = %q (%v), want item-000", line, err)
+                }
+            case err := <-errs:
+                t.Fatal(err)
+            case <-time.After(5 * time.Second):
+                t.Fatal("no line arrived while the handler was still streaming")
+            }
+        })
+    }
+}
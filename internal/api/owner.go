@@ -0,0 +1,44 @@
+package api
+
+import (
+    "context"
+    "net/http"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+)
+
+// stampOwner sets the owner of an item being created to the authenticated
+// caller. Admins may assign another owner by setting it themselves, and
+// without authentication the owner is taken as sent.
+func stampOwner(r *http.Request, item *registry.Item) {
+    p := principalFrom(r.Context())
+    if p == nil || (p.role == RoleAdmin && item.Owner != "") {
+        return
+    }
+    item.Owner = p.subject
+}
+
+// notOwner writes a 403 and reports true when updates are restricted to
+// owners and the caller neither owns item nor is an admin. Items without an
+// owner stay open to every caller.
+func (h *Handler) notOwner(w http.ResponseWriter, r *http.Request, item *registry.Item) bool {
+    p := principalFrom(r.Context())
+    if p == nil || !p.ownerOnly || p.role == RoleAdmin || item.Owner == "" || item.Owner == p.subject {
+        return false
+    }
+    h.respondWithError(w, http.StatusForbidden, CodeNotOwner, "Only the owner of the item may update it")
+    return true
+}
+
+// listByOwner lists the non-deleted items owned by owner
+func (h *Handler) listByOwner(ctx context.Context, owner string) ([]registry.Registerable, error) {
+    if store, ok := h.store.(storage.OwnerStore); ok {
+        return store.ListByOwner(owner), nil
+    }
+    items, err := h.store.ListCtx(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return storage.FilterByOwner(items, owner), nil
+}
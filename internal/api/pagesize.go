@@ -0,0 +1,63 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "os"
+    "strconv"
+)
+
+// DefaultPageSize and DefaultMaxPageSize apply when DEFAULT_PAGE_SIZE and
+// MAX_PAGE_SIZE are unset
+const (
+    DefaultPageSize    = 100
+    DefaultMaxPageSize = 100
+)
+
+// pageLimitHeader reports the number of items a listing was limited to
+const pageLimitHeader = "X-Page-Limit"
+
+// pageSizesFromEnv reads the page size used when a listing asks for none
+// from DEFAULT_PAGE_SIZE, and the largest page a listing may ask for from
+// MAX_PAGE_SIZE. A default above the maximum is lowered to it.
+func pageSizesFromEnv() (int, int, error) {
+    size, err := pageSizeFromEnv("DEFAULT_PAGE_SIZE", DefaultPageSize)
+    if err != nil {
+        return 0, 0, err
+    }
+    max, err := pageSizeFromEnv("MAX_PAGE_SIZE", DefaultMaxPageSize)
+    if err != nil {
+        return 0, 0, err
+    }
+    if size > max {
+        size = max
+    }
+    return size, max, nil
+}
+
+func pageSizeFromEnv(name string, fallback int) (int, error) {
+    value := os.Getenv(name)
+    if value == "" {
+        return fallback, nil
+    }
+    size, err := strconv.Atoi(value)
+    if err != nil || size < 1 {
+        return 0, fmt.Errorf("invalid %s: %q", name, value)
+    }
+    return size, nil
+}
+
+// pageLimit returns the number of items a listing asking for requested
+// items returns: the default page size when it asks for none, and at most
+// the maximum page size. The result is reported in X-Page-Limit.
+func (h *Handler) pageLimit(w http.ResponseWriter, requested int) int {
+    limit := requested
+    if limit <= 0 {
+        limit = h.pageSize
+    }
+    if limit > h.maxPageSize {
+        limit = h.maxPageSize
+    }
+    w.Header().Set(pageLimitHeader, strconv.Itoa(limit))
+    return limit
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "testing"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+)
+
+func TestPageLimit(t *testing.T) {
+    tests := []struct {
+        name        string
+        defaultSize string
+        maxSize     string
+        query       string
+        wantLimit   int
+    }{
+        {name: "default page", query: "", wantLimit: DefaultPageSize},
+        {name: "requested page", query: "?limit=10", wantLimit: 10},
+        {name: "oversized page", query: "?limit=1000000", wantLimit: DefaultMaxPageSize},
+        {name: "negative limit", query: "?limit=-5", wantLimit: DefaultPageSize},
+        {name: "configured default", defaultSize: "20", maxSize: "50", query: "", wantLimit: 20},
+        {name: "configured maximum", defaultSize: "20", maxSize: "50", query: "?limit=80", wantLimit: 50},
+        {name: "default above maximum", defaultSize: "80", maxSize: "30", query: "", wantLimit: 30},
+        {name: "invalid configuration", defaultSize: "many", maxSize: "0", query: "?limit=150", wantLimit: DefaultMaxPageSize},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            t.Setenv("DEFAULT_PAGE_SIZE", tt.defaultSize)
+            t.Setenv("MAX_PAGE_SIZE", tt.maxSize)
+            s := newTestServer(t)
+            for i := 0; i < 150; i++ {
+                id := fmt.Sprintf("item-%03d", i)
+                if _, err := s.store.CreateItem(&registry.Item{ID: id, Type: "service", Name: id, RegistryName: "team-a"}); err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            rec := s.do(t, "GET", "/api/v1/items"+tt.query, nil)
+            if rec.Code != http.StatusOK {
+                t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+            }
+            if got := rec.Header().Get(pageLimitHeader); got != strconv.Itoa(tt.wantLimit) {
+                t.Errorf("%s = %s, want %d", pageLimitHeader, got, tt.wantLimit)
+            }
+            var items []json.RawMessage
+            if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+                t.Fatalf("decode: %v", err)
+            }
+            if len(items) != tt.wantLimit {
+                t.Errorf("got %d items, want %d", len(items), tt.wantLimit)
+            }
+            if total := rec.Header().Get("X-Total-Count"); total != "150" {
+                t.Errorf("X-Total-Count = %s, want 150", total)
+            }
+        })
+    }
+}
+
+// Polling with the latest updatedAt of each page as the next updatedSince
+// reaches every item, even past the default page size and when items were
+// updated in a different order than they were created
+func TestUpdatedSincePolling(t *testing.T) {
+    s := newTestServer(t)
+    base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    const count = 150
+    for i := 0; i < count; i++ {
+        id := fmt.Sprintf("item-%03d", i)
+        item := &registry.Item{ID: id, Type: "service", Name: id, RegistryName: "team-a",
+            CreatedAt: base.Add(time.Duration(i) * time.Second), UpdatedAt: base.Add(time.Duration(count-i) * time.Minute)}
+        if _, err := s.store.CreateItem(item); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    seen := make(map[string]bool)
+    since := base
+    for polls := 0; polls < 10; polls++ {
+        rec := s.do(t, "GET", "/api/v1/items?updatedSince="+url.QueryEscape(since.Format(time.RFC3339)), nil)
+        if rec.Code != http.StatusOK {
+            t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+        }
+        var items []struct {
+            ID        string    `json:"id"`
+            UpdatedAt time.Time `json:"updatedAt"`
+        }
+        if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+            t.Fatalf("decode: %v", err)
+        }
+        if len(items) == 0 {
+            break
+        }
+        for _, item := range items {
+            seen[item.ID] = true
+            if item.UpdatedAt.After(since) {
+                since = item.UpdatedAt
+            }
+        }
+    }
+    if len(seen) != count {
+        t.Errorf("polling saw %d items, want %d", len(seen), count)
+    }
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+    "encoding/json"
+    "mime"
+    "net/http"
+    "strconv"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+)
+
+// mergePatchContentType is the media type of an RFC 7386 JSON merge patch
+const mergePatchContentType = "application/merge-patch+json"
+
+// readOnlyItemFields are maintained by the service and cannot be patched
+var readOnlyItemFields = []string{"id", "owner", "createdBy", "updatedBy", "version", "createdAt", "updatedAt", "deleted"}
+
+// PatchItem applies a JSON merge patch (RFC 7386) or, with the
+// application/json-patch+json content type, a JSON Patch (RFC 6902) to an
+// item. In a merge patch, fields absent from the patch keep their value,
+// objects such as metadata are merged key by key, and null removes a key.
+// Arrays such as tags are replaced whole.
+func (h *Handler) PatchItem(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+
+    mediaType := mergePatchContentType
+    if contentType := r.Header.Get("Content-Type"); contentType != "" {
+        var err error
+        mediaType, _, err = mime.ParseMediaType(contentType)
+        if err != nil || (mediaType != mergePatchContentType && mediaType != jsonPatchContentType && mediaType != "application/json") {
+            h.respondWithError(w, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "PATCH requires "+mergePatchContentType+" or "+jsonPatchContentType)
+            return
+        }
+    }
+
+    var apply func(*registry.Item) (*registry.Item, error)
+    if mediaType == jsonPatchContentType {
+        var raw []map[string]interface{}
+        if err := json.NewDecoder(r.Body).Decode(&raw); err != nil || raw == nil {
+            h.log(r).Info("Failed to decode JSON patch", zap.Error(err))
+            h.respondPayloadError(w, err, "Request body must be a JSON array of operations")
+            return
+        }
+        ops, err := parseJSONPatch(raw)
+        if err != nil {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+            return
+        }
+        apply = func(item *registry.Item) (*registry.Item, error) { return applyJSONPatch(item, ops) }
+    } else {
+        var patch map[string]interface{}
+        if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+            h.log(r).Info("Failed to decode merge patch", zap.Error(err))
+            h.respondPayloadError(w, err, "Request body must be a JSON object")
+            return
+        }
+        for _, field := range readOnlyItemFields {
+            if _, ok := patch[field]; ok {
+                h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Field "+strconv.Quote(field)+" cannot be patched")
+                return
+            }
+        }
+        apply = func(item *registry.Item) (*registry.Item, error) { return applyMergePatch(item, patch) }
+    }
+
+    current, err := h.store.GetItemCtx(r.Context(), id)
+    if err != nil {
+        h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+    if h.outOfScope(w, r, current.RegistryName) || h.notOwner(w, r, current) || h.locked(w, r, id) {
+        return
+    }
+
+    if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+        expected, err := parseVersionETag(ifMatch)
+        if err != nil {
+            h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid If-Match header")
+            return
+        }
+        if current.Version != expected {
+            w.Header().Set("ETag", versionETag(current.Version))
+            h.respondWithJSON(w, http.StatusConflict, current)
+            return
+        }
+    }
+
+    item, err := apply(current)
+    if patchErr, ok := err.(*jsonPatchError); ok {
+        h.respondWithErrorDetails(w, http.StatusUnprocessableEntity, CodePatchFailed, "JSON patch "+patchErr.Error(), patchErr)
+        return
+    }
+    if err != nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidPayload, "Patch does not produce a valid item: "+err.Error())
+        return
+    }
+    if err := item.Validate(); err != nil {
+        h.respondWithErrorDetails(w, http.StatusUnprocessableEntity, CodeValidationFailed, "Patched item is missing required fields",
+            map[string]interface{}{"fields": err.(*registry.ValidationError).Fields})
+        return
+    }
+    if !h.checkType(w, item) || h.metadataTooLarge(w, item) {
+        return
+    }
+    if h.outOfScope(w, r, item.RegistryName) {
+        return
+    }
+
+    // A version above the current one makes every backend apply the update
+    before := current.Version
+    item.Version = before + 1
+    stampUpdatedBy(r, item)
+
+    if dryRun(r) {
+        previewUpdate(item, current)
+        h.respondDryRun(w, item)
+        return
+    }
+
+    updatedItem, err := h.store.UpdateItemCtx(r.Context(), item)
+    if err == registry.ErrStaleVersion {
+        h.respondVersionConflict(w, r, item.ID)
+        return
+    }
+    if err == storage.ErrDuplicateItem {
+        h.respondDuplicate(w, item)
+        return
+    }
+    if err == registry.ErrRegistryNameRequired {
+        h.respondWithError(w, http.StatusUnprocessableEntity, CodeRegistryNameRequired, "Registry name is required")
+        return
+    }
+    if err != nil {
+        h.log(r).Error("Failed to patch item", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to update item")
+        return
+    }
+
+    setItemAttributes(r.Context(), updatedItem)
+    h.metrics.itemUpdated(updatedItem)
+    h.notifier.Notify(notify.EventItemUpdated, updatedItem.ID, updatedItem.Type, updatedItem.RegistryName)
+    h.recordAudit(r, audit.ActionUpdate, updatedItem.ID, before, updatedItem.Version)
+
+    w.Header().Set("ETag", versionETag(updatedItem.Version))
+    h.respondWithJSON(w, http.StatusOK, updatedItem)
+}
+
+// applyMergePatch returns a copy of item with patch merged into its JSON form
+func applyMergePatch(item *registry.Item, patch map[string]interface{}) (*registry.Item, error) {
+    doc, err := itemDocument(item)
+    if err != nil {
+        return nil, err
+    }
+    return itemFromDocument(item, mergePatch(doc, patch))
+}
+
+// itemDocument returns the JSON form of item as generic values, for patches
+// to be applied to
+func itemDocument(item *registry.Item) (interface{}, error) {
+    data, err := json.Marshal(item)
+    if err != nil {
+        return nil, err
+    }
+    var doc interface{}
+    if err := json.Unmarshal(data, &doc); err != nil {
+        return nil, err
+    }
+    return doc, nil
+}
+
+// itemFromDocument decodes a patched JSON form of item, which keeps its ID
+func itemFromDocument(item *registry.Item, doc interface{}) (*registry.Item, error) {
+    data, err := json.Marshal(doc)
+    if err != nil {
+        return nil, err
+    }
+    var patched registry.Item
+    if err := json.Unmarshal(data, &patched); err != nil {
+        return nil, err
+    }
+    patched.ID = item.ID
+    return &patched, nil
+}
+
+// mergePatch implements the RFC 7386 MergePatch algorithm
+func mergePatch(target, patch interface{}) interface{} {
+    patchObject, ok := patch.(map[string]interface{})
+    if !ok {
+        return patch
+    }
+    targetObject, ok := target.(map[string]interface{})
+    if !ok {
+        targetObject = make(map[string]interface{})
+    }
+    for key, value := range patchObject {
+        if value == nil {
+            delete(targetObject, key)
+        } else {
+            targetObject[key] = mergePatch(targetObject[key], value)
+        }
+    }
+    return targetObject
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+)
+
+// ItemQuery is the body of POST /api/v1/items/query. Every criterion that is
+// set must match. Metadata maps keys to the expressions of the meta.{key}
+// parameters of ListItems, such as "gte:3" or "in:a,b".
+type ItemQuery struct {
+    Type         string            `json:"type"`
+    RegistryName string            `json:"registryName"`
+    Metadata     map[string]string `json:"metadata"`
+    Tags         []string          `json:"tags"`
+    CreatedAfter *time.Time        `json:"createdAfter"`
+    Sort         string            `json:"sort"`
+    Order        string            `json:"order"`
+    Limit        int               `json:"limit"`
+    Cursor       string            `json:"cursor"`
+}
+
+// itemCriteria selects non-deleted items; zero fields match everything
+type itemCriteria struct {
+    itemType     string
+    registryName string
+    tags         []string
+    metadata     map[string]string
+    predicates   []storage.MetadataPredicate
+    createdAfter time.Time
+}
+
+// newItemCriteria normalizes the type and parses the metadata filters, which
+// fail with storage.ErrInvalidMetadataFilter
+func (h *Handler) newItemCriteria(itemType, registryName string, tags []string, metadata map[string]string) (itemCriteria, error) {
+    predicates, err := storage.ParseMetadataFilters(metadata)
+    if err != nil {
+        return itemCriteria{}, err
+    }
+    return itemCriteria{
+        itemType:     h.types.Normalize(itemType),
+        registryName: registryName,
+        tags:         tags,
+        metadata:     metadata,
+        predicates:   predicates,
+    }, nil
+}
+
+// matches reports whether item meets every criterion
+func (c itemCriteria) matches(item *registry.Item) bool {
+    if (c.itemType != "" && item.Type != c.itemType) ||
+        (c.registryName != "" && item.RegistryName != c.registryName) ||
+        (!c.createdAfter.IsZero() && !item.CreatedAt.After(c.createdAfter)) ||
+        !item.HasTags(c.tags...) {
+        return false
+    }
+    for _, p := range c.predicates {
+        if !p.Matches(item.Metadata) {
+            return false
+        }
+    }
+    return true
+}
+
+// findItems lists the items matching c in the default order. The most
+// selective lookup the store offers narrows the candidates, and the other
+// criteria are applied to them.
+func (h *Handler) findItems(ctx context.Context, c itemCriteria) ([]*registry.Item, error) {
+    var candidates []registry.Registerable
+    var err error
+    switch {
+    case len(c.metadata) > 0:
+        candidates, err = h.store.ListByMetadataCtx(ctx, c.metadata)
+    case len(c.tags) > 0:
+        candidates, err = h.store.ListByTagCtx(ctx, c.tags...)
+    case c.registryName != "":
+        candidates, err = h.store.ListByRegistryNameCtx(ctx, c.registryName)
+    case c.itemType != "":
+        candidates = h.store.ListByType(c.itemType)
+    default:
+        candidates, err = h.store.ListCtx(ctx)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var matched []*registry.Item
+    for _, candidate := range candidates {
+        if item, ok := candidate.(*registry.Item); ok && c.matches(item) {
+            matched = append(matched, item)
+        }
+    }
+    return matched, nil
+}
+
+// QueryItems lists the items matching the ItemQuery in the body as a Page,
+// for filters too complex for query parameters. Pass the nextCursor of a page
+// as the cursor of the same query to get the next one.
+func (h *Handler) QueryItems(w http.ResponseWriter, r *http.Request) {
+    var query ItemQuery
+    dec := json.NewDecoder(r.Body)
+    dec.DisallowUnknownFields()
+    if err := dec.Decode(&query); err != nil {
+        h.log(r).Info("Rejected invalid item query")
+        h.respondPayloadError(w, err, "Invalid item query: "+err.Error())
+        return
+    }
+    if query.Limit < 0 {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "limit must not be negative")
+        return
+    }
+    limit := h.pageLimit(w, query.Limit)
+    sortBy, err := storage.ParseSort(query.Sort, query.Order)
+    if err != nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+        return
+    }
+    if query.RegistryName != "" && h.outOfScope(w, r, query.RegistryName) {
+        return
+    }
+
+    criteria, err := h.newItemCriteria(query.Type, query.RegistryName, query.Tags, query.Metadata)
+    if err != nil {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+        return
+    }
+    if query.CreatedAfter != nil {
+        criteria.createdAfter = *query.CreatedAfter
+    }
+
+    items, err := h.findItems(r.Context(), criteria)
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+    total := len(items)
+
+    page, err := storage.SortedPage(items, sortBy, limit, query.Cursor)
+    if err == storage.ErrInvalidCursor {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidCursor, "Invalid cursor")
+        return
+    }
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+
+    w.Header().Set("X-Total-Count", strconv.Itoa(total))
+    h.respondWithJSON(w, http.StatusOK, page)
+}
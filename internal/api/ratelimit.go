@@ -0,0 +1,142 @@
+package api
+
+import (
+    "container/list"
+    "fmt"
+    "math"
+    "net"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+
+    "golang.org/x/time/rate"
+)
+
+// defaultRateLimitClients is how many client IPs are tracked when
+// RATE_LIMIT_MAX_CLIENTS is unset
+const defaultRateLimitClients = 10000
+
+// RateLimiter throttles requests with a token bucket per client IP. Only the
+// most recently seen clients are tracked; the least recently seen one is
+// evicted when the limit is reached, so its next request starts a full bucket.
+type RateLimiter struct {
+    rate       rate.Limit
+    burst      int
+    maxClients int
+    trustProxy bool
+
+    mu      sync.Mutex
+    clients map[string]*list.Element
+    lru     *list.List // front is the most recently seen client
+}
+
+// rateLimitClient is the bucket of one client IP
+type rateLimitClient struct {
+    ip      string
+    limiter *rate.Limiter
+}
+
+// NewRateLimiter allows each client rps requests per second with bursts of
+// up to burst requests, tracking at most maxClients clients. With
+// trustProxy set, the client IP is taken from X-Forwarded-For.
+func NewRateLimiter(rps float64, burst, maxClients int, trustProxy bool) *RateLimiter {
+    return &RateLimiter{
+        rate:       rate.Limit(rps),
+        burst:      burst,
+        maxClients: maxClients,
+        trustProxy: trustProxy,
+        clients:    make(map[string]*list.Element),
+        lru:        list.New(),
+    }
+}
+
+// NewRateLimiterFromEnv configures a RateLimiter from RATE_LIMIT_RPS,
+// RATE_LIMIT_BURST, RATE_LIMIT_MAX_CLIENTS and TRUST_PROXY. It returns nil
+// when RATE_LIMIT_RPS is unset, leaving rate limiting disabled.
+func NewRateLimiterFromEnv() (*RateLimiter, error) {
+    value := os.Getenv("RATE_LIMIT_RPS")
+    if value == "" {
+        return nil, nil
+    }
+    rps, err := strconv.ParseFloat(value, 64)
+    if err != nil || rps <= 0 {
+        return nil, fmt.Errorf("invalid RATE_LIMIT_RPS: %q", value)
+    }
+
+    burst := int(math.Ceil(rps))
+    if value := os.Getenv("RATE_LIMIT_BURST"); value != "" {
+        if burst, err = strconv.Atoi(value); err != nil || burst < 1 {
+            return nil, fmt.Errorf("invalid RATE_LIMIT_BURST: %q", value)
+        }
+    }
+
+    maxClients := defaultRateLimitClients
+    if value := os.Getenv("RATE_LIMIT_MAX_CLIENTS"); value != "" {
+        if maxClients, err = strconv.Atoi(value); err != nil || maxClients < 1 {
+            return nil, fmt.Errorf("invalid RATE_LIMIT_MAX_CLIENTS: %q", value)
+        }
+    }
+
+    trustProxy, _ := strconv.ParseBool(os.Getenv("TRUST_PROXY"))
+    return NewRateLimiter(rps, burst, maxClients, trustProxy), nil
+}
+
+// Middleware rejects requests over the client's rate with 429, setting
+// Retry-After to the number of seconds until a token is available
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        reservation := rl.limiter(rl.clientIP(r)).Reserve()
+        if delay := reservation.Delay(); delay > 0 {
+            reservation.Cancel()
+            w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+            writeError(w, http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded", nil)
+            return
+        }
+
+        next.ServeHTTP(w, r)
+    })
+}
+
+// limiter returns the bucket for ip, creating it and evicting the least
+// recently seen client if needed
+func (rl *RateLimiter) limiter(ip string) *rate.Limiter {
+    rl.mu.Lock()
+    defer rl.mu.Unlock()
+
+    if elem, ok := rl.clients[ip]; ok {
+        rl.lru.MoveToFront(elem)
+        return elem.Value.(*rateLimitClient).limiter
+    }
+
+    if rl.lru.Len() >= rl.maxClients {
+        oldest := rl.lru.Back()
+        rl.lru.Remove(oldest)
+        delete(rl.clients, oldest.Value.(*rateLimitClient).ip)
+    }
+
+    client := &rateLimitClient{ip: ip, limiter: rate.NewLimiter(rl.rate, rl.burst)}
+    rl.clients[ip] = rl.lru.PushFront(client)
+    return client.limiter
+}
+
+// clientIP identifies the client of r. Behind a trusted proxy this is the
+// last X-Forwarded-For entry, the address the proxy itself saw, since
+// earlier entries are supplied by the client and can be forged.
+func (rl *RateLimiter) clientIP(r *http.Request) string {
+    if rl.trustProxy {
+        if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+            parts := strings.Split(forwarded, ",")
+            if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+                return ip
+            }
+        }
+    }
+
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
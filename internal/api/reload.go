@@ -0,0 +1,63 @@
+package api
+
+import (
+    "net/http"
+
+    "github.com/Cdaprod/registry-service/pkg/builtins"
+    "go.uber.org/zap"
+)
+
+// PluginReloader registers plugins added to the plugins directory since the
+// service started
+type PluginReloader interface {
+    Reload() (builtins.ReloadResult, error)
+}
+
+// PluginReloadResponse lists the plugin files a reload registered, those
+// that were already loaded and those that failed
+type PluginReloadResponse struct {
+    Registered    []string              `json:"registered"`
+    AlreadyLoaded []string              `json:"alreadyLoaded"`
+    Failed        []PluginReloadFailure `json:"failed"`
+}
+
+// PluginReloadFailure is a plugin file that failed to load, and why
+type PluginReloadFailure struct {
+    Path  string `json:"path"`
+    Error string `json:"error"`
+}
+
+// ReloadPlugins loads the Go plugins added to the plugins directory since
+// startup. Reloading is idempotent: plugins loaded before are reported as
+// such and not registered again. It needs an authenticated admin, since
+// plugins run arbitrary code in the service.
+func (h *Handler) ReloadPlugins(w http.ResponseWriter, r *http.Request) {
+    if p := principalFrom(r.Context()); p == nil || p.role != RoleAdmin {
+        h.respondWithError(w, http.StatusForbidden, CodeForbidden, "Admin role required")
+        return
+    }
+    if h.reloader == nil {
+        h.respondWithError(w, http.StatusNotImplemented, CodeNotImplemented, "The plugin loader does not support reloading")
+        return
+    }
+
+    result, err := h.reloader.Reload()
+    if err != nil {
+        h.log(r).Error("Failed to reload plugins", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to read the plugins directory")
+        return
+    }
+
+    response := PluginReloadResponse{
+        Registered:    append([]string{}, result.Registered...),
+        AlreadyLoaded: append([]string{}, result.AlreadyLoaded...),
+        Failed:        []PluginReloadFailure{},
+    }
+    for _, loadErr := range result.Errors {
+        h.log(r).Error("Failed to load plugin", zap.String("path", loadErr.Path), zap.Error(loadErr.Err))
+        response.Failed = append(response.Failed, PluginReloadFailure{Path: loadErr.Path, Error: loadErr.Err.Error()})
+    }
+    h.log(r).Info("Reloaded plugins", zap.Int("registered", len(response.Registered)), zap.Int("failed", len(response.Failed)))
+
+    h.respondWithJSON(w, http.StatusOK, response)
+}
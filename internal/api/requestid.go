@@ -0,0 +1,65 @@
+package api
+
+import (
+    "context"
+    "net/http"
+
+    "github.com/google/uuid"
+    "go.uber.org/zap"
+)
+
+// maxRequestIDLength bounds client-supplied request IDs so they cannot
+// bloat every log line of the request
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+type loggerKey struct{}
+
+// requestIDFrom returns the ID of a request, if any
+func requestIDFrom(ctx context.Context) string {
+    id, _ := ctx.Value(requestIDKey{}).(string)
+    return id
+}
+
+// loggerFrom returns the request-scoped logger stored in ctx, or fallback
+// when there is none
+func loggerFrom(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+    if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+        return logger
+    }
+    return fallback
+}
+
+// requestIDMiddleware accepts the caller's X-Request-ID or generates one,
+// echoes it in the response, and stores it in the request context along
+// with a child of logger that tags every entry with it
+func requestIDMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            id := r.Header.Get("X-Request-ID")
+            if !validRequestID(id) {
+                id = uuid.New().String()
+            }
+            w.Header().Set("X-Request-ID", id)
+
+            ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+            ctx = context.WithValue(ctx, loggerKey{}, logger.With(zap.String("request_id", id)))
+            next.ServeHTTP(w, r.WithContext(ctx))
+        })
+    }
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII up to
+// maxRequestIDLength characters
+func validRequestID(id string) bool {
+    if id == "" || len(id) > maxRequestIDLength {
+        return false
+    }
+    for i := 0; i < len(id); i++ {
+        if id[i] < 0x21 || id[i] > 0x7e {
+            return false
+        }
+    }
+    return true
+}
@@ -3,72 +3,274 @@ package api
 import (
     "net/http"
     "encoding/json"
+    "io/fs"
 
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
     "github.com/Cdaprod/registry-service/internal/storage"
     "github.com/gorilla/mux"
     "go.uber.org/zap"
 )
 
-func SetupRoutes(r *mux.Router, store *storage.MemoryStorage, logger *zap.Logger) {
-    handler := NewHandler(store, logger)
+// SetupRoutes registers the API, health, metrics and docs routes on r, and
+// serves the built web frontend in assets for every other path. A nil assets
+// serves no frontend. Item changes made through the API are announced on
+// notifier. It returns the Authenticator of the API, nil when authentication
+// is not configured, so that other transports can check the same credentials.
+func SetupRoutes(r *mux.Router, store storage.Store, logger *zap.Logger, assets fs.FS, auditLog *audit.Logger, reloader PluginReloader, notifier *notify.Notifier) *Authenticator {
+    metrics := NewMetrics(store)
+    keys := storage.NewMemoryKeyStore()
+    handler := NewHandler(store, logger, notifier, metrics, keys, auditLog, reloader)
+
+    // Items the storage expires or purges on its own are announced and
+    // recorded like the changes made through the API
+    if sweeper, ok := store.(storage.SweepObserver); ok {
+        sweeper.OnSweep(handler.announceSweep)
+    }
+
+    // Item type normalization, and the allowed types when configured
+    types, err := typePolicyFromEnv()
+    if err != nil {
+        logger.Error("Preserving the case of item types", zap.Error(err))
+    }
+    if len(types.Allowed) > 0 {
+        logger.Info("Restricting item types", zap.Strings("allowed", types.Allowed))
+    }
+    handler.types = types
+
+    // Replay of create responses for retried requests with an Idempotency-Key
+    idempotency, err := NewIdempotencyStoreFromEnv()
+    if err != nil {
+        logger.Error("Using the default idempotency key TTL", zap.Error(err))
+        idempotency = NewIdempotencyStore(DefaultIdempotencyTTL)
+    }
+    handler.idempotency = idempotency
+
+    // Page sizes of item listings
+    if size, max, err := pageSizesFromEnv(); err != nil {
+        logger.Error("Using the default page sizes", zap.Int("default", DefaultPageSize), zap.Int("max", DefaultMaxPageSize), zap.Error(err))
+    } else {
+        handler.pageSize, handler.maxPageSize = size, max
+    }
+
+    // Attachment size limit
+    if limit, err := maxAttachmentBytesFromEnv(); err != nil {
+        logger.Error("Using the default attachment size limit", zap.Int64("limit", DefaultMaxAttachmentBytes), zap.Error(err))
+    } else {
+        handler.blobLimit = limit
+    }
+
+    // Item metadata size limit, off unless configured
+    if limit, err := metadataLimitFromEnv(); err != nil {
+        logger.Error("Item metadata size is not limited", zap.Error(err))
+    } else {
+        handler.metadataLimit = limit
+    }
 
     // API versioning
     v1 := r.PathPrefix("/api/v1").Subrouter()
 
+    // API v2 has its own representations and error envelope, which also
+    // wraps the errors of its authentication
+    v2Handler := NewV2Handler(handler)
+    v2 := r.PathPrefix("/api/v2").Subrouter()
+    v2.Use(v2Handler.v2Errors)
+
+    // JWT and API key authentication for the API, when configured
+    auth := NewAuthenticatorFromEnv(keys)
+    if auth != nil {
+        v1.Use(auth.Middleware)
+        v2.Use(auth.Middleware)
+    } else {
+        logger.Warn("JWT_SECRET, JWT_JWKS_URL and ADMIN_API_KEY are unset; API authentication is disabled")
+    }
+
     // Items endpoints
-    v1.HandleFunc("/items", handler.CreateItem).Methods("POST")
+    v1.HandleFunc("/items", handler.idempotent(handler.CreateItem)).Methods("POST")
     v1.HandleFunc("/items", handler.ListItems).Methods("GET")
+    v1.HandleFunc("/items/batch", handler.CreateItems).Methods("POST")
+    v1.HandleFunc("/items/count", handler.CountItems).Methods("GET")
+    v1.HandleFunc("/items/deleted", handler.ListDeletedItems).Methods("GET")
+    v1.HandleFunc("/items/export", handler.ExportItems).Methods("GET")
+    v1.HandleFunc("/items/import", handler.ImportItems).Methods("POST")
+    v1.HandleFunc("/items/purge-deleted", handler.PurgeDeletedItems).Methods("POST")
+    v1.HandleFunc("/items/delete", handler.DeleteItems).Methods("POST")
+    v1.HandleFunc("/items/get", handler.GetItems).Methods("POST")
+    v1.HandleFunc("/items/query", handler.QueryItems).Methods("POST")
     v1.HandleFunc("/items/{id}", handler.GetItem).Methods("GET")
     v1.HandleFunc("/items/{id}", handler.UpdateItem).Methods("PUT")
+    v1.HandleFunc("/items/{id}", handler.PatchItem).Methods("PATCH")
     v1.HandleFunc("/items/{id}", handler.DeleteItem).Methods("DELETE")
+    v1.HandleFunc("/items/{id}/restore", handler.RestoreItem).Methods("POST")
+    v1.HandleFunc("/items/{id}/history", handler.GetItemHistory).Methods("GET")
+    v1.HandleFunc("/items/{id}/links", handler.GetItemLinks).Methods("GET")
+    v1.HandleFunc("/items/{id}/links", handler.AddItemLinks).Methods("POST")
+    v1.HandleFunc("/items/{id}/lock", handler.LockItem).Methods("POST")
+    v1.HandleFunc("/items/{id}/lock", handler.UnlockItem).Methods("DELETE")
+    v1.HandleFunc("/items/{id}/versions/{version}", handler.GetItemVersion).Methods("GET")
+    v1.HandleFunc("/items/{id}/diff", handler.GetItemDiff).Methods("GET")
+    v1.HandleFunc("/items/{id}/attachment", handler.PutAttachment).Methods("PUT").Name(attachmentRoute)
+    v1.HandleFunc("/items/{id}/attachment", handler.GetAttachment).Methods("GET")
+
+    // API v2 items endpoints
+    v2.HandleFunc("/items", v2Handler.CreateItem).Methods("POST")
+    v2.HandleFunc("/items", v2Handler.ListItems).Methods("GET")
+    v2.HandleFunc("/items/{id}", v2Handler.GetItem).Methods("GET")
+    v2.HandleFunc("/items/{id}", v2Handler.UpdateItem).Methods("PUT")
+    v2.HandleFunc("/items/{id}", v2Handler.PatchItem).Methods("PATCH")
+    v2.HandleFunc("/items/{id}", v2Handler.DeleteItem).Methods("DELETE")
+
+    // Search endpoint
+    v1.HandleFunc("/search", handler.SearchItems).Methods("GET")
+
+    // GraphQL endpoint
+    v1.HandleFunc("/graphql", handler.GraphQL).Methods("GET", "POST")
+    v1.HandleFunc("/graphql/schema", handler.GraphQLSchemaSDL).Methods("GET")
+
+    // Storage statistics endpoint
+    v1.HandleFunc("/stats", handler.GetStats).Methods("GET")
+
+    // Typed item kinds endpoint
+    v1.HandleFunc("/kinds", handler.ListKinds).Methods("GET")
+
+    // Tags endpoint
+    v1.HandleFunc("/tags", handler.ListTags).Methods("GET")
+
+    // Loaded plugins endpoint
+    v1.HandleFunc("/plugins", handler.ListPlugins).Methods("GET")
+    v1.HandleFunc("/admin/plugins/reload", handler.ReloadPlugins).Methods("POST")
+    v1.HandleFunc("/admin/snapshot", handler.GetSnapshot).Methods("GET")
+    v1.HandleFunc("/admin/restore", handler.RestoreSnapshot).Methods("POST")
+
+    // Webhook registration endpoints
+    v1.HandleFunc("/webhooks", handler.ListWebhooks).Methods("GET")
+    v1.HandleFunc("/webhooks", handler.RegisterWebhook).Methods("POST")
+
+    // Audit trail endpoint
+    v1.HandleFunc("/audit", handler.GetAudit).Methods("GET")
+
+    // Item event stream
+    v1.HandleFunc("/ws", handler.Subscribe).Methods("GET")
+
+    // API key endpoints
+    v1.HandleFunc("/keys", handler.CreateKey).Methods("POST")
 
     // New routes for RegistryDashboard
     v1.HandleFunc("/registries", handler.ListRegistries).Methods("GET")
     v1.HandleFunc("/registry/{name}/list", handler.ListRegistryItems).Methods("GET")
+    v1.HandleFunc("/registry/{name}/stats", handler.GetRegistryStats).Methods("GET")
 
-    // Health check endpoint
+    // Health check endpoints; /health is kept as an alias of /health/live
     r.HandleFunc("/health", handler.HealthCheck).Methods("GET")
+    r.HandleFunc("/health/live", handler.HealthCheck).Methods("GET")
+    r.HandleFunc("/health/ready", handler.ReadinessCheck).Methods("GET")
+
+    // Prometheus metrics endpoint
+    r.Handle("/metrics", metrics.Handler()).Methods("GET")
 
-    // Documentation endpoint (consider implementing Swagger/OpenAPI)
+    // Swagger UI and the OpenAPI spec it renders
     r.HandleFunc("/docs", handler.ServeDocs).Methods("GET")
+    r.HandleFunc("/openapi.json", handler.ServeOpenAPI).Methods("GET")
+    r.HandleFunc("/openapi-v2.json", handler.ServeOpenAPIV2).Methods("GET")
+
+    // Profiling and runtime variables, off unless DEBUG_ENDPOINTS is set
+    if enabled, err := debugEndpointsFromEnv(); err != nil {
+        logger.Error("Debug endpoints are disabled", zap.Error(err))
+    } else if enabled {
+        logger.Warn("Debug endpoints are enabled under /debug/ without authentication")
+        registerDebugRoutes(r, handler)
+    }
 
     // Root handler
     r.HandleFunc("/", handler.HomeHandler).Methods("GET")
 
-    // Middleware for logging, CORS, etc.
+    // Middleware for tracing, logging, etc. CORS wraps the whole router in
+    // main, so preflight requests are answered before routing.
+    r.Use(tracingMiddleware)
+    r.Use(requestIDMiddleware(logger))
     r.Use(loggingMiddleware(logger))
-    r.Use(corsMiddleware)
+    r.Use(metrics.Middleware)
+    r.Use(compressionMiddleware)
 
-    // Serve static files from the web/build directory
-    fs := http.FileServer(http.Dir("./web/build"))
-    r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", fs))
+    // Request body size limit for mutating requests
+    if limit, err := maxBodyBytesFromEnv(); err != nil {
+        logger.Error("Using the default request body limit", zap.Int64("limit", DefaultMaxBodyBytes), zap.Error(err))
+        r.Use(bodyLimitMiddleware(DefaultMaxBodyBytes))
+    } else {
+        r.Use(bodyLimitMiddleware(limit))
+    }
+
+    // Per-client rate limiting, when configured
+    if limiter, err := NewRateLimiterFromEnv(); err != nil {
+        logger.Error("Rate limiting is disabled", zap.Error(err))
+    } else if limiter != nil {
+        r.Use(limiter.Middleware)
+    }
 
-    // Serve index.html for any other routes
-    r.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        http.ServeFile(w, r, "./web/build/index.html")
-    })
+    // Handling time limit, innermost so the other middleware see the 503
+    if timeout, err := requestTimeoutFromEnv(); err != nil {
+        logger.Error("Using the default request timeout", zap.Duration("timeout", DefaultRequestTimeout), zap.Error(err))
+        r.Use(timeoutMiddleware(DefaultRequestTimeout))
+    } else if timeout > 0 {
+        r.Use(timeoutMiddleware(timeout))
+    }
+
+    // Serve the web frontend for every other path
+    if assets != nil {
+        r.PathPrefix("/").Handler(staticHandler(assets))
+    }
+
+    return auth
 }
 
 func (h *Handler) ListRegistries(w http.ResponseWriter, r *http.Request) {
-    // For now, let's return a mock list of registries
-    registries := []string{"Registry1", "Registry2", "Registry3"}
-    json.NewEncoder(w).Encode(registries)
+    h.respondWithJSON(w, http.StatusOK, h.store.ListRegistryNames())
 }
 
 func (h *Handler) ListRegistryItems(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     registryName := vars["name"]
 
+    if h.outOfScope(w, r, registryName) {
+        return
+    }
+
     // Use the new method to list items by registry name
-    items := h.store.ListByRegistryName(registryName)
+    items, err := h.store.ListByRegistryNameCtx(r.Context(), registryName)
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
 
     json.NewEncoder(w).Encode(items)
 }
 
+// GetRegistryStats summarizes the items of one registry: how many there are
+// by type, how many are deleted and when the registry last changed
+func (h *Handler) GetRegistryStats(w http.ResponseWriter, r *http.Request) {
+    registryName := mux.Vars(r)["name"]
+
+    if h.outOfScope(w, r, registryName) {
+        return
+    }
+
+    if stats, ok := h.store.(storage.RegistryStatsStore); ok {
+        h.respondWithJSON(w, http.StatusOK, stats.RegistryStats(registryName))
+        return
+    }
+
+    items, err := h.store.ListIncludingDeletedCtx(r.Context())
+    if err != nil {
+        h.scanFailed(w, r, err)
+        return
+    }
+    h.respondWithJSON(w, http.StatusOK, storage.ComputeRegistryStats(registryName, items))
+}
+
 func loggingMiddleware(logger *zap.Logger) mux.MiddlewareFunc {
     return func(next http.Handler) http.Handler {
         return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-            logger.Info("Received request", 
+            loggerFrom(r.Context(), logger).Info("Received request", 
                 zap.String("method", r.Method),
                 zap.String("path", r.URL.Path),
                 zap.String("remote_addr", r.RemoteAddr))
@@ -76,18 +278,3 @@ func loggingMiddleware(logger *zap.Logger) mux.MiddlewareFunc {
         })
     }
 }
-
-func corsMiddleware(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        w.Header().Set("Access-Control-Allow-Origin", "*") // Allow all origins for now
-        w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-        w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-        if r.Method == "OPTIONS" {
-            w.WriteHeader(http.StatusOK)
-            return
-        }
-
-        next.ServeHTTP(w, r)
-    })
-}
\ No newline at end of file
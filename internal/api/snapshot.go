@@ -0,0 +1,148 @@
+package api
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "go.uber.org/zap"
+)
+
+// RestoreResponse reports how many items a restore loaded and how
+type RestoreResponse struct {
+    Mode     string `json:"mode"`
+    Restored int    `json:"restored"`
+}
+
+// GetSnapshot streams every item, deleted ones included, as a single
+// storage.Snapshot document that RestoreSnapshot can load
+func (h *Handler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+    if p := principalFrom(r.Context()); p == nil || p.role != RoleAdmin {
+        h.respondWithError(w, http.StatusForbidden, CodeForbidden, "Admin role required")
+        return
+    }
+    store, ok := h.store.(storage.SnapshotStore)
+    if !ok {
+        h.respondWithError(w, http.StatusNotImplemented, CodeNotImplemented, "The storage backend does not support snapshots")
+        return
+    }
+
+    snapshot := store.Snapshot()
+
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Content-Disposition", `attachment; filename="snapshot.json"`)
+    w.WriteHeader(http.StatusOK)
+
+    // Items are written one at a time so that large registries are not
+    // encoded into a single buffer
+    rc := http.NewResponseController(w)
+    head, _ := json.Marshal(snapshot.TakenAt)
+    if _, err := w.Write([]byte(`{"format":` + strconv.Itoa(snapshot.Format) + `,"takenAt":` + string(head) + `,"items":[`)); err != nil {
+        h.log(r).Error("Failed to write snapshot", zap.Error(err))
+        return
+    }
+    for i, item := range snapshot.Items {
+        data, err := json.Marshal(item)
+        if err != nil {
+            h.log(r).Error("Failed to encode snapshot item", zap.String("id", item.ID), zap.Error(err))
+            return
+        }
+        if i > 0 {
+            data = append([]byte{','}, data...)
+        }
+        if _, err := w.Write(data); err != nil {
+            h.log(r).Error("Failed to write snapshot", zap.Error(err))
+            return
+        }
+        if i%100 == 99 {
+            rc.Flush()
+        }
+    }
+    if _, err := w.Write([]byte("]}\n")); err != nil {
+        h.log(r).Error("Failed to write snapshot", zap.Error(err))
+        return
+    }
+    h.log(r).Info("Wrote snapshot", zap.Int("items", len(snapshot.Items)))
+}
+
+// RestoreSnapshot loads a snapshot written by GetSnapshot. The mode query
+// parameter is replace, which drops every current item first, or merge, the
+// default, which only replaces items whose ID is in the snapshot. IDs,
+// versions and timestamps are kept exactly as recorded.
+func (h *Handler) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+    if p := principalFrom(r.Context()); p == nil || p.role != RoleAdmin {
+        h.respondWithError(w, http.StatusForbidden, CodeForbidden, "Admin role required")
+        return
+    }
+    store, ok := h.store.(storage.SnapshotStore)
+    if !ok {
+        h.respondWithError(w, http.StatusNotImplemented, CodeNotImplemented, "The storage backend does not support snapshots")
+        return
+    }
+
+    mode := r.URL.Query().Get("mode")
+    if mode == "" {
+        mode = storage.RestoreMerge
+    }
+    if mode != storage.RestoreReplace && mode != storage.RestoreMerge {
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "mode must be replace or merge")
+        return
+    }
+
+    var snapshot storage.Snapshot
+    if err := json.NewDecoder(r.Body).Decode(&snapshot); err != nil {
+        h.log(r).Error("Failed to decode snapshot", zap.Error(err))
+        h.respondPayloadError(w, err, "Invalid snapshot")
+        return
+    }
+
+    start := time.Now()
+    result, err := store.Restore(&snapshot, mode)
+    switch {
+    case errors.Is(err, storage.ErrInvalidSnapshot):
+        h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+        return
+    case errors.Is(err, storage.ErrDuplicateItem):
+        h.respondWithError(w, http.StatusConflict, CodeDuplicateItem, err.Error())
+        return
+    case err != nil:
+        h.log(r).Error("Failed to restore snapshot", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to restore snapshot")
+        return
+    }
+    h.announceRestore(r, result)
+    h.log(r).Info("Restored snapshot",
+        zap.String("mode", mode),
+        zap.Int("items", len(result.Loaded)),
+        zap.String("actor", requestActor(r)),
+        zap.Duration("took", time.Since(start)))
+
+    h.respondWithJSON(w, http.StatusOK, RestoreResponse{Mode: mode, Restored: len(result.Loaded)})
+}
+
+// announceRestore publishes the items a snapshot restore created, replaced
+// or removed and adds them to the audit trail. Items loaded as deleted are
+// announced as deleted.
+func (h *Handler) announceRestore(r *http.Request, result *storage.RestoreResult) {
+    for _, item := range result.Loaded {
+        before, replaced := result.Replaced[item.ID]
+        event, action := notify.EventItemCreated, audit.ActionCreate
+        if replaced {
+            event, action = notify.EventItemUpdated, audit.ActionUpdate
+        }
+        if item.IsDeleted() {
+            event = notify.EventItemDeleted
+        }
+        h.notifier.Notify(event, item.ID, item.Type, item.RegistryName)
+        h.recordAudit(r, action, item.ID, before, item.Version)
+    }
+    for _, item := range result.Removed {
+        h.notifier.Notify(notify.EventItemPurged, item.ID, item.Type, item.RegistryName)
+        h.recordAudit(r, audit.ActionPurge, item.ID, item.Version, 0)
+    }
+}
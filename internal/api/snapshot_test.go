@@ -0,0 +1,49 @@
+package api
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/storage"
+)
+
+func TestSnapshotRequiresAdmin(t *testing.T) {
+    tests := []struct {
+        name     string
+        env      map[string]string
+        apiKey   string
+        method   string
+        target   string
+        wantCode int
+    }{
+        {name: "snapshot without authentication", method: "GET", target: "/api/v1/admin/snapshot", wantCode: http.StatusForbidden},
+        {name: "anonymous snapshot with optional authentication", env: map[string]string{"JWT_SECRET": "secret", "AUTH_OPTIONAL": "true"},
+            method: "GET", target: "/api/v1/admin/snapshot", wantCode: http.StatusForbidden},
+        {name: "admin snapshot", env: map[string]string{"ADMIN_API_KEY": testAdminKey, "AUTH_OPTIONAL": "true"}, apiKey: testAdminKey,
+            method: "GET", target: "/api/v1/admin/snapshot", wantCode: http.StatusOK},
+        {name: "restore without authentication", method: "POST", target: "/api/v1/admin/restore", wantCode: http.StatusForbidden},
+        {name: "anonymous restore with optional authentication", env: map[string]string{"JWT_SECRET": "secret", "AUTH_OPTIONAL": "true"},
+            method: "POST", target: "/api/v1/admin/restore", wantCode: http.StatusUnauthorized},
+        {name: "admin restore", env: map[string]string{"ADMIN_API_KEY": testAdminKey}, apiKey: testAdminKey,
+            method: "POST", target: "/api/v1/admin/restore", wantCode: http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            for name, value := range tt.env {
+                t.Setenv(name, value)
+            }
+            s := newTestServer(t)
+            s.apiKey = tt.apiKey
+
+            var body interface{}
+            if tt.method == "POST" {
+                body = storage.Snapshot{Format: storage.SnapshotFormat, TakenAt: time.Now()}
+            }
+            rec := s.do(t, tt.method, tt.target, body)
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+        })
+    }
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/Cdaprod/registry-service/internal/audit"
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+)
+
+// staleStore drops every update as not newer than the stored version, as the
+// SQLite and Postgres backends do for a stale version
+type staleStore struct {
+    *storage.MemoryStorage
+}
+
+func (s staleStore) UpdateItemCtx(ctx context.Context, item *registry.Item) (*registry.Item, error) {
+    return nil, registry.ErrStaleVersion
+}
+
+func TestUpdateItemStaleVersion(t *testing.T) {
+    store := storage.NewMemoryStorage()
+    defer store.Close()
+    if _, err := store.CreateItem(&registry.Item{ID: "svc", Type: "service", Name: "api", RegistryName: "team-a"}); err != nil {
+        t.Fatal(err)
+    }
+    auditLog, err := audit.Open("", 10, zap.NewNop())
+    if err != nil {
+        t.Fatal(err)
+    }
+    r := mux.NewRouter()
+    SetupRoutes(r, staleStore{store}, zap.NewNop(), nil, auditLog, nil, notify.NewNotifier(zap.NewNop()))
+
+    tests := []struct {
+        name        string
+        method      string
+        contentType string
+        body        string
+    }{
+        {name: "put", method: "PUT", contentType: "application/json", body: `{"type":"service","name":"renamed","registryName":"team-a"}`},
+        {name: "merge patch", method: "PATCH", contentType: mergePatchContentType, body: `{"name":"renamed"}`},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest(tt.method, "/api/v1/items/svc", strings.NewReader(tt.body))
+            req.Header.Set("Content-Type", tt.contentType)
+            rec := httptest.NewRecorder()
+            r.ServeHTTP(rec, req)
+
+            if rec.Code != http.StatusConflict {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+            }
+            if etag := rec.Header().Get("ETag"); etag != versionETag(1) {
+                t.Errorf("ETag = %s, want %s", etag, versionETag(1))
+            }
+            if !strings.Contains(rec.Body.String(), `"name":"api"`) {
+                t.Errorf("body = %s, want the stored item", rec.Body)
+            }
+            if entries := auditLog.Recent("svc", 10); len(entries) != 0 {
+                t.Errorf("dropped update was audited: %+v", entries)
+            }
+        })
+    }
+}
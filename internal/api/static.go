@@ -0,0 +1,35 @@
+package api
+
+import (
+    "io/fs"
+    "net/http"
+    "path"
+    "strings"
+)
+
+// staticHandler serves the files of the built web frontend in assets. Other
+// paths get index.html, so that the frontend's client-side routes survive a
+// reload, except under /static/ where a missing bundle should not be answered
+// with HTML, and under /debug/ when the debug endpoints are disabled; they
+// 404, as does everything when the build has no index.html.
+func staticHandler(assets fs.FS) http.Handler {
+    files := http.FileServer(http.FS(assets))
+    _, err := fs.Stat(assets, "index.html")
+    hasIndex := err == nil
+
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+        if info, err := fs.Stat(assets, name); name != "" && err == nil && !info.IsDir() {
+            files.ServeHTTP(w, r)
+            return
+        }
+        if !hasIndex || strings.HasPrefix(name, "static/") || strings.HasPrefix(name, "debug/") {
+            http.NotFound(w, r)
+            return
+        }
+        // The file server answers a request for the root with index.html
+        index := r.Clone(r.Context())
+        index.URL.Path = "/"
+        files.ServeHTTP(w, index)
+    })
+}
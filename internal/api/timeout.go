@@ -0,0 +1,75 @@
+package api
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+)
+
+// DefaultRequestTimeout bounds request handling when REQUEST_TIMEOUT is unset
+const DefaultRequestTimeout = 30 * time.Second
+
+// requestTimeoutFromEnv reads the request timeout from REQUEST_TIMEOUT. Zero
+// disables the timeout.
+func requestTimeoutFromEnv() (time.Duration, error) {
+    value := os.Getenv("REQUEST_TIMEOUT")
+    if value == "" {
+        return DefaultRequestTimeout, nil
+    }
+    timeout, err := time.ParseDuration(value)
+    if err != nil || timeout < 0 {
+        return 0, fmt.Errorf("invalid REQUEST_TIMEOUT: %q", value)
+    }
+    return timeout, nil
+}
+
+// timeoutMiddleware answers 503 when a handler takes longer than timeout,
+// and cancels the request context so storage calls can give up. The handler's
+// response is buffered until it finishes, so streaming responses such as
+// WebSocket, event stream, export, snapshot and NDJSON requests are exempt,
+// as are profiles, which are collected over a requested number of seconds.
+func timeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+    body, _ := json.Marshal(ErrorResponse{Code: CodeRequestTimeout, Message: "Request took longer than " + timeout.String()})
+    return func(next http.Handler) http.Handler {
+        limited := http.TimeoutHandler(next, timeout, string(body))
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if isStreamingRequest(r) {
+                next.ServeHTTP(w, r)
+                return
+            }
+            limited.ServeHTTP(timeoutResponseWriter{w}, r)
+        })
+    }
+}
+
+// isStreamingRequest reports whether r asks for a response that is written
+// over time rather than at once
+func isStreamingRequest(r *http.Request) bool {
+    return r.Header.Get("Upgrade") != "" ||
+        strings.Contains(r.Header.Get("Accept"), "text/event-stream") ||
+        strings.HasSuffix(r.URL.Path, "/items/export") ||
+        strings.HasSuffix(r.URL.Path, "/admin/snapshot") ||
+        strings.HasPrefix(r.URL.Path, "/debug/pprof/") ||
+        wantsNDJSON(r)
+}
+
+// timeoutResponseWriter labels the body http.TimeoutHandler writes on a
+// timeout as JSON. Responses written by handlers carry their own headers.
+type timeoutResponseWriter struct {
+    http.ResponseWriter
+}
+
+func (w timeoutResponseWriter) WriteHeader(status int) {
+    if status == http.StatusServiceUnavailable && w.Header().Get("Content-Type") == "" {
+        w.Header().Set("Content-Type", "application/json")
+    }
+    w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController
+func (w timeoutResponseWriter) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
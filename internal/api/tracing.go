@@ -0,0 +1,40 @@
+package api
+
+import (
+    "context"
+    "net/http"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/gorilla/mux"
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware starts a server span for every request routed through mux,
+// named after the matched route template
+func tracingMiddleware(next http.Handler) http.Handler {
+    return otelhttp.NewHandler(next, "registry-service",
+        otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+            return r.Method + " " + routeTemplate(r)
+        }),
+    )
+}
+
+// routeTemplate returns the path template of the route mux matched for r
+func routeTemplate(r *http.Request) string {
+    if current := mux.CurrentRoute(r); current != nil {
+        if tmpl, err := current.GetPathTemplate(); err == nil {
+            return tmpl
+        }
+    }
+    return "unmatched"
+}
+
+// setItemAttributes records the item ID and type on the request's root span
+func setItemAttributes(ctx context.Context, item *registry.Item) {
+    trace.SpanFromContext(ctx).SetAttributes(
+        attribute.String("item.id", item.ID),
+        attribute.String("item.type", item.Type),
+    )
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// Case policies applied to item types by TYPE_CASE
+const (
+    TypeCasePreserve = "preserve"
+    TypeCaseLower    = "lower"
+    TypeCaseUpper    = "upper"
+)
+
+// TypePolicy normalizes item types, so that "api", " API" and "Api" do not
+// end up as different types, and optionally restricts them to a known set
+type TypePolicy struct {
+    // Case is one of the TypeCase policies; empty preserves case
+    Case string
+    // Allowed lists the accepted types after normalization; empty accepts
+    // any type
+    Allowed []string
+}
+
+// typePolicyFromEnv reads the type policy from TYPE_CASE and the
+// comma-separated ALLOWED_TYPES. Allowed types are normalized themselves. An
+// invalid TYPE_CASE is reported, and case is then preserved.
+func typePolicyFromEnv() (TypePolicy, error) {
+    var err error
+    policy := TypePolicy{Case: os.Getenv("TYPE_CASE")}
+    switch policy.Case {
+    case "", TypeCasePreserve, TypeCaseLower, TypeCaseUpper:
+    default:
+        err = fmt.Errorf("invalid TYPE_CASE: %q; expected preserve, lower or upper", policy.Case)
+        policy.Case = TypeCasePreserve
+    }
+
+    for _, itemType := range strings.Split(os.Getenv("ALLOWED_TYPES"), ",") {
+        if itemType = policy.Normalize(itemType); itemType != "" {
+            policy.Allowed = append(policy.Allowed, itemType)
+        }
+    }
+    return policy, err
+}
+
+// Normalize trims itemType and applies the case policy
+func (p TypePolicy) Normalize(itemType string) string {
+    itemType = strings.TrimSpace(itemType)
+    switch p.Case {
+    case TypeCaseLower:
+        return strings.ToLower(itemType)
+    case TypeCaseUpper:
+        return strings.ToUpper(itemType)
+    }
+    return itemType
+}
+
+// Allows reports whether a normalized type may be stored
+func (p TypePolicy) Allows(itemType string) bool {
+    if len(p.Allowed) == 0 {
+        return true
+    }
+    for _, allowed := range p.Allowed {
+        if itemType == allowed {
+            return true
+        }
+    }
+    return false
+}
+
+// check normalizes the type of item and describes why it may not be stored,
+// if it may not; an empty type is left to Validate
+func (p TypePolicy) check(item *registry.Item) *registry.FieldError {
+    item.Type = p.Normalize(item.Type)
+    if item.Type == "" || p.Allows(item.Type) {
+        return nil
+    }
+    return &registry.FieldError{Field: "type", Message: strconv.Quote(item.Type) + " is not one of " + strings.Join(p.Allowed, ", ")}
+}
+
+// checkType normalizes the type of item and answers 422 if it is not allowed
+func (h *Handler) checkType(w http.ResponseWriter, item *registry.Item) bool {
+    if fieldErr := h.types.check(item); fieldErr != nil {
+        h.respondWithErrorDetails(w, http.StatusUnprocessableEntity, CodeValidationFailed, "Item type is not allowed",
+            map[string]interface{}{"fields": []registry.FieldError{*fieldErr}})
+        return false
+    }
+    return true
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+)
+
+func TestTypePolicyNormalize(t *testing.T) {
+    tests := []struct {
+        policy string
+        input  string
+        want   string
+    }{
+        {policy: "", input: " Api ", want: "Api"},
+        {policy: TypeCasePreserve, input: "API", want: "API"},
+        {policy: TypeCaseLower, input: " API", want: "api"},
+        {policy: TypeCaseLower, input: "Api", want: "api"},
+        {policy: TypeCaseUpper, input: "api ", want: "API"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.policy+"/"+tt.input, func(t *testing.T) {
+            if got := (TypePolicy{Case: tt.policy}).Normalize(tt.input); got != tt.want {
+                t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestTypePolicyRequests(t *testing.T) {
+    tests := []struct {
+        name     string
+        allowed  string
+        itemType string
+        query    string
+        wantCode int
+        wantType string
+    }{
+        {name: "folded on create", itemType: " API ", query: "Api", wantCode: http.StatusCreated, wantType: "api"},
+        {name: "allowed type", allowed: "API, service", itemType: "Service", query: "SERVICE", wantCode: http.StatusCreated, wantType: "service"},
+        {name: "unknown type", allowed: "api,service", itemType: "database", wantCode: http.StatusUnprocessableEntity},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            t.Setenv("TYPE_CASE", TypeCaseLower)
+            t.Setenv("ALLOWED_TYPES", tt.allowed)
+            s := newTestServer(t)
+
+            rec := s.do(t, "POST", "/api/v1/items", map[string]interface{}{"id": "svc", "type": tt.itemType, "name": "api", "registryName": "team-a"})
+            if rec.Code != tt.wantCode {
+                t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+            }
+            if tt.wantCode != http.StatusCreated {
+                if code := errorCode(t, rec); code != CodeValidationFailed {
+                    t.Errorf("code = %s, want %s", code, CodeValidationFailed)
+                }
+                return
+            }
+            if stored, err := s.store.GetItem("svc"); err != nil || stored.Type != tt.wantType {
+                t.Fatalf("stored item = %+v, %v, want type %q", stored, err, tt.wantType)
+            }
+
+            rec = s.do(t, "GET", "/api/v1/items?type="+tt.query, nil)
+            var items []struct {
+                ID string `json:"id"`
+            }
+            if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+                t.Fatalf("decode %q: %v", rec.Body, err)
+            }
+            if len(items) != 1 || items[0].ID != "svc" {
+                t.Errorf("?type=%s returned %s, want the item", tt.query, rec.Body)
+            }
+        })
+    }
+}
@@ -0,0 +1,455 @@
+package api
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/registry"
+    "github.com/Cdaprod/registry-service/internal/storage"
+    "github.com/gorilla/mux"
+    "go.uber.org/zap"
+)
+
+// API v2 shares the store and the mutation handlers of v1, so that both
+// validate, audit and announce changes alike, but has its own representations:
+//   - items are ItemV2 values, and single items are wrapped in {"data": ...}
+//   - listings are always cursor pages with a total
+//   - errors are wrapped in {"error": {...}} with the request ID, and every
+//     error has a code, including version conflicts
+//   - unknown fields in item bodies are rejected
+
+// ItemV2 is an item in API v2. Unlike in v1, the fields of typed kinds stay
+// in the metadata, empty collections are never null and unset times are left
+// out.
+type ItemV2 struct {
+    ID           string                 `json:"id"`
+    Type         string                 `json:"type"`
+    Name         string                 `json:"name"`
+    RegistryName string                 `json:"registryName"`
+    Owner        string                 `json:"owner,omitempty"`
+    Metadata     map[string]interface{} `json:"metadata"`
+    Tags         []string               `json:"tags"`
+    Links        map[string][]string    `json:"links"`
+    Version      int64                  `json:"version"`
+    CreatedAt    time.Time              `json:"createdAt"`
+    CreatedBy    string                 `json:"createdBy,omitempty"`
+    UpdatedAt    time.Time              `json:"updatedAt"`
+    UpdatedBy    string                 `json:"updatedBy,omitempty"`
+    ExpiresAt    *time.Time             `json:"expiresAt,omitempty"`
+    DeletedAt    *time.Time             `json:"deletedAt,omitempty"`
+}
+
+// newItemV2 converts a stored item to its v2 representation
+func newItemV2(item *registry.Item) ItemV2 {
+    v := ItemV2{
+        ID:           item.ID,
+        Type:         item.Type,
+        Name:         item.Name,
+        RegistryName: item.RegistryName,
+        Owner:        item.Owner,
+        Metadata:     item.Metadata,
+        Tags:         item.Tags,
+        Links:        item.Links,
+        Version:      item.Version,
+        CreatedAt:    item.CreatedAt,
+        CreatedBy:    item.CreatedBy,
+        UpdatedAt:    item.UpdatedAt,
+        UpdatedBy:    item.UpdatedBy,
+    }
+    if v.Metadata == nil {
+        v.Metadata = map[string]interface{}{}
+    }
+    if v.Tags == nil {
+        v.Tags = []string{}
+    }
+    if v.Links == nil {
+        v.Links = map[string][]string{}
+    }
+    if !item.ExpiresAt.IsZero() {
+        expiresAt := item.ExpiresAt
+        v.ExpiresAt = &expiresAt
+    }
+    if !item.DeletedAt.IsZero() {
+        deletedAt := item.DeletedAt
+        v.DeletedAt = &deletedAt
+    }
+    return v
+}
+
+// ItemInputV2 is the body of POST /api/v2/items and PUT /api/v2/items/{id}.
+// The version to update is given by If-Match or ?expectedVersion=, and the
+// other fields are set by the server.
+type ItemInputV2 struct {
+    ID           string                 `json:"id,omitempty"`
+    Type         string                 `json:"type"`
+    Name         string                 `json:"name"`
+    RegistryName string                 `json:"registryName"`
+    Owner        string                 `json:"owner,omitempty"`
+    Metadata     map[string]interface{} `json:"metadata,omitempty"`
+    Tags         []string               `json:"tags,omitempty"`
+    Links        map[string][]string    `json:"links,omitempty"`
+    ExpiresAt    *time.Time             `json:"expiresAt,omitempty"`
+}
+
+// v1Body encodes in as the body of the v1 request for the same change. The
+// metadata of typed kinds is copied to the top level, where v1 expects the
+// fields of the kind.
+func (in ItemInputV2) v1Body() ([]byte, error) {
+    data, err := json.Marshal(in)
+    if err != nil || len(in.Metadata) == 0 || !isKind(in.Type) {
+        return data, err
+    }
+
+    var doc map[string]interface{}
+    if err := json.Unmarshal(data, &doc); err != nil {
+        return nil, err
+    }
+    flat := make(map[string]interface{}, len(doc)+len(in.Metadata))
+    for key, value := range in.Metadata {
+        flat[key] = value
+    }
+    for key, value := range doc {
+        flat[key] = value
+    }
+    return json.Marshal(flat)
+}
+
+// isKind reports whether itemType has a typed kind
+func isKind(itemType string) bool {
+    for _, kind := range registry.Kinds() {
+        if kind == itemType {
+            return true
+        }
+    }
+    return false
+}
+
+// DataV2 wraps the item of a v2 response
+type DataV2 struct {
+    Data ItemV2 `json:"data"`
+}
+
+// ItemPageV2 is one page of a v2 item listing. Total counts the matching
+// items on all pages.
+type ItemPageV2 struct {
+    Data       []ItemV2 `json:"data"`
+    NextCursor string   `json:"nextCursor,omitempty"`
+    Total      int      `json:"total"`
+}
+
+// ErrorResponseV2 is the JSON body of every v2 error
+type ErrorResponseV2 struct {
+    Error ErrorV2 `json:"error"`
+}
+
+// ErrorV2 describes a v2 error. RequestID matches the X-Request-ID header.
+type ErrorV2 struct {
+    Code      string      `json:"code"`
+    Message   string      `json:"message"`
+    Details   interface{} `json:"details,omitempty"`
+    RequestID string      `json:"requestId,omitempty"`
+}
+
+// V2Handler serves API v2 on top of the store and v1 handlers of h
+type V2Handler struct {
+    h *Handler
+}
+
+// NewV2Handler creates the API v2 handlers
+func NewV2Handler(h *Handler) *V2Handler {
+    return &V2Handler{h: h}
+}
+
+// ListItems lists non-deleted items one cursor page at a time. Items can be
+// filtered by type, registryName, tag and meta.{key}, and ordered with sort
+// and order as in v1. Scoped API keys only see the items of their
+// registries.
+func (v *V2Handler) ListItems(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query()
+    var requested int
+    if value := query.Get("limit"); value != "" {
+        limit, err := strconv.Atoi(value)
+        if err != nil || limit < 0 {
+            v.h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid limit")
+            return
+        }
+        requested = limit
+    }
+    limit := v.h.pageLimit(w, requested)
+
+    sortBy, err := storage.ParseSort(query.Get("sort"), query.Get("order"))
+    if err != nil {
+        v.h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+        return
+    }
+    registryName := query.Get("registryName")
+    if registryName != "" && v.h.outOfScope(w, r, registryName) {
+        return
+    }
+    filters, err := metadataFilters(query)
+    if err != nil {
+        v.h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+        return
+    }
+    criteria, err := v.h.newItemCriteria(query.Get("type"), registryName, query["tag"], filters)
+    if err != nil {
+        v.h.respondWithError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+        return
+    }
+
+    found, err := v.h.findItems(r.Context(), criteria)
+    if err != nil {
+        v.h.scanFailed(w, r, err)
+        return
+    }
+    p := principalFrom(r.Context())
+    var items []*registry.Item
+    for _, item := range found {
+        if p.allowsRegistry(item.RegistryName) {
+            items = append(items, item)
+        }
+    }
+
+    page, err := storage.SortedPage(items, sortBy, limit, query.Get("cursor"))
+    if err == storage.ErrInvalidCursor {
+        v.h.respondWithError(w, http.StatusBadRequest, CodeInvalidCursor, "Invalid cursor")
+        return
+    }
+    if err != nil {
+        v.h.scanFailed(w, r, err)
+        return
+    }
+
+    response := ItemPageV2{Data: make([]ItemV2, 0, len(page.Items)), NextCursor: page.NextCursor, Total: len(items)}
+    for _, entry := range page.Items {
+        if item, ok := entry.(*registry.Item); ok {
+            response.Data = append(response.Data, newItemV2(item))
+        }
+    }
+    v.h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// GetItem returns an item, or 304 when If-None-Match or If-Modified-Since
+// show the caller has it already
+func (v *V2Handler) GetItem(w http.ResponseWriter, r *http.Request) {
+    item, err := v.h.store.GetItemCtx(r.Context(), mux.Vars(r)["id"])
+    if err != nil {
+        v.h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+    setItemAttributes(r.Context(), item)
+
+    // v2 has a single representation, so its entity tags are strong
+    w.Header().Set("ETag", versionETag(item.Version))
+    if !item.UpdatedAt.IsZero() {
+        w.Header().Set("Last-Modified", item.UpdatedAt.UTC().Format(http.TimeFormat))
+    }
+    if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+        if etagListMatches(ifNoneMatch, item.Version) {
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+    } else if notModifiedSince(r.Header.Get("If-Modified-Since"), item.UpdatedAt) {
+        w.WriteHeader(http.StatusNotModified)
+        return
+    }
+    v.h.respondWithJSON(w, http.StatusOK, DataV2{Data: newItemV2(item)})
+}
+
+// CreateItem creates the item in the body. ?upsert=, If-None-Match and
+// Idempotency-Key work as in v1.
+func (v *V2Handler) CreateItem(w http.ResponseWriter, r *http.Request) {
+    body, ok := v.decodeInput(w, r)
+    if !ok {
+        return
+    }
+    target := "/api/v1/items"
+    if upsert := r.URL.Query().Get("upsert"); upsert != "" {
+        target += "?upsert=" + url.QueryEscape(upsert)
+    }
+    v.delegate(w, r, v.h.idempotent(v.h.CreateItem), http.MethodPost, target, "", "application/json", body, "If-None-Match", idempotencyKeyHeader)
+}
+
+// UpdateItem replaces an item, or creates it under the ID of the path. The
+// update is conditional with If-Match or ?expectedVersion=.
+func (v *V2Handler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+    body, ok := v.decodeInput(w, r)
+    if !ok {
+        return
+    }
+    id := mux.Vars(r)["id"]
+    target := "/api/v1/items/" + url.PathEscape(id)
+    if expected := r.URL.Query().Get("expectedVersion"); expected != "" {
+        target += "?expectedVersion=" + url.QueryEscape(expected)
+    }
+    v.delegate(w, r, v.h.UpdateItem, http.MethodPut, target, id, "application/json", body, "If-Match")
+}
+
+// PatchItem applies a JSON merge patch or JSON Patch, chosen by the
+// Content-Type, to an item
+func (v *V2Handler) PatchItem(w http.ResponseWriter, r *http.Request) {
+    body, err := io.ReadAll(r.Body)
+    if err != nil {
+        v.h.respondPayloadError(w, err, "Failed to read request body")
+        return
+    }
+    id := mux.Vars(r)["id"]
+    v.delegate(w, r, v.h.PatchItem, http.MethodPatch, "/api/v1/items/"+url.PathEscape(id), id, r.Header.Get("Content-Type"), body, "If-Match")
+}
+
+// DeleteItem soft-deletes an item, or removes it for good with ?purge=true
+func (v *V2Handler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+    target := "/api/v1/items/" + url.PathEscape(id)
+    if purge := r.URL.Query().Get("purge"); purge != "" {
+        target += "?purge=" + url.QueryEscape(purge)
+    }
+    v.delegate(w, r, v.h.DeleteItem, http.MethodDelete, target, id, "", nil)
+}
+
+// decodeInput reads an ItemInputV2 from the body of r and returns it as the
+// body of the v1 request
+func (v *V2Handler) decodeInput(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+    var input ItemInputV2
+    dec := json.NewDecoder(r.Body)
+    dec.DisallowUnknownFields()
+    if err := dec.Decode(&input); err != nil {
+        v.h.log(r).Info("Rejected invalid item", zap.Error(err))
+        v.h.respondPayloadError(w, err, "Invalid item: "+err.Error())
+        return nil, false
+    }
+    body, err := input.v1Body()
+    if err != nil {
+        v.h.respondPayloadError(w, err, "Invalid item: "+err.Error())
+        return nil, false
+    }
+    return body, true
+}
+
+// delegate serves a change with the v1 handler for it and answers with the
+// stored item, which is read back since v1 responses have the v1 form.
+// Errors are passed on for v2Errors to rewrite. The headers named in keep
+// are passed to the v1 handler.
+func (v *V2Handler) delegate(w http.ResponseWriter, r *http.Request, handler http.HandlerFunc, method, target, id, contentType string, body []byte, keep ...string) {
+    header := make(http.Header)
+    for _, name := range keep {
+        if values := r.Header.Values(name); len(values) > 0 {
+            header[http.CanonicalHeaderKey(name)] = values
+        }
+    }
+    rec := serveDelegated(handler, r, method, target, id, header, contentType, body)
+
+    if rec.code >= http.StatusBadRequest {
+        for name, values := range rec.Header() {
+            w.Header()[name] = values
+        }
+        w.WriteHeader(rec.code)
+        w.Write(rec.body.Bytes())
+        return
+    }
+    if rec.code == http.StatusNoContent {
+        w.WriteHeader(http.StatusNoContent)
+        return
+    }
+
+    var stored struct {
+        ID string `json:"id"`
+    }
+    if err := json.Unmarshal(rec.body.Bytes(), &stored); err != nil || stored.ID == "" {
+        v.h.log(r).Error("Unexpected v1 response", zap.String("target", target), zap.Int("status", rec.code))
+        v.h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Unexpected response")
+        return
+    }
+    item, err := v.h.store.GetItemCtx(r.Context(), stored.ID)
+    if err != nil {
+        v.h.respondWithError(w, http.StatusNotFound, CodeItemNotFound, "Item not found")
+        return
+    }
+
+    w.Header().Set("ETag", versionETag(item.Version))
+    if rec.code == http.StatusCreated {
+        w.Header().Set("Location", "/api/v2/items/"+url.PathEscape(item.ID))
+    }
+    v.h.respondWithJSON(w, rec.code, DataV2{Data: newItemV2(item)})
+}
+
+// v2Errors rewrites the error responses of the handlers and middleware it
+// wraps, which have the v1 form, into ErrorResponseV2. Version conflicts,
+// which v1 answers with the current item, get the VERSION_CONFLICT code and
+// the item in their details.
+func (v *V2Handler) v2Errors(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ew := &v2ErrorWriter{ResponseWriter: w}
+        next.ServeHTTP(ew, r)
+        if ew.status == 0 {
+            return
+        }
+
+        var response ErrorResponse
+        var current struct {
+            ID string `json:"id"`
+        }
+        if err := json.Unmarshal(ew.body.Bytes(), &response); err != nil || response.Code == "" {
+            response = ErrorResponse{Code: CodeInternal, Message: http.StatusText(ew.status)}
+            if ew.status < http.StatusInternalServerError {
+                response.Code = CodeInvalidRequest
+            }
+            if ew.status == http.StatusConflict && json.Unmarshal(ew.body.Bytes(), &current) == nil && current.ID != "" {
+                if item, err := v.h.store.GetItemCtx(r.Context(), current.ID); err == nil {
+                    response = ErrorResponse{
+                        Code:    CodeVersionConflict,
+                        Message: "The item is at version " + strconv.FormatInt(item.Version, 10),
+                        Details: map[string]interface{}{"current": newItemV2(item)},
+                    }
+                }
+            }
+        }
+
+        body, _ := json.Marshal(ErrorResponseV2{Error: ErrorV2{
+            Code:      response.Code,
+            Message:   response.Message,
+            Details:   response.Details,
+            RequestID: requestIDFrom(r.Context()),
+        }})
+        w.Header().Del("Content-Length")
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(ew.status)
+        w.Write(body)
+    })
+}
+
+// v2ErrorWriter holds back error responses for v2Errors and passes others
+// through
+type v2ErrorWriter struct {
+    http.ResponseWriter
+    status int
+    body   bytes.Buffer
+}
+
+func (w *v2ErrorWriter) WriteHeader(status int) {
+    if w.status != 0 {
+        return
+    }
+    if status >= http.StatusBadRequest {
+        w.status = status
+        return
+    }
+    w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *v2ErrorWriter) Write(b []byte) (int, error) {
+    if w.status != 0 {
+        return w.body.Write(b)
+    }
+    return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController
+func (w *v2ErrorWriter) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+    "net/http"
+    "time"
+
+    "github.com/Cdaprod/registry-service/internal/notify"
+    "github.com/gorilla/websocket"
+    "go.uber.org/zap"
+)
+
+// WebSocket keepalive and limits
+const (
+    wsWriteWait      = 10 * time.Second
+    wsPongWait       = 60 * time.Second
+    wsPingInterval   = wsPongWait * 9 / 10
+    wsMaxMessageSize = 4096
+)
+
+// wsUpgrader accepts same-origin browser connections and clients that send
+// no Origin header
+var wsUpgrader = websocket.Upgrader{
+    ReadBufferSize:  1024,
+    WriteBufferSize: 1024,
+}
+
+// wsSubscribed acknowledges a subscription message; events matching the
+// filter follow it
+type wsSubscribed struct {
+    Event string `json:"event"`
+    notify.Filter
+}
+
+// Subscribe upgrades the request to a WebSocket that streams item events.
+// The client sends a subscription message {"types": [...], "registries": [...]}
+// and may send another at any time to replace it. Empty lists match
+// everything. Each message is acknowledged with {"event": "subscribed", ...},
+// after which matching create, update, delete, restore and purge events are
+// sent as they happen. API keys only receive events for their registries.
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+    conn, err := wsUpgrader.Upgrade(w, r, nil)
+    if err != nil {
+        // Upgrade has already written an error response
+        h.log(r).Info("WebSocket upgrade failed", zap.Error(err))
+        return
+    }
+    defer conn.Close()
+
+    logger := h.log(r)
+    principal := principalFrom(r.Context())
+
+    // The reader passes subscription messages to the writer below, which
+    // owns every write to the connection
+    filters := make(chan notify.Filter)
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        conn.SetReadLimit(wsMaxMessageSize)
+        conn.SetReadDeadline(time.Now().Add(wsPongWait))
+        conn.SetPongHandler(func(string) error {
+            return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+        })
+        for {
+            var filter notify.Filter
+            if err := conn.ReadJSON(&filter); err != nil {
+                if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+                    logger.Info("WebSocket closed", zap.Error(err))
+                }
+                return
+            }
+            select {
+            case filters <- filter:
+            case <-r.Context().Done():
+                return
+            }
+        }
+    }()
+
+    ping := time.NewTicker(wsPingInterval)
+    defer ping.Stop()
+
+    var sub *notify.Subscription
+    defer func() {
+        if sub != nil {
+            sub.Close()
+        }
+    }()
+    // Receiving from a nil channel blocks until the first subscription
+    var events <-chan notify.Event
+
+    for {
+        select {
+        case filter := <-filters:
+            if sub == nil {
+                sub = h.notifier.Broker().Subscribe(filter)
+                events = sub.Events()
+            } else {
+                sub.SetFilter(filter)
+            }
+            if !h.wsWrite(conn, wsSubscribed{Event: "subscribed", Filter: filter}) {
+                return
+            }
+        case event := <-events:
+            if !principal.allowsRegistry(event.RegistryName) {
+                continue
+            }
+            if !h.wsWrite(conn, event) {
+                return
+            }
+        case <-ping.C:
+            conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+            if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+                return
+            }
+        case <-done:
+            return
+        }
+    }
+}
+
+// wsWrite sends v as a JSON message, reporting whether the connection is
+// still usable
+func (h *Handler) wsWrite(conn *websocket.Conn, v interface{}) bool {
+    conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+    return conn.WriteJSON(v) == nil
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+    "bytes"
+    "encoding/json"
+    "mime"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "go.uber.org/zap"
+    "gopkg.in/yaml.v3"
+)
+
+// yamlContentType is the media type of YAML responses
+const yamlContentType = "application/yaml"
+
+// isYAMLMediaType reports whether mediaType is one of the names YAML goes by
+func isYAMLMediaType(mediaType string) bool {
+    switch mediaType {
+    case yamlContentType, "application/x-yaml", "text/yaml", "text/x-yaml":
+        return true
+    }
+    return false
+}
+
+// wantsYAML reports whether the Accept header of r prefers YAML to JSON.
+// JSON wins ties, so it stays the default.
+func wantsYAML(r *http.Request) bool {
+    var yamlQ, jsonQ float64
+    for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+        mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+        if err != nil {
+            continue
+        }
+        q := 1.0
+        if value, ok := params["q"]; ok {
+            if q, err = strconv.ParseFloat(value, 64); err != nil {
+                continue
+            }
+        }
+        switch {
+        case isYAMLMediaType(mediaType) && q > yamlQ:
+            yamlQ = q
+        case mediaType == "application/json" && q > jsonQ:
+            jsonQ = q
+        }
+    }
+    return yamlQ > jsonQ
+}
+
+// isYAMLRequest reports whether the body of r is labeled as YAML
+func isYAMLRequest(r *http.Request) bool {
+    mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+    return err == nil && isYAMLMediaType(mediaType)
+}
+
+// respondNegotiated writes payload as YAML when r prefers it, and as JSON
+// otherwise
+func (h *Handler) respondNegotiated(w http.ResponseWriter, r *http.Request, code int, payload interface{}) {
+    if !wantsYAML(r) {
+        h.respondWithJSON(w, code, payload)
+        return
+    }
+
+    body, err := json.Marshal(payload)
+    if err == nil {
+        body, err = yamlFromJSON(body)
+    }
+    if err != nil {
+        h.log(r).Error("Failed to encode YAML response", zap.Error(err))
+        h.respondWithError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode response")
+        return
+    }
+    w.Header().Set("Content-Type", yamlContentType)
+    w.WriteHeader(code)
+    w.Write(body)
+}
+
+// yamlFromJSON converts a JSON document to block-style YAML with the same
+// keys in the same order. Strings that would read as another type, such as
+// "true" or "12", stay quoted.
+func yamlFromJSON(data []byte) ([]byte, error) {
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil {
+        return nil, err
+    }
+    plainStyle(&doc)
+
+    var buf bytes.Buffer
+    enc := yaml.NewEncoder(&buf)
+    enc.SetIndent(2)
+    if err := enc.Encode(&doc); err != nil {
+        return nil, err
+    }
+    if err := enc.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// plainStyle drops the flow style and quoting that nodes parsed from JSON
+// carry, so the encoder picks the YAML style of each value
+func plainStyle(node *yaml.Node) {
+    node.Style = 0
+    for _, child := range node.Content {
+        plainStyle(child)
+    }
+}
+
+// jsonFromYAML converts a YAML request body to JSON, so that it is decoded
+// like a JSON one
+func jsonFromYAML(data []byte) ([]byte, error) {
+    var doc interface{}
+    if err := yaml.Unmarshal(data, &doc); err != nil {
+        return nil, err
+    }
+    return json.Marshal(doc)
+}
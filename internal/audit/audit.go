@@ -0,0 +1,143 @@
+// Package audit records an append-only trail of item mutations, separate
+// from the application log.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Audited actions
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+)
+
+// DefaultRetain is the number of recent entries kept for queries when no
+// other number is configured
+const DefaultRetain = 1000
+
+// Entry records one mutation of an item. A version of zero means the item
+// did not exist before or after the action.
+type Entry struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Actor         string    `json:"actor"`
+	Action        string    `json:"action"`
+	ItemID        string    `json:"itemId"`
+	BeforeVersion int64     `json:"beforeVersion"`
+	AfterVersion  int64     `json:"afterVersion"`
+	RequestID     string    `json:"requestId,omitempty"`
+}
+
+// Logger writes each Entry as a JSON line to its sink and keeps the most
+// recent ones in memory for queries
+type Logger struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+	logger *zap.Logger
+
+	// recent is a ring buffer of the last len(recent) entries, with next the
+	// slot the following entry goes to
+	recent []Entry
+	next   int
+	full   bool
+}
+
+// New creates a Logger writing to w and keeping the last retain entries. A
+// retain below one uses DefaultRetain. Failed writes are reported to logger.
+func New(w io.Writer, retain int, logger *zap.Logger) *Logger {
+	if retain < 1 {
+		retain = DefaultRetain
+	}
+	return &Logger{
+		enc:    json.NewEncoder(w),
+		logger: logger,
+		recent: make([]Entry, retain),
+	}
+}
+
+// Open creates a Logger for sink, which is "stdout", "stderr", a file path
+// that entries are appended to, or empty to only keep entries in memory
+func Open(sink string, retain int, logger *zap.Logger) (*Logger, error) {
+	switch sink {
+	case "":
+		return New(io.Discard, retain, logger), nil
+	case "stdout":
+		return New(os.Stdout, retain, logger), nil
+	case "stderr":
+		return New(os.Stderr, retain, logger), nil
+	}
+
+	f, err := os.OpenFile(sink, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l := New(f, retain, logger)
+	l.closer = f
+	return l, nil
+}
+
+// Record appends entry to the trail, stamping it with the current time when
+// it has none
+func (l *Logger) Record(entry Entry) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.enc.Encode(entry); err != nil {
+		l.logger.Error("Failed to write audit entry", zap.String("itemId", entry.ItemID), zap.String("action", entry.Action), zap.Error(err))
+	}
+
+	l.recent[l.next] = entry
+	l.next = (l.next + 1) % len(l.recent)
+	l.full = l.full || l.next == 0
+}
+
+// Recent returns up to limit of the retained entries, newest first. A
+// non-empty itemID only returns that item's entries, and a limit below one
+// returns every match.
+func (l *Logger) Recent(itemID string, limit int) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.recent)
+	}
+
+	result := []Entry{}
+	for i := 1; i <= count; i++ {
+		entry := l.recent[(l.next-i+len(l.recent))%len(l.recent)]
+		if itemID != "" && entry.ItemID != itemID {
+			continue
+		}
+		result = append(result, entry)
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+// Close closes the sink when it is a file
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closer.Close()
+}
@@ -0,0 +1,144 @@
+// Package graphql parses GraphQL documents and provides what a server needs
+// to execute them against its own resolvers: field collection with fragments
+// and the @skip and @include directives, argument values, ordered result
+// objects and errors in the shape of the GraphQL response format. Schemas,
+// validation and introspection are left to the caller.
+package graphql
+
+// Operation types
+const (
+	OperationQuery        = "query"
+	OperationMutation     = "mutation"
+	OperationSubscription = "subscription"
+)
+
+// Document is a parsed GraphQL request document
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query, mutation or subscription of a Document
+type Operation struct {
+	Type         string
+	Name         string
+	Variables    []*VariableDefinition
+	SelectionSet []Selection
+	Loc          Location
+}
+
+// VariableDefinition declares a variable of an Operation
+type VariableDefinition struct {
+	Name    string
+	Type    Type
+	Default interface{}
+	Loc     Location
+}
+
+// Type is a type reference such as String, [ID!] or Int!
+type Type struct {
+	// Name is the named type, empty for a list
+	Name    string
+	Elem    *Type
+	NonNull bool
+}
+
+func (t Type) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// Fragment is a named fragment definition
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+	Loc           Location
+}
+
+// Selection is a *Field, *FragmentSpread or *InlineFragment
+type Selection interface {
+	selection()
+}
+
+// Field selects a field, optionally under an alias
+type Field struct {
+	Alias        string
+	Name         string
+	Arguments    []*Argument
+	Directives   []*Directive
+	SelectionSet []Selection
+	Loc          Location
+}
+
+// ResponseKey is the key of the field in the result: its alias, if any
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Argument returns the argument called name, or nil
+func (f *Field) Argument(name string) *Argument {
+	for _, arg := range f.Arguments {
+		if arg.Name == name {
+			return arg
+		}
+	}
+	return nil
+}
+
+// FragmentSpread includes a named fragment
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Loc        Location
+}
+
+// InlineFragment includes selections, optionally only for a type
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+	Loc           Location
+}
+
+func (*Field) selection()          {}
+func (*FragmentSpread) selection() {}
+func (*InlineFragment) selection() {}
+
+// Directive annotates a selection, as in @skip(if: $hidden)
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+	Loc       Location
+}
+
+// Argument is a named argument of a field or directive. Its Value is nil,
+// bool, int64, float64, string, EnumValue, Variable, []interface{} or
+// []*ObjectField.
+type Argument struct {
+	Name  string
+	Value interface{}
+	Loc   Location
+}
+
+// EnumValue is an unquoted name used as a value
+type EnumValue string
+
+// Variable refers to a variable of the operation by name
+type Variable string
+
+// ObjectField is a field of an input object value
+type ObjectField struct {
+	Name  string
+	Value interface{}
+}
@@ -0,0 +1,240 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Location is a position in a document, counted from 1
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is an error in the format of the errors entry of a GraphQL response
+type Error struct {
+	Message    string                 `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Object is a result object whose fields are encoded in the order they were
+// set, which GraphQL requires to be the order they were selected in
+type Object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewObject creates an empty Object
+func NewObject() *Object {
+	return &Object{values: make(map[string]interface{})}
+}
+
+// Set sets the field key to value
+func (o *Object) Set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Get returns the value of the field key
+func (o *Object) Get(key string) interface{} {
+	return o.values[key]
+}
+
+// MarshalJSON encodes the fields in order
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// CoerceVariables checks the variables supplied for op against its
+// definitions, filling in defaults. Types are checked by the resolvers that
+// read the values; only required variables are enforced here.
+func CoerceVariables(op *Operation, supplied map[string]interface{}) (map[string]interface{}, error) {
+	variables := make(map[string]interface{}, len(op.Variables))
+	for _, def := range op.Variables {
+		value, ok := supplied[def.Name]
+		if !ok && def.Default != nil {
+			value, ok = def.Default, true
+		}
+		if def.Type.NonNull && value == nil {
+			return nil, &Error{Message: "Variable $" + def.Name + " of required type " + def.Type.String() + " was not provided", Locations: []Location{def.Loc}}
+		}
+		if ok {
+			variables[def.Name] = value
+		}
+	}
+	return variables, nil
+}
+
+// Value resolves an argument value to plain Go values: variables are
+// replaced by their value, enums by their name, lists by []interface{} and
+// input objects by map[string]interface{}
+func Value(value interface{}, variables map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case Variable:
+		return variables[string(v)]
+	case EnumValue:
+		return string(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, elem := range v {
+			list[i] = Value(elem, variables)
+		}
+		return list
+	case []*ObjectField:
+		object := make(map[string]interface{}, len(v))
+		for _, field := range v {
+			object[field.Name] = Value(field.Value, variables)
+		}
+		return object
+	}
+	return value
+}
+
+// Arguments resolves the arguments of field, rejecting any that are not in
+// allowed
+func Arguments(field *Field, variables map[string]interface{}, allowed ...string) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(field.Arguments))
+	for _, arg := range field.Arguments {
+		known := false
+		for _, name := range allowed {
+			known = known || name == arg.Name
+		}
+		if !known {
+			return nil, &Error{Message: "Unknown argument " + strconv.Quote(arg.Name) + " on field " + strconv.Quote(field.Name), Locations: []Location{arg.Loc}}
+		}
+		args[arg.Name] = Value(arg.Value, variables)
+	}
+	return args, nil
+}
+
+// CollectFields flattens selections into the fields selected on an object of
+// typeName, expanding fragments whose type condition is typeName and
+// dropping selections excluded by @skip or @include. Fields with the same
+// response key are merged.
+func CollectFields(doc *Document, selections []Selection, typeName string, variables map[string]interface{}) ([]*Field, error) {
+	var fields []*Field
+	byKey := make(map[string]*Field)
+	err := collectFields(doc, selections, typeName, variables, map[string]bool{}, &fields, byKey)
+	return fields, err
+}
+
+func collectFields(doc *Document, selections []Selection, typeName string, variables map[string]interface{}, visited map[string]bool, fields *[]*Field, byKey map[string]*Field) error {
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *Field:
+			if include, err := included(s.Directives, variables); err != nil {
+				return err
+			} else if !include {
+				continue
+			}
+			key := s.ResponseKey()
+			if existing, ok := byKey[key]; ok {
+				if existing.Name != s.Name {
+					return &Error{Message: "Fields " + strconv.Quote(key) + " select different fields", Locations: []Location{existing.Loc, s.Loc}}
+				}
+				merged := *existing
+				merged.SelectionSet = append(append([]Selection{}, existing.SelectionSet...), s.SelectionSet...)
+				*existing = merged
+				continue
+			}
+			field := *s
+			byKey[key] = &field
+			*fields = append(*fields, &field)
+		case *InlineFragment:
+			if include, err := included(s.Directives, variables); err != nil {
+				return err
+			} else if !include {
+				continue
+			}
+			if s.TypeCondition != "" && s.TypeCondition != typeName {
+				continue
+			}
+			if err := collectFields(doc, s.SelectionSet, typeName, variables, visited, fields, byKey); err != nil {
+				return err
+			}
+		case *FragmentSpread:
+			if include, err := included(s.Directives, variables); err != nil {
+				return err
+			} else if !include {
+				continue
+			}
+			fragment, ok := doc.Fragments[s.Name]
+			if !ok {
+				return &Error{Message: "Unknown fragment " + strconv.Quote(s.Name), Locations: []Location{s.Loc}}
+			}
+			if visited[s.Name] {
+				return &Error{Message: "Fragment " + strconv.Quote(s.Name) + " spreads itself", Locations: []Location{s.Loc}}
+			}
+			if fragment.TypeCondition != typeName {
+				continue
+			}
+			visited[s.Name] = true
+			err := collectFields(doc, fragment.SelectionSet, typeName, variables, visited, fields, byKey)
+			delete(visited, s.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// included applies the @skip and @include directives
+func included(directives []*Directive, variables map[string]interface{}) (bool, error) {
+	for _, directive := range directives {
+		if directive.Name != "skip" && directive.Name != "include" {
+			continue
+		}
+		var condition interface{}
+		for _, arg := range directive.Arguments {
+			if arg.Name == "if" {
+				condition = Value(arg.Value, variables)
+			}
+		}
+		value, ok := condition.(bool)
+		if !ok {
+			return false, &Error{Message: "Directive @" + directive.Name + " requires a Boolean if argument", Locations: []Location{directive.Loc}}
+		}
+		if value == (directive.Name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Path appends key to path without sharing path's backing array, so that
+// sibling fields do not overwrite each other's paths
+func Path(path []interface{}, key interface{}) []interface{} {
+	return append(append(make([]interface{}, 0, len(path)+1), path...), key)
+}
+
+// FieldError creates an Error for field at path
+func FieldError(field *Field, path []interface{}, message string) *Error {
+	return &Error{Message: strings.TrimSpace(message), Locations: []Location{field.Loc}, Path: path}
+}
@@ -0,0 +1,168 @@
+package graphql
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// parseOperation parses src, which must hold a single operation
+func parseOperation(t *testing.T, src string) (*Document, *Operation) {
+	t.Helper()
+	doc, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, err := doc.Operation("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc, op
+}
+
+func TestCollectFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		variables map[string]interface{}
+		want      []string
+		wantErr   string
+	}{
+		{name: "fields in order", src: `{ id name version }`, want: []string{"id", "name", "version"}},
+		{name: "aliases", src: `{ a: name b: name }`, want: []string{"a", "b"}},
+		{name: "fragment spread", src: `{ id ...f } fragment f on Item { name }`, want: []string{"id", "name"}},
+		{name: "fragment on another type", src: `{ id ...f } fragment f on Link { relation }`, want: []string{"id"}},
+		{name: "inline fragment", src: `{ ... on Item { id } ... on Link { relation } ... { name } }`, want: []string{"id", "name"}},
+		{name: "merged fields", src: `{ id name ...f } fragment f on Item { id }`, want: []string{"id", "name"}},
+		{name: "skip", src: `query($hide: Boolean) { id name @skip(if: $hide) }`, variables: map[string]interface{}{"hide": true},
+			want: []string{"id"}},
+		{name: "include", src: `{ id @include(if: false) ... @include(if: true) { name } }`, want: []string{"name"}},
+		{name: "conflicting alias", src: `{ x: id x: name }`, wantErr: `Fields "x" select different fields`},
+		{name: "unknown fragment", src: `{ ...f }`, wantErr: `Unknown fragment "f"`},
+		{name: "cyclic fragment", src: `{ ...f } fragment f on Item { ...g } fragment g on Item { ...f }`, wantErr: `Fragment "f" spreads itself`},
+		{name: "directive without condition", src: `{ id @skip }`, wantErr: "Directive @skip requires a Boolean if argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, op := parseOperation(t, tt.src)
+			fields, err := CollectFields(doc, op.SelectionSet, "Item", tt.variables)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("CollectFields() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, field := range fields {
+				keys = append(keys, field.ResponseKey())
+			}
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("fields = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectFieldsMergesSelections(t *testing.T) {
+	doc, op := parseOperation(t, `{ links { relation } links { ids } }`)
+	fields, err := CollectFields(doc, op.SelectionSet, "Item", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || len(fields[0].SelectionSet) != 2 {
+		t.Fatalf("fields = %#v, want one links field selecting two fields", fields)
+	}
+	// Merging must not change the parsed document
+	if first := op.SelectionSet[0].(*Field); len(first.SelectionSet) != 1 {
+		t.Errorf("the parsed field selects %d fields, want 1", len(first.SelectionSet))
+	}
+}
+
+func TestArguments(t *testing.T) {
+	_, op := parseOperation(t, `query($id: ID!) { items(type: SERVICE, id: $id, tags: ["a", $id], filter: {env: $id}) }`)
+	field := op.SelectionSet[0].(*Field)
+	variables := map[string]interface{}{"id": "svc"}
+
+	args, err := Arguments(field, variables, "type", "id", "tags", "filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"type":   "SERVICE",
+		"id":     "svc",
+		"tags":   []interface{}{"a", "svc"},
+		"filter": map[string]interface{}{"env": "svc"},
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("Arguments() = %#v, want %#v", args, want)
+	}
+
+	if _, err := Arguments(field, variables, "type", "id", "tags"); err == nil || err.Error() != `Unknown argument "filter" on field "items"` {
+		t.Errorf("Arguments() error = %v, want an unknown argument error", err)
+	}
+}
+
+func TestCoerceVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		supplied map[string]interface{}
+		want     map[string]interface{}
+		wantErr  string
+	}{
+		{name: "supplied", supplied: map[string]interface{}{"id": "a", "limit": 5.0}, want: map[string]interface{}{"id": "a", "limit": 5.0}},
+		{name: "default", supplied: map[string]interface{}{"id": "a"}, want: map[string]interface{}{"id": "a", "limit": int64(10)}},
+		{name: "explicit null", supplied: map[string]interface{}{"id": "a", "limit": nil}, want: map[string]interface{}{"id": "a", "limit": nil}},
+		{name: "missing required", supplied: map[string]interface{}{}, wantErr: "Variable $id of required type ID! was not provided"},
+		{name: "null required", supplied: map[string]interface{}{"id": nil}, wantErr: "Variable $id of required type ID! was not provided"},
+	}
+	_, op := parseOperation(t, `query($id: ID!, $limit: Int = 10, $tag: String) { items { id } }`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceVariables(op, tt.supplied)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("CoerceVariables() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CoerceVariables() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjectMarshalJSON(t *testing.T) {
+	o := NewObject()
+	o.Set("zeta", 1)
+	o.Set("alpha", []interface{}{"a"})
+	nested := NewObject()
+	nested.Set("b", nil)
+	nested.Set("a", true)
+	o.Set("nested", nested)
+	o.Set("zeta", 2)
+
+	data, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"zeta":2,"alpha":["a"],"nested":{"b":null,"a":true}}`; string(data) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", data, want)
+	}
+}
+
+func TestPath(t *testing.T) {
+	base := make([]interface{}, 1, 4)
+	base[0] = "items"
+	first := Path(base, 0)
+	second := Path(base, 1)
+	if !reflect.DeepEqual(first, []interface{}{"items", 0}) || !reflect.DeepEqual(second, []interface{}{"items", 1}) {
+		t.Errorf("paths = %v and %v, want [items 0] and [items 1]", first, second)
+	}
+}
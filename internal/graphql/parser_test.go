@@ -0,0 +1,196 @@
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		# Items of a registry
+		query Items($registry: String = "team-a", $tags: [String!]!) {
+			list: items(registryName: $registry, tags: $tags, filter: {env: "prod", count: "gt:5"}, limit: 10) {
+				...itemFields
+				... on Item @include(if: true) { version }
+			}
+		}
+
+		fragment itemFields on Item {
+			id
+			metadata(key: """block
+			  string""")
+		}
+
+		mutation { deleteItem(id: "a", purge: false) }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Operations) != 2 {
+		t.Fatalf("got %d operations, want 2", len(doc.Operations))
+	}
+	op := doc.Operations[0]
+	if op.Type != OperationQuery || op.Name != "Items" || op.Loc != (Location{Line: 3, Column: 3}) {
+		t.Errorf("operation = %s %q at %v, want query \"Items\" at 3:3", op.Type, op.Name, op.Loc)
+	}
+	if len(op.Variables) != 2 {
+		t.Fatalf("got %d variables, want 2", len(op.Variables))
+	}
+	if v := op.Variables[0]; v.Name != "registry" || v.Type.String() != "String" || v.Default != "team-a" {
+		t.Errorf("variable 0 = $%s: %s = %v", v.Name, v.Type, v.Default)
+	}
+	if v := op.Variables[1]; v.Name != "tags" || v.Type.String() != "[String!]!" || v.Default != nil {
+		t.Errorf("variable 1 = $%s: %s = %v", v.Name, v.Type, v.Default)
+	}
+
+	field, ok := op.SelectionSet[0].(*Field)
+	if !ok || field.Name != "items" || field.ResponseKey() != "list" {
+		t.Fatalf("selection = %#v, want items aliased as list", op.SelectionSet[0])
+	}
+	wantArgs := map[string]interface{}{
+		"registryName": Variable("registry"),
+		"tags":         Variable("tags"),
+		"filter": []*ObjectField{
+			{Name: "env", Value: "prod"},
+			{Name: "count", Value: "gt:5"},
+		},
+		"limit": int64(10),
+	}
+	for name, want := range wantArgs {
+		arg := field.Argument(name)
+		if arg == nil {
+			t.Errorf("argument %s is missing", name)
+		} else if !reflect.DeepEqual(arg.Value, want) {
+			t.Errorf("argument %s = %#v, want %#v", name, arg.Value, want)
+		}
+	}
+	if field.Argument("offset") != nil {
+		t.Errorf("argument offset is present")
+	}
+
+	if spread, ok := field.SelectionSet[0].(*FragmentSpread); !ok || spread.Name != "itemFields" {
+		t.Errorf("selection 0 = %#v, want a spread of itemFields", field.SelectionSet[0])
+	}
+	inline, ok := field.SelectionSet[1].(*InlineFragment)
+	if !ok || inline.TypeCondition != "Item" || len(inline.Directives) != 1 || inline.Directives[0].Name != "include" {
+		t.Errorf("selection 1 = %#v, want an inline fragment on Item with @include", field.SelectionSet[1])
+	}
+
+	fragment := doc.Fragments["itemFields"]
+	if fragment == nil || fragment.TypeCondition != "Item" || len(fragment.SelectionSet) != 2 {
+		t.Fatalf("fragment = %#v", fragment)
+	}
+	if arg := fragment.SelectionSet[1].(*Field).Argument("key"); arg == nil || arg.Value != "block\nstring" {
+		t.Errorf("block string argument = %#v, want \"block\\nstring\"", arg)
+	}
+
+	mutation := doc.Operations[1]
+	if mutation.Type != OperationMutation || mutation.Name != "" {
+		t.Errorf("operation = %s %q, want an anonymous mutation", mutation.Type, mutation.Name)
+	}
+	if arg := mutation.SelectionSet[0].(*Field).Argument("purge"); arg == nil || arg.Value != false {
+		t.Errorf("purge = %#v, want false", arg)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{src: `1`, want: int64(1)},
+		{src: `-12`, want: int64(-12)},
+		{src: `1.5e3`, want: 1500.0},
+		{src: `"a\"bé"`, want: "a\"bé"},
+		{src: `true`, want: true},
+		{src: `null`, want: nil},
+		{src: `SERVICE`, want: EnumValue("SERVICE")},
+		{src: `$id`, want: Variable("id")},
+		{src: `[1, "a", [true]]`, want: []interface{}{int64(1), "a", []interface{}{true}}},
+		{src: `{}`, want: []*ObjectField{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			doc, err := Parse(`{ f(v: ` + tt.src + `) }`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := doc.Operations[0].SelectionSet[0].(*Field).Argument("v").Value
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("value = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantMsg string
+		wantLoc *Location
+	}{
+		{name: "empty document", src: `  `, wantMsg: "The document has no operation"},
+		{name: "missing argument value", src: `{ item(id: ) { id } }`, wantMsg: "Syntax error", wantLoc: &Location{Line: 1, Column: 12}},
+		{name: "unclosed selection set", src: "{\n  item(id: \"a\") { id }", wantMsg: "Syntax error", wantLoc: &Location{Line: 2, Column: 23}},
+		{name: "unterminated string", src: `{ item(id: "a) { id } }`, wantMsg: "Syntax error", wantLoc: &Location{Line: 1, Column: 12}},
+		{name: "variable in default value", src: `query($a: Int = $b) { f }`, wantMsg: "Syntax error", wantLoc: &Location{Line: 1, Column: 17}},
+		{name: "unknown definition", src: `schema { query: Query }`, wantMsg: "Syntax error", wantLoc: &Location{Line: 1, Column: 1}},
+		{name: "duplicate fragment", src: `{ ...f } fragment f on Item { id } fragment f on Item { name }`,
+			wantMsg: `There can be only one fragment named "f"`, wantLoc: &Location{Line: 1, Column: 36}},
+		{name: "too deeply nested", src: strings.Repeat("{ f ", maxDepth+1) + strings.Repeat("}", maxDepth+1),
+			wantMsg: "Syntax error: the document is nested more than 64 levels deep"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			gqlErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Parse() error = %#v, want an *Error", err)
+			}
+			if !strings.HasPrefix(gqlErr.Message, tt.wantMsg) {
+				t.Errorf("message = %q, want prefix %q", gqlErr.Message, tt.wantMsg)
+			}
+			if tt.wantLoc != nil && (len(gqlErr.Locations) != 1 || gqlErr.Locations[0] != *tt.wantLoc) {
+				t.Errorf("locations = %v, want %v", gqlErr.Locations, *tt.wantLoc)
+			}
+		})
+	}
+}
+
+func TestDocumentOperation(t *testing.T) {
+	doc, err := Parse(`query A { a } mutation B { b }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		wantType string
+		wantErr  string
+	}{
+		{name: "A", wantType: OperationQuery},
+		{name: "B", wantType: OperationMutation},
+		{name: "", wantErr: "operationName is required when the document has several operations"},
+		{name: "C", wantErr: `Unknown operation "C"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, err := doc.Operation(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Operation() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if op.Type != tt.wantType || op.Name != tt.name {
+				t.Errorf("operation = %s %q, want %s %q", op.Type, op.Name, tt.wantType, tt.name)
+			}
+		})
+	}
+}
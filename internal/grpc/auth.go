@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Cdaprod/registry-service/internal/api"
+	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/Cdaprod/registry-service/internal/storage"
+	"github.com/Cdaprod/registry-service/proto/registrypb"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// lockTokenKey is the metadata key of the lock token proving that a caller
+// holds the lease on an item, as X-Lock-Token does over HTTP
+const lockTokenKey = "x-lock-token"
+
+type callerKey struct{}
+
+// callerFrom returns the authenticated caller of a call, nil when the call
+// is anonymous
+func callerFrom(ctx context.Context) *api.Caller {
+	c, _ := ctx.Value(callerKey{}).(*api.Caller)
+	return c
+}
+
+// actor names the caller of a call on items and in the audit log
+func (s *Server) actor(ctx context.Context) string {
+	if s.auth == nil {
+		return grpcActor
+	}
+	return callerFrom(ctx).Actor()
+}
+
+// authenticate resolves the caller of a call from the x-api-key or
+// authorization metadata, which carry the credentials of the HTTP headers
+func (s *Server) authenticate(ctx context.Context, read bool) (context.Context, error) {
+	header := make(http.Header)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		header[http.CanonicalHeaderKey(key)] = values
+	}
+
+	caller, err := s.auth.Authenticate(header, read)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized: "+err.Error())
+	}
+	return context.WithValue(ctx, callerKey{}, caller), nil
+}
+
+// unaryAuth rejects unary calls without valid credentials. GetItem passes
+// without them when authentication is optional, and DeleteItem additionally
+// requires the admin role, as over HTTP.
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod == registrypb.RegistryService_GetItem_FullMethodName)
+	if err != nil {
+		return nil, err
+	}
+	if info.FullMethod == registrypb.RegistryService_DeleteItem_FullMethodName && !callerFrom(ctx).IsAdmin() {
+		return nil, status.Error(codes.PermissionDenied, "admin role required")
+	}
+	return handler(ctx, req)
+}
+
+// streamAuth rejects ListItems calls without valid credentials, unless
+// authentication is optional
+func (s *Server) streamAuth(srv interface{}, stream grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context(), true)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream carries the caller in the context of a stream
+type authenticatedStream struct {
+	grpclib.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// outOfScope returns a PermissionDenied error when the caller's API key is
+// not scoped to one of registryNames
+func outOfScope(ctx context.Context, registryNames ...string) error {
+	caller := callerFrom(ctx)
+	for _, name := range registryNames {
+		if !caller.AllowsRegistry(name) {
+			return status.Errorf(codes.PermissionDenied, "API key is not scoped to registry %q", name)
+		}
+	}
+	return nil
+}
+
+// mayModify returns an error when the caller may not change current: it is
+// in a registry outside the caller's scope, owned by someone else while
+// updates are restricted to owners, or locked by another holder
+func (s *Server) mayModify(ctx context.Context, current *registry.Item) error {
+	if err := outOfScope(ctx, current.RegistryName); err != nil {
+		return err
+	}
+	if !callerFrom(ctx).MayUpdate(current.Owner) {
+		return status.Error(codes.PermissionDenied, "only the owner of the item may update it")
+	}
+
+	locks, ok := s.store.(storage.LockStore)
+	if !ok {
+		return nil
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(lockTokenKey); len(values) > 0 {
+			token = values[0]
+		}
+	}
+	if lease, err := locks.CheckLock(current.ID, token); errors.Is(err, storage.ErrItemLocked) {
+		return status.Errorf(codes.FailedPrecondition, "item is locked by %s", lease.Holder)
+	}
+	return nil
+}
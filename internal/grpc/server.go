@@ -0,0 +1,297 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Cdaprod/registry-service/internal/api"
+	"github.com/Cdaprod/registry-service/internal/audit"
+	"github.com/Cdaprod/registry-service/internal/notify"
+	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/Cdaprod/registry-service/internal/storage"
+	"github.com/Cdaprod/registry-service/proto/registrypb"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements the RegistryService gRPC API on top of a storage.Store
+type Server struct {
+	registrypb.UnimplementedRegistryServiceServer
+
+	store    storage.Store
+	logger   *zap.Logger
+	auditLog *audit.Logger
+	notifier *notify.Notifier
+	auth     *api.Authenticator
+}
+
+// grpcActor names gRPC callers on items and in the audit log when the API
+// is not authenticated
+const grpcActor = "grpc"
+
+// NewServer creates a new gRPC registry server that announces mutations on
+// notifier and records them in auditLog. Calls are checked by auth with the
+// rules of the HTTP API; a nil auth leaves the gRPC API unauthenticated.
+func NewServer(store storage.Store, logger *zap.Logger, auditLog *audit.Logger, notifier *notify.Notifier, auth *api.Authenticator) *Server {
+	return &Server{
+		store:    store,
+		logger:   logger,
+		auditLog: auditLog,
+		notifier: notifier,
+		auth:     auth,
+	}
+}
+
+// Register creates a grpc.Server with the registry service registered on it
+func (s *Server) Register() *grpclib.Server {
+	var opts []grpclib.ServerOption
+	if s.auth != nil {
+		opts = append(opts, grpclib.UnaryInterceptor(s.unaryAuth), grpclib.StreamInterceptor(s.streamAuth))
+	}
+	srv := grpclib.NewServer(opts...)
+	registrypb.RegisterRegistryServiceServer(srv, s)
+	return srv
+}
+
+// CreateItem registers a new item
+func (s *Server) CreateItem(ctx context.Context, req *registrypb.CreateItemRequest) (*registrypb.Item, error) {
+	item, err := FromProto(req.GetItem())
+	if err != nil {
+		return nil, err
+	}
+	if err := outOfScope(ctx, item.RegistryName); err != nil {
+		return nil, err
+	}
+	actor := s.actor(ctx)
+	item.CreatedBy = actor
+	item.UpdatedBy = actor
+	if callerFrom(ctx) != nil {
+		item.Owner = actor
+	}
+
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+
+	created, err := s.createIfAbsent(ctx, item)
+	if err != nil {
+		if status.Code(err) == codes.AlreadyExists {
+			return nil, err
+		}
+		s.logger.Error("Failed to create item", zap.Error(err))
+		return nil, storeError(err)
+	}
+	s.announce(notify.EventItemCreated, audit.ActionCreate, actor, created, 0, created.Version)
+
+	return ToProto(created)
+}
+
+// createIfAbsent creates item unless a stored item already has its ID, as
+// creates over HTTP do. Stores with compare-and-swap check and create
+// atomically.
+func (s *Server) createIfAbsent(ctx context.Context, item *registry.Item) (*registry.Item, error) {
+	exists := status.Errorf(codes.AlreadyExists, "item already exists: %s", item.ID)
+	if cas, ok := s.store.(storage.CASStore); ok {
+		created, err := cas.CompareAndSwap(item.ID, 0, item)
+		if errors.Is(err, storage.ErrVersionConflict) {
+			return nil, exists
+		}
+		return created, err
+	}
+	if _, err := s.store.GetItemCtx(ctx, item.ID); err == nil {
+		return nil, exists
+	}
+	return s.store.CreateItemCtx(ctx, item)
+}
+
+// GetItem retrieves a non-deleted item by ID
+func (s *Server) GetItem(ctx context.Context, req *registrypb.GetItemRequest) (*registrypb.Item, error) {
+	item, err := s.store.GetItemCtx(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "item not found: %s", req.GetId())
+	}
+	if err := outOfScope(ctx, item.RegistryName); err != nil {
+		return nil, err
+	}
+
+	return ToProto(item)
+}
+
+// UpdateItem updates an existing item
+func (s *Server) UpdateItem(ctx context.Context, req *registrypb.UpdateItemRequest) (*registrypb.Item, error) {
+	item, err := FromProto(req.GetItem())
+	if err != nil {
+		return nil, err
+	}
+	if item.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "item id is required")
+	}
+	if err := outOfScope(ctx, item.RegistryName); err != nil {
+		return nil, err
+	}
+	// Storage keeps CreatedBy on update, so it only applies when this creates the item
+	actor := s.actor(ctx)
+	item.CreatedBy = actor
+	item.UpdatedBy = actor
+
+	var before int64
+	if current, err := s.store.GetItemCtx(ctx, item.ID); err == nil {
+		if err := s.mayModify(ctx, current); err != nil {
+			return nil, err
+		}
+		before = current.Version
+	} else if callerFrom(ctx) != nil {
+		item.Owner = actor
+	}
+
+	updated, err := s.store.UpdateItemCtx(ctx, item)
+	if err != nil {
+		s.logger.Error("Failed to update item", zap.Error(err))
+		return nil, storeError(err)
+	}
+	event, action := notify.EventItemUpdated, audit.ActionUpdate
+	if before == 0 {
+		event, action = notify.EventItemCreated, audit.ActionCreate
+	}
+	s.announce(event, action, actor, updated, before, updated.Version)
+
+	return ToProto(updated)
+}
+
+// DeleteItem soft-deletes an item by ID
+func (s *Server) DeleteItem(ctx context.Context, req *registrypb.DeleteItemRequest) (*registrypb.DeleteItemResponse, error) {
+	current, err := s.store.GetItemCtx(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "item not found: %s", req.GetId())
+	}
+	if err := s.mayModify(ctx, current); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.DeleteItemCtx(ctx, req.GetId()); err != nil {
+		return nil, status.Errorf(codes.NotFound, "item not found: %s", req.GetId())
+	}
+	s.announce(notify.EventItemDeleted, audit.ActionDelete, s.actor(ctx), current, current.Version, current.Version)
+
+	return &registrypb.DeleteItemResponse{}, nil
+}
+
+// announce publishes the item event of a mutation made through the gRPC API
+// by actor and adds it to the audit trail
+func (s *Server) announce(event, action, actor string, item *registry.Item, before, after int64) {
+	s.notifier.Notify(event, item.ID, item.Type, item.RegistryName)
+	s.auditLog.Record(audit.Entry{
+		Actor:         actor,
+		Action:        action,
+		ItemID:        item.ID,
+		BeforeVersion: before,
+		AfterVersion:  after,
+	})
+}
+
+// ListItems streams non-deleted items, optionally filtered by type or registry name
+func (s *Server) ListItems(req *registrypb.ListItemsRequest, stream registrypb.RegistryService_ListItemsServer) error {
+	ctx := stream.Context()
+	var items []registry.Registerable
+	var err error
+	switch {
+	case req.GetType() != "":
+		items = s.store.ListByType(req.GetType())
+	case req.GetRegistryName() != "":
+		items, err = s.store.ListByRegistryNameCtx(ctx, req.GetRegistryName())
+	case req.GetLimit() > 0 || req.GetOffset() > 0:
+		items = s.store.ListPaginated(int(req.GetLimit()), int(req.GetOffset()))
+	default:
+		items, err = s.store.ListCtx(ctx)
+	}
+	if err != nil {
+		return status.FromContextError(err).Err()
+	}
+
+	for _, r := range items {
+		item, ok := r.(*registry.Item)
+		if !ok {
+			continue
+		}
+		// A type filter can be combined with a registry name filter
+		if req.GetRegistryName() != "" && item.RegistryName != req.GetRegistryName() {
+			continue
+		}
+		// Scoped API keys only see the items of their registries
+		if !callerFrom(ctx).AllowsRegistry(item.RegistryName) {
+			continue
+		}
+
+		msg, err := ToProto(item)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storeError maps a storage error to a gRPC status
+func storeError(err error) error {
+	if errors.Is(err, registry.ErrRegistryNameRequired) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, registry.ErrStaleVersion) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// FromProto converts a protobuf item into a registry.Item
+func FromProto(msg *registrypb.Item) (*registry.Item, error) {
+	if msg == nil {
+		return nil, status.Error(codes.InvalidArgument, "item is required")
+	}
+
+	item := &registry.Item{
+		ID:           msg.GetId(),
+		Type:         msg.GetType(),
+		Name:         msg.GetName(),
+		RegistryName: msg.GetRegistryName(),
+		Version:      msg.GetVersion(),
+	}
+	if msg.GetMetadata() != nil {
+		item.Metadata = msg.GetMetadata().AsMap()
+	}
+	if msg.GetCreatedAt() != nil {
+		item.CreatedAt = msg.GetCreatedAt().AsTime()
+	}
+	if msg.GetUpdatedAt() != nil {
+		item.UpdatedAt = msg.GetUpdatedAt().AsTime()
+	}
+
+	return item, nil
+}
+
+// ToProto converts a registry.Item into its protobuf form
+func ToProto(item *registry.Item) (*registrypb.Item, error) {
+	metadata, err := structpb.NewStruct(item.Metadata)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode metadata: %v", err)
+	}
+
+	return &registrypb.Item{
+		Id:           item.ID,
+		Type:         item.Type,
+		Name:         item.Name,
+		RegistryName: item.RegistryName,
+		Metadata:     metadata,
+		CreatedAt:    timestamppb.New(item.CreatedAt),
+		UpdatedAt:    timestamppb.New(item.UpdatedAt),
+		Version:      item.Version,
+		Deleted:      item.IsDeleted(),
+	}, nil
+}
@@ -0,0 +1,311 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/api"
+	"github.com/Cdaprod/registry-service/internal/audit"
+	"github.com/Cdaprod/registry-service/internal/notify"
+	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/Cdaprod/registry-service/internal/storage"
+	"github.com/Cdaprod/registry-service/pkg/plugins"
+	"github.com/Cdaprod/registry-service/proto/registrypb"
+	"go.uber.org/zap"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testAdminKey = "test-admin-key"
+
+// newTestClient serves s on an in-memory listener and returns a client of it
+func newTestClient(t *testing.T, s *Server) registrypb.RegistryServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := s.Register()
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpclib.DialContext(context.Background(), "bufnet",
+		grpclib.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpclib.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return registrypb.NewRegistryServiceClient(conn)
+}
+
+// newAuthenticatedServer returns a Server that requires API keys, and a key
+// scoped to the "team-a" registry
+func newAuthenticatedServer(t *testing.T) (*Server, *storage.MemoryStorage, string) {
+	t.Helper()
+	t.Setenv("ADMIN_API_KEY", testAdminKey)
+	keys := storage.NewMemoryKeyStore()
+	auth := api.NewAuthenticatorFromEnv(keys)
+	if auth == nil {
+		t.Fatal("authentication is not configured")
+	}
+	scoped, _, err := keys.CreateKey([]string{"team-a"})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	auditLog, err := audit.Open("", 10, zap.NewNop())
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+
+	store := storage.NewMemoryStorage()
+	return NewServer(store, zap.NewNop(), auditLog, notify.NewNotifier(zap.NewNop()), auth), store, scoped
+}
+
+func withKey(key string) context.Context {
+	if key == "" {
+		return context.Background()
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func TestDeleteItemAuthentication(t *testing.T) {
+	s, store, scoped := newAuthenticatedServer(t)
+	client := newTestClient(t, s)
+
+	tests := []struct {
+		name string
+		key  string
+		want codes.Code
+	}{
+		{name: "no credentials", key: "", want: codes.Unauthenticated},
+		{name: "unknown key", key: "not-a-key", want: codes.Unauthenticated},
+		{name: "scoped key", key: scoped, want: codes.PermissionDenied},
+		{name: "admin key", key: testAdminKey, want: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := store.CreateItem(&registry.Item{ID: "delete-" + tt.name, Type: "service", Name: "api", RegistryName: "team-a"})
+			if err != nil {
+				t.Fatalf("create item: %v", err)
+			}
+
+			_, err = client.DeleteItem(withKey(tt.key), &registrypb.DeleteItemRequest{Id: item.ID})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("DeleteItem code = %v, want %v (%v)", got, tt.want, err)
+			}
+			if _, err := store.GetItem(item.ID); (err == nil) != (tt.want != codes.OK) {
+				t.Errorf("item deleted = %v, want %v", err != nil, tt.want == codes.OK)
+			}
+		})
+	}
+}
+
+func TestRegistryScope(t *testing.T) {
+	s, store, scoped := newAuthenticatedServer(t)
+	client := newTestClient(t, s)
+
+	inScope, err := store.CreateItem(&registry.Item{ID: "a", Type: "service", Name: "a", RegistryName: "team-a"})
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	outOfScope, err := store.CreateItem(&registry.Item{ID: "b", Type: "service", Name: "b", RegistryName: "team-b"})
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+		want codes.Code
+	}{
+		{
+			name: "get in scope",
+			call: func(ctx context.Context) error {
+				_, err := client.GetItem(ctx, &registrypb.GetItemRequest{Id: inScope.ID})
+				return err
+			},
+			want: codes.OK,
+		},
+		{
+			name: "get out of scope",
+			call: func(ctx context.Context) error {
+				_, err := client.GetItem(ctx, &registrypb.GetItemRequest{Id: outOfScope.ID})
+				return err
+			},
+			want: codes.PermissionDenied,
+		},
+		{
+			name: "create out of scope",
+			call: func(ctx context.Context) error {
+				_, err := client.CreateItem(ctx, &registrypb.CreateItemRequest{Item: &registrypb.Item{Type: "service", Name: "c", RegistryName: "team-b"}})
+				return err
+			},
+			want: codes.PermissionDenied,
+		},
+		{
+			name: "move into scope",
+			call: func(ctx context.Context) error {
+				_, err := client.UpdateItem(ctx, &registrypb.UpdateItemRequest{Item: &registrypb.Item{Id: outOfScope.ID, Type: "service", Name: "b", RegistryName: "team-a"}})
+				return err
+			},
+			want: codes.PermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call(withKey(scoped))); got != tt.want {
+				t.Errorf("code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	stream, err := client.ListItems(withKey(scoped), &registrypb.ListItemsRequest{})
+	if err != nil {
+		t.Fatalf("ListItems: %v", err)
+	}
+	var ids []string
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		ids = append(ids, msg.GetId())
+	}
+	if len(ids) != 1 || ids[0] != inScope.ID {
+		t.Errorf("ListItems returned %v, want only %s", ids, inScope.ID)
+	}
+}
+
+func TestCallerRecorded(t *testing.T) {
+	s, _, scoped := newAuthenticatedServer(t)
+	client := newTestClient(t, s)
+
+	created, err := client.CreateItem(withKey(scoped), &registrypb.CreateItemRequest{Item: &registrypb.Item{Id: "a", Type: "service", Name: "a", RegistryName: "team-a"}})
+	if err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	entries := s.auditLog.Recent(created.GetId(), 1)
+	if len(entries) != 1 {
+		t.Fatalf("got %d audit entries, want 1", len(entries))
+	}
+	if entries[0].Actor == grpcActor || entries[0].Actor == "" {
+		t.Errorf("audit actor = %q, want the API key", entries[0].Actor)
+	}
+}
+
+func TestCreateItemTakenID(t *testing.T) {
+	s, store, _ := newAuthenticatedServer(t)
+	client := newTestClient(t, s)
+
+	if _, err := store.CreateItem(&registry.Item{ID: "a", Type: "service", Name: "a", RegistryName: "team-a"}); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	_, err := client.CreateItem(withKey(testAdminKey), &registrypb.CreateItemRequest{Item: &registrypb.Item{Id: "a", Type: "service", Name: "b", RegistryName: "team-a"}})
+	if got := status.Code(err); got != codes.AlreadyExists {
+		t.Fatalf("CreateItem code = %v, want %v", got, codes.AlreadyExists)
+	}
+	if stored, _ := store.GetItem("a"); stored.Name != "a" {
+		t.Errorf("stored item was replaced by %q", stored.Name)
+	}
+}
+
+// recordingHooks is a plugins.LifecyclePlugin that reports each call as
+// "registered:<id>" or "deleted:<id>"
+type recordingHooks chan string
+
+func (h recordingHooks) OnItemRegistered(item registry.Registerable) {
+	h <- "registered:" + item.GetID()
+}
+
+func (h recordingHooks) OnItemDeleted(id string) {
+	h <- "deleted:" + id
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	auditLog, err := audit.Open("", 10, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := notify.NewNotifier(zap.NewNop())
+	hooks := make(recordingHooks, 10)
+	dispatcher := plugins.NewLifecycleDispatcher(store, zap.NewNop())
+	dispatcher.Add("test", hooks)
+	dispatcher.Start(notifier.Broker())
+	defer dispatcher.Stop()
+	client := newTestClient(t, NewServer(store, zap.NewNop(), auditLog, notifier, nil))
+
+	tests := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{
+			name: "create",
+			call: func() error {
+				_, err := client.CreateItem(context.Background(), &registrypb.CreateItemRequest{Item: &registrypb.Item{Id: "a", Type: "service", Name: "a", RegistryName: "team-a"}})
+				return err
+			},
+			want: "registered:a",
+		},
+		{
+			name: "update",
+			call: func() error {
+				_, err := client.UpdateItem(context.Background(), &registrypb.UpdateItemRequest{Item: &registrypb.Item{Id: "a", Type: "service", Name: "b", RegistryName: "team-a"}})
+				return err
+			},
+			want: "registered:a",
+		},
+		{
+			name: "delete",
+			call: func() error {
+				_, err := client.DeleteItem(context.Background(), &registrypb.DeleteItemRequest{Id: "a"})
+				return err
+			},
+			want: "deleted:a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case got := <-hooks:
+				if got != tt.want {
+					t.Errorf("hook call = %s, want %s", got, tt.want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("no hook call, want %s", tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "stale version", err: registry.ErrStaleVersion, want: codes.Aborted},
+		{name: "wrapped stale version", err: fmt.Errorf("update a: %w", registry.ErrStaleVersion), want: codes.Aborted},
+		{name: "registry name required", err: registry.ErrRegistryNameRequired, want: codes.InvalidArgument},
+		{name: "other error", err: errors.New("disk full"), want: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(storeError(tt.err)); got != tt.want {
+				t.Errorf("storeError(%v) code = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// subscriptionBuffer is the number of events queued per subscriber before
+// further events are dropped for it
+const subscriptionBuffer = 64
+
+// Filter selects the events a subscriber receives by item type and registry
+// name. An empty list matches every value.
+type Filter struct {
+	Types      []string `json:"types"`
+	Registries []string `json:"registries"`
+}
+
+// Matches reports whether e passes the filter
+func (f Filter) Matches(e Event) bool {
+	return matchesAny(f.Types, e.Type) && matchesAny(f.Registries, e.RegistryName)
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Broker fans item events out to in-process subscribers, such as streaming
+// API connections
+type Broker struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBroker creates a Broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the broker's events that match its filter
+type Subscription struct {
+	broker  *Broker
+	events  chan Event
+	mu      sync.RWMutex
+	filter  Filter
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// Subscribe registers a subscriber for events matching filter. Close the
+// subscription when done with it.
+func (b *Broker) Subscribe(filter Filter) *Subscription {
+	s := &Subscription{
+		broker: b,
+		events: make(chan Event, subscriptionBuffer),
+		filter: filter,
+	}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Publish delivers e to every matching subscriber without blocking. A
+// subscriber whose queue is full misses the event.
+func (b *Broker) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subs {
+		if !s.Filter().Matches(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribers returns the number of open subscriptions
+func (b *Broker) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// Events returns the channel the subscription's events arrive on. It is
+// closed by Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Filter returns the subscription's current filter
+func (s *Subscription) Filter() Filter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter
+}
+
+// SetFilter replaces the subscription's filter for subsequent events
+func (s *Subscription) SetFilter(filter Filter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = filter
+}
+
+// Dropped returns the number of events missed because the queue was full
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unregisters the subscription and closes its events channel
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		s.broker.mu.Unlock()
+		close(s.events)
+	})
+}
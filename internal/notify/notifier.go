@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Item lifecycle event names
+const (
+	EventItemCreated  = "item.created"
+	EventItemUpdated  = "item.updated"
+	EventItemDeleted  = "item.deleted"
+	EventItemRestored = "item.restored"
+	EventItemPurged   = "item.purged"
+)
+
+const (
+	maxAttempts    = 3
+	initialBackoff = 500 * time.Millisecond
+)
+
+// Event is the JSON payload POSTed to webhook URLs and published to broker
+// subscribers
+type Event struct {
+	Event        string    `json:"event"`
+	ItemID       string    `json:"itemId"`
+	Type         string    `json:"type"`
+	RegistryName string    `json:"registryName,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Notifier delivers item lifecycle events to registered webhook URLs and
+// publishes them to its Broker
+type Notifier struct {
+	mu     sync.RWMutex
+	urls   []string
+	client *http.Client
+	logger *zap.Logger
+	broker *Broker
+}
+
+// NewNotifier creates a Notifier delivering to the given webhook URLs
+func NewNotifier(logger *zap.Logger, urls ...string) *Notifier {
+	n := &Notifier{
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+		broker: NewBroker(),
+	}
+	for _, url := range urls {
+		n.AddURL(url)
+	}
+	return n
+}
+
+// AddURL registers a webhook URL. Duplicate and empty URLs are ignored.
+func (n *Notifier) AddURL(url string) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, existing := range n.urls {
+		if existing == url {
+			return
+		}
+	}
+	n.urls = append(n.urls, url)
+}
+
+// URLs returns the registered webhook URLs
+func (n *Notifier) URLs() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	urls := make([]string, len(n.urls))
+	copy(urls, n.urls)
+	return urls
+}
+
+// Broker returns the broker events are published to
+func (n *Notifier) Broker() *Broker {
+	return n.broker
+}
+
+// Notify publishes an event to the broker and asynchronously delivers it to
+// every registered webhook URL
+func (n *Notifier) Notify(event, itemID, itemType, registryName string) {
+	e := Event{
+		Event:        event,
+		ItemID:       itemID,
+		Type:         itemType,
+		RegistryName: registryName,
+		Timestamp:    time.Now(),
+	}
+	n.broker.Publish(e)
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		n.logger.Error("Failed to encode webhook event", zap.Error(err))
+		return
+	}
+
+	for _, url := range n.URLs() {
+		go n.deliver(url, event, payload)
+	}
+}
+
+// deliver POSTs payload to url, retrying with exponential backoff
+func (n *Notifier) deliver(url, event string, payload []byte) {
+	backoff := initialBackoff
+	var err error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = n.post(url, payload); err == nil {
+			return
+		}
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	n.logger.Error("Failed to deliver webhook",
+		zap.String("url", url),
+		zap.String("event", event),
+		zap.Int("attempts", maxAttempts),
+		zap.Error(err))
+}
+
+func (n *Notifier) post(url string, payload []byte) error {
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
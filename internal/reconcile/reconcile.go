@@ -0,0 +1,347 @@
+// Package reconcile keeps the items registered by plugins in sync with the
+// external systems they represent. A Scheduler periodically asks each
+// Reconciler for the items its system currently holds, then creates the new
+// ones, updates the changed ones and soft-deletes the ones that are gone.
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/audit"
+	"github.com/Cdaprod/registry-service/internal/notify"
+	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/Cdaprod/registry-service/internal/storage"
+	"go.uber.org/zap"
+)
+
+// SourceKey is the metadata key naming the Reconciler that manages an item.
+// Items are only soft-deleted by the Reconciler named in it.
+const SourceKey = "reconciledBy"
+
+// DefaultInterval is the time between reconciliations of a source when no
+// other interval is configured
+const DefaultInterval = 5 * time.Minute
+
+// Reconciler is implemented by plugins whose items mirror resources of an
+// external system, such as containers or repositories
+type Reconciler interface {
+	// Reconcile returns every item the external system currently holds.
+	// Each item needs an ID that stays the same across calls and a
+	// RegistryName. An error skips this reconciliation, so that a source
+	// that cannot be reached does not delete its items.
+	Reconcile(ctx context.Context) ([]*registry.Item, error)
+}
+
+// Result counts what one reconciliation did
+type Result struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Restored  int `json:"restored"`
+	Deleted   int `json:"deleted"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Intervals configures how often sources are reconciled
+type Intervals struct {
+	// Default applies to sources without an interval of their own; zero
+	// uses DefaultInterval
+	Default time.Duration
+	// Sources maps source names to their intervals
+	Sources map[string]time.Duration
+}
+
+// of returns the interval of the source name
+func (iv Intervals) of(name string) time.Duration {
+	if interval := iv.Sources[name]; interval > 0 {
+		return interval
+	}
+	if iv.Default > 0 {
+		return iv.Default
+	}
+	return DefaultInterval
+}
+
+// Scheduler runs the Reconcilers added to it, each on its own interval,
+// between Start and Stop
+type Scheduler struct {
+	store     storage.Store
+	notifier  *notify.Notifier
+	auditLog  *audit.Logger
+	intervals Intervals
+	logger    *zap.Logger
+
+	mu      sync.Mutex
+	sources []source
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// source is a Reconciler and the interval it runs on
+type source struct {
+	name       string
+	reconciler Reconciler
+	interval   time.Duration
+}
+
+// NewScheduler creates a Scheduler that applies changes to store, announces
+// them on notifier and records them in auditLog
+func NewScheduler(store storage.Store, notifier *notify.Notifier, auditLog *audit.Logger, intervals Intervals, logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		store:     store,
+		notifier:  notifier,
+		auditLog:  auditLog,
+		intervals: intervals,
+		logger:    logger,
+	}
+}
+
+// Add schedules r under name, which is recorded in SourceKey of its items.
+// Sources added to a started Scheduler are reconciled at once.
+func (s *Scheduler) Add(name string, r Reconciler) {
+	src := source{name: name, reconciler: r, interval: s.intervals.of(name)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources = append(s.sources, src)
+	if s.ctx != nil && s.ctx.Err() == nil {
+		s.wg.Add(1)
+		go s.run(s.ctx, src)
+	}
+}
+
+// Len returns the number of scheduled Reconcilers
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sources)
+}
+
+// Start reconciles every source at once and then on its interval, until
+// Stop is called
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx != nil {
+		return
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for _, src := range s.sources {
+		s.wg.Add(1)
+		go s.run(s.ctx, src)
+	}
+}
+
+// Stop ends the schedule and waits for running reconciliations, whose
+// context is canceled
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	s.wg.Wait()
+}
+
+// run reconciles src until ctx is done
+func (s *Scheduler) run(ctx context.Context, src source) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(src.interval)
+	defer ticker.Stop()
+	for {
+		result, err := s.Reconcile(ctx, src.name, src.reconciler)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Reconciliation failed", zap.String("source", src.name), zap.Error(err))
+		} else if err == nil && result.Created+result.Updated+result.Restored+result.Deleted > 0 {
+			s.logger.Info("Reconciled items",
+				zap.String("source", src.name),
+				zap.Int("created", result.Created),
+				zap.Int("updated", result.Updated),
+				zap.Int("restored", result.Restored),
+				zap.Int("deleted", result.Deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile brings the items of the source name in line with what r returns.
+// Items that did not change are not written, so reconciling an unchanged
+// source does nothing. An item with the ID of one the source does not manage
+// yet is taken over. A failing item is skipped and reported once the others
+// are done.
+func (s *Scheduler) Reconcile(ctx context.Context, name string, r Reconciler) (Result, error) {
+	var result Result
+
+	desired, err := s.fetch(ctx, r)
+	if err != nil {
+		return result, err
+	}
+	// The explicit operator keeps names that contain a colon literal
+	managed, err := s.store.ListByMetadataCtx(ctx, map[string]string{SourceKey: "eq:" + name})
+	if err != nil {
+		return result, err
+	}
+	current := make(map[string]*registry.Item, len(managed))
+	for _, entry := range managed {
+		if item, ok := entry.(*registry.Item); ok {
+			current[item.ID] = item
+		}
+	}
+
+	actor := "reconcile:" + name
+	var failed []string
+	seen := make(map[string]bool, len(desired))
+	for _, item := range desired {
+		if item == nil || item.ID == "" || seen[item.ID] {
+			s.logger.Warn("Ignoring reconciled item without a unique ID", zap.String("source", name))
+			continue
+		}
+		seen[item.ID] = true
+		if err := s.apply(ctx, name, actor, item, current[item.ID], &result); err != nil {
+			s.logger.Warn("Failed to reconcile item", zap.String("source", name), zap.String("id", item.ID), zap.Error(err))
+			failed = append(failed, item.ID)
+		}
+	}
+
+	for id, item := range current {
+		if seen[id] {
+			continue
+		}
+		if err := s.store.DeleteItemCtx(ctx, id); err != nil {
+			s.logger.Warn("Failed to delete reconciled item", zap.String("source", name), zap.String("id", id), zap.Error(err))
+			failed = append(failed, id)
+			continue
+		}
+		result.Deleted++
+		s.announce(notify.EventItemDeleted, audit.ActionDelete, actor, item, item.Version, item.Version)
+	}
+
+	if len(failed) > 0 {
+		return result, fmt.Errorf("failed to reconcile %d item(s): %v", len(failed), failed)
+	}
+	return result, ctx.Err()
+}
+
+// fetch calls r, turning a panic into an error so that one plugin cannot
+// stop the server
+func (s *Scheduler) fetch(ctx context.Context, r Reconciler) (items []*registry.Item, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("reconciler panicked: %v", p)
+		}
+	}()
+	return r.Reconcile(ctx)
+}
+
+// apply stores item for the source name, given the item the source already
+// manages under its ID, if any
+func (s *Scheduler) apply(ctx context.Context, name, actor string, item, existing *registry.Item, result *Result) error {
+	metadata := make(map[string]interface{}, len(item.Metadata)+1)
+	for key, value := range item.Metadata {
+		metadata[key] = value
+	}
+	metadata[SourceKey] = name
+
+	if existing == nil {
+		// The ID may belong to an item the source does not manage yet, or
+		// to one it deleted before
+		if found, err := s.store.GetItemCtx(ctx, item.ID); err == nil {
+			existing = found
+		} else if restored, err := s.store.RestoreItem(item.ID); err == nil {
+			existing = restored
+			result.Restored++
+			s.announce(notify.EventItemRestored, audit.ActionRestore, actor, restored, restored.Version, restored.Version)
+		}
+	}
+
+	if existing == nil {
+		created, err := s.store.CreateItemCtx(ctx, &registry.Item{
+			ID:           item.ID,
+			Type:         item.Type,
+			Name:         item.Name,
+			RegistryName: item.RegistryName,
+			Metadata:     metadata,
+			Tags:         item.Tags,
+			CreatedBy:    actor,
+			UpdatedBy:    actor,
+		})
+		if err != nil {
+			return err
+		}
+		result.Created++
+		s.announce(notify.EventItemCreated, audit.ActionCreate, actor, created, 0, created.Version)
+		return nil
+	}
+
+	if !changed(existing, item.Name, item.RegistryName, metadata, item.Tags) {
+		result.Unchanged++
+		return nil
+	}
+	// Types are fixed once an item is created
+	updated, err := s.store.UpdateItemCtx(ctx, &registry.Item{
+		ID:           existing.ID,
+		Type:         existing.Type,
+		Name:         item.Name,
+		RegistryName: item.RegistryName,
+		Owner:        existing.Owner,
+		CreatedBy:    existing.CreatedBy,
+		UpdatedBy:    actor,
+		Metadata:     metadata,
+		Tags:         item.Tags,
+		Links:        existing.Links,
+		CreatedAt:    existing.CreatedAt,
+		Version:      existing.Version + 1,
+		ExpiresAt:    existing.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	result.Updated++
+	s.announce(notify.EventItemUpdated, audit.ActionUpdate, actor, updated, existing.Version, updated.Version)
+	return nil
+}
+
+// changed reports whether existing differs from the given fields. Metadata
+// is compared in its JSON form, since backends that store JSON return
+// numbers as float64.
+func changed(existing *registry.Item, name, registryName string, metadata map[string]interface{}, tags []string) bool {
+	if existing.Name != name || existing.RegistryName != registryName || len(existing.Tags) != len(tags) {
+		return true
+	}
+	for i, tag := range tags {
+		if existing.Tags[i] != tag {
+			return true
+		}
+	}
+	before, err := json.Marshal(existing.Metadata)
+	if err != nil {
+		return true
+	}
+	after, err := json.Marshal(metadata)
+	return err != nil || string(before) != string(after)
+}
+
+// announce publishes the item event and records the audit entry of a change
+func (s *Scheduler) announce(event, action, actor string, item *registry.Item, before, after int64) {
+	s.notifier.Notify(event, item.ID, item.Type, item.RegistryName)
+	s.auditLog.Record(audit.Entry{
+		Actor:         actor,
+		Action:        action,
+		ItemID:        item.ID,
+		BeforeVersion: before,
+		AfterVersion:  after,
+	})
+}
@@ -2,23 +2,58 @@ package registry
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrRegistryNameRequired is returned when an Item without a RegistryName is stored
+var ErrRegistryNameRequired = errors.New("registry name must be set")
+
+// ErrStaleVersion is returned when an update is dropped because its version
+// is not newer than the stored one
+var ErrStaleVersion = errors.New("stale version: the stored item is as new or newer")
+
+// FieldError describes a single Item field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every Item field that failed validation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return "invalid item: " + strings.Join(messages, "; ")
+}
+
 // Item represents an item in the registry with metadata and timestamps
 type Item struct {
     ID           string                 `json:"id"`
     Type         string                 `json:"type"`
     Name         string                 `json:"name"`
-    RegistryName string                 `json:"registryName"`  // New field
+    RegistryName string                 `json:"registryName"`
+    Owner        string                 `json:"owner"` // team or user that created the item; kept on update
+    CreatedBy    string                 `json:"createdBy"` // actor that created the item
+    UpdatedBy    string                 `json:"updatedBy"` // actor of the latest create or update
     Metadata     map[string]interface{} `json:"metadata"`
+    Tags         []string               `json:"tags"`
+    Links        map[string][]string    `json:"links"` // relation name to target item IDs
     CreatedAt    time.Time              `json:"createdAt"`
     UpdatedAt    time.Time              `json:"updatedAt"`
     Version      int64                  `json:"version"`
+    ExpiresAt    time.Time              `json:"expiresAt,omitempty"` // optional; zero means the item never expires
+    DeletedAt    time.Time              `json:"deletedAt,omitempty"` // when the item was soft-deleted; zero while it is not
     deleted      bool                   // field to track if the item is deleted
     mu           sync.RWMutex           // mutex for thread-safe operations
 }
@@ -33,17 +68,116 @@ func (i *Item) GetType() string {
     return i.Type
 }
 
-// Update updates the item's name, version, and metadata
-func (i *Item) Update(name, itemType string, metadata map[string]interface{}) {
+// GetName returns the name of the item
+func (i *Item) GetName() string {
+    return i.Name
+}
+
+// GetMetadata returns the metadata of the item
+func (i *Item) GetMetadata() map[string]interface{} {
+    return i.Metadata
+}
+
+// MetadataSize approximates the size of the item's metadata by the length of
+// its JSON encoding, which is how most backends store it. Empty metadata, or
+// metadata that cannot be encoded, counts as 0.
+func (i *Item) MetadataSize() int {
+    if len(i.Metadata) == 0 {
+        return 0
+    }
+    data, err := json.Marshal(i.Metadata)
+    if err != nil {
+        return 0
+    }
+    return len(data)
+}
+
+// GetVersion returns the version of the item
+func (i *Item) GetVersion() int64 {
+    return i.Version
+}
+
+// Update updates the item's name, type, registry name, version, metadata, and tags
+func (i *Item) Update(name, itemType, registryName string, metadata map[string]interface{}, tags []string) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 	i.Name = name
 	i.Type = itemType
+	i.RegistryName = registryName
 	i.Metadata = metadata
+	i.Tags = tags
 	i.Version++
 	i.UpdatedAt = time.Now()
 }
 
+// HasTags checks if the item carries every one of the given tags
+func (i *Item) HasTags(tags ...string) bool {
+	for _, want := range tags {
+		found := false
+		for _, tag := range i.Tags {
+			if tag == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that the fields required to create an Item are set,
+// returning a *ValidationError that names each missing field
+func (i *Item) Validate() error {
+	var fields []FieldError
+	if i.Type == "" {
+		fields = append(fields, FieldError{Field: "type", Message: "must not be empty"})
+	}
+	if i.Name == "" {
+		fields = append(fields, FieldError{Field: "name", Message: "must not be empty"})
+	}
+	if i.RegistryName == "" {
+		fields = append(fields, FieldError{Field: "registryName", Message: "must not be empty"})
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// AddLinks records targets under the relation, skipping IDs already linked
+// under it, and reports whether any were added
+func (i *Item) AddLinks(relation string, targets ...string) bool {
+	added := false
+	for _, target := range targets {
+		if containsString(i.Links[relation], target) {
+			continue
+		}
+		if i.Links == nil {
+			i.Links = make(map[string][]string)
+		}
+		i.Links[relation] = append(i.Links[relation], target)
+		added = true
+	}
+	return added
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired checks if the item has an expiry that has passed at the given time
+func (i *Item) IsExpired(now time.Time) bool {
+	return !i.ExpiresAt.IsZero() && !now.Before(i.ExpiresAt)
+}
+
 // IsDeleted checks if the item is marked as deleted
 func (i *Item) IsDeleted() bool {
 	i.mu.RLock()
@@ -51,12 +185,14 @@ func (i *Item) IsDeleted() bool {
 	return i.deleted
 }
 
-// SoftDelete marks the item as deleted
+// SoftDelete marks the item as deleted and records when
 func (i *Item) SoftDelete() {
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	now := time.Now()
 	i.deleted = true
-	i.UpdatedAt = time.Now()
+	i.DeletedAt = now
+	i.UpdatedAt = now
 }
 
 // Restore removes the deleted mark from the item
@@ -64,9 +200,26 @@ func (i *Item) Restore() {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 	i.deleted = false
+	i.DeletedAt = time.Time{}
 	i.UpdatedAt = time.Now()
 }
 
+// Tombstone returns a deleted copy of the item that keeps only the fields
+// identifying it and its last change, so clients can drop their own copy
+func (i *Item) Tombstone() *Item {
+	return &Item{
+		ID:           i.ID,
+		Type:         i.Type,
+		Name:         i.Name,
+		RegistryName: i.RegistryName,
+		Version:      i.Version,
+		CreatedAt:    i.CreatedAt,
+		UpdatedAt:    i.UpdatedAt,
+		DeletedAt:    i.DeletedAt,
+		deleted:      true,
+	}
+}
+
 // ItemStore represents an in-memory store for items
 type ItemStore struct {
 	mu    sync.RWMutex
@@ -80,8 +233,14 @@ func NewItemStore() *ItemStore {
 	}
 }
 
-// UpsertItem inserts or updates an item in the store
+// UpsertItem inserts or updates an item in the store. An update whose
+// version is not newer than the stored one is dropped, and the stored item
+// is returned with ErrStaleVersion.
 func (s *ItemStore) UpsertItem(item *Item) (*Item, error) {
+	if item.RegistryName == "" {
+		return nil, ErrRegistryNameRequired
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -93,9 +252,10 @@ func (s *ItemStore) UpsertItem(item *Item) (*Item, error) {
 		existingItem, exists := s.items[item.ID]
 		if exists {
 			if item.Version <= existingItem.Version {
-				return existingItem, nil // Return existing item if version is not newer
+				return existingItem, ErrStaleVersion
 			}
 			item.CreatedAt = existingItem.CreatedAt
+			item.CreatedBy = existingItem.CreatedBy
 		} else {
 			item.CreatedAt = time.Now()
 		}
@@ -170,20 +330,29 @@ func (i *Item) MarshalJSON() ([]byte, error) {
 		*Alias
 		CreatedAt string `json:"createdAt"`
 		UpdatedAt string `json:"updatedAt"`
+		ExpiresAt string `json:"expiresAt,omitempty"`
+		DeletedAt string `json:"deletedAt,omitempty"`
+		Deleted   bool   `json:"deleted"`
 	}{
 		Alias:     (*Alias)(i),
 		CreatedAt: i.CreatedAt.Format(time.RFC3339),
 		UpdatedAt: i.UpdatedAt.Format(time.RFC3339),
+		ExpiresAt: formatOptionalTimestamp(i.ExpiresAt),
+		DeletedAt: formatOptionalTimestamp(i.DeletedAt),
+		Deleted:   i.IsDeleted(),
 	})
 }
 
-// UnmarshalJSON implements custom JSON unmarshaling for Item
+// UnmarshalJSON implements custom JSON unmarshaling for Item. Like the deleted
+// flag, deletedAt is only set by SoftDelete and is not read back.
 func (i *Item) UnmarshalJSON(data []byte) error {
 	type Alias Item
 	aux := &struct {
 		*Alias
 		CreatedAt string `json:"createdAt"`
 		UpdatedAt string `json:"updatedAt"`
+		ExpiresAt string `json:"expiresAt"`
+		DeletedAt string `json:"deletedAt"`
 	}{
 		Alias: (*Alias)(i),
 	}
@@ -191,13 +360,31 @@ func (i *Item) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	var err error
-	i.CreatedAt, err = time.Parse(time.RFC3339, aux.CreatedAt)
-	if err != nil {
+	if i.CreatedAt, err = parseTimestamp(aux.CreatedAt); err != nil {
+		return err
+	}
+	if i.UpdatedAt, err = parseTimestamp(aux.UpdatedAt); err != nil {
 		return err
 	}
-	i.UpdatedAt, err = time.Parse(time.RFC3339, aux.UpdatedAt)
-	if err != nil {
+	if i.ExpiresAt, err = parseTimestamp(aux.ExpiresAt); err != nil {
 		return err
 	}
 	return nil
 }
+
+// formatOptionalTimestamp formats t as RFC3339, or returns an empty string for the zero time
+func formatOptionalTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// parseTimestamp parses an RFC3339 timestamp, treating an empty string as the
+// zero time so clients can omit server-managed fields on create.
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
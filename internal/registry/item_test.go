@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestItemJSONRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		item *Item
+	}{
+		{
+			name: "registry name",
+			item: &Item{ID: "a", Type: "service", Name: "api", RegistryName: "team-a", CreatedAt: created, UpdatedAt: created, Version: 3},
+		},
+		{
+			name: "expiry",
+			item: &Item{ID: "b", Type: "service", Name: "api", RegistryName: "team-b", CreatedAt: created, UpdatedAt: created,
+				ExpiresAt: created.Add(time.Hour), Version: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.item)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var got Item
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unmarshal %s: %v", data, err)
+			}
+			if got.ID != tt.item.ID || got.RegistryName != tt.item.RegistryName || got.Version != tt.item.Version {
+				t.Errorf("round trip of %s = %+v", data, &got)
+			}
+			if !got.CreatedAt.Equal(tt.item.CreatedAt) || !got.ExpiresAt.Equal(tt.item.ExpiresAt) {
+				t.Errorf("round trip times = %v, %v, want %v, %v", got.CreatedAt, got.ExpiresAt, tt.item.CreatedAt, tt.item.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestRegistryNameRequired(t *testing.T) {
+	item := &Item{Type: "service", Name: "api"}
+
+	var validation *ValidationError
+	if err := item.Validate(); !errors.As(err, &validation) || len(validation.Fields) != 1 || validation.Fields[0].Field != "registryName" {
+		t.Errorf("Validate() = %v, want a registryName field error", err)
+	}
+	if _, err := NewItemStore().UpsertItem(item); !errors.Is(err, ErrRegistryNameRequired) {
+		t.Errorf("UpsertItem() = %v, want %v", err, ErrRegistryNameRequired)
+	}
+}
+
+func TestItemUpdateRegistryName(t *testing.T) {
+	item := &Item{ID: "a", Type: "service", Name: "api", RegistryName: "team-a", Version: 1}
+	item.Update("api", "service", "team-b", nil, nil)
+	if item.RegistryName != "team-b" || item.Version != 2 {
+		t.Errorf("after Update registryName = %q, version = %d, want team-b, 2", item.RegistryName, item.Version)
+	}
+}
+
+func TestUpsertItemStaleVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     int64
+		wantErr     error
+		wantVersion int64
+		wantName    string
+	}{
+		{name: "newer version", version: 3, wantVersion: 3, wantName: "updated"},
+		{name: "same version", version: 2, wantErr: ErrStaleVersion, wantVersion: 2, wantName: "stored"},
+		{name: "older version", version: 1, wantErr: ErrStaleVersion, wantVersion: 2, wantName: "stored"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewItemStore()
+			if _, err := store.UpsertItem(&Item{ID: "a", Type: "service", Name: "stored", RegistryName: "team-a", Version: 2}); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := store.UpsertItem(&Item{ID: "a", Type: "service", Name: "updated", RegistryName: "team-a", Version: tt.version})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpsertItem() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.Version != tt.wantVersion || got.Name != tt.wantName {
+				t.Errorf("UpsertItem() = %s at version %d, want %s at version %d", got.Name, got.Version, tt.wantName, tt.wantVersion)
+			}
+			if stored, _ := store.GetItem("a"); stored.Name != tt.wantName {
+				t.Errorf("stored item = %s, want %s", stored.Name, tt.wantName)
+			}
+		})
+	}
+}
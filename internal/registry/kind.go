@@ -0,0 +1,189 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory returns a new, empty Registerable of a typed kind, ready to be
+// unmarshaled into
+type Factory func() Registerable
+
+var (
+	kindsMu sync.RWMutex
+	kinds   = make(map[string]Factory)
+)
+
+// itemFields are the JSON fields of Item. Any other field of a typed kind is
+// stored in the Item's metadata.
+var itemFields = map[string]bool{
+	"id": true, "type": true, "name": true, "registryName": true,
+	"metadata": true, "tags": true, "links": true, "version": true,
+	"createdAt": true, "updatedAt": true, "expiresAt": true, "deleted": true,
+}
+
+// RegisterKind makes items whose type is itemType decode into the
+// Registerable returned by factory. The kind's JSON fields beyond those of
+// Item are kept in the item's metadata, so every backend can store it. A
+// kind that has a Validate() error method is validated on decode, and should
+// report invalid fields with a *ValidationError.
+// Registering a type again replaces its factory.
+func RegisterKind(itemType string, factory Factory) {
+	kindsMu.Lock()
+	defer kindsMu.Unlock()
+	kinds[itemType] = factory
+}
+
+// Kinds returns the item types with a registered factory, sorted
+func Kinds() []string {
+	kindsMu.RLock()
+	defer kindsMu.RUnlock()
+
+	types := make([]string, 0, len(kinds))
+	for itemType := range kinds {
+		types = append(types, itemType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func kindFactory(itemType string) (Factory, bool) {
+	kindsMu.RLock()
+	defer kindsMu.RUnlock()
+	factory, ok := kinds[itemType]
+	return factory, ok
+}
+
+// DecodeItem unmarshals a JSON item. When its type has a registered kind,
+// the JSON is first decoded into and validated by that kind, and the kind's
+// own fields are moved into the metadata of the returned Item.
+func DecodeItem(data []byte) (*Item, error) {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+
+	factory, ok := kindFactory(head.Type)
+	if !ok {
+		var item Item
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, err
+		}
+		return &item, nil
+	}
+
+	kind := factory()
+	if err := json.Unmarshal(data, kind); err != nil {
+		return nil, fmt.Errorf("invalid %s item: %w", head.Type, err)
+	}
+	if v, ok := kind.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return itemFromKind(data, kind)
+}
+
+// ToItem converts r to an Item that any backend can store. Items are
+// returned as they are; other Registerables keep the JSON fields that Item
+// lacks in its metadata.
+func ToItem(r Registerable) (*Item, error) {
+	if item, ok := r.(*Item); ok {
+		return item, nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return itemFromKind(data, r)
+}
+
+// itemFromKind unmarshals data into an Item and adds the fields of kind
+// that Item lacks to its metadata
+func itemFromKind(data []byte, kind Registerable) (*Item, error) {
+	var item Item
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+
+	fields, err := kindFields(kind)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		if itemFields[key] {
+			continue
+		}
+		if item.Metadata == nil {
+			item.Metadata = make(map[string]interface{})
+		}
+		item.Metadata[key] = value
+	}
+	return &item, nil
+}
+
+// EncodeItem returns the JSON form of item. When its type has a registered
+// kind, the kind's fields are lifted out of the metadata to the top level,
+// as the item was created.
+func EncodeItem(item *Item) (interface{}, error) {
+	factory, ok := kindFactory(item.Type)
+	if !ok {
+		return item, nil
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	// Decode the kind from the metadata, with the item fields on top
+	flat := make(map[string]interface{}, len(doc)+len(item.Metadata))
+	for key, value := range item.Metadata {
+		flat[key] = value
+	}
+	for key, value := range doc {
+		flat[key] = value
+	}
+	if data, err = json.Marshal(flat); err != nil {
+		return nil, err
+	}
+	kind := factory()
+	if err := json.Unmarshal(data, kind); err != nil {
+		return nil, fmt.Errorf("stored %s item does not match its kind: %w", item.Type, err)
+	}
+
+	fields, err := kindFields(kind)
+	if err != nil {
+		return nil, err
+	}
+	metadata, _ := doc["metadata"].(map[string]interface{})
+	for key, value := range fields {
+		if itemFields[key] {
+			continue
+		}
+		doc[key] = value
+		delete(metadata, key)
+	}
+	return doc, nil
+}
+
+// kindFields returns the JSON fields of kind
+func kindFields(kind Registerable) (map[string]interface{}, error) {
+	data, err := json.Marshal(kind)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -1,10 +1,17 @@
 package registry
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
 	"sync"
+
+	"github.com/google/uuid"
 )
 
 // Registerable is an interface for any item that can be registered
@@ -15,11 +22,68 @@ type Registerable interface {
 
 // Registry interface defines methods for managing registerable items
 type Registry interface {
+	// Register stores item. Implementations differ for an ID that is already
+	// taken: CentralRegistry rejects it with ErrAlreadyRegistered, while the
+	// storage backends update the stored item. Use Upsert to replace items
+	// whatever the implementation.
 	Register(item Registerable) error
 	Get(id string) (Registerable, bool)
+	// Unregister removes the item from view. Items implementing SoftDeletable
+	// are only marked as deleted, as the storage backends do.
 	Unregister(id string) error
 	List() []Registerable
 	ListByType(itemType string) []Registerable
+	// Close releases the resources held by the registry, such as database
+	// handles. It is called once, after the servers have shut down.
+	Close() error
+}
+
+// SoftDeletable is implemented by items that carry their own deleted state,
+// such as *Item, so registries can keep them after they are unregistered
+type SoftDeletable interface {
+	Registerable
+	SoftDelete()
+	Restore()
+	IsDeleted() bool
+}
+
+var _ SoftDeletable = (*Item)(nil)
+
+// MetadataProvider is implemented by items that expose their name, metadata
+// and version, so generic code can sort and filter them without asserting
+// to *Item
+type MetadataProvider interface {
+	Registerable
+	GetName() string
+	GetMetadata() map[string]interface{}
+	GetVersion() int64
+}
+
+var _ MetadataProvider = (*Item)(nil)
+
+// isDeleted reports whether item is marked as deleted
+func isDeleted(item Registerable) bool {
+	d, ok := item.(SoftDeletable)
+	return ok && d.IsDeleted()
+}
+
+// ErrAlreadyRegistered is returned by CentralRegistry.Register for an ID that
+// is already taken
+var ErrAlreadyRegistered = errors.New("item already registered")
+
+// Upserter is implemented by registries whose Register rejects IDs that are
+// already taken, to replace the registered item instead
+type Upserter interface {
+	Upsert(item Registerable) error
+}
+
+// Upsert stores item in reg, replacing any item with the same ID, so that
+// re-registering behaves the same with every Registry
+func Upsert(reg Registry, item Registerable) error {
+	if u, ok := reg.(Upserter); ok {
+		return u.Upsert(item)
+	}
+	return reg.Register(item)
 }
 
 // CentralRegistry provides a thread-safe implementation of the Registry interface
@@ -34,55 +98,111 @@ func NewCentralRegistry() *CentralRegistry {
 	}
 }
 
+// Register adds item, failing with ErrAlreadyRegistered when its ID is taken
+// by an item that is not deleted
 func (r *CentralRegistry) Register(item Registerable) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, exists := r.items[item.GetID()]; exists {
-		return fmt.Errorf("item already registered: %s", item.GetID())
+	if existing, exists := r.items[item.GetID()]; exists && !isDeleted(existing) {
+		return fmt.Errorf("%w: %s", ErrAlreadyRegistered, item.GetID())
 	}
 	r.items[item.GetID()] = item
 	return nil
 }
 
+// Upsert registers item, replacing the item registered under the same ID if
+// there is one
+func (r *CentralRegistry) Upsert(item Registerable) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[item.GetID()] = item
+	return nil
+}
+
+// Get returns the item registered under id unless it is deleted
 func (r *CentralRegistry) Get(id string) (Registerable, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	item, exists := r.items[id]
-	return item, exists
+	if !exists || isDeleted(item) {
+		return nil, false
+	}
+	return item, true
 }
 
+// Unregister soft-deletes the item registered under id when it implements
+// SoftDeletable and removes it otherwise
 func (r *CentralRegistry) Unregister(id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, exists := r.items[id]; !exists {
+	item, exists := r.items[id]
+	if !exists {
 		return fmt.Errorf("item not found: %s", id)
 	}
+	if d, ok := item.(SoftDeletable); ok {
+		d.SoftDelete()
+		return nil
+	}
 	delete(r.items, id)
 	return nil
 }
 
+// Restore clears the deleted mark of the item registered under id
+func (r *CentralRegistry) Restore(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, exists := r.items[id]
+	if !exists {
+		return fmt.Errorf("item not found: %s", id)
+	}
+	if d, ok := item.(SoftDeletable); ok && d.IsDeleted() {
+		d.Restore()
+	}
+	return nil
+}
+
+// List returns every item that is not deleted
 func (r *CentralRegistry) List() []Registerable {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	var items []Registerable
+	for _, item := range r.items {
+		if !isDeleted(item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ListIncludingDeleted returns every item regardless of its deleted mark
+func (r *CentralRegistry) ListIncludingDeleted() []Registerable {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make([]Registerable, 0, len(r.items))
 	for _, item := range r.items {
 		items = append(items, item)
 	}
 	return items
 }
 
+// ListByType returns every item of itemType that is not deleted
 func (r *CentralRegistry) ListByType(itemType string) []Registerable {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	var items []Registerable
 	for _, item := range r.items {
-		if item.GetType() == itemType {
+		if item.GetType() == itemType && !isDeleted(item) {
 			items = append(items, item)
 		}
 	}
 	return items
 }
 
+// Close is a no-op, since CentralRegistry holds nothing but its map
+func (r *CentralRegistry) Close() error {
+	return nil
+}
+
 // RegistryServer provides HTTP handlers for interacting with the registry
 type RegistryServer struct {
 	registry Registry
@@ -92,17 +212,134 @@ func NewRegistryServer(registry Registry) *RegistryServer {
 	return &RegistryServer{registry: registry}
 }
 
+// HandleRegister decodes an Item from the request body, registers it and
+// responds 201 with the stored item. Items without an ID are assigned one.
 func (s *RegistryServer) HandleRegister(w http.ResponseWriter, r *http.Request) {
-	// Implementation for handling registration via HTTP
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var item Item
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+
+	if err := s.registry.Register(&item); err != nil {
+		status := http.StatusConflict // CentralRegistry rejects IDs that are already registered
+		if errors.Is(err, ErrRegistryNameRequired) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Registries may merge into an existing entry, so respond with what was stored
+	var stored Registerable = &item
+	if current, ok := s.registry.Get(item.ID); ok {
+		stored = current
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(stored)
 }
 
+// HandleGet responds with the item named by the id query parameter, or 404
 func (s *RegistryServer) HandleGet(w http.ResponseWriter, r *http.Request) {
-	// Implementation for handling get requests via HTTP
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id query parameter", http.StatusBadRequest)
+		return
+	}
+
+	item, ok := s.registry.Get(id)
+	if !ok {
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
 }
 
+// HandleList responds with the registered items ordered by ID. Without query
+// parameters that is a JSON array of every item. A limit or cursor parameter
+// switches to pages of the form {"items": [...], "nextCursor": "..."}, where
+// nextCursor is passed back as cursor for the following page and is omitted
+// on the last one.
 func (s *RegistryServer) HandleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	after, err := decodeListCursor(query.Get("cursor"))
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
 	items := s.registry.List()
-	json.NewEncoder(w).Encode(items)
+	sort.Slice(items, func(i, j int) bool { return items[i].GetID() < items[j].GetID() })
+	start := sort.Search(len(items), func(i int) bool { return items[i].GetID() > after })
+	items = items[start:]
+
+	nextCursor := ""
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+		nextCursor = base64.RawURLEncoding.EncodeToString([]byte(items[limit-1].GetID()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	paged := query.Has("limit") || query.Has("cursor")
+	if paged {
+		io.WriteString(w, `{"items":`)
+	}
+	// Items are encoded one at a time so the response is never buffered whole
+	enc := json.NewEncoder(w)
+	io.WriteString(w, "[")
+	for i, item := range items {
+		if i > 0 {
+			io.WriteString(w, ",")
+		}
+		if err := enc.Encode(item); err != nil {
+			return
+		}
+	}
+	io.WriteString(w, "]")
+	if paged {
+		if nextCursor != "" {
+			io.WriteString(w, `,"nextCursor":`)
+			enc.Encode(nextCursor)
+		}
+		io.WriteString(w, "}")
+	}
+	io.WriteString(w, "\n")
+}
+
+// decodeListCursor returns the ID a HandleList cursor continues after. The
+// empty cursor starts at the first item.
+func decodeListCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	id, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(id) == 0 {
+		return "", errors.New("invalid cursor")
+	}
+	return string(id), nil
 }
 
 // SetupRoutes configures the HTTP routes for the registry server
@@ -120,7 +357,7 @@ func (s *RegistryServer) SetupRoutes() *http.ServeMux {
 // 	server := NewRegistryServer(registry)
 
 //     // Initialize BuiltinLoader and load built-in plugins
-//     builtinLoader := builtins.NewBuiltinLoader(reg, "pkg/plugins/")
+//     builtinLoader := builtins.NewBuiltinLoader(reg, "pkg/plugins/", logger)
 //     if err := builtinLoader.LoadAll(); err != nil {
 //         fmt.Printf("Error loading built-ins: %v\n", err)
 //     }
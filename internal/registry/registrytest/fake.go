@@ -0,0 +1,185 @@
+// Package registrytest provides an in-memory registry.Registry for tests of
+// code that uses one, such as plugins, with error injection and a record of
+// the calls made.
+package registrytest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// Call records one method call made on a FakeRegistry. Arg is the item ID
+// for Register, Get and Unregister, the type for ListByType, and empty for
+// the other methods. Err is the error the call returned.
+type Call struct {
+	Method string
+	Arg    string
+	Err    error
+}
+
+// FakeRegistry is a registry.Registry that keeps items in a map. Register
+// replaces any item with the same ID, as the storage backends do, and
+// Unregister removes items outright. Methods can be made to fail once with
+// the FailNext functions. It is safe for concurrent use.
+type FakeRegistry struct {
+	mu       sync.Mutex
+	items    map[string]registry.Registerable
+	failNext map[string]error
+	calls    []Call
+	closed   bool
+}
+
+var _ registry.Registry = (*FakeRegistry)(nil)
+
+// NewFakeRegistry creates a FakeRegistry holding items. Seeding it is not
+// recorded as calls.
+func NewFakeRegistry(items ...registry.Registerable) *FakeRegistry {
+	f := &FakeRegistry{
+		items:    make(map[string]registry.Registerable),
+		failNext: make(map[string]error),
+	}
+	for _, item := range items {
+		f.items[item.GetID()] = item
+	}
+	return f
+}
+
+// FailNext makes the next call of method, such as "Register", return err
+// instead of doing anything. Get, List and ListByType cannot fail, so err
+// makes Get report the item as missing and the listings return nothing.
+func (f *FakeRegistry) FailNext(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failNext[method] = err
+}
+
+// FailNextRegister makes the next Register call return err
+func (f *FakeRegistry) FailNextRegister(err error) {
+	f.FailNext("Register", err)
+}
+
+// FailNextUnregister makes the next Unregister call return err
+func (f *FakeRegistry) FailNextUnregister(err error) {
+	f.FailNext("Unregister", err)
+}
+
+// Calls returns the calls made so far, oldest first
+func (f *FakeRegistry) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the calls made so far to method, oldest first
+func (f *FakeRegistry) CallsTo(method string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []Call
+	for _, call := range f.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Closed reports whether Close has succeeded
+func (f *FakeRegistry) Closed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// begin records a call of method with arg and returns the error injected
+// for it, if any; the caller must hold the lock
+func (f *FakeRegistry) begin(method, arg string) error {
+	err := f.failNext[method]
+	delete(f.failNext, method)
+	f.calls = append(f.calls, Call{Method: method, Arg: arg, Err: err})
+	return err
+}
+
+// Register stores item, replacing any item with the same ID
+func (f *FakeRegistry) Register(item registry.Registerable) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Register", item.GetID()); err != nil {
+		return err
+	}
+	f.items[item.GetID()] = item
+	return nil
+}
+
+// Get returns the item stored under id
+func (f *FakeRegistry) Get(id string) (registry.Registerable, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Get", id); err != nil {
+		return nil, false
+	}
+	item, ok := f.items[id]
+	return item, ok
+}
+
+// Unregister removes the item stored under id
+func (f *FakeRegistry) Unregister(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Unregister", id); err != nil {
+		return err
+	}
+	if _, ok := f.items[id]; !ok {
+		err := fmt.Errorf("item not found: %s", id)
+		f.calls[len(f.calls)-1].Err = err
+		return err
+	}
+	delete(f.items, id)
+	return nil
+}
+
+// List returns every stored item ordered by ID
+func (f *FakeRegistry) List() []registry.Registerable {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("List", ""); err != nil {
+		return nil
+	}
+	return f.sorted("")
+}
+
+// ListByType returns the stored items of itemType ordered by ID
+func (f *FakeRegistry) ListByType(itemType string) []registry.Registerable {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("ListByType", itemType); err != nil {
+		return nil
+	}
+	return f.sorted(itemType)
+}
+
+// Close marks the registry as closed
+func (f *FakeRegistry) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Close", ""); err != nil {
+		return err
+	}
+	f.closed = true
+	return nil
+}
+
+// sorted returns the stored items of itemType, or all of them when it is
+// empty, ordered by ID; the caller must hold the lock
+func (f *FakeRegistry) sorted(itemType string) []registry.Registerable {
+	var items []registry.Registerable
+	for _, item := range f.items {
+		if itemType == "" || item.GetType() == itemType {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].GetID() < items[j].GetID() })
+	return items
+}
@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"reflect"
+	"time"
+)
+
+// Revision actions recorded in an item's history
+const (
+	RevisionCreated  = "created"
+	RevisionUpdated  = "updated"
+	RevisionDeleted  = "deleted"
+	RevisionRestored = "restored"
+)
+
+// ItemRevision is a snapshot of an Item taken when it was mutated
+type ItemRevision struct {
+	Version      int64                  `json:"version"`
+	Action       string                 `json:"action"`
+	Name         string                 `json:"name"`
+	Type         string                 `json:"type"`
+	RegistryName string                 `json:"registryName"`
+	Owner        string                 `json:"owner,omitempty"`
+	CreatedBy    string                 `json:"createdBy,omitempty"`
+	UpdatedBy    string                 `json:"updatedBy,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata"`
+	Tags         []string               `json:"tags"`
+	Links        map[string][]string    `json:"links,omitempty"`
+	Timestamp    time.Time              `json:"timestamp"`
+}
+
+// NewItemRevision snapshots the current state of item for the given action
+func NewItemRevision(item *Item, action string) ItemRevision {
+	metadata := make(map[string]interface{}, len(item.Metadata))
+	for k, v := range item.Metadata {
+		metadata[k] = v
+	}
+
+	var links map[string][]string
+	if len(item.Links) > 0 {
+		links = make(map[string][]string, len(item.Links))
+		for relation, targets := range item.Links {
+			links[relation] = append([]string(nil), targets...)
+		}
+	}
+
+	return ItemRevision{
+		Version:      item.Version,
+		Action:       action,
+		Name:         item.Name,
+		Type:         item.Type,
+		RegistryName: item.RegistryName,
+		Owner:        item.Owner,
+		CreatedBy:    item.CreatedBy,
+		UpdatedBy:    item.UpdatedBy,
+		Metadata:     metadata,
+		Tags:         append([]string(nil), item.Tags...),
+		Links:        links,
+		Timestamp:    time.Now(),
+	}
+}
+
+// FieldChange is a value that differs between two revisions
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// MetadataDiff lists the metadata keys that differ between two revisions
+type MetadataDiff struct {
+	Added   map[string]interface{} `json:"added"`
+	Removed map[string]interface{} `json:"removed"`
+	Changed map[string]FieldChange `json:"changed"`
+}
+
+// RevisionDiff describes what changed from one revision of an Item to
+// another. Name and Type are nil when they are unchanged.
+type RevisionDiff struct {
+	ID       string       `json:"id"`
+	From     int64        `json:"from"`
+	To       int64        `json:"to"`
+	Name     *FieldChange `json:"name,omitempty"`
+	Type     *FieldChange `json:"type,omitempty"`
+	Metadata MetadataDiff `json:"metadata"`
+}
+
+// DiffRevisions compares the name, type and metadata of two revisions of the
+// item with the given ID
+func DiffRevisions(id string, from, to *ItemRevision) RevisionDiff {
+	diff := RevisionDiff{
+		ID:   id,
+		From: from.Version,
+		To:   to.Version,
+		Metadata: MetadataDiff{
+			Added:   make(map[string]interface{}),
+			Removed: make(map[string]interface{}),
+			Changed: make(map[string]FieldChange),
+		},
+	}
+	if from.Name != to.Name {
+		diff.Name = &FieldChange{From: from.Name, To: to.Name}
+	}
+	if from.Type != to.Type {
+		diff.Type = &FieldChange{From: from.Type, To: to.Type}
+	}
+
+	for key, old := range from.Metadata {
+		current, ok := to.Metadata[key]
+		if !ok {
+			diff.Metadata.Removed[key] = old
+		} else if !reflect.DeepEqual(old, current) {
+			diff.Metadata.Changed[key] = FieldChange{From: old, To: current}
+		}
+	}
+	for key, current := range to.Metadata {
+		if _, ok := from.Metadata[key]; !ok {
+			diff.Metadata.Added[key] = current
+		}
+	}
+	return diff
+}
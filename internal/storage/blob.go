@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBlobNotFound is returned when an Item has no attachment
+var ErrBlobNotFound = errors.New("blob not found")
+
+// Blob is a binary payload attached to an Item
+type Blob struct {
+	ContentType string
+	Data        []byte
+	UpdatedAt   time.Time
+}
+
+// BlobStore is the storage contract for Item attachments, keyed by Item ID
+type BlobStore interface {
+	// PutBlob stores data as the attachment of an Item, replacing any
+	// previous one
+	PutBlob(id, contentType string, data []byte) (*Blob, error)
+	// GetBlob returns the attachment of an Item, or ErrBlobNotFound
+	GetBlob(id string) (*Blob, error)
+	// DeleteBlob drops the attachment of an Item, if it has one
+	DeleteBlob(id string) error
+}
+
+// MemoryBlobStore keeps attachments in memory
+type MemoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string]*Blob
+}
+
+var _ BlobStore = (*MemoryBlobStore)(nil)
+
+// NewMemoryBlobStore creates an empty in-memory blob store
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: make(map[string]*Blob)}
+}
+
+// PutBlob stores a copy of data under id
+func (bs *MemoryBlobStore) PutBlob(id, contentType string, data []byte) (*Blob, error) {
+	blob := &Blob{
+		ContentType: contentType,
+		Data:        append([]byte(nil), data...),
+		UpdatedAt:   time.Now(),
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.blobs[id] = blob
+	return blob, nil
+}
+
+// GetBlob returns the blob stored under id. Blobs are never modified in
+// place, so the caller may read it without holding a lock.
+func (bs *MemoryBlobStore) GetBlob(id string) (*Blob, error) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	blob, ok := bs.blobs[id]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return blob, nil
+}
+
+// DeleteBlob drops the blob stored under id
+func (bs *MemoryBlobStore) DeleteBlob(id string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	delete(bs.blobs, id)
+	return nil
+}
@@ -0,0 +1,585 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+	bolt "go.etcd.io/bbolt"
+)
+
+var itemsBucket = []byte("items")
+
+var _ Store = (*BoltStorage)(nil)
+
+// BoltStorage implements persistent storage for Items backed by a BoltDB file
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+// boltRecord is the on-disk representation of an Item. The deleted flag is
+// kept alongside the item because Item.UnmarshalJSON does not read it back.
+type boltRecord struct {
+	Item    *registry.Item `json:"item"`
+	Deleted bool           `json:"deleted"`
+}
+
+// NewBoltStorage opens (or creates) the BoltDB file at path
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(itemsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create items bucket: %w", err)
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+// Close releases the underlying database file
+func (bs *BoltStorage) Close() error {
+	return bs.db.Close()
+}
+
+// Ping checks that the database file is open and readable
+func (bs *BoltStorage) Ping(ctx context.Context) error {
+	return bs.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(itemsBucket) == nil {
+			return errors.New("items bucket is missing")
+		}
+		return nil
+	})
+}
+
+// Register adds or updates an Item in the storage
+func (bs *BoltStorage) Register(item registry.Registerable) error {
+	itemObj, err := registry.ToItem(item)
+	if err != nil {
+		return err
+	}
+
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return registerBoltItem(tx.Bucket(itemsBucket), itemObj)
+	})
+}
+
+// registerBoltItem adds or updates an Item within an open write transaction
+func registerBoltItem(b *bolt.Bucket, itemObj *registry.Item) error {
+	if itemObj.RegistryName == "" {
+		return registry.ErrRegistryNameRequired
+	}
+
+	record := boltRecord{Item: itemObj}
+	if data := b.Get([]byte(itemObj.ID)); data != nil {
+		existing, err := decodeBoltRecord(data)
+		if err != nil {
+			return err
+		}
+		existing.Item.Name = itemObj.Name
+		existing.Item.RegistryName = itemObj.RegistryName
+		existing.Item.Metadata = itemObj.Metadata
+		existing.Item.Tags = itemObj.Tags
+		if itemObj.Links != nil {
+			existing.Item.Links = itemObj.Links
+		}
+		existing.Item.ExpiresAt = itemObj.ExpiresAt
+		existing.Item.UpdatedBy = itemObj.UpdatedBy
+		existing.Item.Version++
+		existing.Item.UpdatedAt = time.Now()
+		record = existing
+	} else {
+		itemObj.Version = 1
+		stampCreated(itemObj)
+	}
+
+	return putBoltRecord(b, record)
+}
+
+// Get retrieves an item from the storage
+func (bs *BoltStorage) Get(id string) (registry.Registerable, bool) {
+	var record boltRecord
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(itemsBucket).Get([]byte(id))
+		if data == nil {
+			return errors.New("item not found")
+		}
+		var err error
+		record, err = decodeBoltRecord(data)
+		return err
+	})
+	if err != nil || record.Deleted || record.Item.IsExpired(time.Now()) {
+		return nil, false
+	}
+	return record.Item, true
+}
+
+// Unregister soft-deletes an Item in the storage
+func (bs *BoltStorage) Unregister(id string) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(itemsBucket)
+
+		data := b.Get([]byte(id))
+		if data == nil {
+			return errors.New("item not found")
+		}
+
+		record, err := decodeBoltRecord(data)
+		if err != nil {
+			return err
+		}
+
+		record.Item.SoftDelete()
+		record.Deleted = true
+		return putBoltRecord(b, record)
+	})
+}
+
+// AddLinks records targets under the relation on a non-deleted Item. The
+// version is bumped only when a new target was added.
+func (bs *BoltStorage) AddLinks(id, relation string, targets []string) (*registry.Item, error) {
+	var item *registry.Item
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(itemsBucket)
+
+		data := b.Get([]byte(id))
+		if data == nil {
+			return errors.New("item not found")
+		}
+		record, err := decodeBoltRecord(data)
+		if err != nil {
+			return err
+		}
+		if record.Deleted {
+			return errors.New("item not found")
+		}
+
+		item = record.Item
+		if !item.AddLinks(relation, targets...) {
+			return nil
+		}
+		item.Version++
+		item.UpdatedAt = time.Now()
+		return putBoltRecord(b, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// PurgeItem permanently removes an Item, deleted or not
+func (bs *BoltStorage) PurgeItem(id string) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(itemsBucket)
+		if b.Get([]byte(id)) == nil {
+			return errors.New("item not found")
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// PurgeDeleted permanently removes every soft-deleted Item and returns how
+// many were removed
+func (bs *BoltStorage) PurgeDeleted() (int, error) {
+	purged := 0
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(itemsBucket)
+
+		// Deleting while iterating a bolt cursor skips keys, so collect first
+		var ids [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			if record, err := decodeBoltRecord(v); err == nil && record.Deleted {
+				ids = append(ids, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := b.Delete(id); err != nil {
+				return err
+			}
+		}
+		purged = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// List returns all non-deleted Items in the storage
+func (bs *BoltStorage) List() []registry.Registerable {
+	return bs.filter(func(item *registry.Item) bool { return true })
+}
+
+// ListByType returns all non-deleted Items of a specific type
+func (bs *BoltStorage) ListByType(itemType string) []registry.Registerable {
+	return bs.filter(func(item *registry.Item) bool {
+		return item.GetType() == itemType
+	})
+}
+
+// ListByRegistryName returns all non-deleted Items of a specific registry name
+func (bs *BoltStorage) ListByRegistryName(registryName string) []registry.Registerable {
+	return bs.filter(func(item *registry.Item) bool {
+		return item.RegistryName == registryName
+	})
+}
+
+// ListByMetadata returns all non-deleted Items whose metadata matches every
+// filter, or none if a filter is malformed
+func (bs *BoltStorage) ListByMetadata(filters map[string]string) []registry.Registerable {
+	predicates, err := ParseMetadataFilters(filters)
+	if err != nil {
+		return nil
+	}
+	return bs.filter(func(item *registry.Item) bool {
+		return matchesMetadata(item, predicates)
+	})
+}
+
+// ListByTag returns all non-deleted Items carrying every one of the given tags
+func (bs *BoltStorage) ListByTag(tags ...string) []registry.Registerable {
+	return bs.filter(func(item *registry.Item) bool {
+		return item.HasTags(tags...)
+	})
+}
+
+// ListTags returns every distinct tag on non-deleted Items with its item count
+func (bs *BoltStorage) ListTags() []TagCount {
+	return countTags(toItems(bs.List()))
+}
+
+// ListRegistryNames returns every registry name in use by non-deleted Items
+// with its item count
+func (bs *BoltStorage) ListRegistryNames() []RegistryCount {
+	return countRegistries(toItems(bs.List()))
+}
+
+// SearchItems performs a case-insensitive substring search across the name,
+// type, and string metadata values of all non-deleted Items
+func (bs *BoltStorage) SearchItems(query string) []SearchResult {
+	return searchItems(toItems(bs.List()), query)
+}
+
+// ListPaginated returns a slice of non-deleted Items with pagination support.
+// Items are taken in creation order so pages are stable between calls.
+//
+// Deprecated: offsets shift as Items are added and removed; use ListPage.
+func (bs *BoltStorage) ListPaginated(limit, offset int) []registry.Registerable {
+	result := bs.List()
+	if offset >= len(result) {
+		return []registry.Registerable{}
+	}
+
+	end := offset + limit
+	if end > len(result) {
+		end = len(result)
+	}
+	return result[offset:end]
+}
+
+// Count returns the number of Items matching filter
+func (bs *BoltStorage) Count(filter CountFilter) int {
+	count := 0
+
+	bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(itemsBucket).ForEach(func(k, v []byte) error {
+			record, err := decodeBoltRecord(v)
+			if err == nil && filter.matches(record.Item) {
+				count++
+			}
+			return nil
+		})
+	})
+
+	return count
+}
+
+// ListIncludingDeleted returns every Item in the storage regardless of its deleted flag
+func (bs *BoltStorage) ListIncludingDeleted() []registry.Registerable {
+	result, _ := bs.filterCtx(context.Background(), true, func(*registry.Item) bool { return true })
+	if result == nil {
+		return []registry.Registerable{}
+	}
+	return result
+}
+
+// filter returns all non-deleted Items accepted by match
+func (bs *BoltStorage) filter(match func(item *registry.Item) bool) []registry.Registerable {
+	result, _ := bs.filterCtx(context.Background(), false, match)
+	return result
+}
+
+// filterCtx returns the Items accepted by match in DefaultSort order,
+// checking ctx periodically during the scan so a cancelled request ends its
+// read transaction
+func (bs *BoltStorage) filterCtx(ctx context.Context, includeDeleted bool, match func(item *registry.Item) bool) ([]registry.Registerable, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []registry.Registerable
+	visited := 0
+
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(itemsBucket).ForEach(func(k, v []byte) error {
+			if visited++; visited%scanCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			record, err := decodeBoltRecord(v)
+			if err != nil || (record.Deleted && !includeDeleted) {
+				return nil
+			}
+			if match(record.Item) {
+				result = append(result, record.Item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	SortItems(result, DefaultSort)
+	return result, nil
+}
+
+// ListCtx returns all non-deleted Items, abandoning the scan if ctx is done
+func (bs *BoltStorage) ListCtx(ctx context.Context) ([]registry.Registerable, error) {
+	return bs.filterCtx(ctx, false, func(*registry.Item) bool { return true })
+}
+
+// ListIncludingDeletedCtx returns every Item regardless of its deleted flag,
+// abandoning the scan if ctx is done
+func (bs *BoltStorage) ListIncludingDeletedCtx(ctx context.Context) ([]registry.Registerable, error) {
+	return bs.filterCtx(ctx, true, func(*registry.Item) bool { return true })
+}
+
+// ListByRegistryNameCtx returns all non-deleted Items of a specific registry
+// name, abandoning the scan if ctx is done
+func (bs *BoltStorage) ListByRegistryNameCtx(ctx context.Context, registryName string) ([]registry.Registerable, error) {
+	return bs.filterCtx(ctx, false, func(item *registry.Item) bool {
+		return item.RegistryName == registryName
+	})
+}
+
+// ListByMetadataCtx returns all non-deleted Items whose metadata matches every
+// filter, abandoning the scan if ctx is done
+func (bs *BoltStorage) ListByMetadataCtx(ctx context.Context, filters map[string]string) ([]registry.Registerable, error) {
+	predicates, err := ParseMetadataFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	return bs.filterCtx(ctx, false, func(item *registry.Item) bool {
+		return matchesMetadata(item, predicates)
+	})
+}
+
+// ListByTagCtx returns all non-deleted Items carrying every one of the given
+// tags, abandoning the scan if ctx is done
+func (bs *BoltStorage) ListByTagCtx(ctx context.Context, tags ...string) ([]registry.Registerable, error) {
+	return bs.filterCtx(ctx, false, func(item *registry.Item) bool {
+		return item.HasTags(tags...)
+	})
+}
+
+// ListUpdatedSinceCtx returns every Item, deleted or not, last updated after
+// since, abandoning the scan if ctx is done
+func (bs *BoltStorage) ListUpdatedSinceCtx(ctx context.Context, since time.Time) ([]registry.Registerable, error) {
+	return bs.filterCtx(ctx, true, func(item *registry.Item) bool {
+		return item.UpdatedAt.After(since)
+	})
+}
+
+// ListPage returns a page of non-deleted Items in creation order
+func (bs *BoltStorage) ListPage(ctx context.Context, limit int, cursor string) (Page, error) {
+	items, err := bs.ListCtx(ctx)
+	if err != nil {
+		return Page{}, err
+	}
+	return pageAfter(toItems(items), limit, cursor)
+}
+
+// SearchItemsCtx performs the same search as SearchItems, abandoning the scan
+// if ctx is done
+func (bs *BoltStorage) SearchItemsCtx(ctx context.Context, query string) ([]SearchResult, error) {
+	items, err := bs.ListCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return searchItems(toItems(items), query), nil
+}
+
+// CreateItem adds an Item to the storage
+func (bs *BoltStorage) CreateItem(item *registry.Item) (*registry.Item, error) {
+	return item, bs.Register(item)
+}
+
+// GetItem retrieves an Item from the storage
+func (bs *BoltStorage) GetItem(id string) (*registry.Item, error) {
+	item, ok := bs.Get(id)
+	if !ok {
+		return nil, errors.New("item not found")
+	}
+	return item.(*registry.Item), nil
+}
+
+// UpdateItem updates an existing Item in the storage
+func (bs *BoltStorage) UpdateItem(item *registry.Item) (*registry.Item, error) {
+	if err := bs.Register(item); err != nil {
+		return nil, err
+	}
+	return bs.getIncludingDeleted(item.ID)
+}
+
+// CreateItems adds a batch of Items to the storage in a single transaction.
+// Items without an ID are assigned one. A failing item does not prevent the
+// remaining items from being stored.
+func (bs *BoltStorage) CreateItems(items []*registry.Item) []BatchResult {
+	results := make([]BatchResult, len(items))
+
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(itemsBucket)
+		for i, item := range items {
+			results[i] = newBatchResult(i, item, func(item *registry.Item) error {
+				return registerBoltItem(b, item)
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		for i := range results {
+			results[i].Success = false
+			results[i].Error = err.Error()
+		}
+	}
+
+	return results
+}
+
+// DeleteItem soft-deletes an Item in the storage
+func (bs *BoltStorage) DeleteItem(id string) error {
+	return bs.Unregister(id)
+}
+
+// RestoreItem clears the deleted flag on an Item in the storage
+func (bs *BoltStorage) RestoreItem(id string) (*registry.Item, error) {
+	var record boltRecord
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(itemsBucket)
+
+		data := b.Get([]byte(id))
+		if data == nil {
+			return errors.New("item not found")
+		}
+
+		var err error
+		record, err = decodeBoltRecord(data)
+		if err != nil {
+			return err
+		}
+		if !record.Deleted {
+			return nil
+		}
+
+		record.Item.Restore()
+		record.Deleted = false
+		return putBoltRecord(b, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record.Item, nil
+}
+
+// CreateItemCtx adds an Item to the storage, recording a span as a child of ctx
+func (bs *BoltStorage) CreateItemCtx(ctx context.Context, item *registry.Item) (created *registry.Item, err error) {
+	err = traceOp(ctx, "BoltStorage.CreateItem", item.ID, func() error {
+		created, err = bs.CreateItem(item)
+		return err
+	})
+	return created, err
+}
+
+// GetItemCtx retrieves an Item from the storage, recording a span as a child of ctx
+func (bs *BoltStorage) GetItemCtx(ctx context.Context, id string) (item *registry.Item, err error) {
+	err = traceOp(ctx, "BoltStorage.GetItem", id, func() error {
+		item, err = bs.GetItem(id)
+		return err
+	})
+	return item, err
+}
+
+// UpdateItemCtx updates an existing Item in the storage, recording a span as a child of ctx
+func (bs *BoltStorage) UpdateItemCtx(ctx context.Context, item *registry.Item) (updated *registry.Item, err error) {
+	err = traceOp(ctx, "BoltStorage.UpdateItem", item.ID, func() error {
+		updated, err = bs.UpdateItem(item)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteItemCtx soft-deletes an Item in the storage, recording a span as a child of ctx
+func (bs *BoltStorage) DeleteItemCtx(ctx context.Context, id string) error {
+	return traceOp(ctx, "BoltStorage.DeleteItem", id, func() error {
+		return bs.DeleteItem(id)
+	})
+}
+
+// getIncludingDeleted reads an Item regardless of its deleted flag
+func (bs *BoltStorage) getIncludingDeleted(id string) (*registry.Item, error) {
+	var record boltRecord
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(itemsBucket).Get([]byte(id))
+		if data == nil {
+			return errors.New("item not found")
+		}
+		var err error
+		record, err = decodeBoltRecord(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record.Item, nil
+}
+
+func putBoltRecord(b *bolt.Bucket, record boltRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode item: %w", err)
+	}
+	return b.Put([]byte(record.Item.ID), data)
+}
+
+func decodeBoltRecord(data []byte) (boltRecord, error) {
+	record := boltRecord{Item: &registry.Item{}}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return boltRecord{}, fmt.Errorf("failed to decode item: %w", err)
+	}
+	if record.Deleted {
+		markDeleted(record.Item)
+	}
+	return record, nil
+}
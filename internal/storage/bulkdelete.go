@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// ErrEmptyDeleteFilter is returned by DeleteByFilter when the filter has no
+// criteria, since it would otherwise delete every Item
+var ErrEmptyDeleteFilter = errors.New("delete filter needs at least one criterion")
+
+// DeleteFilter selects the Items matching every non-empty criterion
+type DeleteFilter struct {
+	IDs          []string `json:"ids"`
+	Type         string   `json:"type"`
+	RegistryName string   `json:"registryName"`
+}
+
+// IsEmpty reports whether the filter has no criteria
+func (f DeleteFilter) IsEmpty() bool {
+	return len(f.IDs) == 0 && f.Type == "" && f.RegistryName == ""
+}
+
+// Matches reports whether item meets every criterion of the filter
+func (f DeleteFilter) Matches(item *registry.Item) bool {
+	return (len(f.IDs) == 0 || containsKey(f.IDs, item.ID)) &&
+		(f.Type == "" || item.Type == f.Type) &&
+		(f.RegistryName == "" || item.RegistryName == f.RegistryName)
+}
+
+// BulkDeleteStore is implemented by stores that can soft-delete every Item
+// matching a filter in one operation
+type BulkDeleteStore interface {
+	DeleteByFilter(filter DeleteFilter) ([]*registry.Item, error)
+}
+
+var _ BulkDeleteStore = (*MemoryStorage)(nil)
+
+// DeleteByFilter soft-deletes every non-deleted Item matching filter under a
+// single lock acquisition and returns them. An empty filter deletes nothing
+// and returns ErrEmptyDeleteFilter.
+func (ms *MemoryStorage) DeleteByFilter(filter DeleteFilter) ([]*registry.Item, error) {
+	if filter.IsEmpty() {
+		return nil, ErrEmptyDeleteFilter
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var candidates []*registry.Item
+	if len(filter.IDs) > 0 {
+		for _, id := range filter.IDs {
+			if item, ok := ms.items[id]; ok {
+				candidates = append(candidates, item)
+			}
+		}
+	} else {
+		for _, item := range ms.items {
+			candidates = append(candidates, item)
+		}
+	}
+
+	deleted := []*registry.Item{}
+	for _, item := range candidates {
+		// Repeated IDs yield the same Item, which is skipped once deleted
+		if item.IsDeleted() || !filter.Matches(item) {
+			continue
+		}
+		ms.softDelete(item)
+		deleted = append(deleted, item)
+	}
+	return deleted, nil
+}
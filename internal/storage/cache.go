@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// Default CachingStorage settings
+const (
+	DefaultCacheSize = 1024
+	DefaultCacheTTL  = 30 * time.Second
+)
+
+// CachingStorage decorates a Store with a bounded LRU cache of single-item
+// reads. Writes made through it invalidate the affected entries, and the
+// TTL bounds how stale an entry can get when other processes write to the
+// same backend. Cached Items are shared between callers, as with the memory
+// backend, so they must not be modified.
+type CachingStorage struct {
+	Store
+
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	// order holds *cacheEntry values, most recently used first
+	order *list.List
+	// generation is bumped by every invalidation so that a read racing with
+	// a write does not cache the Item it read before the write
+	generation uint64
+}
+
+type cacheEntry struct {
+	item    *registry.Item
+	expires time.Time
+}
+
+var _ Store = (*CachingStorage)(nil)
+
+// NewCachingStorage caches up to size Items read from store for ttl each. A
+// size below one uses DefaultCacheSize, and a zero ttl keeps entries until
+// they are evicted or invalidated.
+func NewCachingStorage(store Store, size int, ttl time.Duration) *CachingStorage {
+	if size < 1 {
+		size = DefaultCacheSize
+	}
+	return &CachingStorage{
+		Store:   store,
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Len returns the number of cached Items
+func (cs *CachingStorage) Len() int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.order.Len()
+}
+
+// Get returns a cached Item or reads it from the wrapped Store
+func (cs *CachingStorage) Get(id string) (registry.Registerable, bool) {
+	if item, ok := cs.lookup(id); ok {
+		return item, true
+	}
+
+	generation := cs.currentGeneration()
+	found, ok := cs.Store.Get(id)
+	if item, isItem := found.(*registry.Item); ok && isItem {
+		cs.add(item, generation)
+	}
+	return found, ok
+}
+
+// GetItem returns a cached Item or reads it from the wrapped Store
+func (cs *CachingStorage) GetItem(id string) (*registry.Item, error) {
+	if item, ok := cs.lookup(id); ok {
+		return item, nil
+	}
+
+	generation := cs.currentGeneration()
+	item, err := cs.Store.GetItem(id)
+	if err == nil {
+		cs.add(item, generation)
+	}
+	return item, err
+}
+
+// GetItemCtx returns a cached Item or reads it from the wrapped Store, which
+// records its span only on a cache miss
+func (cs *CachingStorage) GetItemCtx(ctx context.Context, id string) (*registry.Item, error) {
+	if item, ok := cs.lookup(id); ok {
+		return item, nil
+	}
+
+	generation := cs.currentGeneration()
+	item, err := cs.Store.GetItemCtx(ctx, id)
+	if err == nil {
+		cs.add(item, generation)
+	}
+	return item, err
+}
+
+// Register stores item and invalidates its cache entry
+func (cs *CachingStorage) Register(item registry.Registerable) error {
+	defer cs.invalidate(item.GetID())
+	return cs.Store.Register(item)
+}
+
+// Unregister soft-deletes the Item and invalidates its cache entry
+func (cs *CachingStorage) Unregister(id string) error {
+	defer cs.invalidate(id)
+	return cs.Store.Unregister(id)
+}
+
+// CreateItem stores item and invalidates its cache entry
+func (cs *CachingStorage) CreateItem(item *registry.Item) (*registry.Item, error) {
+	defer cs.invalidate(item.ID)
+	return cs.Store.CreateItem(item)
+}
+
+// CreateItems stores items and invalidates their cache entries
+func (cs *CachingStorage) CreateItems(items []*registry.Item) []BatchResult {
+	results := cs.Store.CreateItems(items)
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.ID != "" {
+			ids = append(ids, result.ID)
+		}
+	}
+	cs.invalidate(ids...)
+	return results
+}
+
+// UpdateItem updates item and invalidates its cache entry
+func (cs *CachingStorage) UpdateItem(item *registry.Item) (*registry.Item, error) {
+	defer cs.invalidate(item.ID)
+	return cs.Store.UpdateItem(item)
+}
+
+// DeleteItem soft-deletes the Item and invalidates its cache entry
+func (cs *CachingStorage) DeleteItem(id string) error {
+	defer cs.invalidate(id)
+	return cs.Store.DeleteItem(id)
+}
+
+// RestoreItem restores the Item and invalidates its cache entry
+func (cs *CachingStorage) RestoreItem(id string) (*registry.Item, error) {
+	defer cs.invalidate(id)
+	return cs.Store.RestoreItem(id)
+}
+
+// AddLinks links the Item and invalidates its cache entry
+func (cs *CachingStorage) AddLinks(id, relation string, targets []string) (*registry.Item, error) {
+	defer cs.invalidate(id)
+	return cs.Store.AddLinks(id, relation, targets)
+}
+
+// PurgeItem removes the Item and invalidates its cache entry
+func (cs *CachingStorage) PurgeItem(id string) error {
+	defer cs.invalidate(id)
+	return cs.Store.PurgeItem(id)
+}
+
+// PurgeDeleted removes every soft-deleted Item and empties the cache, since
+// the purged IDs are not known
+func (cs *CachingStorage) PurgeDeleted() (int, error) {
+	defer cs.clear()
+	return cs.Store.PurgeDeleted()
+}
+
+// CreateItemCtx stores item and invalidates its cache entry
+func (cs *CachingStorage) CreateItemCtx(ctx context.Context, item *registry.Item) (*registry.Item, error) {
+	defer cs.invalidate(item.ID)
+	return cs.Store.CreateItemCtx(ctx, item)
+}
+
+// UpdateItemCtx updates item and invalidates its cache entry
+func (cs *CachingStorage) UpdateItemCtx(ctx context.Context, item *registry.Item) (*registry.Item, error) {
+	defer cs.invalidate(item.ID)
+	return cs.Store.UpdateItemCtx(ctx, item)
+}
+
+// DeleteItemCtx soft-deletes the Item and invalidates its cache entry
+func (cs *CachingStorage) DeleteItemCtx(ctx context.Context, id string) error {
+	defer cs.invalidate(id)
+	return cs.Store.DeleteItemCtx(ctx, id)
+}
+
+// lookup returns the cached Item with the given ID, dropping it when its TTL
+// or the Item itself has expired
+func (cs *CachingStorage) lookup(id string) (*registry.Item, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	elem, ok := cs.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	now := time.Now()
+	if (!entry.expires.IsZero() && now.After(entry.expires)) || entry.item.IsExpired(now) {
+		cs.remove(elem)
+		return nil, false
+	}
+	cs.order.MoveToFront(elem)
+	return entry.item, true
+}
+
+// add caches item unless an invalidation happened since generation was read,
+// evicting the least recently used Item when the cache is full
+func (cs *CachingStorage) add(item *registry.Item, generation uint64) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if generation != cs.generation {
+		return
+	}
+
+	entry := &cacheEntry{item: item}
+	if cs.ttl > 0 {
+		entry.expires = time.Now().Add(cs.ttl)
+	}
+	if elem, ok := cs.entries[item.ID]; ok {
+		elem.Value = entry
+		cs.order.MoveToFront(elem)
+		return
+	}
+
+	cs.entries[item.ID] = cs.order.PushFront(entry)
+	if cs.order.Len() > cs.size {
+		cs.remove(cs.order.Back())
+	}
+}
+
+// invalidate drops the cache entries of ids
+func (cs *CachingStorage) invalidate(ids ...string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.generation++
+	for _, id := range ids {
+		if elem, ok := cs.entries[id]; ok {
+			cs.remove(elem)
+		}
+	}
+}
+
+// clear drops every cache entry
+func (cs *CachingStorage) clear() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.generation++
+	cs.entries = make(map[string]*list.Element)
+	cs.order.Init()
+}
+
+func (cs *CachingStorage) currentGeneration() uint64 {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.generation
+}
+
+// remove drops elem from the cache; cs.mu must be held
+func (cs *CachingStorage) remove(elem *list.Element) {
+	cs.order.Remove(elem)
+	delete(cs.entries, elem.Value.(*cacheEntry).item.ID)
+}
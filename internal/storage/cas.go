@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// ErrVersionConflict is returned by CompareAndSwap when the stored version
+// is not the expected one
+var ErrVersionConflict = errors.New("version conflict")
+
+// CASStore is implemented by stores that can update an Item atomically on
+// the condition that it is still at a given version
+type CASStore interface {
+	CompareAndSwap(id string, expectedVersion int64, newItem *registry.Item) (*registry.Item, error)
+}
+
+var _ CASStore = (*MemoryStorage)(nil)
+
+// CompareAndSwap replaces the Item with the given ID by newItem only if the
+// stored version equals expectedVersion, returning ErrVersionConflict
+// otherwise. An expectedVersion of zero creates the Item only if the ID is
+// unused, and a soft-deleted Item always conflicts. The check and the write
+// happen under a single lock acquisition.
+func (ms *MemoryStorage) CompareAndSwap(id string, expectedVersion int64, newItem *registry.Item) (*registry.Item, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var version int64
+	existing, exists := ms.items[id]
+	if exists {
+		version = existing.Version
+	}
+	if version != expectedVersion || (exists && existing.IsDeleted()) {
+		return nil, ErrVersionConflict
+	}
+
+	newItem.ID = id
+	if err := ms.register(newItem); err != nil {
+		return nil, err
+	}
+	return ms.items[id], nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		expected    int64
+		deleted     bool
+		wantErr     error
+		wantVersion int64
+	}{
+		{name: "current version", id: "a", expected: 1, wantVersion: 2},
+		{name: "stale version", id: "a", expected: 0, wantErr: ErrVersionConflict, wantVersion: 1},
+		{name: "future version", id: "a", expected: 2, wantErr: ErrVersionConflict, wantVersion: 1},
+		{name: "deleted item", id: "a", expected: 1, deleted: true, wantErr: ErrVersionConflict, wantVersion: 1},
+		{name: "create unused ID", id: "b", expected: 0, wantVersion: 1},
+		{name: "update unused ID", id: "b", expected: 1, wantErr: ErrVersionConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := NewMemoryStorage()
+			defer ms.Close()
+			if _, err := ms.CreateItem(&registry.Item{ID: "a", Type: "service", Name: "a", RegistryName: "team-a"}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.deleted {
+				if err := ms.DeleteItem("a"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := ms.CompareAndSwap(tt.id, tt.expected, &registry.Item{Type: "service", Name: "swapped", RegistryName: "team-a"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompareAndSwap() = %v, want %v", err, tt.wantErr)
+			}
+			ms.mu.RLock()
+			defer ms.mu.RUnlock()
+			var version int64
+			if stored, ok := ms.items[tt.id]; ok {
+				version = stored.Version
+			}
+			if version != tt.wantVersion {
+				t.Errorf("stored version = %d, want %d", version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	ms := NewMemoryStorage()
+	defer ms.Close()
+	if _, err := ms.CreateItem(&registry.Item{ID: "a", Type: "service", Name: "a", RegistryName: "team-a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ms.CompareAndSwap("a", 1, &registry.Item{Type: "service", Name: "a", RegistryName: "team-a"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	swapped := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			swapped++
+		case !errors.Is(err, ErrVersionConflict):
+			t.Errorf("CompareAndSwap() = %v", err)
+		}
+	}
+	if swapped != 1 {
+		t.Errorf("%d callers swapped version 1, want exactly 1", swapped)
+	}
+	if stored, _ := ms.GetItem("a"); stored.Version != 2 {
+		t.Errorf("stored version = %d, want 2", stored.Version)
+	}
+}
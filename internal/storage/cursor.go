@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// ErrInvalidCursor is returned for a pagination cursor that cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page is one page of a cursor-paginated listing. NextCursor is empty on the
+// last page.
+type Page struct {
+	Items      []registry.Registerable `json:"items"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
+
+// cursorKey is the sort key of the last Item on a page. Listings are ordered
+// by CreatedAt and then ID, so the key identifies a fixed position that later
+// inserts and deletes cannot shift. Cursors of other orders also name their
+// Sort and carry the field it orders by.
+type cursorKey struct {
+	CreatedAt time.Time  `json:"c"`
+	ID        string     `json:"i"`
+	Sort      string     `json:"s,omitempty"`
+	Name      string     `json:"n,omitempty"`
+	Type      string     `json:"t,omitempty"`
+	UpdatedAt *time.Time `json:"u,omitempty"`
+}
+
+// cursorSort identifies s in a cursor, as the empty string for DefaultSort
+// so that its cursors keep their original form
+func cursorSort(s Sort) string {
+	if s == DefaultSort {
+		return ""
+	}
+	if s.Desc {
+		return s.Field + ":desc"
+	}
+	return s.Field
+}
+
+// encodeCursor returns the opaque cursor token positioned after item
+func encodeCursor(item *registry.Item) string {
+	return encodeSortedCursor(item, DefaultSort)
+}
+
+// encodeSortedCursor returns the cursor token positioned after item in order s
+func encodeSortedCursor(item *registry.Item, s Sort) string {
+	key := cursorKey{CreatedAt: item.CreatedAt, ID: item.ID, Sort: cursorSort(s)}
+	switch s.Field {
+	case SortName:
+		key.Name = item.Name
+	case SortType:
+		key.Type = item.Type
+	case SortUpdatedAt:
+		key.UpdatedAt = &item.UpdatedAt
+	}
+	data, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor token of the default order. The empty token
+// decodes to a nil key, which starts at the first Item.
+func decodeCursor(token string) (*cursorKey, error) {
+	return decodeSortedCursor(token, DefaultSort)
+}
+
+// decodeSortedCursor parses a cursor token, which must belong to order s
+func decodeSortedCursor(token string, s Sort) (*cursorKey, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var key cursorKey
+	if err := json.Unmarshal(data, &key); err != nil || key.ID == "" || key.Sort != cursorSort(s) {
+		return nil, ErrInvalidCursor
+	}
+	return &key, nil
+}
+
+// position returns an Item at the key's place in its order
+func (k *cursorKey) position() *registry.Item {
+	item := &registry.Item{ID: k.ID, Name: k.Name, Type: k.Type, CreatedAt: k.CreatedAt}
+	if k.UpdatedAt != nil {
+		item.UpdatedAt = *k.UpdatedAt
+	}
+	return item
+}
+
+// after reports whether item sorts after the cursor key
+func (k *cursorKey) after(item *registry.Item) bool {
+	if k == nil {
+		return true
+	}
+	if !item.CreatedAt.Equal(k.CreatedAt) {
+		return item.CreatedAt.After(k.CreatedAt)
+	}
+	return item.ID > k.ID
+}
+
+// stampCreated sets the timestamps of a newly stored Item that arrived
+// without them, so it has a place in creation order
+func stampCreated(item *registry.Item) {
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = time.Now()
+	}
+	if item.UpdatedAt.IsZero() {
+		item.UpdatedAt = item.CreatedAt
+	}
+}
+
+// sortByCreation orders items by CreatedAt and then ID
+func sortByCreation(items []*registry.Item) {
+	sort.Slice(items, func(i, j int) bool { return DefaultSort.less(items[i], items[j]) })
+}
+
+// pageAfter returns up to limit of items following the cursor token in
+// creation order. A limit of zero or less returns every remaining Item.
+func pageAfter(items []*registry.Item, limit int, cursor string) (Page, error) {
+	key, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+
+	sortByCreation(items)
+	start := sort.Search(len(items), func(i int) bool { return key.after(items[i]) })
+	return newPage(items[start:], limit), nil
+}
+
+// SortedPage returns the page of items in order s that follows cursor, which
+// must have been returned for the same order. It sorts items in place.
+func SortedPage(items []*registry.Item, s Sort, limit int, cursor string) (Page, error) {
+	key, err := decodeSortedCursor(cursor, s)
+	if err != nil {
+		return Page{}, err
+	}
+
+	sort.Slice(items, func(i, j int) bool { return s.less(items[i], items[j]) })
+	start := 0
+	if key != nil {
+		position := key.position()
+		start = sort.Search(len(items), func(i int) bool { return s.less(position, items[i]) })
+	}
+	return newSortedPage(items[start:], s, limit), nil
+}
+
+// newPage builds a Page from the Items that follow the cursor, in order,
+// setting NextCursor when more than limit of them remain
+func newPage(rest []*registry.Item, limit int) Page {
+	return newSortedPage(rest, DefaultSort, limit)
+}
+
+func newSortedPage(rest []*registry.Item, s Sort, limit int) Page {
+	page := Page{Items: []registry.Registerable{}}
+	if limit > 0 && len(rest) > limit {
+		rest = rest[:limit]
+		page.NextCursor = encodeSortedCursor(rest[len(rest)-1], s)
+	}
+	for _, item := range rest {
+		page.Items = append(page.Items, item)
+	}
+	return page
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// Operators of a metadata filter, written before the operand as op:operand,
+// as in meta.count=gt:5. A filter value that does not start with one of them
+// is matched for equality as a whole, so eq: is only needed for values that
+// do.
+const (
+	MetadataOpEq       = "eq"
+	MetadataOpNe       = "ne"
+	MetadataOpGt       = "gt"
+	MetadataOpGte      = "gte"
+	MetadataOpLt       = "lt"
+	MetadataOpLte      = "lte"
+	MetadataOpIn       = "in"
+	MetadataOpContains = "contains"
+)
+
+// ErrInvalidMetadataFilter is returned for a malformed metadata filter; the
+// returned error wraps it with the reason
+var ErrInvalidMetadataFilter = errors.New("invalid metadata filter")
+
+// MetadataPredicate is a parsed metadata filter, a test of the value of one
+// top-level metadata key. Every operator requires the key to be present.
+type MetadataPredicate struct {
+	Key string
+	Op  string
+	// Values holds the operand, or each listed value for in
+	Values []string
+	// number is the operand of gt, gte, lt and lte
+	number float64
+}
+
+// ParseMetadataFilter parses the filter expression expr on the metadata key
+func ParseMetadataFilter(key, expr string) (MetadataPredicate, error) {
+	p := MetadataPredicate{Key: key, Op: MetadataOpEq, Values: []string{expr}}
+	op, operand, found := strings.Cut(expr, ":")
+	if !found {
+		return p, nil
+	}
+
+	switch op {
+	case MetadataOpEq, MetadataOpNe:
+		p.Values = []string{operand}
+	case MetadataOpGt, MetadataOpGte, MetadataOpLt, MetadataOpLte:
+		number, ok := parseNumber(operand)
+		if !ok {
+			return p, fmt.Errorf("%w: %s of %s needs a number, not %q", ErrInvalidMetadataFilter, op, key, operand)
+		}
+		p.Values = []string{operand}
+		p.number = number
+	case MetadataOpIn:
+		if operand == "" {
+			return p, fmt.Errorf("%w: in of %s needs at least one value", ErrInvalidMetadataFilter, key)
+		}
+		p.Values = strings.Split(operand, ",")
+	case MetadataOpContains:
+		if operand == "" {
+			return p, fmt.Errorf("%w: contains of %s needs a value", ErrInvalidMetadataFilter, key)
+		}
+		p.Values = []string{operand}
+	default:
+		// Not an operator, so the colon is part of the value, as in a URL
+		return p, nil
+	}
+	p.Op = op
+	return p, nil
+}
+
+// ParseMetadataFilters parses a filter expression per metadata key into
+// predicates ordered by key
+func ParseMetadataFilters(filters map[string]string) ([]MetadataPredicate, error) {
+	predicates := make([]MetadataPredicate, 0, len(filters))
+	for key, expr := range filters {
+		p, err := ParseMetadataFilter(key, expr)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, p)
+	}
+	sort.Slice(predicates, func(i, j int) bool { return predicates[i].Key < predicates[j].Key })
+	return predicates, nil
+}
+
+// Matches reports whether metadata satisfies the predicate. Values are
+// compared in their fmt.Sprint form, except by gt, gte, lt and lte, which
+// only match values that read as JSON numbers, and by contains, which looks
+// for an element of a list and for a substring of anything else.
+func (p MetadataPredicate) Matches(metadata map[string]interface{}) bool {
+	value, ok := metadata[p.Key]
+	if !ok {
+		return false
+	}
+
+	switch p.Op {
+	case MetadataOpNe:
+		return fmt.Sprint(value) != p.Values[0]
+	case MetadataOpGt, MetadataOpGte, MetadataOpLt, MetadataOpLte:
+		number, ok := parseNumber(fmt.Sprint(value))
+		if !ok {
+			return false
+		}
+		switch p.Op {
+		case MetadataOpGt:
+			return number > p.number
+		case MetadataOpGte:
+			return number >= p.number
+		case MetadataOpLt:
+			return number < p.number
+		default:
+			return number <= p.number
+		}
+	case MetadataOpIn:
+		text := fmt.Sprint(value)
+		for _, want := range p.Values {
+			if text == want {
+				return true
+			}
+		}
+		return false
+	case MetadataOpContains:
+		if list, ok := value.([]interface{}); ok {
+			for _, element := range list {
+				if fmt.Sprint(element) == p.Values[0] {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.Contains(fmt.Sprint(value), p.Values[0])
+	}
+	return fmt.Sprint(value) == p.Values[0]
+}
+
+// parseNumber reads s as a JSON number
+func parseNumber(s string) (float64, bool) {
+	if !json.Valid([]byte(s)) {
+		return 0, false
+	}
+	number, err := json.Number(s).Float64()
+	return number, err == nil
+}
+
+// matchesMetadata reports whether the item's metadata satisfies every
+// predicate
+func matchesMetadata(item registry.MetadataProvider, predicates []MetadataPredicate) bool {
+	metadata := item.GetMetadata()
+	for _, p := range predicates {
+		if !p.Matches(metadata) {
+			return false
+		}
+	}
+	return true
+}
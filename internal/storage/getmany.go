@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// GetManyStore is implemented by stores that can look up several Items in
+// one operation
+type GetManyStore interface {
+	GetMany(ids []string) (found []*registry.Item, notFound []string)
+}
+
+var _ GetManyStore = (*MemoryStorage)(nil)
+
+// GetMany returns the non-deleted Items with the given IDs in the order they
+// were asked for, under a single read lock. IDs without such an Item are
+// returned in notFound. Repeated IDs are only looked up once.
+func (ms *MemoryStorage) GetMany(ids []string) ([]*registry.Item, []string) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	now := time.Now()
+	found := []*registry.Item{}
+	notFound := []string{}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		item, ok := ms.items[id]
+		if !ok || item.IsDeleted() || item.IsExpired(now) {
+			notFound = append(notFound, id)
+			continue
+		}
+		found = append(found, item)
+	}
+	return found, notFound
+}
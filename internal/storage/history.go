@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// DefaultHistoryLimit is the number of revisions kept per item unless
+// changed with SetHistoryLimit
+const DefaultHistoryLimit = 100
+
+// ErrRevisionNotFound is returned when a requested item revision is not in history
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// HistoryStore is implemented by stores that record item revisions
+type HistoryStore interface {
+	History(id string) ([]registry.ItemRevision, error)
+	Revision(id string, version int64) (*registry.ItemRevision, error)
+}
+
+var _ HistoryStore = (*MemoryStorage)(nil)
+
+// SetHistoryLimit bounds the number of revisions kept per item. Older
+// revisions are discarded first. A limit below one disables the bound.
+func (ms *MemoryStorage) SetHistoryLimit(limit int) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.historyLimit = limit
+	if limit < 1 {
+		return
+	}
+	for id, revisions := range ms.history {
+		if len(revisions) > limit {
+			ms.history[id] = append([]registry.ItemRevision(nil), revisions[len(revisions)-limit:]...)
+		}
+	}
+}
+
+// History returns the recorded revisions of an Item, oldest first
+func (ms *MemoryStorage) History(id string) ([]registry.ItemRevision, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if _, ok := ms.items[id]; !ok {
+		return nil, errors.New("item not found")
+	}
+
+	revisions := make([]registry.ItemRevision, len(ms.history[id]))
+	copy(revisions, ms.history[id])
+	return revisions, nil
+}
+
+// Revision returns the latest recorded revision of an Item at the given version
+func (ms *MemoryStorage) Revision(id string, version int64) (*registry.ItemRevision, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if _, ok := ms.items[id]; !ok {
+		return nil, errors.New("item not found")
+	}
+
+	revisions := ms.history[id]
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if revisions[i].Version == version {
+			revision := revisions[i]
+			return &revision, nil
+		}
+	}
+	return nil, ErrRevisionNotFound
+}
+
+// recordRevision appends a snapshot of item to its history; the caller must
+// hold the write lock
+func (ms *MemoryStorage) recordRevision(item *registry.Item, action string) {
+	revisions := append(ms.history[item.ID], registry.NewItemRevision(item, action))
+	if ms.historyLimit > 0 && len(revisions) > ms.historyLimit {
+		revisions = revisions[len(revisions)-ms.historyLimit:]
+	}
+	ms.history[item.ID] = revisions
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrKeyScopeRequired is returned when an API key is minted without registries
+var ErrKeyScopeRequired = errors.New("api key must be scoped to at least one registry")
+
+// APIKey describes a minted API key and the registries it may access. The
+// plaintext key is never stored; keys are looked up by their hash.
+type APIKey struct {
+	ID         string    `json:"id"`
+	Registries []string  `json:"registries"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// AllowsRegistry reports whether the key is scoped to registryName
+func (k *APIKey) AllowsRegistry(registryName string) bool {
+	for _, name := range k.Registries {
+		if name == registryName {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyStore is the storage contract for API keys
+type KeyStore interface {
+	// CreateKey mints a key scoped to registries and returns its plaintext,
+	// which is not recoverable afterwards
+	CreateKey(registries []string) (string, *APIKey, error)
+	// LookupKey resolves a plaintext key
+	LookupKey(key string) (*APIKey, bool)
+}
+
+var _ KeyStore = (*MemoryKeyStore)(nil)
+
+// MemoryKeyStore implements in-memory storage for API keys
+type MemoryKeyStore struct {
+	keys map[string]*APIKey
+	mu   sync.RWMutex
+}
+
+// NewMemoryKeyStore creates a new MemoryKeyStore
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		keys: make(map[string]*APIKey),
+	}
+}
+
+// CreateKey mints a random key scoped to registries
+func (ks *MemoryKeyStore) CreateKey(registries []string) (string, *APIKey, error) {
+	if len(registries) == 0 {
+		return "", nil, ErrKeyScopeRequired
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	key := "rk_" + hex.EncodeToString(secret)
+
+	apiKey := &APIKey{
+		ID:         uuid.New().String(),
+		Registries: append([]string(nil), registries...),
+		CreatedAt:  time.Now(),
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.keys[HashAPIKey(key)] = apiKey
+	return key, apiKey, nil
+}
+
+// LookupKey resolves a plaintext key by its hash
+func (ks *MemoryKeyStore) LookupKey(key string) (*APIKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	apiKey, ok := ks.keys[HashAPIKey(key)]
+	return apiKey, ok
+}
+
+// HashAPIKey returns the hex SHA-256 digest under which a key is stored. Keys
+// are long random strings, so a fast unsalted hash is sufficient.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrItemLocked is returned when another holder's lease covers an Item
+	ErrItemLocked = errors.New("item is locked")
+	// ErrLockNotHeld is returned when releasing an Item that has no lease
+	ErrLockNotHeld = errors.New("item is not locked")
+)
+
+// leaseSweepInterval is how often expired leases are dropped
+const leaseSweepInterval = 30 * time.Second
+
+// Lease is an advisory lock on an Item. Writes to the Item must present the
+// token until the lease is released or expires.
+type Lease struct {
+	ItemID    string    `json:"itemId"`
+	Token     string    `json:"token"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// expired reports whether the lease has run out at now
+func (l Lease) expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// LockStore is implemented by stores that keep leases on Items
+type LockStore interface {
+	// AcquireLock leases the Item with the given ID to holder for ttl. A
+	// token matching the current lease renews it; any other token fails
+	// with ErrItemLocked while the lease lasts.
+	AcquireLock(id, holder, token string, ttl time.Duration) (Lease, error)
+	// ReleaseLock ends the lease on an Item, which token must match
+	ReleaseLock(id, token string) error
+	// CheckLock returns ErrItemLocked and the lease when the Item is leased
+	// under a token other than token
+	CheckLock(id, token string) (Lease, error)
+}
+
+var _ LockStore = (*MemoryStorage)(nil)
+
+// AcquireLock leases a non-deleted Item, starting the sweeper that drops
+// expired leases on first use
+func (ms *MemoryStorage) AcquireLock(id, holder, token string, ttl time.Duration) (Lease, error) {
+	if _, ok := ms.Get(id); !ok {
+		return Lease{}, errors.New("item not found")
+	}
+	ms.leaseSweepOnce.Do(func() { go ms.sweepLeases(leaseSweepInterval) })
+
+	ms.leaseMu.Lock()
+	defer ms.leaseMu.Unlock()
+
+	now := time.Now()
+	lease, held := ms.leases[id]
+	if held && !lease.expired(now) {
+		if token != lease.Token {
+			return lease, ErrItemLocked
+		}
+	} else {
+		lease = Lease{ItemID: id, Token: uuid.New().String()}
+	}
+	lease.Holder = holder
+	lease.ExpiresAt = now.Add(ttl)
+	ms.leases[id] = lease
+	return lease, nil
+}
+
+// ReleaseLock ends the lease on an Item
+func (ms *MemoryStorage) ReleaseLock(id, token string) error {
+	ms.leaseMu.Lock()
+	defer ms.leaseMu.Unlock()
+
+	lease, held := ms.leases[id]
+	if !held || lease.expired(time.Now()) {
+		return ErrLockNotHeld
+	}
+	if token != lease.Token {
+		return ErrItemLocked
+	}
+	delete(ms.leases, id)
+	return nil
+}
+
+// CheckLock reports whether token may write to an Item
+func (ms *MemoryStorage) CheckLock(id, token string) (Lease, error) {
+	ms.leaseMu.Lock()
+	defer ms.leaseMu.Unlock()
+
+	lease, held := ms.leases[id]
+	if !held || lease.expired(time.Now()) || token == lease.Token {
+		return Lease{}, nil
+	}
+	return lease, ErrItemLocked
+}
+
+// sweepLeases periodically drops expired leases until Close is called
+func (ms *MemoryStorage) sweepLeases(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ms.stopLeaseSweep:
+			return
+		case now := <-ticker.C:
+			ms.leaseMu.Lock()
+			for id, lease := range ms.leases {
+				if lease.expired(now) {
+					delete(ms.leases, id)
+				}
+			}
+			ms.leaseMu.Unlock()
+		}
+	}
+}
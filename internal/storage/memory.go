@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"context"
 	"errors"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/Cdaprod/registry-service/internal/registry"
 )
@@ -13,37 +16,224 @@ var _ registry.Registry = (*MemoryStorage)(nil)
 type MemoryStorage struct {
 	items map[string]*registry.Item
 	mu    sync.RWMutex
+
+	// Secondary indexes over non-deleted Items, keyed by type and by
+	// registry name and then by ID
+	byType     map[string]map[string]*registry.Item
+	byRegistry map[string]map[string]*registry.Item
+	// deletedByRegistry indexes the soft-deleted Items the same way, so
+	// per-registry stats need not scan every Item
+	deletedByRegistry map[string]map[string]*registry.Item
+
+	history      map[string][]registry.ItemRevision
+	historyLimit int
+
+	// naturalKey lists the fields that are unique among non-deleted Items,
+	// and byNaturalKey maps their joined values to the holding Item's ID
+	naturalKey   []string
+	byNaturalKey map[string]string
+
+	// byMetadata indexes non-deleted Items by the values of the configured
+	// metadata keys: key, then value, then ID
+	byMetadata map[string]map[string]map[string]*registry.Item
+
+	stopSweep chan struct{}
+	closeOnce sync.Once
+
+	// retention is how long soft-deleted Items are kept before the sweeper
+	// purges them, zero keeping them until purged explicitly; nextSweep and
+	// retentionPurged are guarded by mu
+	retention       time.Duration
+	nextSweep       time.Time
+	retentionPurged int64
+	// onSweep is called with the changes of each sweep that changed
+	// anything; it is guarded by mu
+	onSweep func(SweepResult)
+
+	// leases are advisory locks on Items, kept apart from the Items so that
+	// lock checks do not contend with storage operations
+	leases         map[string]Lease
+	leaseMu        sync.Mutex
+	leaseSweepOnce sync.Once
+	stopLeaseSweep chan struct{}
 }
 
 // NewMemoryStorage creates a new MemoryStorage
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		items: make(map[string]*registry.Item),
+		items:             make(map[string]*registry.Item),
+		byType:            make(map[string]map[string]*registry.Item),
+		byRegistry:        make(map[string]map[string]*registry.Item),
+		deletedByRegistry: make(map[string]map[string]*registry.Item),
+		history:           make(map[string][]registry.ItemRevision),
+		historyLimit:      DefaultHistoryLimit,
+		leases:            make(map[string]Lease),
+		stopLeaseSweep:    make(chan struct{}),
+	}
+}
+
+// NewMemoryStorageWithExpiry creates a new MemoryStorage that soft-deletes
+// expired Items every interval until Close is called
+func NewMemoryStorageWithExpiry(interval time.Duration) *MemoryStorage {
+	ms := NewMemoryStorage()
+	ms.startSweep(interval)
+	return ms
+}
+
+// DefaultRetentionSweepInterval is how often a MemoryStorage with a retention
+// sweeps when no expiry sweep interval is configured
+const DefaultRetentionSweepInterval = time.Minute
+
+// NewMemoryStorageWithRetention creates a new MemoryStorage that, every
+// interval until Close is called, soft-deletes expired Items and permanently
+// removes Items soft-deleted more than retention ago, so that a long-running
+// instance does not keep every deleted Item forever
+func NewMemoryStorageWithRetention(retention, interval time.Duration) *MemoryStorage {
+	ms := NewMemoryStorage()
+	ms.retention = retention
+	ms.startSweep(interval)
+	return ms
+}
+
+// Close stops the expiry and lease sweepers, if they are running
+func (ms *MemoryStorage) Close() error {
+	ms.closeOnce.Do(func() {
+		if ms.stopSweep != nil {
+			close(ms.stopSweep)
+		}
+		close(ms.stopLeaseSweep)
+	})
+	return nil
+}
+
+// Ping always succeeds, since in-memory storage has nothing to connect to
+func (ms *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// startSweep starts the sweeper, running every interval
+func (ms *MemoryStorage) startSweep(interval time.Duration) {
+	ms.stopSweep = make(chan struct{})
+	ms.nextSweep = time.Now().Add(interval)
+	go ms.sweep(interval)
+}
+
+// sweep periodically soft-deletes Items whose expiry has passed and, with a
+// retention, purges Items deleted for longer than it
+func (ms *MemoryStorage) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ms.stopSweep:
+			return
+		case now := <-ticker.C:
+			ms.mu.Lock()
+			var result SweepResult
+			for _, item := range ms.items {
+				if !item.IsDeleted() && item.IsExpired(now) {
+					ms.softDelete(item)
+					result.Expired = append(result.Expired, NewSnapshotItem(item).Item())
+				}
+			}
+			if ms.retention > 0 {
+				cutoff := now.Add(-ms.retention)
+				for _, item := range ms.items {
+					if item.IsDeleted() && item.DeletedAt.Before(cutoff) {
+						ms.purge(item)
+						ms.retentionPurged++
+						result.Purged = append(result.Purged, item)
+					}
+				}
+			}
+			ms.nextSweep = now.Add(interval)
+			onSweep := ms.onSweep
+			ms.mu.Unlock()
+
+			if onSweep != nil && (len(result.Expired) > 0 || len(result.Purged) > 0) {
+				onSweep(result)
+			}
+		}
 	}
 }
 
+// SweepResult lists the Items a sweep changed
+type SweepResult struct {
+	// Expired holds copies of the Items soft-deleted because their expiry
+	// passed
+	Expired []*registry.Item
+	// Purged holds the Items removed because they were deleted for longer
+	// than the retention
+	Purged []*registry.Item
+}
+
+// SweepObserver is implemented by stores that change Items on their own,
+// such as a MemoryStorage with an expiry sweeper
+type SweepObserver interface {
+	// OnSweep sets fn to be called after each sweep that changed Items,
+	// outside of any storage lock
+	OnSweep(fn func(SweepResult))
+}
+
+var _ SweepObserver = (*MemoryStorage)(nil)
+
+// OnSweep sets fn to be called with the Items each sweep expired or purged
+func (ms *MemoryStorage) OnSweep(fn func(SweepResult)) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.onSweep = fn
+}
+
 // Register adds or updates an Item in the storage
 // Register adds or updates an Item in the storage
 func (ms *MemoryStorage) Register(item registry.Registerable) error {
+    // Typed kinds are stored as Items, with their own fields in the metadata
+    itemObj, err := registry.ToItem(item)
+    if err != nil {
+        return err
+    }
+
     ms.mu.Lock()
     defer ms.mu.Unlock()
 
-    itemObj, ok := item.(*registry.Item)
-    if !ok {
-        return errors.New("invalid item type")
-    }
+    return ms.register(itemObj)
+}
 
+// register adds or updates an Item; the caller must hold the write lock
+func (ms *MemoryStorage) register(itemObj *registry.Item) error {
     if itemObj.RegistryName == "" {
-        return errors.New("registry name must be set")
+        return registry.ErrRegistryNameRequired
     }
 
     if existing, exists := ms.items[itemObj.ID]; exists {
+        // Updates keep the type, and deleted Items hold no natural key
+        if !existing.IsDeleted() && ms.keyTaken(existing.ID, itemObj.Name, itemObj.RegistryName, existing.Type) {
+            return ErrDuplicateItem
+        }
+        ms.unindex(existing)
         existing.Name = itemObj.Name
+        existing.RegistryName = itemObj.RegistryName
         existing.Metadata = itemObj.Metadata
+        existing.Tags = itemObj.Tags
+        if itemObj.Links != nil {
+            existing.Links = itemObj.Links
+        }
+        existing.ExpiresAt = itemObj.ExpiresAt
+        existing.UpdatedBy = itemObj.UpdatedBy
         existing.Version++
+        existing.UpdatedAt = time.Now()
+        ms.index(existing)
+        ms.recordRevision(existing, registry.RevisionUpdated)
     } else {
+        if ms.keyTaken(itemObj.ID, itemObj.Name, itemObj.RegistryName, itemObj.Type) {
+            return ErrDuplicateItem
+        }
         itemObj.Version = 1
+        stampCreated(itemObj)
         ms.items[itemObj.ID] = itemObj
+        ms.index(itemObj)
+        ms.recordRevision(itemObj, registry.RevisionCreated)
     }
 
     return nil
@@ -55,7 +245,7 @@ func (ms *MemoryStorage) Get(id string) (registry.Registerable, bool) {
 	defer ms.mu.RUnlock()
 
 	item, exists := ms.items[id]
-	if !exists || item.IsDeleted() {
+	if !exists || item.IsDeleted() || item.IsExpired(time.Now()) {
 		return nil, false
 	}
 	return item, true
@@ -71,7 +261,7 @@ func (ms *MemoryStorage) Unregister(id string) error {
 		return errors.New("item not found")
 	}
 
-	item.SoftDelete()
+	ms.softDelete(item)
 	return nil
 }
 
@@ -87,6 +277,21 @@ func (ms *MemoryStorage) List() []registry.Registerable {
 		}
 	}
 
+	SortItems(result, DefaultSort)
+	return result
+}
+
+// ListIncludingDeleted returns every Item in the storage regardless of its deleted flag
+func (ms *MemoryStorage) ListIncludingDeleted() []registry.Registerable {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	result := make([]registry.Registerable, 0, len(ms.items))
+	for _, item := range ms.items {
+		result = append(result, item)
+	}
+
+	SortItems(result, DefaultSort)
 	return result
 }
 
@@ -95,54 +300,158 @@ func (ms *MemoryStorage) ListByType(itemType string) []registry.Registerable {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
+	return indexed(ms.byType[itemType])
+}
+
+// ListByRegistryName returns all non-deleted Items of a specific registry name
+func (ms *MemoryStorage) ListByRegistryName(registryName string) []registry.Registerable {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return indexed(ms.byRegistry[registryName])
+}
+
+// ListByMetadata returns all non-deleted Items whose metadata matches every
+// filter, or none if a filter is malformed
+func (ms *MemoryStorage) ListByMetadata(filters map[string]string) []registry.Registerable {
+	predicates, err := ParseMetadataFilters(filters)
+	if err != nil {
+		return nil
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	candidates, ok := ms.metadataCandidates(predicates)
+	if !ok {
+		candidates = ms.items
+	}
+	return matchingMetadata(candidates, predicates)
+}
+
+// ListByTag returns all non-deleted Items carrying every one of the given tags
+func (ms *MemoryStorage) ListByTag(tags ...string) []registry.Registerable {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
 	var result []registry.Registerable
 	for _, item := range ms.items {
-		if !item.IsDeleted() && item.GetType() == itemType {
+		if !item.IsDeleted() && item.HasTags(tags...) {
 			result = append(result, item)
 		}
 	}
 
+	SortItems(result, DefaultSort)
 	return result
 }
 
-// ListByRegistryName returns all non-deleted Items of a specific registry name
-func (ms *MemoryStorage) ListByRegistryName(registryName string) []registry.Registerable {
-    ms.mu.RLock()
-    defer ms.mu.RUnlock()
+// ListTags returns every distinct tag on non-deleted Items with its item count
+func (ms *MemoryStorage) ListTags() []TagCount {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
 
-    var result []registry.Registerable
-    for _, item := range ms.items {
-        if !item.IsDeleted() && item.RegistryName == registryName {
-            result = append(result, item)
-        }
-    }
+	items := make([]*registry.Item, 0, len(ms.items))
+	for _, item := range ms.items {
+		if !item.IsDeleted() {
+			items = append(items, item)
+		}
+	}
+
+	return countTags(items)
+}
+
+// ListRegistryNames returns every registry name in use by non-deleted Items
+// with its item count, read from the registry name index
+func (ms *MemoryStorage) ListRegistryNames() []RegistryCount {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	result := make([]RegistryCount, 0, len(ms.byRegistry))
+	for name, bucket := range ms.byRegistry {
+		result = append(result, RegistryCount{Name: name, Count: len(bucket)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result
+}
+
+// SearchItems performs a case-insensitive substring search across the name,
+// type, and string metadata values of all non-deleted Items
+func (ms *MemoryStorage) SearchItems(query string) []SearchResult {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	items := make([]*registry.Item, 0, len(ms.items))
+	for _, item := range ms.items {
+		if !item.IsDeleted() {
+			items = append(items, item)
+		}
+	}
 
-    return result
+	return searchItems(items, query)
 }
 
-// ListPaginated returns a slice of non-deleted Items with pagination support
+// ListPaginated returns a slice of non-deleted Items with pagination support.
+// Items are taken in creation order so pages are stable between calls.
+//
+// Deprecated: offsets shift as Items are added and removed; use ListPage.
 func (ms *MemoryStorage) ListPaginated(limit, offset int) []registry.Registerable {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
-	var result []registry.Registerable
+	var items []*registry.Item
 	for _, item := range ms.items {
 		if !item.IsDeleted() {
-			result = append(result, item)
+			items = append(items, item)
 		}
 	}
+	sortByCreation(items)
 
 	// Apply pagination
-	if offset > len(result) {
+	if offset > len(items) {
 		return []registry.Registerable{}
 	}
 
 	end := offset + limit
-	if end > len(result) {
-		end = len(result)
+	if end > len(items) {
+		end = len(items)
+	}
+
+	result := make([]registry.Registerable, 0, end-offset)
+	for _, item := range items[offset:end] {
+		result = append(result, item)
+	}
+	return result
+}
+
+// Count returns the number of Items matching filter without building a list.
+// Non-deleted Items are counted from the type and registry name indexes.
+func (ms *MemoryStorage) Count(filter CountFilter) int {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	candidates := ms.items
+	if !filter.IncludeDeleted {
+		switch {
+		case filter.Type != "" && filter.RegistryName != "":
+			candidates = ms.byType[filter.Type]
+			if len(ms.byRegistry[filter.RegistryName]) < len(candidates) {
+				candidates = ms.byRegistry[filter.RegistryName]
+			}
+		case filter.Type != "":
+			return len(ms.byType[filter.Type])
+		case filter.RegistryName != "":
+			return len(ms.byRegistry[filter.RegistryName])
+		}
 	}
 
-	return result[offset:end]
+	count := 0
+	for _, item := range candidates {
+		if filter.matches(item) {
+			count++
+		}
+	}
+	return count
 }
 
 // Additional methods for compatibility with existing code
@@ -158,6 +467,7 @@ func (ms *MemoryStorage) ListItems() ([]*registry.Item, error) {
 			result = append(result, item)
 		}
 	}
+	sortByCreation(result)
 
 	return result, nil
 }
@@ -182,10 +492,396 @@ func (ms *MemoryStorage) UpdateItem(item *registry.Item) (*registry.Item, error)
 	if err != nil {
 		return nil, err
 	}
-	return item, nil
+
+	// Register updates an existing entry in place, so return the stored item
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.items[item.ID], nil
+}
+
+// CreateItems adds a batch of Items to the storage under a single lock
+// acquisition. Items without an ID are assigned one. A failing item does not
+// prevent the remaining items from being stored.
+func (ms *MemoryStorage) CreateItems(items []*registry.Item) []BatchResult {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		results[i] = newBatchResult(i, item, func(item *registry.Item) error {
+			return ms.register(item)
+		})
+	}
+
+	return results
 }
 
 // DeleteItem soft-deletes an Item in the storage
 func (ms *MemoryStorage) DeleteItem(id string) error {
 	return ms.Unregister(id)
-}
\ No newline at end of file
+}
+
+// CreateItemCtx adds an Item to the storage, tracing lock acquisition and the write
+func (ms *MemoryStorage) CreateItemCtx(ctx context.Context, item *registry.Item) (_ *registry.Item, err error) {
+	ctx, span := startSpan(ctx, "MemoryStorage.CreateItem", item.ID)
+	defer func() { endSpan(span, err) }()
+
+	ms.lockCtx(ctx)
+	defer ms.mu.Unlock()
+
+	_, registerSpan := startSpan(ctx, "MemoryStorage.register", item.ID)
+	err = ms.register(item)
+	endSpan(registerSpan, err)
+	return item, err
+}
+
+// GetItemCtx retrieves an Item from the storage, tracing lock acquisition and lookup
+func (ms *MemoryStorage) GetItemCtx(ctx context.Context, id string) (_ *registry.Item, err error) {
+	ctx, span := startSpan(ctx, "MemoryStorage.GetItem", id)
+	defer func() { endSpan(span, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ms.rlockCtx(ctx)
+	defer ms.mu.RUnlock()
+
+	_, lookupSpan := startSpan(ctx, "MemoryStorage.lookup", id)
+	item, exists := ms.items[id]
+	lookupSpan.End()
+
+	if !exists || item.IsDeleted() || item.IsExpired(time.Now()) {
+		return nil, errors.New("item not found")
+	}
+	return item, nil
+}
+
+// UpdateItemCtx updates an existing Item in the storage, tracing lock acquisition and the write
+func (ms *MemoryStorage) UpdateItemCtx(ctx context.Context, item *registry.Item) (_ *registry.Item, err error) {
+	ctx, span := startSpan(ctx, "MemoryStorage.UpdateItem", item.ID)
+	defer func() { endSpan(span, err) }()
+
+	ms.lockCtx(ctx)
+	defer ms.mu.Unlock()
+
+	_, registerSpan := startSpan(ctx, "MemoryStorage.register", item.ID)
+	err = ms.register(item)
+	endSpan(registerSpan, err)
+	if err != nil {
+		return nil, err
+	}
+	return ms.items[item.ID], nil
+}
+
+// DeleteItemCtx soft-deletes an Item in the storage, tracing lock acquisition and lookup
+func (ms *MemoryStorage) DeleteItemCtx(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "MemoryStorage.DeleteItem", id)
+	defer func() { endSpan(span, err) }()
+
+	ms.lockCtx(ctx)
+	defer ms.mu.Unlock()
+
+	_, lookupSpan := startSpan(ctx, "MemoryStorage.lookup", id)
+	item, ok := ms.items[id]
+	lookupSpan.End()
+
+	if !ok {
+		return errors.New("item not found")
+	}
+
+	ms.softDelete(item)
+	return nil
+}
+
+// ListCtx returns all non-deleted Items, abandoning the scan if ctx is done
+func (ms *MemoryStorage) ListCtx(ctx context.Context) ([]registry.Registerable, error) {
+	return ms.scanCtx(ctx, false, func(*registry.Item) bool { return true })
+}
+
+// ListIncludingDeletedCtx returns every Item regardless of its deleted flag,
+// abandoning the scan if ctx is done
+func (ms *MemoryStorage) ListIncludingDeletedCtx(ctx context.Context) ([]registry.Registerable, error) {
+	return ms.scanCtx(ctx, true, func(*registry.Item) bool { return true })
+}
+
+// ListByRegistryNameCtx returns all non-deleted Items of a specific registry
+// name. The lookup is served from the registry name index, so ctx is only
+// checked before it starts.
+func (ms *MemoryStorage) ListByRegistryNameCtx(ctx context.Context, registryName string) ([]registry.Registerable, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ms.rlockCtx(ctx)
+	defer ms.mu.RUnlock()
+
+	return indexed(ms.byRegistry[registryName]), nil
+}
+
+// ListByMetadataCtx returns all non-deleted Items whose metadata matches every
+// filter, abandoning the scan if ctx is done. An eq or in filter on an
+// indexed key avoids the scan.
+func (ms *MemoryStorage) ListByMetadataCtx(ctx context.Context, filters map[string]string) ([]registry.Registerable, error) {
+	predicates, err := ParseMetadataFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ms.rlockCtx(ctx)
+	if candidates, ok := ms.metadataCandidates(predicates); ok {
+		defer ms.mu.RUnlock()
+		return matchingMetadata(candidates, predicates), nil
+	}
+	ms.mu.RUnlock()
+
+	return ms.scanCtx(ctx, false, func(item *registry.Item) bool {
+		return matchesMetadata(item, predicates)
+	})
+}
+
+// ListByTagCtx returns all non-deleted Items carrying every one of the given
+// tags, abandoning the scan if ctx is done
+func (ms *MemoryStorage) ListByTagCtx(ctx context.Context, tags ...string) ([]registry.Registerable, error) {
+	return ms.scanCtx(ctx, false, func(item *registry.Item) bool {
+		return item.HasTags(tags...)
+	})
+}
+
+// ListUpdatedSinceCtx returns every Item, deleted or not, last updated after
+// since, abandoning the scan if ctx is done
+func (ms *MemoryStorage) ListUpdatedSinceCtx(ctx context.Context, since time.Time) ([]registry.Registerable, error) {
+	return ms.scanCtx(ctx, true, func(item *registry.Item) bool {
+		return item.UpdatedAt.After(since)
+	})
+}
+
+// SearchItemsCtx performs the same search as SearchItems, abandoning the scan
+// if ctx is done
+func (ms *MemoryStorage) SearchItemsCtx(ctx context.Context, query string) ([]SearchResult, error) {
+	items, err := ms.scanCtx(ctx, false, func(*registry.Item) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	return searchItems(toItems(items), query), nil
+}
+
+// ListPage returns a page of non-deleted Items in creation order
+func (ms *MemoryStorage) ListPage(ctx context.Context, limit int, cursor string) (Page, error) {
+	items, err := ms.scanCtx(ctx, false, func(*registry.Item) bool { return true })
+	if err != nil {
+		return Page{}, err
+	}
+	return pageAfter(toItems(items), limit, cursor)
+}
+
+// scanCtx collects the Items accepted by match in DefaultSort order, checking
+// ctx before and periodically during the scan so a cancelled request releases
+// the read lock
+func (ms *MemoryStorage) scanCtx(ctx context.Context, includeDeleted bool, match func(*registry.Item) bool) ([]registry.Registerable, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ms.rlockCtx(ctx)
+	defer ms.mu.RUnlock()
+
+	var result []registry.Registerable
+	visited := 0
+	for _, item := range ms.items {
+		if visited++; visited%scanCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if (includeDeleted || !item.IsDeleted()) && match(item) {
+			result = append(result, item)
+		}
+	}
+
+	SortItems(result, DefaultSort)
+	return result, nil
+}
+
+// index adds an Item to the secondary indexes, or a soft-deleted one to the
+// index of deleted Items; the caller must hold the write lock
+func (ms *MemoryStorage) index(item *registry.Item) {
+	if item.IsDeleted() {
+		addToIndex(ms.deletedByRegistry, item.RegistryName, item)
+		return
+	}
+	// Restored Items move back from the deleted index
+	removeFromIndex(ms.deletedByRegistry, item.RegistryName, item.ID)
+	addToIndex(ms.byType, item.GetType(), item)
+	addToIndex(ms.byRegistry, item.RegistryName, item)
+	if len(ms.naturalKey) > 0 {
+		ms.byNaturalKey[ms.keyOf(item.Name, item.RegistryName, item.Type)] = item.ID
+	}
+	ms.indexMetadata(item)
+}
+
+// softDelete marks item as deleted, moves it to the index of deleted Items
+// and records the revision; the caller must hold the write lock
+func (ms *MemoryStorage) softDelete(item *registry.Item) {
+	item.SoftDelete()
+	ms.unindex(item)
+	ms.index(item)
+	ms.recordRevision(item, registry.RevisionDeleted)
+}
+
+// unindex removes an Item from the secondary indexes; the caller must hold
+// the write lock
+func (ms *MemoryStorage) unindex(item *registry.Item) {
+	removeFromIndex(ms.byType, item.GetType(), item.ID)
+	removeFromIndex(ms.byRegistry, item.RegistryName, item.ID)
+	removeFromIndex(ms.deletedByRegistry, item.RegistryName, item.ID)
+	ms.unindexMetadata(item)
+	if len(ms.naturalKey) > 0 {
+		key := ms.keyOf(item.Name, item.RegistryName, item.Type)
+		if ms.byNaturalKey[key] == item.ID {
+			delete(ms.byNaturalKey, key)
+		}
+	}
+}
+
+func addToIndex(index map[string]map[string]*registry.Item, key string, item *registry.Item) {
+	bucket, ok := index[key]
+	if !ok {
+		bucket = make(map[string]*registry.Item)
+		index[key] = bucket
+	}
+	bucket[item.ID] = item
+}
+
+func removeFromIndex(index map[string]map[string]*registry.Item, key, id string) {
+	bucket, ok := index[key]
+	if !ok {
+		return
+	}
+	delete(bucket, id)
+	if len(bucket) == 0 {
+		delete(index, key)
+	}
+}
+
+// indexed returns the Items in an index bucket in DefaultSort order
+func indexed(bucket map[string]*registry.Item) []registry.Registerable {
+	var result []registry.Registerable
+	for _, item := range bucket {
+		result = append(result, item)
+	}
+	SortItems(result, DefaultSort)
+	return result
+}
+
+// lockCtx acquires the write lock, recording the wait as a child span of ctx
+func (ms *MemoryStorage) lockCtx(ctx context.Context) {
+	_, span := tracer.Start(ctx, "MemoryStorage.lock")
+	ms.mu.Lock()
+	span.End()
+}
+
+// rlockCtx acquires the read lock, recording the wait as a child span of ctx
+func (ms *MemoryStorage) rlockCtx(ctx context.Context) {
+	_, span := tracer.Start(ctx, "MemoryStorage.rlock")
+	ms.mu.RLock()
+	span.End()
+}
+
+// RestoreItem clears the deleted flag on an Item in the storage. Restoring an
+// item that is not deleted is a no-op.
+func (ms *MemoryStorage) RestoreItem(id string) (*registry.Item, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	item, ok := ms.items[id]
+	if !ok {
+		return nil, errors.New("item not found")
+	}
+
+	if item.IsDeleted() {
+		if ms.keyTaken(item.ID, item.Name, item.RegistryName, item.Type) {
+			return nil, ErrDuplicateItem
+		}
+		item.Restore()
+		ms.index(item)
+		ms.recordRevision(item, registry.RevisionRestored)
+	}
+	return item, nil
+}
+
+// AddLinks records targets under the relation on a non-deleted Item. The
+// version is bumped only when a new target was added.
+func (ms *MemoryStorage) AddLinks(id, relation string, targets []string) (*registry.Item, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	item, ok := ms.items[id]
+	if !ok || item.IsDeleted() {
+		return nil, errors.New("item not found")
+	}
+
+	if item.AddLinks(relation, targets...) {
+		item.Version++
+		item.UpdatedAt = time.Now()
+		ms.recordRevision(item, registry.RevisionUpdated)
+	}
+	return item, nil
+}
+
+// PurgeItem permanently removes an Item, deleted or not, along with its
+// history
+func (ms *MemoryStorage) PurgeItem(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	item, ok := ms.items[id]
+	if !ok {
+		return errors.New("item not found")
+	}
+
+	ms.purge(item)
+	return nil
+}
+
+// PurgeDeleted permanently removes every soft-deleted Item along with its
+// history and returns how many were removed
+func (ms *MemoryStorage) PurgeDeleted() (int, error) {
+	purged, err := ms.PurgeDeletedItems()
+	return len(purged), err
+}
+
+// DeletedPurger is implemented by stores that report which Items a purge of
+// the soft-deleted Items removed
+type DeletedPurger interface {
+	PurgeDeletedItems() ([]*registry.Item, error)
+}
+
+var _ DeletedPurger = (*MemoryStorage)(nil)
+
+// PurgeDeletedItems permanently removes every soft-deleted Item along with
+// its history and returns the removed Items
+func (ms *MemoryStorage) PurgeDeletedItems() ([]*registry.Item, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var purged []*registry.Item
+	for _, item := range ms.items {
+		if item.IsDeleted() {
+			ms.purge(item)
+			purged = append(purged, item)
+		}
+	}
+	return purged, nil
+}
+
+// purge drops item from the map, the indexes and the history; the caller must
+// hold the write lock
+func (ms *MemoryStorage) purge(item *registry.Item) {
+	ms.unindex(item)
+	delete(ms.items, item.ID)
+	delete(ms.history, item.ID)
+}
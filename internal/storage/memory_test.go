@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+func TestCreateItemRequiresRegistryName(t *testing.T) {
+	ms := NewMemoryStorage()
+	defer ms.Close()
+
+	if _, err := ms.CreateItem(&registry.Item{ID: "a", Type: "service", Name: "api"}); !errors.Is(err, registry.ErrRegistryNameRequired) {
+		t.Fatalf("CreateItem() = %v, want %v", err, registry.ErrRegistryNameRequired)
+	}
+	if _, err := ms.CreateItem(&registry.Item{ID: "a", Type: "service", Name: "api", RegistryName: "team-a"}); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	if items := ms.ListByRegistryName("team-a"); len(items) != 1 || items[0].GetID() != "a" {
+		t.Errorf("ListByRegistryName(team-a) = %v, want item a", items)
+	}
+}
+
+func TestItemExpiry(t *testing.T) {
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		expiresIn   time.Duration
+		wantFound   bool
+		wantDeleted bool
+	}{
+		{name: "not expired", interval: 10 * time.Millisecond, expiresIn: time.Hour, wantFound: true},
+		{name: "no expiry", interval: 10 * time.Millisecond, wantFound: true},
+		{name: "expired before the sweep", interval: time.Hour, expiresIn: -time.Second},
+		{name: "expired and swept", interval: 10 * time.Millisecond, expiresIn: 20 * time.Millisecond, wantDeleted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := NewMemoryStorageWithExpiry(tt.interval)
+			defer ms.Close()
+
+			item := &registry.Item{ID: "a", Type: "service", Name: "api", RegistryName: "team-a"}
+			if tt.expiresIn != 0 {
+				item.ExpiresAt = time.Now().Add(tt.expiresIn)
+			}
+			if _, err := ms.CreateItem(item); err != nil {
+				t.Fatalf("CreateItem: %v", err)
+			}
+
+			deleted := func() bool {
+				ms.mu.RLock()
+				defer ms.mu.RUnlock()
+				return ms.items["a"].IsDeleted()
+			}
+			deadline := time.Now().Add(time.Second)
+			for tt.wantDeleted && !deleted() && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			if _, err := ms.GetItem("a"); (err == nil) != tt.wantFound {
+				t.Errorf("GetItem() error = %v, want found = %v", err, tt.wantFound)
+			}
+			if got := deleted(); got != tt.wantDeleted {
+				t.Errorf("deleted = %v, want %v", got, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestListIndexesSkipDeletedItems(t *testing.T) {
+	ms := NewMemoryStorage()
+	defer ms.Close()
+	for _, id := range []string{"a", "b"} {
+		if _, err := ms.CreateItem(&registry.Item{ID: id, Type: "service", Name: id, RegistryName: "team-a"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func() error
+		wantIDs int
+	}{
+		{name: "created", change: func() error { return nil }, wantIDs: 2},
+		{name: "deleted", change: func() error { return ms.DeleteItem("a") }, wantIDs: 1},
+		{name: "restored", change: func() error { _, err := ms.RestoreItem("a"); return err }, wantIDs: 2},
+		{name: "purged", change: func() error { return ms.PurgeItem("b") }, wantIDs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(ms.ListByType("service")); got != tt.wantIDs {
+				t.Errorf("ListByType returned %d items, want %d", got, tt.wantIDs)
+			}
+			if got := len(ms.ListByRegistryName("team-a")); got != tt.wantIDs {
+				t.Errorf("ListByRegistryName returned %d items, want %d", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+// newBenchmarkStorage returns a MemoryStorage of n items spread over 50 types
+// and 100 registries
+func newBenchmarkStorage(b *testing.B, n int) *MemoryStorage {
+	b.Helper()
+	ms := NewMemoryStorage()
+	b.Cleanup(func() { ms.Close() })
+	for i := 0; i < n; i++ {
+		item := &registry.Item{
+			ID:           fmt.Sprintf("item-%d", i),
+			Type:         fmt.Sprintf("type-%d", i%50),
+			Name:         fmt.Sprintf("item-%d", i),
+			RegistryName: fmt.Sprintf("registry-%d", i%100),
+		}
+		if _, err := ms.CreateItem(item); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return ms
+}
+
+// scan lists the non-deleted Items matching keep by scanning every Item, as
+// the lookups did before the type and registry indexes
+func (ms *MemoryStorage) scan(keep func(*registry.Item) bool) []registry.Registerable {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var result []registry.Registerable
+	for _, item := range ms.items {
+		if !item.IsDeleted() && keep(item) {
+			result = append(result, item)
+		}
+	}
+	SortItems(result, DefaultSort)
+	return result
+}
+
+func BenchmarkListByType(b *testing.B) {
+	ms := newBenchmarkStorage(b, 50000)
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ms.ListByType("type-7")
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ms.scan(func(item *registry.Item) bool { return item.Type == "type-7" })
+		}
+	})
+}
+
+func BenchmarkListByRegistry(b *testing.B) {
+	ms := newBenchmarkStorage(b, 50000)
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ms.ListByRegistryName("registry-7")
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ms.scan(func(item *registry.Item) bool { return item.RegistryName == "registry-7" })
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// ValidateIndexedMetadataKeys reports empty or repeated metadata keys
+func ValidateIndexedMetadataKeys(keys []string) error {
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key == "" {
+			return errors.New("indexed metadata keys must not be empty")
+		}
+		if seen[key] {
+			return fmt.Errorf("metadata key %q is indexed twice", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// SetIndexedMetadataKeys makes ListByMetadata look Items up by the values of
+// the given top-level metadata keys instead of scanning every Item. No keys
+// disables the index.
+func (ms *MemoryStorage) SetIndexedMetadataKeys(keys []string) error {
+	if err := ValidateIndexedMetadataKeys(keys); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.byMetadata = make(map[string]map[string]map[string]*registry.Item, len(keys))
+	for _, key := range keys {
+		ms.byMetadata[key] = make(map[string]map[string]*registry.Item)
+	}
+	for _, item := range ms.items {
+		if !item.IsDeleted() {
+			ms.indexMetadata(item)
+		}
+	}
+	return nil
+}
+
+// IndexedMetadataKeys returns the indexed metadata keys in sorted order
+func (ms *MemoryStorage) IndexedMetadataKeys() []string {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.indexedMetadataKeys()
+}
+
+// indexedMetadataKeys returns the indexed metadata keys in sorted order; the
+// caller must hold the lock
+func (ms *MemoryStorage) indexedMetadataKeys() []string {
+	keys := make([]string, 0, len(ms.byMetadata))
+	for key := range ms.byMetadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// indexMetadata adds a non-deleted Item to the metadata index under the
+// fmt.Sprint form of each indexed value, which is how matchesMetadata compares
+// them; the caller must hold the write lock
+func (ms *MemoryStorage) indexMetadata(item *registry.Item) {
+	for key, byValue := range ms.byMetadata {
+		if value, ok := item.Metadata[key]; ok {
+			addToIndex(byValue, fmt.Sprint(value), item)
+		}
+	}
+}
+
+// unindexMetadata removes an Item from the metadata index; the caller must
+// hold the write lock
+func (ms *MemoryStorage) unindexMetadata(item *registry.Item) {
+	for key, byValue := range ms.byMetadata {
+		if value, ok := item.Metadata[key]; ok {
+			removeFromIndex(byValue, fmt.Sprint(value), item.ID)
+		}
+	}
+}
+
+// metadataCandidates returns the Items that may match predicates, narrowed by
+// the smallest bucket of an eq or in predicate on an indexed key, and whether
+// the index was used at all; the caller must hold the lock
+func (ms *MemoryStorage) metadataCandidates(predicates []MetadataPredicate) (map[string]*registry.Item, bool) {
+	var smallest map[string]*registry.Item
+	found := false
+	for _, p := range predicates {
+		byValue, ok := ms.byMetadata[p.Key]
+		if !ok || (p.Op != MetadataOpEq && p.Op != MetadataOpIn) {
+			continue
+		}
+		bucket := byValue[p.Values[0]]
+		if len(p.Values) > 1 {
+			// The Items of an in predicate are those of every listed value
+			bucket = make(map[string]*registry.Item)
+			for _, want := range p.Values {
+				for id, item := range byValue[want] {
+					bucket[id] = item
+				}
+			}
+		}
+		if !found || len(bucket) < len(smallest) {
+			smallest = bucket
+			found = true
+		}
+	}
+	return smallest, found
+}
+
+// matchingMetadata returns the non-deleted candidates whose metadata satisfies
+// every predicate in DefaultSort order
+func matchingMetadata(candidates map[string]*registry.Item, predicates []MetadataPredicate) []registry.Registerable {
+	var result []registry.Registerable
+	for _, item := range candidates {
+		if !item.IsDeleted() && matchesMetadata(item, predicates) {
+			result = append(result, item)
+		}
+	}
+	SortItems(result, DefaultSort)
+	return result
+}
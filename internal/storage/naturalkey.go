@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// NaturalKeyFields are the Item fields a natural key can be made of
+var NaturalKeyFields = []string{"registryName", "name", "type"}
+
+// ErrDuplicateItem is returned when storing an Item would give two
+// non-deleted Items the same natural key
+var ErrDuplicateItem = errors.New("an item with the same natural key exists")
+
+// NaturalKeyStore is implemented by stores that can deduplicate Items by a
+// natural key
+type NaturalKeyStore interface {
+	// FindByNaturalKey returns the non-deleted Item whose natural key equals
+	// that of item. It finds nothing while no natural key is configured.
+	FindByNaturalKey(item *registry.Item) (*registry.Item, bool)
+}
+
+var _ NaturalKeyStore = (*MemoryStorage)(nil)
+
+// ValidateNaturalKey reports fields that are not in NaturalKeyFields
+func ValidateNaturalKey(fields []string) error {
+	for _, field := range fields {
+		known := false
+		for _, name := range NaturalKeyFields {
+			known = known || field == name
+		}
+		if !known {
+			return fmt.Errorf("unknown natural key field %q; use %s", field, strings.Join(NaturalKeyFields, ", "))
+		}
+	}
+	return nil
+}
+
+// SetNaturalKey makes the combination of fields unique among non-deleted
+// Items. Storing an Item whose key another Item already has then fails with
+// ErrDuplicateItem. No fields disables the check. Items stored before the
+// call are indexed but not checked against each other.
+func (ms *MemoryStorage) SetNaturalKey(fields []string) error {
+	if err := ValidateNaturalKey(fields); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.naturalKey = fields
+	ms.byNaturalKey = make(map[string]string)
+	for _, item := range ms.items {
+		ms.index(item)
+	}
+	return nil
+}
+
+// FindByNaturalKey returns the non-deleted Item whose natural key equals
+// that of item
+func (ms *MemoryStorage) FindByNaturalKey(item *registry.Item) (*registry.Item, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if len(ms.naturalKey) == 0 {
+		return nil, false
+	}
+	id, ok := ms.byNaturalKey[ms.keyOf(item.Name, item.RegistryName, item.Type)]
+	if !ok {
+		return nil, false
+	}
+	return ms.items[id], true
+}
+
+// keyOf builds the natural key of an Item with the given fields
+func (ms *MemoryStorage) keyOf(name, registryName, itemType string) string {
+	values := make([]string, len(ms.naturalKey))
+	for i, field := range ms.naturalKey {
+		switch field {
+		case "registryName":
+			values[i] = registryName
+		case "name":
+			values[i] = name
+		case "type":
+			values[i] = itemType
+		}
+	}
+	// Names practically never contain NUL, so it keeps the values apart
+	return strings.Join(values, "\x00")
+}
+
+// keyTaken reports whether an Item other than id holds the natural key made
+// of the given fields; the caller must hold the lock
+func (ms *MemoryStorage) keyTaken(id, name, registryName, itemType string) bool {
+	if len(ms.naturalKey) == 0 {
+		return false
+	}
+	holder, ok := ms.byNaturalKey[ms.keyOf(name, registryName, itemType)]
+	return ok && holder != id
+}
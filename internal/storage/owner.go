@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// OwnerStore is implemented by stores that can list the Items of one owner
+// without the caller filtering a full listing
+type OwnerStore interface {
+	ListByOwner(owner string) []registry.Registerable
+}
+
+var _ OwnerStore = (*MemoryStorage)(nil)
+
+// ListByOwner returns all non-deleted Items owned by owner
+func (ms *MemoryStorage) ListByOwner(owner string) []registry.Registerable {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var result []registry.Registerable
+	for _, item := range ms.items {
+		if !item.IsDeleted() && item.Owner == owner {
+			result = append(result, item)
+		}
+	}
+
+	SortItems(result, DefaultSort)
+	return result
+}
+
+// FilterByOwner keeps the Items of items owned by owner, for stores that do
+// not implement OwnerStore
+func FilterByOwner(items []registry.Registerable, owner string) []registry.Registerable {
+	var result []registry.Registerable
+	for _, item := range items {
+		if itemObj, ok := item.(*registry.Item); ok && itemObj.Owner == owner {
+			result = append(result, itemObj)
+		}
+	}
+	return result
+}
@@ -0,0 +1,671 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresMigrations build and evolve the schema. They are applied in order
+// on startup and recorded in schema_migrations, so append new steps rather
+// than editing existing ones.
+var postgresMigrations = []string{
+	`CREATE TABLE items (
+		id            TEXT PRIMARY KEY,
+		type          TEXT NOT NULL,
+		name          TEXT NOT NULL,
+		registry_name TEXT NOT NULL,
+		metadata      JSONB NOT NULL DEFAULT '{}',
+		tags          JSONB NOT NULL DEFAULT '[]',
+		links         JSONB NOT NULL DEFAULT '{}',
+		version       BIGINT NOT NULL,
+		created_at    TIMESTAMPTZ NOT NULL,
+		updated_at    TIMESTAMPTZ NOT NULL,
+		deleted       BOOLEAN NOT NULL DEFAULT FALSE
+	);
+	CREATE INDEX idx_items_type ON items(type, deleted);
+	CREATE INDEX idx_items_registry_name ON items(registry_name, deleted);
+	CREATE INDEX idx_items_created ON items(created_at, id);
+	CREATE INDEX idx_items_metadata ON items USING GIN (metadata jsonb_path_ops);`,
+	`ALTER TABLE items ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_items_owner ON items(owner, deleted);`,
+	`ALTER TABLE items ADD COLUMN created_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE items ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';`,
+}
+
+const postgresColumns = `id, type, name, registry_name, metadata, tags, links, version, created_at, updated_at, deleted, owner, created_by, updated_by`
+
+var _ Store = (*PostgresStorage)(nil)
+
+// PostgresStorage implements persistent storage for Items backed by
+// PostgreSQL, with metadata kept as JSONB so filters run in the database
+type PostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStorage connects a pool to the database at url and applies any
+// pending schema migrations. Pool settings such as pool_max_conns can be
+// given as url parameters.
+func NewPostgresStorage(url string) (*PostgresStorage, error) {
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	if err := migratePostgres(ctx, pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to migrate postgres schema: %w", err)
+	}
+
+	return &PostgresStorage{pool: pool}, nil
+}
+
+// migratePostgres applies the migrations not yet recorded in
+// schema_migrations, each in its own transaction
+func migratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+
+	for i, migration := range postgresMigrations {
+		version := i + 1
+		err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			// The lock serializes instances migrating the same database at startup
+			if _, err := tx.Exec(ctx, `LOCK TABLE schema_migrations IN EXCLUSIVE MODE`); err != nil {
+				return err
+			}
+
+			var applied bool
+			err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied)
+			if err != nil || applied {
+				return err
+			}
+
+			if _, err := tx.Exec(ctx, migration); err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// Close releases every pooled connection
+func (ps *PostgresStorage) Close() error {
+	ps.pool.Close()
+	return nil
+}
+
+// Ping checks that a pooled connection to the database can be used
+func (ps *PostgresStorage) Ping(ctx context.Context) error {
+	return ps.pool.Ping(ctx)
+}
+
+// Register adds or updates an Item in the storage. Updates whose version is
+// not newer than the stored version are dropped with registry.ErrStaleVersion,
+// mirroring ItemStore.UpsertItem.
+func (ps *PostgresStorage) Register(item registry.Registerable) error {
+	itemObj, err := registry.ToItem(item)
+	if err != nil {
+		return err
+	}
+
+	if itemObj.RegistryName == "" {
+		return registry.ErrRegistryNameRequired
+	}
+
+	metadata, err := json.Marshal(itemObj.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	tags, err := json.Marshal(itemObj.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode tags: %w", err)
+	}
+
+	links, err := json.Marshal(itemObj.Links)
+	if err != nil {
+		return fmt.Errorf("failed to encode links: %w", err)
+	}
+
+	now := time.Now()
+	if itemObj.CreatedAt.IsZero() {
+		itemObj.CreatedAt = now
+	}
+	itemObj.UpdatedAt = now
+	if itemObj.Version < 1 {
+		itemObj.Version = 1
+	}
+
+	result, err := ps.pool.Exec(context.Background(), `
+		INSERT INTO items (`+postgresColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, FALSE, $11, $12, $13)
+		ON CONFLICT (id) DO UPDATE SET
+			type = excluded.type,
+			name = excluded.name,
+			registry_name = excluded.registry_name,
+			metadata = excluded.metadata,
+			tags = excluded.tags,
+			links = CASE WHEN jsonb_typeof(excluded.links) = 'null' THEN items.links ELSE excluded.links END,
+			version = excluded.version,
+			updated_at = excluded.updated_at,
+			updated_by = excluded.updated_by
+		WHERE excluded.version > items.version`,
+		itemObj.ID,
+		itemObj.Type,
+		itemObj.Name,
+		itemObj.RegistryName,
+		string(metadata),
+		string(tags),
+		string(links),
+		itemObj.Version,
+		itemObj.CreatedAt,
+		itemObj.UpdatedAt,
+		itemObj.Owner,
+		itemObj.CreatedBy,
+		itemObj.UpdatedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register item: %w", err)
+	}
+	// The conditional upsert touches no row when the update is stale
+	if result.RowsAffected() == 0 {
+		return registry.ErrStaleVersion
+	}
+	return nil
+}
+
+// Get retrieves an item from the storage
+func (ps *PostgresStorage) Get(id string) (registry.Registerable, bool) {
+	row := ps.pool.QueryRow(context.Background(), `SELECT `+postgresColumns+` FROM items WHERE id = $1 AND NOT deleted`, id)
+	item, err := scanPostgresItem(row)
+	if err != nil {
+		return nil, false
+	}
+	return item, true
+}
+
+// Unregister soft-deletes an Item in the storage
+func (ps *PostgresStorage) Unregister(id string) error {
+	tag, err := ps.pool.Exec(context.Background(), `UPDATE items SET deleted = TRUE, updated_at = $1 WHERE id = $2`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("item not found")
+	}
+	return nil
+}
+
+// AddLinks records targets under the relation on a non-deleted Item. The
+// version is bumped only when a new target was added.
+func (ps *PostgresStorage) AddLinks(id, relation string, targets []string) (*registry.Item, error) {
+	item, err := ps.GetItem(id)
+	if err != nil {
+		return nil, err
+	}
+	if !item.AddLinks(relation, targets...) {
+		return item, nil
+	}
+
+	links, err := json.Marshal(item.Links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode links: %w", err)
+	}
+
+	// The version guard rejects the write if the item changed since it was read
+	item.UpdatedAt = time.Now()
+	tag, err := ps.pool.Exec(context.Background(),
+		`UPDATE items SET links = $1, version = version + 1, updated_at = $2 WHERE id = $3 AND version = $4 AND NOT deleted`,
+		string(links), item.UpdatedAt, id, item.Version,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add links: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, errors.New("item changed while adding links")
+	}
+
+	item.Version++
+	return item, nil
+}
+
+// PurgeItem permanently removes an Item, deleted or not
+func (ps *PostgresStorage) PurgeItem(id string) error {
+	tag, err := ps.pool.Exec(context.Background(), `DELETE FROM items WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("item not found")
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes every soft-deleted Item and returns how
+// many were removed
+func (ps *PostgresStorage) PurgeDeleted() (int, error) {
+	tag, err := ps.pool.Exec(context.Background(), `DELETE FROM items WHERE deleted`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted items: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// List returns all non-deleted Items in the storage
+func (ps *PostgresStorage) List() []registry.Registerable {
+	result, _ := ps.ListCtx(context.Background())
+	return result
+}
+
+// ListByType returns all non-deleted Items of a specific type
+func (ps *PostgresStorage) ListByType(itemType string) []registry.Registerable {
+	result, _ := ps.queryCtx(context.Background(), `SELECT `+postgresColumns+` FROM items WHERE type = $1 AND NOT deleted ORDER BY created_at, id`, itemType)
+	return result
+}
+
+// ListByRegistryName returns all non-deleted Items of a specific registry name
+func (ps *PostgresStorage) ListByRegistryName(registryName string) []registry.Registerable {
+	result, _ := ps.ListByRegistryNameCtx(context.Background(), registryName)
+	return result
+}
+
+// ListByMetadata returns all non-deleted Items whose metadata matches every filter
+func (ps *PostgresStorage) ListByMetadata(filters map[string]string) []registry.Registerable {
+	result, _ := ps.ListByMetadataCtx(context.Background(), filters)
+	return result
+}
+
+// ListByTag returns all non-deleted Items carrying every one of the given tags
+func (ps *PostgresStorage) ListByTag(tags ...string) []registry.Registerable {
+	result, _ := ps.ListByTagCtx(context.Background(), tags...)
+	return result
+}
+
+// ListTags returns every distinct tag on non-deleted Items with its item count
+func (ps *PostgresStorage) ListTags() []TagCount {
+	return countTags(toItems(ps.List()))
+}
+
+// ListRegistryNames returns every registry name in use by non-deleted Items
+// with its item count
+func (ps *PostgresStorage) ListRegistryNames() []RegistryCount {
+	result := []RegistryCount{}
+
+	rows, err := ps.pool.Query(context.Background(), `SELECT registry_name, COUNT(*) FROM items WHERE NOT deleted GROUP BY registry_name ORDER BY registry_name`)
+	if err != nil {
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rc RegistryCount
+		if err := rows.Scan(&rc.Name, &rc.Count); err != nil {
+			continue
+		}
+		result = append(result, rc)
+	}
+	return result
+}
+
+// SearchItems performs a case-insensitive substring search across the name,
+// type, and string metadata values of all non-deleted Items
+func (ps *PostgresStorage) SearchItems(query string) []SearchResult {
+	return searchItems(toItems(ps.List()), query)
+}
+
+// Count returns the number of Items matching filter
+func (ps *PostgresStorage) Count(filter CountFilter) int {
+	query := `SELECT COUNT(*) FROM items WHERE TRUE`
+	var args []interface{}
+	if !filter.IncludeDeleted {
+		query += ` AND NOT deleted`
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		query += ` AND type = $` + strconv.Itoa(len(args))
+	}
+	if filter.RegistryName != "" {
+		args = append(args, filter.RegistryName)
+		query += ` AND registry_name = $` + strconv.Itoa(len(args))
+	}
+
+	var count int
+	if err := ps.pool.QueryRow(context.Background(), query, args...).Scan(&count); err != nil {
+		return 0
+	}
+	return count
+}
+
+// ListPaginated returns a slice of non-deleted Items with pagination support
+//
+// Deprecated: offsets shift as Items are added and removed; use ListPage.
+func (ps *PostgresStorage) ListPaginated(limit, offset int) []registry.Registerable {
+	result, _ := ps.queryCtx(context.Background(), `SELECT `+postgresColumns+` FROM items WHERE NOT deleted ORDER BY created_at, id LIMIT $1 OFFSET $2`, limit, offset)
+	if result == nil {
+		return []registry.Registerable{}
+	}
+	return result
+}
+
+// ListIncludingDeleted returns every Item in the storage regardless of its deleted flag
+func (ps *PostgresStorage) ListIncludingDeleted() []registry.Registerable {
+	result, _ := ps.ListIncludingDeletedCtx(context.Background())
+	if result == nil {
+		return []registry.Registerable{}
+	}
+	return result
+}
+
+// CreateItem adds an Item to the storage
+func (ps *PostgresStorage) CreateItem(item *registry.Item) (*registry.Item, error) {
+	return item, ps.Register(item)
+}
+
+// CreateItems adds a batch of Items to the storage. Items without an ID are
+// assigned one. A failing item does not prevent the remaining items from being stored.
+func (ps *PostgresStorage) CreateItems(items []*registry.Item) []BatchResult {
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		results[i] = newBatchResult(i, item, func(item *registry.Item) error {
+			return ps.Register(item)
+		})
+	}
+	return results
+}
+
+// GetItem retrieves an Item from the storage
+func (ps *PostgresStorage) GetItem(id string) (*registry.Item, error) {
+	item, ok := ps.Get(id)
+	if !ok {
+		return nil, errors.New("item not found")
+	}
+	return item.(*registry.Item), nil
+}
+
+// UpdateItem updates an existing Item in the storage
+func (ps *PostgresStorage) UpdateItem(item *registry.Item) (*registry.Item, error) {
+	if err := ps.Register(item); err != nil {
+		return nil, err
+	}
+	row := ps.pool.QueryRow(context.Background(), `SELECT `+postgresColumns+` FROM items WHERE id = $1`, item.ID)
+	return scanPostgresItem(row)
+}
+
+// DeleteItem soft-deletes an Item in the storage
+func (ps *PostgresStorage) DeleteItem(id string) error {
+	return ps.Unregister(id)
+}
+
+// RestoreItem clears the deleted flag on an Item in the storage
+func (ps *PostgresStorage) RestoreItem(id string) (*registry.Item, error) {
+	ctx := context.Background()
+
+	_, err := ps.pool.Exec(ctx, `UPDATE items SET deleted = FALSE, updated_at = $1 WHERE id = $2 AND deleted`, time.Now(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore item: %w", err)
+	}
+	row := ps.pool.QueryRow(ctx, `SELECT `+postgresColumns+` FROM items WHERE id = $1`, id)
+	item, err := scanPostgresItem(row)
+	if err != nil {
+		return nil, errors.New("item not found")
+	}
+	return item, nil
+}
+
+// CreateItemCtx adds an Item to the storage, recording a span as a child of ctx
+func (ps *PostgresStorage) CreateItemCtx(ctx context.Context, item *registry.Item) (created *registry.Item, err error) {
+	err = traceOp(ctx, "PostgresStorage.CreateItem", item.ID, func() error {
+		created, err = ps.CreateItem(item)
+		return err
+	})
+	return created, err
+}
+
+// GetItemCtx retrieves an Item from the storage, recording a span as a child of ctx
+func (ps *PostgresStorage) GetItemCtx(ctx context.Context, id string) (item *registry.Item, err error) {
+	err = traceOp(ctx, "PostgresStorage.GetItem", id, func() error {
+		item, err = ps.GetItem(id)
+		return err
+	})
+	return item, err
+}
+
+// UpdateItemCtx updates an existing Item in the storage, recording a span as a child of ctx
+func (ps *PostgresStorage) UpdateItemCtx(ctx context.Context, item *registry.Item) (updated *registry.Item, err error) {
+	err = traceOp(ctx, "PostgresStorage.UpdateItem", item.ID, func() error {
+		updated, err = ps.UpdateItem(item)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteItemCtx soft-deletes an Item in the storage, recording a span as a child of ctx
+func (ps *PostgresStorage) DeleteItemCtx(ctx context.Context, id string) error {
+	return traceOp(ctx, "PostgresStorage.DeleteItem", id, func() error {
+		return ps.DeleteItem(id)
+	})
+}
+
+// ListCtx returns all non-deleted Items, abandoning the query if ctx is done
+func (ps *PostgresStorage) ListCtx(ctx context.Context) ([]registry.Registerable, error) {
+	return ps.queryCtx(ctx, `SELECT `+postgresColumns+` FROM items WHERE NOT deleted ORDER BY created_at, id`)
+}
+
+// ListIncludingDeletedCtx returns every Item regardless of its deleted flag,
+// abandoning the query if ctx is done
+func (ps *PostgresStorage) ListIncludingDeletedCtx(ctx context.Context) ([]registry.Registerable, error) {
+	result, err := ps.queryCtx(ctx, `SELECT `+postgresColumns+` FROM items ORDER BY created_at, id`)
+	if err == nil && result == nil {
+		result = []registry.Registerable{}
+	}
+	return result, err
+}
+
+// ListByRegistryNameCtx returns all non-deleted Items of a specific registry
+// name, abandoning the query if ctx is done
+func (ps *PostgresStorage) ListByRegistryNameCtx(ctx context.Context, registryName string) ([]registry.Registerable, error) {
+	return ps.queryCtx(ctx, `SELECT `+postgresColumns+` FROM items WHERE registry_name = $1 AND NOT deleted ORDER BY created_at, id`, registryName)
+}
+
+// ListByMetadataCtx returns all non-deleted Items whose metadata matches every
+// filter, abandoning the query if ctx is done. Each equality filter becomes a
+// JSONB containment test served by the GIN index; a value that is valid JSON,
+// such as 3 or true, also matches metadata holding that number or boolean.
+// Filters with other operators are only applied in Go.
+func (ps *PostgresStorage) ListByMetadataCtx(ctx context.Context, filters map[string]string) ([]registry.Registerable, error) {
+	predicates, err := ParseMetadataFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + postgresColumns + ` FROM items WHERE NOT deleted`
+	var args []interface{}
+	for _, p := range predicates {
+		if p.Op != MetadataOpEq {
+			continue
+		}
+		key, value := p.Key, p.Values[0]
+		candidates := []interface{}{value}
+		var typed interface{}
+		if err := json.Unmarshal([]byte(value), &typed); err == nil {
+			switch typed.(type) {
+			case float64, bool:
+				candidates = append(candidates, typed)
+			}
+		}
+
+		clause := ""
+		for _, candidate := range candidates {
+			doc, err := json.Marshal(map[string]interface{}{key: candidate})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode metadata filter: %w", err)
+			}
+			args = append(args, string(doc))
+			if clause != "" {
+				clause += ` OR `
+			}
+			clause += `metadata @> $` + strconv.Itoa(len(args)) + `::jsonb`
+		}
+		query += ` AND (` + clause + `)`
+	}
+	query += ` ORDER BY created_at, id`
+
+	items, err := ps.queryCtx(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Containment is exact on JSON values; recheck with the shared matcher so
+	// results agree with the other backends on number formatting
+	var result []registry.Registerable
+	for _, item := range items {
+		if matchesMetadata(item.(*registry.Item), predicates) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// ListByTagCtx returns all non-deleted Items carrying every one of the given
+// tags, abandoning the query if ctx is done
+func (ps *PostgresStorage) ListByTagCtx(ctx context.Context, tags ...string) ([]registry.Registerable, error) {
+	doc, err := json.Marshal(tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tags: %w", err)
+	}
+	return ps.queryCtx(ctx, `SELECT `+postgresColumns+` FROM items WHERE tags @> $1::jsonb AND NOT deleted ORDER BY created_at, id`, string(doc))
+}
+
+// ListUpdatedSinceCtx returns every Item, deleted or not, last updated after
+// since, abandoning the query if ctx is done
+func (ps *PostgresStorage) ListUpdatedSinceCtx(ctx context.Context, since time.Time) ([]registry.Registerable, error) {
+	result, err := ps.queryCtx(ctx, `SELECT `+postgresColumns+` FROM items WHERE updated_at > $1 ORDER BY created_at, id`, since)
+	if err == nil && result == nil {
+		result = []registry.Registerable{}
+	}
+	return result, err
+}
+
+// SearchItemsCtx performs the same search as SearchItems, abandoning the scan
+// if ctx is done
+func (ps *PostgresStorage) SearchItemsCtx(ctx context.Context, query string) ([]SearchResult, error) {
+	items, err := ps.ListCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return searchItems(toItems(items), query), nil
+}
+
+// ListPage returns a page of non-deleted Items in creation order, seeking
+// past the cursor in the query so only the page itself is read
+func (ps *PostgresStorage) ListPage(ctx context.Context, limit int, cursor string) (Page, error) {
+	key, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+
+	query := `SELECT ` + postgresColumns + ` FROM items WHERE NOT deleted`
+	var args []interface{}
+	if key != nil {
+		args = append(args, key.CreatedAt, key.ID)
+		query += ` AND (created_at, id) > ($1, $2)`
+	}
+	query += ` ORDER BY created_at, id`
+	if limit > 0 {
+		// Read one extra row to learn whether another page follows
+		args = append(args, limit+1)
+		query += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+
+	items, err := ps.queryCtx(ctx, query, args...)
+	if err != nil {
+		return Page{}, err
+	}
+	return newPage(toItems(items), limit), nil
+}
+
+// queryCtx runs a SELECT over the items table and decodes every row,
+// returning ctx.Err() if ctx is done before all rows are read
+func (ps *PostgresStorage) queryCtx(ctx context.Context, query string, args ...interface{}) ([]registry.Registerable, error) {
+	rows, err := ps.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []registry.Registerable
+	for rows.Next() {
+		item, err := scanPostgresItem(rows)
+		if err != nil {
+			continue
+		}
+		result = append(result, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func scanPostgresItem(row pgx.Row) (*registry.Item, error) {
+	var (
+		item                  registry.Item
+		metadata, tags, links []byte
+		deleted               bool
+	)
+
+	err := row.Scan(
+		&item.ID,
+		&item.Type,
+		&item.Name,
+		&item.RegistryName,
+		&metadata,
+		&tags,
+		&links,
+		&item.Version,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+		&deleted,
+		&item.Owner,
+		&item.CreatedBy,
+		&item.UpdatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(metadata, &item.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if err := json.Unmarshal(tags, &item.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+	if err := json.Unmarshal(links, &item.Links); err != nil {
+		return nil, fmt.Errorf("failed to decode links: %w", err)
+	}
+	if deleted {
+		markDeleted(&item)
+	}
+
+	return &item, nil
+}
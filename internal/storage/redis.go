@@ -0,0 +1,645 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys. Each Item is stored as JSON under its item key; the sets index
+// Item IDs so listings by type and registry name read only matching Items.
+// The type, registry and live sets hold non-deleted Items only.
+const (
+	redisKeyPrefix = "registry:"
+	redisAllKey    = redisKeyPrefix + "items"
+	redisLiveKey   = redisKeyPrefix + "live"
+)
+
+// redisMaxRetries bounds how often a write is retried after another client
+// changed the Item between WATCH and EXEC
+const redisMaxRetries = 10
+
+// errRedisConflict is returned when a write kept losing the optimistic race
+var errRedisConflict = errors.New("item changed concurrently")
+
+var _ Store = (*RedisStorage)(nil)
+
+// RedisStorage implements storage for Items shared by every instance
+// connected to the same Redis database
+type RedisStorage struct {
+	client *redis.Client
+}
+
+// redisRecord is the stored representation of an Item. As with bolt, the
+// deleted flag is kept alongside the item because Item.UnmarshalJSON does
+// not read it back.
+type redisRecord struct {
+	Item    *registry.Item `json:"item"`
+	Deleted bool           `json:"deleted"`
+}
+
+// NewRedisStorage connects to the Redis server at addr and checks that it is reachable
+func NewRedisStorage(addr, password string, db int) (*RedisStorage, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisStorage{client: client}, nil
+}
+
+// Close releases the connections to Redis
+func (rs *RedisStorage) Close() error {
+	return rs.client.Close()
+}
+
+// Ping checks that the Redis server can be reached
+func (rs *RedisStorage) Ping(ctx context.Context) error {
+	return rs.client.Ping(ctx).Err()
+}
+
+// Register adds or updates an Item in the storage. An update carrying a
+// version is dropped with registry.ErrStaleVersion unless it is newer than
+// the stored one, mirroring ItemStore.UpsertItem; an update without a version
+// bumps the stored one.
+func (rs *RedisStorage) Register(item registry.Registerable) error {
+	itemObj, err := registry.ToItem(item)
+	if err != nil {
+		return err
+	}
+	return rs.register(context.Background(), itemObj)
+}
+
+func (rs *RedisStorage) register(ctx context.Context, itemObj *registry.Item) error {
+	if itemObj.RegistryName == "" {
+		return registry.ErrRegistryNameRequired
+	}
+
+	return rs.update(ctx, itemObj.ID, func(current *redisRecord) (*redisRecord, error) {
+		if current == nil {
+			itemObj.Version = 1
+			stampCreated(itemObj)
+			return &redisRecord{Item: itemObj}, nil
+		}
+
+		existing := current.Item
+		if itemObj.Version != 0 && itemObj.Version <= existing.Version {
+			return nil, registry.ErrStaleVersion
+		}
+		existing.Name = itemObj.Name
+		existing.RegistryName = itemObj.RegistryName
+		existing.Metadata = itemObj.Metadata
+		existing.Tags = itemObj.Tags
+		if itemObj.Links != nil {
+			existing.Links = itemObj.Links
+		}
+		existing.ExpiresAt = itemObj.ExpiresAt
+		existing.UpdatedBy = itemObj.UpdatedBy
+		existing.Version++
+		existing.UpdatedAt = time.Now()
+		return current, nil
+	})
+}
+
+// Get retrieves an item from the storage
+func (rs *RedisStorage) Get(id string) (registry.Registerable, bool) {
+	record, err := getRedisRecord(context.Background(), rs.client, id)
+	if err != nil || record == nil || record.Deleted || record.Item.IsExpired(time.Now()) {
+		return nil, false
+	}
+	return record.Item, true
+}
+
+// Unregister soft-deletes an Item in the storage
+func (rs *RedisStorage) Unregister(id string) error {
+	return rs.update(context.Background(), id, func(current *redisRecord) (*redisRecord, error) {
+		if current == nil {
+			return nil, errors.New("item not found")
+		}
+		current.Item.SoftDelete()
+		current.Deleted = true
+		return current, nil
+	})
+}
+
+// AddLinks records targets under the relation on a non-deleted Item. The
+// version is bumped only when a new target was added.
+func (rs *RedisStorage) AddLinks(id, relation string, targets []string) (*registry.Item, error) {
+	var item *registry.Item
+
+	err := rs.update(context.Background(), id, func(current *redisRecord) (*redisRecord, error) {
+		if current == nil || current.Deleted {
+			return nil, errors.New("item not found")
+		}
+
+		item = current.Item
+		if !item.AddLinks(relation, targets...) {
+			return nil, nil
+		}
+		item.Version++
+		item.UpdatedAt = time.Now()
+		return current, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// PurgeItem permanently removes an Item, deleted or not
+func (rs *RedisStorage) PurgeItem(id string) error {
+	purged, err := rs.purge(context.Background(), id, false)
+	if err == nil && !purged {
+		return errors.New("item not found")
+	}
+	return err
+}
+
+// PurgeDeleted permanently removes every soft-deleted Item and returns how
+// many were removed
+func (rs *RedisStorage) PurgeDeleted() (int, error) {
+	ctx := context.Background()
+	ids, err := rs.client.SDiff(ctx, redisAllKey, redisLiveKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list deleted items: %w", err)
+	}
+
+	count := 0
+	for _, id := range ids {
+		purged, err := rs.purge(ctx, id, true)
+		if err != nil {
+			return count, err
+		}
+		if purged {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// purge removes the Item id and its index entries, reporting whether it
+// existed. With onlyDeleted set, an Item restored in the meantime is kept.
+func (rs *RedisStorage) purge(ctx context.Context, id string, onlyDeleted bool) (bool, error) {
+	key := redisItemKey(id)
+	purged := false
+
+	err := rs.retry(ctx, key, func(tx *redis.Tx) error {
+		current, err := getRedisRecord(ctx, tx, id)
+		if err != nil || current == nil || (onlyDeleted && !current.Deleted) {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			pipe.SRem(ctx, redisAllKey, id)
+			for _, set := range redisIndexKeys(current) {
+				pipe.SRem(ctx, set, id)
+			}
+			return nil
+		})
+		purged = err == nil
+		return err
+	})
+	return purged, err
+}
+
+// List returns all non-deleted Items in the storage
+func (rs *RedisStorage) List() []registry.Registerable {
+	result, _ := rs.ListCtx(context.Background())
+	return result
+}
+
+// ListByType returns all non-deleted Items of a specific type
+func (rs *RedisStorage) ListByType(itemType string) []registry.Registerable {
+	result, _ := rs.scan(context.Background(), redisTypeKey(itemType), false, func(*registry.Item) bool { return true })
+	return result
+}
+
+// ListByRegistryName returns all non-deleted Items of a specific registry name
+func (rs *RedisStorage) ListByRegistryName(registryName string) []registry.Registerable {
+	result, _ := rs.ListByRegistryNameCtx(context.Background(), registryName)
+	return result
+}
+
+// ListByMetadata returns all non-deleted Items whose metadata matches every filter
+func (rs *RedisStorage) ListByMetadata(filters map[string]string) []registry.Registerable {
+	result, _ := rs.ListByMetadataCtx(context.Background(), filters)
+	return result
+}
+
+// ListByTag returns all non-deleted Items carrying every one of the given tags
+func (rs *RedisStorage) ListByTag(tags ...string) []registry.Registerable {
+	result, _ := rs.ListByTagCtx(context.Background(), tags...)
+	return result
+}
+
+// ListTags returns every distinct tag on non-deleted Items with its item count
+func (rs *RedisStorage) ListTags() []TagCount {
+	return countTags(toItems(rs.List()))
+}
+
+// ListRegistryNames returns every registry name in use by non-deleted Items
+// with its item count
+func (rs *RedisStorage) ListRegistryNames() []RegistryCount {
+	return countRegistries(toItems(rs.List()))
+}
+
+// SearchItems performs a case-insensitive substring search across the name,
+// type, and string metadata values of all non-deleted Items
+func (rs *RedisStorage) SearchItems(query string) []SearchResult {
+	return searchItems(toItems(rs.List()), query)
+}
+
+// ListPaginated returns a slice of non-deleted Items with pagination support.
+// Items are taken in creation order so pages are stable between calls.
+//
+// Deprecated: offsets shift as Items are added and removed; use ListPage.
+func (rs *RedisStorage) ListPaginated(limit, offset int) []registry.Registerable {
+	result := rs.List()
+	if offset >= len(result) {
+		return []registry.Registerable{}
+	}
+
+	end := offset + limit
+	if end > len(result) {
+		end = len(result)
+	}
+	return result[offset:end]
+}
+
+// Count returns the number of Items matching filter. Counts of non-deleted
+// Items are answered from the index sets without reading any Item.
+func (rs *RedisStorage) Count(filter CountFilter) int {
+	ctx := context.Background()
+
+	if filter.IncludeDeleted {
+		items, err := rs.scan(ctx, redisAllKey, true, filter.matches)
+		if err != nil {
+			return 0
+		}
+		return len(items)
+	}
+
+	sets := []string{redisLiveKey}
+	if filter.Type != "" {
+		sets = append(sets, redisTypeKey(filter.Type))
+	}
+	if filter.RegistryName != "" {
+		sets = append(sets, redisRegistryKey(filter.RegistryName))
+	}
+
+	if len(sets) == 1 {
+		n, _ := rs.client.SCard(ctx, redisLiveKey).Result()
+		return int(n)
+	}
+	ids, _ := rs.client.SInter(ctx, sets...).Result()
+	return len(ids)
+}
+
+// ListIncludingDeleted returns every Item in the storage regardless of its deleted flag
+func (rs *RedisStorage) ListIncludingDeleted() []registry.Registerable {
+	result, _ := rs.ListIncludingDeletedCtx(context.Background())
+	if result == nil {
+		return []registry.Registerable{}
+	}
+	return result
+}
+
+// scan loads the Items whose IDs are members of set and returns those
+// accepted by match in DefaultSort order. Items are fetched in batches,
+// stopping early once ctx is done.
+func (rs *RedisStorage) scan(ctx context.Context, set string, includeDeleted bool, match func(item *registry.Item) bool) ([]registry.Registerable, error) {
+	ids, err := rs.client.SMembers(ctx, set).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []registry.Registerable
+	for start := 0; start < len(ids); start += scanCheckInterval {
+		end := start + scanCheckInterval
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		keys := make([]string, end-start)
+		for i, id := range ids[start:end] {
+			keys[i] = redisItemKey(id)
+		}
+		values, err := rs.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				continue // purged since SMEMBERS
+			}
+			record, err := decodeRedisRecord([]byte(data))
+			if err != nil || (record.Deleted && !includeDeleted) {
+				continue
+			}
+			if match(record.Item) {
+				result = append(result, record.Item)
+			}
+		}
+	}
+
+	SortItems(result, DefaultSort)
+	return result, nil
+}
+
+// ListCtx returns all non-deleted Items, abandoning the scan if ctx is done
+func (rs *RedisStorage) ListCtx(ctx context.Context) ([]registry.Registerable, error) {
+	return rs.scan(ctx, redisLiveKey, false, func(*registry.Item) bool { return true })
+}
+
+// ListIncludingDeletedCtx returns every Item regardless of its deleted flag,
+// abandoning the scan if ctx is done
+func (rs *RedisStorage) ListIncludingDeletedCtx(ctx context.Context) ([]registry.Registerable, error) {
+	return rs.scan(ctx, redisAllKey, true, func(*registry.Item) bool { return true })
+}
+
+// ListByRegistryNameCtx returns all non-deleted Items of a specific registry
+// name, abandoning the scan if ctx is done
+func (rs *RedisStorage) ListByRegistryNameCtx(ctx context.Context, registryName string) ([]registry.Registerable, error) {
+	return rs.scan(ctx, redisRegistryKey(registryName), false, func(*registry.Item) bool { return true })
+}
+
+// ListByMetadataCtx returns all non-deleted Items whose metadata matches every
+// filter, abandoning the scan if ctx is done
+func (rs *RedisStorage) ListByMetadataCtx(ctx context.Context, filters map[string]string) ([]registry.Registerable, error) {
+	predicates, err := ParseMetadataFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	return rs.scan(ctx, redisLiveKey, false, func(item *registry.Item) bool {
+		return matchesMetadata(item, predicates)
+	})
+}
+
+// ListByTagCtx returns all non-deleted Items carrying every one of the given
+// tags, abandoning the scan if ctx is done
+func (rs *RedisStorage) ListByTagCtx(ctx context.Context, tags ...string) ([]registry.Registerable, error) {
+	return rs.scan(ctx, redisLiveKey, false, func(item *registry.Item) bool {
+		return item.HasTags(tags...)
+	})
+}
+
+// ListUpdatedSinceCtx returns every Item, deleted or not, last updated after
+// since, abandoning the scan if ctx is done
+func (rs *RedisStorage) ListUpdatedSinceCtx(ctx context.Context, since time.Time) ([]registry.Registerable, error) {
+	return rs.scan(ctx, redisAllKey, true, func(item *registry.Item) bool {
+		return item.UpdatedAt.After(since)
+	})
+}
+
+// ListPage returns a page of non-deleted Items in creation order
+func (rs *RedisStorage) ListPage(ctx context.Context, limit int, cursor string) (Page, error) {
+	items, err := rs.ListCtx(ctx)
+	if err != nil {
+		return Page{}, err
+	}
+	return pageAfter(toItems(items), limit, cursor)
+}
+
+// SearchItemsCtx performs the same search as SearchItems, abandoning the scan
+// if ctx is done
+func (rs *RedisStorage) SearchItemsCtx(ctx context.Context, query string) ([]SearchResult, error) {
+	items, err := rs.ListCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return searchItems(toItems(items), query), nil
+}
+
+// CreateItem adds an Item to the storage
+func (rs *RedisStorage) CreateItem(item *registry.Item) (*registry.Item, error) {
+	return item, rs.Register(item)
+}
+
+// GetItem retrieves an Item from the storage
+func (rs *RedisStorage) GetItem(id string) (*registry.Item, error) {
+	item, ok := rs.Get(id)
+	if !ok {
+		return nil, errors.New("item not found")
+	}
+	return item.(*registry.Item), nil
+}
+
+// UpdateItem updates an existing Item in the storage
+func (rs *RedisStorage) UpdateItem(item *registry.Item) (*registry.Item, error) {
+	if err := rs.Register(item); err != nil {
+		return nil, err
+	}
+
+	record, err := getRedisRecord(context.Background(), rs.client, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errors.New("item not found")
+	}
+	return record.Item, nil
+}
+
+// CreateItems adds a batch of Items to the storage. Items without an ID are
+// assigned one. A failing item does not prevent the remaining items from being stored.
+func (rs *RedisStorage) CreateItems(items []*registry.Item) []BatchResult {
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		results[i] = newBatchResult(i, item, func(item *registry.Item) error {
+			return rs.Register(item)
+		})
+	}
+	return results
+}
+
+// DeleteItem soft-deletes an Item in the storage
+func (rs *RedisStorage) DeleteItem(id string) error {
+	return rs.Unregister(id)
+}
+
+// RestoreItem clears the deleted flag on an Item in the storage
+func (rs *RedisStorage) RestoreItem(id string) (*registry.Item, error) {
+	var item *registry.Item
+
+	err := rs.update(context.Background(), id, func(current *redisRecord) (*redisRecord, error) {
+		if current == nil {
+			return nil, errors.New("item not found")
+		}
+
+		item = current.Item
+		if !current.Deleted {
+			return nil, nil
+		}
+		item.Restore()
+		current.Deleted = false
+		return current, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// CreateItemCtx adds an Item to the storage, recording a span as a child of ctx
+func (rs *RedisStorage) CreateItemCtx(ctx context.Context, item *registry.Item) (created *registry.Item, err error) {
+	err = traceOp(ctx, "RedisStorage.CreateItem", item.ID, func() error {
+		created, err = rs.CreateItem(item)
+		return err
+	})
+	return created, err
+}
+
+// GetItemCtx retrieves an Item from the storage, recording a span as a child of ctx
+func (rs *RedisStorage) GetItemCtx(ctx context.Context, id string) (item *registry.Item, err error) {
+	err = traceOp(ctx, "RedisStorage.GetItem", id, func() error {
+		item, err = rs.GetItem(id)
+		return err
+	})
+	return item, err
+}
+
+// UpdateItemCtx updates an existing Item in the storage, recording a span as a child of ctx
+func (rs *RedisStorage) UpdateItemCtx(ctx context.Context, item *registry.Item) (updated *registry.Item, err error) {
+	err = traceOp(ctx, "RedisStorage.UpdateItem", item.ID, func() error {
+		updated, err = rs.UpdateItem(item)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteItemCtx soft-deletes an Item in the storage, recording a span as a child of ctx
+func (rs *RedisStorage) DeleteItemCtx(ctx context.Context, id string) error {
+	return traceOp(ctx, "RedisStorage.DeleteItem", id, func() error {
+		return rs.DeleteItem(id)
+	})
+}
+
+// update applies fn to the stored record of id, which is nil when no such
+// Item exists, and writes back the record fn returns along with any index
+// changes. A nil record from fn leaves the Item untouched. The item key is
+// watched, so the write only lands if no other client changed the Item
+// since it was read.
+func (rs *RedisStorage) update(ctx context.Context, id string, fn func(current *redisRecord) (*redisRecord, error)) error {
+	key := redisItemKey(id)
+
+	return rs.retry(ctx, key, func(tx *redis.Tx) error {
+		current, err := getRedisRecord(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		before := redisIndexKeys(current)
+
+		next, err := fn(current)
+		if err != nil || next == nil {
+			return err
+		}
+		data, err := json.Marshal(next)
+		if err != nil {
+			return fmt.Errorf("failed to encode item: %w", err)
+		}
+		after := redisIndexKeys(next)
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			pipe.SAdd(ctx, redisAllKey, id)
+			for _, set := range before {
+				if !containsKey(after, set) {
+					pipe.SRem(ctx, set, id)
+				}
+			}
+			for _, set := range after {
+				pipe.SAdd(ctx, set, id)
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// retry runs fn in a transaction watching key, starting over when another
+// client modified key before the transaction committed
+func (rs *RedisStorage) retry(ctx context.Context, key string, fn func(tx *redis.Tx) error) error {
+	for attempt := 0; attempt < redisMaxRetries; attempt++ {
+		err := rs.client.Watch(ctx, fn, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return errRedisConflict
+}
+
+func redisItemKey(id string) string {
+	return redisKeyPrefix + "item:" + id
+}
+
+func redisTypeKey(itemType string) string {
+	return redisKeyPrefix + "type:" + itemType
+}
+
+func redisRegistryKey(registryName string) string {
+	return redisKeyPrefix + "registry:" + registryName
+}
+
+// redisIndexKeys returns the sets that list the Item of record; deleted
+// Items are only kept in the set of all Items
+func redisIndexKeys(record *redisRecord) []string {
+	if record == nil || record.Deleted {
+		return nil
+	}
+	return []string{redisLiveKey, redisTypeKey(record.Item.Type), redisRegistryKey(record.Item.RegistryName)}
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// getRedisRecord reads the record of id, returning nil if it does not exist
+func getRedisRecord(ctx context.Context, c redis.Cmdable, id string) (*redisRecord, error) {
+	data, err := c.Get(ctx, redisItemKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := decodeRedisRecord(data)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func decodeRedisRecord(data []byte) (redisRecord, error) {
+	record := redisRecord{Item: &registry.Item{}}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return redisRecord{}, fmt.Errorf("failed to decode item: %w", err)
+	}
+	if record.Deleted {
+		markDeleted(record.Item)
+	}
+	return record, nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// Search scores weighted by where the query matched. Name matches outrank
+// type matches, which outrank metadata matches.
+const (
+	nameMatchScore     = 100
+	typeMatchScore     = 10
+	metadataMatchScore = 1
+)
+
+// SearchResult is an Item matched by a search along with its relevance score
+type SearchResult struct {
+	Item  *registry.Item
+	Score int
+}
+
+// MarshalJSON renders the item's fields with an additional score field
+func (sr SearchResult) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(sr.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["score"] = sr.Score
+
+	return json.Marshal(fields)
+}
+
+// scoreItem returns the relevance of item for a lower-cased query, or zero if
+// the query matches neither the name, the type, nor any string metadata value.
+func scoreItem(item *registry.Item, query string) int {
+	score := 0
+	if strings.Contains(strings.ToLower(item.Name), query) {
+		score += nameMatchScore
+	}
+	if strings.Contains(strings.ToLower(item.Type), query) {
+		score += typeMatchScore
+	}
+	for _, value := range item.Metadata {
+		if s, ok := value.(string); ok && strings.Contains(strings.ToLower(s), query) {
+			score += metadataMatchScore
+		}
+	}
+	return score
+}
+
+// searchItems scores items against query and returns the matches ordered by
+// descending score, breaking ties by ID so results are stable.
+func searchItems(items []*registry.Item, query string) []SearchResult {
+	query = strings.ToLower(strings.TrimSpace(query))
+	results := []SearchResult{}
+	if query == "" {
+		return results
+	}
+
+	for _, item := range items {
+		if score := scoreItem(item, query); score > 0 {
+			results = append(results, SearchResult{Item: item, Score: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Item.ID < results[j].Item.ID
+	})
+
+	return results
+}
+
+// toItems converts a list of Registerables to Items, skipping other types
+func toItems(list []registry.Registerable) []*registry.Item {
+	items := make([]*registry.Item, 0, len(list))
+	for _, r := range list {
+		if item, ok := r.(*registry.Item); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
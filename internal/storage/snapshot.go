@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// SnapshotFormat is the version of the snapshot document written by Snapshot
+const SnapshotFormat = 1
+
+// Restore modes
+const (
+	// RestoreReplace drops every stored Item before loading the snapshot
+	RestoreReplace = "replace"
+	// RestoreMerge keeps stored Items, replacing those whose ID is in the
+	// snapshot
+	RestoreMerge = "merge"
+)
+
+// ErrInvalidSnapshot is returned by Restore for a snapshot that cannot be
+// loaded; the returned error wraps it with the reason
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// Snapshot is every Item of a store, deleted ones included, at one point in
+// time
+type Snapshot struct {
+	Format  int            `json:"format"`
+	TakenAt time.Time      `json:"takenAt"`
+	Items   []SnapshotItem `json:"items"`
+}
+
+// SnapshotItem is an Item as recorded in a Snapshot. Unlike the Item's own
+// JSON form it keeps timestamps at full precision and the deleted flag.
+type SnapshotItem struct {
+	ID           string                 `json:"id"`
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	RegistryName string                 `json:"registryName"`
+	Owner        string                 `json:"owner,omitempty"`
+	CreatedBy    string                 `json:"createdBy,omitempty"`
+	UpdatedBy    string                 `json:"updatedBy,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata"`
+	Tags         []string               `json:"tags"`
+	Links        map[string][]string    `json:"links,omitempty"`
+	CreatedAt    time.Time              `json:"createdAt"`
+	UpdatedAt    time.Time              `json:"updatedAt"`
+	Version      int64                  `json:"version"`
+	ExpiresAt    *time.Time             `json:"expiresAt,omitempty"`
+	Deleted      bool                   `json:"deleted"`
+	DeletedAt    *time.Time             `json:"deletedAt,omitempty"`
+}
+
+// NewSnapshotItem copies item into a SnapshotItem, so that later changes to
+// the Item do not show in the snapshot
+func NewSnapshotItem(item *registry.Item) SnapshotItem {
+	revision := registry.NewItemRevision(item, "")
+	s := SnapshotItem{
+		ID:           item.ID,
+		Type:         item.Type,
+		Name:         item.Name,
+		RegistryName: item.RegistryName,
+		Owner:        item.Owner,
+		CreatedBy:    item.CreatedBy,
+		UpdatedBy:    item.UpdatedBy,
+		Metadata:     revision.Metadata,
+		Tags:         revision.Tags,
+		Links:        revision.Links,
+		CreatedAt:    item.CreatedAt,
+		UpdatedAt:    item.UpdatedAt,
+		Version:      item.Version,
+		Deleted:      item.IsDeleted(),
+	}
+	if !item.ExpiresAt.IsZero() {
+		expiresAt := item.ExpiresAt
+		s.ExpiresAt = &expiresAt
+	}
+	if s.Deleted {
+		deletedAt := item.DeletedAt
+		s.DeletedAt = &deletedAt
+	}
+	return s
+}
+
+// Item returns the Item the SnapshotItem records, with its ID, version and
+// timestamps unchanged
+func (s SnapshotItem) Item() *registry.Item {
+	item := &registry.Item{
+		ID:           s.ID,
+		Type:         s.Type,
+		Name:         s.Name,
+		RegistryName: s.RegistryName,
+		Owner:        s.Owner,
+		CreatedBy:    s.CreatedBy,
+		UpdatedBy:    s.UpdatedBy,
+		Metadata:     s.Metadata,
+		Tags:         s.Tags,
+		Links:        s.Links,
+		CreatedAt:    s.CreatedAt,
+		Version:      s.Version,
+	}
+	if s.ExpiresAt != nil {
+		item.ExpiresAt = *s.ExpiresAt
+	}
+	if s.Deleted {
+		item.SoftDelete()
+		// Snapshots from before deletion times were recorded fall back to
+		// the last change, which was the deletion
+		item.DeletedAt = s.UpdatedAt
+		if s.DeletedAt != nil {
+			item.DeletedAt = *s.DeletedAt
+		}
+	}
+	// SoftDelete stamps the time, so the recorded one is set afterwards
+	item.UpdatedAt = s.UpdatedAt
+	return item
+}
+
+// SnapshotStore is implemented by stores that can save and load all of their
+// Items at once
+type SnapshotStore interface {
+	Snapshot() *Snapshot
+	Restore(snapshot *Snapshot, mode string) (*RestoreResult, error)
+}
+
+// RestoreResult lists the Items a restore changed
+type RestoreResult struct {
+	// Loaded holds copies of the Items of the snapshot as they were stored
+	Loaded []*registry.Item
+	// Replaced maps the ID of each loaded Item that replaced a stored one to
+	// the version the stored one had
+	Replaced map[string]int64
+	// Removed holds the stored Items a RestoreReplace dropped because the
+	// snapshot did not hold them
+	Removed []*registry.Item
+}
+
+var _ SnapshotStore = (*MemoryStorage)(nil)
+
+// Snapshot returns every Item, deleted ones included, ordered by ID. It is
+// taken under a single read lock, so it is consistent.
+func (ms *MemoryStorage) Snapshot() *Snapshot {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	items := make([]SnapshotItem, 0, len(ms.items))
+	for _, item := range ms.items {
+		items = append(items, NewSnapshotItem(item))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return &Snapshot{Format: SnapshotFormat, TakenAt: time.Now().UTC(), Items: items}
+}
+
+// Restore loads the Items of snapshot under a single write lock and returns
+// what it changed. RestoreReplace drops every stored Item first, while
+// RestoreMerge only replaces the Items with an ID in the snapshot. The history
+// of every loaded Item starts afresh. Nothing changes when the snapshot is
+// invalid or would leave two non-deleted Items with the same natural key.
+func (ms *MemoryStorage) Restore(snapshot *Snapshot, mode string) (*RestoreResult, error) {
+	if mode != RestoreReplace && mode != RestoreMerge {
+		return nil, fmt.Errorf("unknown restore mode %q; expected %s or %s", mode, RestoreReplace, RestoreMerge)
+	}
+	if snapshot.Format != SnapshotFormat {
+		return nil, fmt.Errorf("%w: unsupported format %d", ErrInvalidSnapshot, snapshot.Format)
+	}
+
+	loaded := make(map[string]*registry.Item, len(snapshot.Items))
+	for i, s := range snapshot.Items {
+		switch {
+		case s.ID == "":
+			return nil, fmt.Errorf("%w: item %d has no id", ErrInvalidSnapshot, i)
+		case s.RegistryName == "":
+			return nil, fmt.Errorf("%w: item %s has no registryName", ErrInvalidSnapshot, s.ID)
+		case s.Version < 1:
+			return nil, fmt.Errorf("%w: item %s has version %d", ErrInvalidSnapshot, s.ID, s.Version)
+		}
+		if _, ok := loaded[s.ID]; ok {
+			return nil, fmt.Errorf("%w: item %s appears more than once", ErrInvalidSnapshot, s.ID)
+		}
+		loaded[s.ID] = s.Item()
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	items := loaded
+	if mode == RestoreMerge {
+		items = make(map[string]*registry.Item, len(ms.items)+len(loaded))
+		for id, item := range ms.items {
+			items[id] = item
+		}
+		for id, item := range loaded {
+			items[id] = item
+		}
+	}
+
+	if len(ms.naturalKey) > 0 {
+		holders := make(map[string]string)
+		for id, item := range items {
+			if item.IsDeleted() {
+				continue
+			}
+			key := ms.keyOf(item.Name, item.RegistryName, item.Type)
+			if holder, ok := holders[key]; ok {
+				return nil, fmt.Errorf("%w: items %s and %s", ErrDuplicateItem, holder, id)
+			}
+			holders[key] = id
+		}
+	}
+
+	result := &RestoreResult{Replaced: make(map[string]int64)}
+	for id, item := range ms.items {
+		if _, ok := loaded[id]; ok {
+			result.Replaced[id] = item.Version
+		} else if mode == RestoreReplace {
+			result.Removed = append(result.Removed, item)
+		}
+	}
+	for _, item := range loaded {
+		result.Loaded = append(result.Loaded, NewSnapshotItem(item).Item())
+	}
+
+	if mode == RestoreReplace {
+		ms.history = make(map[string][]registry.ItemRevision)
+	}
+	for id := range loaded {
+		delete(ms.history, id)
+	}
+
+	ms.items = items
+	ms.byType = make(map[string]map[string]*registry.Item)
+	ms.byRegistry = make(map[string]map[string]*registry.Item)
+	ms.deletedByRegistry = make(map[string]map[string]*registry.Item)
+	ms.byNaturalKey = make(map[string]string)
+	for key := range ms.byMetadata {
+		ms.byMetadata[key] = make(map[string]map[string]*registry.Item)
+	}
+	for _, item := range ms.items {
+		ms.index(item)
+	}
+	return result, nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// Fields a listing can be sorted by
+const (
+	SortName      = "name"
+	SortType      = "type"
+	SortCreatedAt = "createdAt"
+	SortUpdatedAt = "updatedAt"
+)
+
+// Sort selects the order of a listing. Items that compare equal on Field are
+// ordered by ID so the order is stable between calls.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// DefaultSort orders Items by creation time, oldest first. Every listing
+// returns Items in this order unless asked otherwise.
+var DefaultSort = Sort{Field: SortCreatedAt}
+
+// ParseSort reads a sort field and an "asc" or "desc" order. Empty values
+// fall back to DefaultSort's field and ascending order.
+func ParseSort(field, order string) (Sort, error) {
+	s := DefaultSort
+	switch field {
+	case "":
+	case SortName, SortType, SortCreatedAt, SortUpdatedAt:
+		s.Field = field
+	default:
+		return Sort{}, fmt.Errorf("unsupported sort field %q", field)
+	}
+
+	switch order {
+	case "", "asc":
+	case "desc":
+		s.Desc = true
+	default:
+		return Sort{}, fmt.Errorf("unsupported sort order %q", order)
+	}
+
+	return s, nil
+}
+
+// SortItems orders list in place. Name and type orders apply to every entry
+// implementing registry.MetadataProvider; time orders only to *registry.Item.
+// Entries the order does not apply to are ordered by ID after the others.
+func SortItems(list []registry.Registerable, s Sort) {
+	sort.Slice(list, func(i, j int) bool {
+		aok, bok := s.appliesTo(list[i]), s.appliesTo(list[j])
+		if aok && bok {
+			return s.less(list[i], list[j])
+		}
+		if aok != bok {
+			return aok
+		}
+		return list[i].GetID() < list[j].GetID()
+	})
+}
+
+// appliesTo reports whether entry carries the field s orders by
+func (s Sort) appliesTo(entry registry.Registerable) bool {
+	switch s.Field {
+	case SortName:
+		_, ok := entry.(registry.MetadataProvider)
+		return ok
+	case SortType:
+		return true
+	}
+	_, ok := entry.(*registry.Item)
+	return ok
+}
+
+// less reports whether a sorts before b. Both must satisfy appliesTo.
+func (s Sort) less(a, b registry.Registerable) bool {
+	var cmp int
+	switch s.Field {
+	case SortName:
+		cmp = compareStrings(a.(registry.MetadataProvider).GetName(), b.(registry.MetadataProvider).GetName())
+	case SortType:
+		cmp = compareStrings(a.GetType(), b.GetType())
+	case SortUpdatedAt:
+		cmp = compareTimes(a.(*registry.Item).UpdatedAt, b.(*registry.Item).UpdatedAt)
+	default:
+		cmp = compareTimes(a.(*registry.Item).CreatedAt, b.(*registry.Item).CreatedAt)
+	}
+	if cmp == 0 {
+		cmp = compareStrings(a.GetID(), b.GetID())
+	}
+
+	if s.Desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
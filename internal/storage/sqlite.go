@@ -0,0 +1,714 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS items (
+	id            TEXT PRIMARY KEY,
+	type          TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	registry_name TEXT NOT NULL,
+	metadata      TEXT NOT NULL DEFAULT '{}',
+	version       INTEGER NOT NULL,
+	created_at    TEXT NOT NULL,
+	updated_at    TEXT NOT NULL,
+	deleted       INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_items_type ON items(type, deleted);
+CREATE INDEX IF NOT EXISTS idx_items_registry_name ON items(registry_name, deleted);
+`
+
+// sqliteMigrations evolve databases created by earlier releases. Each runs on
+// startup; "duplicate column" errors mean it has already been applied.
+var sqliteMigrations = []string{
+	`ALTER TABLE items ADD COLUMN tags TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE items ADD COLUMN links TEXT NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE items ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE items ADD COLUMN created_by TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE items ADD COLUMN updated_by TEXT NOT NULL DEFAULT ''`,
+}
+
+// sqliteTimeFormat stores timestamps in UTC with a fixed-width fraction, so
+// that they sort chronologically as text
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// sqliteTime formats t for the created_at and updated_at columns
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+const sqliteColumns = `id, type, name, registry_name, metadata, tags, links, version, created_at, updated_at, deleted, owner, created_by, updated_by`
+
+var _ Store = (*SQLiteStorage)(nil)
+
+// SQLiteStorage implements persistent storage for Items backed by SQLite
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// NewSQLiteStorage opens the SQLite database at dsn and ensures the schema exists
+func NewSQLiteStorage(dsn string) (*SQLiteStorage, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	// SQLite only supports a single writer; serialize access through one connection.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+
+	for _, migration := range sqliteMigrations {
+		if _, err := db.Exec(migration); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate sqlite schema: %w", err)
+		}
+	}
+
+	if err := migrateSQLiteTimes(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate sqlite timestamps: %w", err)
+	}
+
+	return &SQLiteStorage{db: db}, nil
+}
+
+// migrateSQLiteTimes rewrites timestamps stored by earlier releases, which
+// kept the local offset and trimmed the fraction, in sqliteTimeFormat
+func migrateSQLiteTimes(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const stored = `'????-??-??T??:??:??.?????????Z'`
+	rows, err := tx.Query(`SELECT id, created_at, updated_at FROM items
+		WHERE created_at NOT GLOB ` + stored + ` OR updated_at NOT GLOB ` + stored)
+	if err != nil {
+		return err
+	}
+	type stamps struct{ id, createdAt, updatedAt string }
+	var stale []stamps
+	for rows.Next() {
+		var row stamps
+		if err := rows.Scan(&row.id, &row.createdAt, &row.updatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		stale = append(stale, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, row := range stale {
+		createdAt, err := time.Parse(time.RFC3339Nano, row.createdAt)
+		if err != nil {
+			return fmt.Errorf("item %s: %w", row.id, err)
+		}
+		updatedAt, err := time.Parse(time.RFC3339Nano, row.updatedAt)
+		if err != nil {
+			return fmt.Errorf("item %s: %w", row.id, err)
+		}
+		if _, err := tx.Exec(`UPDATE items SET created_at = ?, updated_at = ? WHERE id = ?`,
+			sqliteTime(createdAt), sqliteTime(updatedAt), row.id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close releases the underlying database handle
+func (ss *SQLiteStorage) Close() error {
+	return ss.db.Close()
+}
+
+// Ping checks that the database can be reached
+func (ss *SQLiteStorage) Ping(ctx context.Context) error {
+	return ss.db.PingContext(ctx)
+}
+
+// Register adds or updates an Item in the storage. Updates whose version is
+// not newer than the stored version are dropped with registry.ErrStaleVersion,
+// mirroring ItemStore.UpsertItem.
+func (ss *SQLiteStorage) Register(item registry.Registerable) error {
+	itemObj, err := registry.ToItem(item)
+	if err != nil {
+		return err
+	}
+
+	if itemObj.RegistryName == "" {
+		return registry.ErrRegistryNameRequired
+	}
+
+	metadata, err := json.Marshal(itemObj.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	tags, err := json.Marshal(itemObj.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode tags: %w", err)
+	}
+
+	links, err := json.Marshal(itemObj.Links)
+	if err != nil {
+		return fmt.Errorf("failed to encode links: %w", err)
+	}
+
+	now := time.Now()
+	if itemObj.CreatedAt.IsZero() {
+		itemObj.CreatedAt = now
+	}
+	itemObj.UpdatedAt = now
+	if itemObj.Version < 1 {
+		itemObj.Version = 1
+	}
+
+	result, err := ss.db.Exec(`
+		INSERT INTO items (`+sqliteColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			type = excluded.type,
+			name = excluded.name,
+			registry_name = excluded.registry_name,
+			metadata = excluded.metadata,
+			tags = excluded.tags,
+			links = CASE WHEN excluded.links = 'null' THEN items.links ELSE excluded.links END,
+			version = excluded.version,
+			updated_at = excluded.updated_at,
+			updated_by = excluded.updated_by
+		WHERE excluded.version > items.version`,
+		itemObj.ID,
+		itemObj.Type,
+		itemObj.Name,
+		itemObj.RegistryName,
+		string(metadata),
+		string(tags),
+		string(links),
+		itemObj.Version,
+		sqliteTime(itemObj.CreatedAt),
+		sqliteTime(itemObj.UpdatedAt),
+		itemObj.Owner,
+		itemObj.CreatedBy,
+		itemObj.UpdatedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register item: %w", err)
+	}
+	// The conditional upsert touches no row when the update is stale
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return registry.ErrStaleVersion
+	}
+	return nil
+}
+
+// Get retrieves an item from the storage
+func (ss *SQLiteStorage) Get(id string) (registry.Registerable, bool) {
+	row := ss.db.QueryRow(`SELECT `+sqliteColumns+` FROM items WHERE id = ? AND deleted = 0`, id)
+	item, err := scanSQLiteItem(row)
+	if err != nil {
+		return nil, false
+	}
+	return item, true
+}
+
+// Unregister soft-deletes an Item in the storage
+func (ss *SQLiteStorage) Unregister(id string) error {
+	res, err := ss.db.Exec(
+		`UPDATE items SET deleted = 1, updated_at = ? WHERE id = ?`,
+		sqliteTime(time.Now()), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("item not found")
+	}
+	return nil
+}
+
+// AddLinks records targets under the relation on a non-deleted Item. The
+// version is bumped only when a new target was added.
+func (ss *SQLiteStorage) AddLinks(id, relation string, targets []string) (*registry.Item, error) {
+	item, err := ss.GetItem(id)
+	if err != nil {
+		return nil, err
+	}
+	if !item.AddLinks(relation, targets...) {
+		return item, nil
+	}
+
+	links, err := json.Marshal(item.Links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode links: %w", err)
+	}
+
+	// The version guard rejects the write if the item changed since it was read
+	item.UpdatedAt = time.Now()
+	res, err := ss.db.Exec(
+		`UPDATE items SET links = ?, version = version + 1, updated_at = ? WHERE id = ? AND version = ? AND deleted = 0`,
+		string(links), sqliteTime(item.UpdatedAt), id, item.Version,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add links: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, errors.New("item changed while adding links")
+	}
+
+	item.Version++
+	return item, nil
+}
+
+// PurgeItem permanently removes an Item, deleted or not
+func (ss *SQLiteStorage) PurgeItem(id string) error {
+	res, err := ss.db.Exec(`DELETE FROM items WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge item: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("item not found")
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes every soft-deleted Item and returns how
+// many were removed
+func (ss *SQLiteStorage) PurgeDeleted() (int, error) {
+	res, err := ss.db.Exec(`DELETE FROM items WHERE deleted = 1`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted items: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// List returns all non-deleted Items in the storage
+func (ss *SQLiteStorage) List() []registry.Registerable {
+	return ss.query(`SELECT ` + sqliteColumns + ` FROM items WHERE deleted = 0 ORDER BY created_at, id`)
+}
+
+// ListByType returns all non-deleted Items of a specific type
+func (ss *SQLiteStorage) ListByType(itemType string) []registry.Registerable {
+	return ss.query(`SELECT `+sqliteColumns+` FROM items WHERE type = ? AND deleted = 0 ORDER BY created_at, id`, itemType)
+}
+
+// ListByRegistryName returns all non-deleted Items of a specific registry name
+func (ss *SQLiteStorage) ListByRegistryName(registryName string) []registry.Registerable {
+	return ss.query(`SELECT `+sqliteColumns+` FROM items WHERE registry_name = ? AND deleted = 0 ORDER BY created_at, id`, registryName)
+}
+
+// ListByMetadata returns all non-deleted Items whose metadata matches every
+// filter, or none if a filter is malformed. Values are compared after
+// fmt.Sprint conversion, so matching happens in Go.
+func (ss *SQLiteStorage) ListByMetadata(filters map[string]string) []registry.Registerable {
+	predicates, err := ParseMetadataFilters(filters)
+	if err != nil {
+		return nil
+	}
+
+	var result []registry.Registerable
+	for _, item := range ss.List() {
+		if matchesMetadata(item.(*registry.Item), predicates) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// ListByTag returns all non-deleted Items carrying every one of the given tags
+func (ss *SQLiteStorage) ListByTag(tags ...string) []registry.Registerable {
+	var result []registry.Registerable
+	for _, item := range ss.List() {
+		if item.(*registry.Item).HasTags(tags...) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// ListTags returns every distinct tag on non-deleted Items with its item count
+func (ss *SQLiteStorage) ListTags() []TagCount {
+	return countTags(toItems(ss.List()))
+}
+
+// ListRegistryNames returns every registry name in use by non-deleted Items
+// with its item count
+func (ss *SQLiteStorage) ListRegistryNames() []RegistryCount {
+	result := []RegistryCount{}
+
+	rows, err := ss.db.Query(`SELECT registry_name, COUNT(*) FROM items WHERE deleted = 0 GROUP BY registry_name ORDER BY registry_name`)
+	if err != nil {
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rc RegistryCount
+		if err := rows.Scan(&rc.Name, &rc.Count); err != nil {
+			continue
+		}
+		result = append(result, rc)
+	}
+	return result
+}
+
+// SearchItems performs a case-insensitive substring search across the name,
+// type, and string metadata values of all non-deleted Items
+func (ss *SQLiteStorage) SearchItems(query string) []SearchResult {
+	return searchItems(toItems(ss.List()), query)
+}
+
+// Count returns the number of Items matching filter
+func (ss *SQLiteStorage) Count(filter CountFilter) int {
+	query := `SELECT COUNT(*) FROM items WHERE 1 = 1`
+	var args []interface{}
+	if !filter.IncludeDeleted {
+		query += ` AND deleted = 0`
+	}
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	if filter.RegistryName != "" {
+		query += ` AND registry_name = ?`
+		args = append(args, filter.RegistryName)
+	}
+
+	var count int
+	if err := ss.db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0
+	}
+	return count
+}
+
+// ListPaginated returns a slice of non-deleted Items with pagination support
+func (ss *SQLiteStorage) ListPaginated(limit, offset int) []registry.Registerable {
+	result := ss.query(`SELECT `+sqliteColumns+` FROM items WHERE deleted = 0 ORDER BY created_at, id LIMIT ? OFFSET ?`, limit, offset)
+	if result == nil {
+		return []registry.Registerable{}
+	}
+	return result
+}
+
+// ListIncludingDeleted returns every Item in the storage regardless of its deleted flag
+func (ss *SQLiteStorage) ListIncludingDeleted() []registry.Registerable {
+	result := ss.query(`SELECT ` + sqliteColumns + ` FROM items ORDER BY created_at, id`)
+	if result == nil {
+		return []registry.Registerable{}
+	}
+	return result
+}
+
+// CreateItem adds an Item to the storage
+func (ss *SQLiteStorage) CreateItem(item *registry.Item) (*registry.Item, error) {
+	return item, ss.Register(item)
+}
+
+// CreateItems adds a batch of Items to the storage. Items without an ID are
+// assigned one. A failing item does not prevent the remaining items from being stored.
+func (ss *SQLiteStorage) CreateItems(items []*registry.Item) []BatchResult {
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		results[i] = newBatchResult(i, item, func(item *registry.Item) error {
+			return ss.Register(item)
+		})
+	}
+	return results
+}
+
+// GetItem retrieves an Item from the storage
+func (ss *SQLiteStorage) GetItem(id string) (*registry.Item, error) {
+	item, ok := ss.Get(id)
+	if !ok {
+		return nil, errors.New("item not found")
+	}
+	return item.(*registry.Item), nil
+}
+
+// UpdateItem updates an existing Item in the storage
+func (ss *SQLiteStorage) UpdateItem(item *registry.Item) (*registry.Item, error) {
+	if err := ss.Register(item); err != nil {
+		return nil, err
+	}
+	row := ss.db.QueryRow(`SELECT `+sqliteColumns+` FROM items WHERE id = ?`, item.ID)
+	return scanSQLiteItem(row)
+}
+
+// DeleteItem soft-deletes an Item in the storage
+func (ss *SQLiteStorage) DeleteItem(id string) error {
+	return ss.Unregister(id)
+}
+
+// RestoreItem clears the deleted flag on an Item in the storage
+func (ss *SQLiteStorage) RestoreItem(id string) (*registry.Item, error) {
+	_, err := ss.db.Exec(
+		`UPDATE items SET deleted = 0, updated_at = ? WHERE id = ? AND deleted = 1`,
+		sqliteTime(time.Now()), id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore item: %w", err)
+	}
+	row := ss.db.QueryRow(`SELECT `+sqliteColumns+` FROM items WHERE id = ?`, id)
+	item, err := scanSQLiteItem(row)
+	if err != nil {
+		return nil, errors.New("item not found")
+	}
+	return item, nil
+}
+
+// CreateItemCtx adds an Item to the storage, recording a span as a child of ctx
+func (ss *SQLiteStorage) CreateItemCtx(ctx context.Context, item *registry.Item) (created *registry.Item, err error) {
+	err = traceOp(ctx, "SQLiteStorage.CreateItem", item.ID, func() error {
+		created, err = ss.CreateItem(item)
+		return err
+	})
+	return created, err
+}
+
+// GetItemCtx retrieves an Item from the storage, recording a span as a child of ctx
+func (ss *SQLiteStorage) GetItemCtx(ctx context.Context, id string) (item *registry.Item, err error) {
+	err = traceOp(ctx, "SQLiteStorage.GetItem", id, func() error {
+		item, err = ss.GetItem(id)
+		return err
+	})
+	return item, err
+}
+
+// UpdateItemCtx updates an existing Item in the storage, recording a span as a child of ctx
+func (ss *SQLiteStorage) UpdateItemCtx(ctx context.Context, item *registry.Item) (updated *registry.Item, err error) {
+	err = traceOp(ctx, "SQLiteStorage.UpdateItem", item.ID, func() error {
+		updated, err = ss.UpdateItem(item)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteItemCtx soft-deletes an Item in the storage, recording a span as a child of ctx
+func (ss *SQLiteStorage) DeleteItemCtx(ctx context.Context, id string) error {
+	return traceOp(ctx, "SQLiteStorage.DeleteItem", id, func() error {
+		return ss.DeleteItem(id)
+	})
+}
+
+// ListCtx returns all non-deleted Items, abandoning the query if ctx is done
+func (ss *SQLiteStorage) ListCtx(ctx context.Context) ([]registry.Registerable, error) {
+	return ss.queryCtx(ctx, `SELECT `+sqliteColumns+` FROM items WHERE deleted = 0 ORDER BY created_at, id`)
+}
+
+// ListIncludingDeletedCtx returns every Item regardless of its deleted flag,
+// abandoning the query if ctx is done
+func (ss *SQLiteStorage) ListIncludingDeletedCtx(ctx context.Context) ([]registry.Registerable, error) {
+	result, err := ss.queryCtx(ctx, `SELECT `+sqliteColumns+` FROM items ORDER BY created_at, id`)
+	if err == nil && result == nil {
+		result = []registry.Registerable{}
+	}
+	return result, err
+}
+
+// ListByRegistryNameCtx returns all non-deleted Items of a specific registry
+// name, abandoning the query if ctx is done
+func (ss *SQLiteStorage) ListByRegistryNameCtx(ctx context.Context, registryName string) ([]registry.Registerable, error) {
+	return ss.queryCtx(ctx, `SELECT `+sqliteColumns+` FROM items WHERE registry_name = ? AND deleted = 0 ORDER BY created_at, id`, registryName)
+}
+
+// ListByMetadataCtx returns all non-deleted Items whose metadata matches every
+// filter, abandoning the scan if ctx is done
+func (ss *SQLiteStorage) ListByMetadataCtx(ctx context.Context, filters map[string]string) ([]registry.Registerable, error) {
+	predicates, err := ParseMetadataFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	items, err := ss.ListCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []registry.Registerable
+	for _, item := range items {
+		if matchesMetadata(item.(*registry.Item), predicates) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// ListByTagCtx returns all non-deleted Items carrying every one of the given
+// tags, abandoning the scan if ctx is done
+func (ss *SQLiteStorage) ListByTagCtx(ctx context.Context, tags ...string) ([]registry.Registerable, error) {
+	items, err := ss.ListCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []registry.Registerable
+	for _, item := range items {
+		if item.(*registry.Item).HasTags(tags...) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// ListUpdatedSinceCtx returns every Item, deleted or not, last updated after
+// since, abandoning the scan if ctx is done
+func (ss *SQLiteStorage) ListUpdatedSinceCtx(ctx context.Context, since time.Time) ([]registry.Registerable, error) {
+	result, err := ss.queryCtx(ctx, `SELECT `+sqliteColumns+` FROM items WHERE updated_at > ? ORDER BY created_at, id`, sqliteTime(since))
+	if err == nil && result == nil {
+		result = []registry.Registerable{}
+	}
+	return result, err
+}
+
+// SearchItemsCtx performs the same search as SearchItems, abandoning the scan
+// if ctx is done
+func (ss *SQLiteStorage) SearchItemsCtx(ctx context.Context, query string) ([]SearchResult, error) {
+	items, err := ss.ListCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return searchItems(toItems(items), query), nil
+}
+
+// ListPage returns a page of non-deleted Items in creation order, seeking
+// past the cursor in the query so only the page itself is read
+func (ss *SQLiteStorage) ListPage(ctx context.Context, limit int, cursor string) (Page, error) {
+	key, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+
+	query := `SELECT ` + sqliteColumns + ` FROM items WHERE deleted = 0`
+	var args []interface{}
+	if key != nil {
+		createdAt := sqliteTime(key.CreatedAt)
+		query += ` AND (created_at > ? OR (created_at = ? AND id > ?))`
+		args = append(args, createdAt, createdAt, key.ID)
+	}
+	query += ` ORDER BY created_at, id`
+	if limit > 0 {
+		// Read one extra row to learn whether another page follows
+		query += ` LIMIT ?`
+		args = append(args, limit+1)
+	}
+
+	items, err := ss.queryCtx(ctx, query, args...)
+	if err != nil {
+		return Page{}, err
+	}
+	return newPage(toItems(items), limit), nil
+}
+
+// queryCtx runs a SELECT over the items table and decodes every row,
+// returning ctx.Err() if ctx is done before all rows are read
+func (ss *SQLiteStorage) queryCtx(ctx context.Context, query string, args ...interface{}) ([]registry.Registerable, error) {
+	rows, err := ss.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []registry.Registerable
+	for rows.Next() {
+		item, err := scanSQLiteItem(rows)
+		if err != nil {
+			continue
+		}
+		result = append(result, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// query runs a SELECT over the items table and decodes every row
+func (ss *SQLiteStorage) query(query string, args ...interface{}) []registry.Registerable {
+	rows, err := ss.db.Query(query, args...)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var result []registry.Registerable
+	for rows.Next() {
+		item, err := scanSQLiteItem(rows)
+		if err != nil {
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// sqliteScanner is satisfied by both *sql.Row and *sql.Rows
+type sqliteScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSQLiteItem(s sqliteScanner) (*registry.Item, error) {
+	var (
+		item                  registry.Item
+		metadata, tags, links string
+		createdAt, updatedAt  string
+		deleted               bool
+	)
+
+	err := s.Scan(
+		&item.ID,
+		&item.Type,
+		&item.Name,
+		&item.RegistryName,
+		&metadata,
+		&tags,
+		&links,
+		&item.Version,
+		&createdAt,
+		&updatedAt,
+		&deleted,
+		&item.Owner,
+		&item.CreatedBy,
+		&item.UpdatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(metadata), &item.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if err := json.Unmarshal([]byte(tags), &item.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+	if err := json.Unmarshal([]byte(links), &item.Links); err != nil {
+		return nil, fmt.Errorf("failed to decode links: %w", err)
+	}
+	if item.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, err
+	}
+	if item.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAt); err != nil {
+		return nil, err
+	}
+	if deleted {
+		markDeleted(&item)
+	}
+
+	return &item, nil
+}
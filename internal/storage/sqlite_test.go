@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// sqliteIDs returns the IDs of items in order
+func sqliteIDs(items []registry.Registerable) []string {
+	ids := []string{}
+	for _, item := range items {
+		ids = append(ids, item.(*registry.Item).ID)
+	}
+	return ids
+}
+
+// sqliteOrderTimes are creation times, a to c, whose RFC 3339 text with a
+// trimmed fraction and a local offset sorts out of order: "05Z" sorts after
+// "05.5Z", and the offset of c makes it sort before both
+var sqliteOrderTimes = map[string]time.Time{
+	"a": time.Date(2024, 1, 2, 12, 0, 5, 0, time.UTC),
+	"b": time.Date(2024, 1, 2, 12, 0, 5, 500000000, time.UTC),
+	"c": time.Date(2024, 1, 2, 7, 0, 6, 0, time.FixedZone("EST", -5*3600)),
+}
+
+func TestSQLiteStorageOrder(t *testing.T) {
+	ss, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "registry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+	for _, id := range []string{"c", "b", "a"} {
+		if _, err := ss.CreateItem(&registry.Item{ID: id, Type: "service", Name: id, RegistryName: "team-a", CreatedAt: sqliteOrderTimes[id]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"a", "b", "c"}
+	if got := sqliteIDs(ss.List()); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+	if got := sqliteIDs(ss.ListByType("service")); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListByType() = %v, want %v", got, want)
+	}
+	if got := sqliteIDs(ss.ListPaginated(2, 1)); !reflect.DeepEqual(got, want[1:]) {
+		t.Errorf("ListPaginated(2, 1) = %v, want %v", got, want[1:])
+	}
+
+	var paged []string
+	cursor := ""
+	for i := 0; i < len(want)+1; i++ {
+		page, err := ss.ListPage(context.Background(), 1, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paged = append(paged, sqliteIDs(page.Items)...)
+		if cursor = page.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if !reflect.DeepEqual(paged, want) {
+		t.Errorf("ListPage() = %v, want %v", paged, want)
+	}
+
+	for id, created := range sqliteOrderTimes {
+		item, err := ss.GetItem(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !item.CreatedAt.Equal(created) {
+			t.Errorf("CreatedAt of %s = %v, want %v", id, item.CreatedAt, created)
+		}
+	}
+}
+
+func TestSQLiteStorageMigratesTimes(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "registry.sqlite")
+	ss, err := NewSQLiteStorage(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.Close()
+
+	// Earlier releases stored time.RFC3339Nano text in the local offset
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, created := range sqliteOrderTimes {
+		stamp := created.Format(time.RFC3339Nano)
+		if _, err := db.Exec(`INSERT INTO items (id, type, name, registry_name, version, created_at, updated_at) VALUES (?, 'service', ?, 'team-a', 1, ?, ?)`,
+			id, id, stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	ss, err = NewSQLiteStorage(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+
+	if got, want := sqliteIDs(ss.List()), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+	since := sqliteOrderTimes["a"].Add(time.Second / 4)
+	items, err := ss.ListUpdatedSinceCtx(context.Background(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sqliteIDs(items), []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListUpdatedSinceCtx() = %v, want %v", got, want)
+	}
+
+	var stored string
+	if err := ss.db.QueryRow(`SELECT created_at FROM items WHERE id = 'c'`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2024-01-02T12:00:06.000000000Z"; stored != want {
+		t.Errorf("stored created_at = %q, want %q", stored, want)
+	}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// Stats summarizes the contents of a store. Types, Registries and ByType
+// only count non-deleted Items. IndexedMetadataKeys lists the metadata keys
+// the store indexes, if any, MetadataSize how large the metadata of
+// non-deleted Items is, and Retention describes the store's retention of
+// deleted Items, if it has one.
+type Stats struct {
+	Total               int             `json:"total"`
+	Active              int             `json:"active"`
+	Deleted             int             `json:"deleted"`
+	Types               int             `json:"types"`
+	Registries          int             `json:"registries"`
+	ByType              map[string]int  `json:"byType"`
+	IndexedMetadataKeys []string        `json:"indexedMetadataKeys"`
+	MetadataSize        SizeStats       `json:"metadataSize"`
+	Retention           *RetentionStats `json:"retention,omitempty"`
+}
+
+// SizeStats summarizes the approximate metadata sizes of the non-deleted
+// Items in bytes, as measured by Item.MetadataSize. P95 is the size that 95%
+// of the Items do not exceed.
+type SizeStats struct {
+	Avg int `json:"avg"`
+	P95 int `json:"p95"`
+	Max int `json:"max"`
+}
+
+// RetentionStats describes how long soft-deleted Items are kept, when they
+// are next looked at, and how many have been purged for being kept longer
+type RetentionStats struct {
+	Retention string    `json:"retention"`
+	NextSweep time.Time `json:"nextSweep"`
+	Purged    int64     `json:"purged"`
+}
+
+// StatsStore is implemented by stores that can compute Stats without
+// listing every Item
+type StatsStore interface {
+	Stats() Stats
+}
+
+var _ StatsStore = (*MemoryStorage)(nil)
+
+// Stats computes the store's Stats in a single pass under the read lock
+func (ms *MemoryStorage) Stats() Stats {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	tally := newStatsTally()
+	for _, item := range ms.items {
+		tally.add(item)
+	}
+	tally.result.IndexedMetadataKeys = ms.indexedMetadataKeys()
+	if ms.retention > 0 {
+		tally.result.Retention = &RetentionStats{
+			Retention: ms.retention.String(),
+			NextSweep: ms.nextSweep,
+			Purged:    ms.retentionPurged,
+		}
+	}
+	return tally.stats()
+}
+
+// RegistryStats summarizes the Items of one registry. Count and ByType only
+// count non-deleted Items, and LastUpdated is nil for an empty registry.
+type RegistryStats struct {
+	Name        string         `json:"name"`
+	Count       int            `json:"count"`
+	Deleted     int            `json:"deleted"`
+	ByType      map[string]int `json:"byType"`
+	LastUpdated *time.Time     `json:"lastUpdated,omitempty"`
+}
+
+// RegistryStatsStore is implemented by stores that can compute the
+// RegistryStats of a registry without listing every Item
+type RegistryStatsStore interface {
+	RegistryStats(registryName string) RegistryStats
+}
+
+var _ RegistryStatsStore = (*MemoryStorage)(nil)
+
+// RegistryStats computes the RegistryStats of registryName from the registry
+// name indexes, visiting only that registry's Items
+func (ms *MemoryStorage) RegistryStats(registryName string) RegistryStats {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	tally := newRegistryTally(registryName)
+	for _, item := range ms.byRegistry[registryName] {
+		tally.add(item)
+	}
+	for _, item := range ms.deletedByRegistry[registryName] {
+		tally.add(item)
+	}
+	return tally.result
+}
+
+// ComputeRegistryStats computes the RegistryStats of registryName from a
+// listing that includes deleted Items, for stores that do not implement
+// RegistryStatsStore
+func ComputeRegistryStats(registryName string, items []registry.Registerable) RegistryStats {
+	tally := newRegistryTally(registryName)
+	for _, item := range items {
+		if itemObj, ok := item.(*registry.Item); ok && itemObj.RegistryName == registryName {
+			tally.add(itemObj)
+		}
+	}
+	return tally.result
+}
+
+type registryTally struct {
+	result RegistryStats
+}
+
+func newRegistryTally(registryName string) *registryTally {
+	return &registryTally{result: RegistryStats{Name: registryName, ByType: make(map[string]int)}}
+}
+
+func (t *registryTally) add(item *registry.Item) {
+	if t.result.LastUpdated == nil || item.UpdatedAt.After(*t.result.LastUpdated) {
+		updated := item.UpdatedAt
+		t.result.LastUpdated = &updated
+	}
+	if item.IsDeleted() {
+		t.result.Deleted++
+		return
+	}
+	t.result.Count++
+	t.result.ByType[item.Type]++
+}
+
+// ComputeStats computes Stats from a listing that includes deleted Items,
+// for stores that do not implement StatsStore
+func ComputeStats(items []registry.Registerable) Stats {
+	tally := newStatsTally()
+	for _, item := range items {
+		if itemObj, ok := item.(*registry.Item); ok {
+			tally.add(itemObj)
+		}
+	}
+	return tally.stats()
+}
+
+type statsTally struct {
+	result     Stats
+	registries map[string]bool
+	sizes      []int
+}
+
+func newStatsTally() *statsTally {
+	return &statsTally{
+		result:     Stats{ByType: make(map[string]int), IndexedMetadataKeys: []string{}},
+		registries: make(map[string]bool),
+	}
+}
+
+func (t *statsTally) add(item *registry.Item) {
+	t.result.Total++
+	if item.IsDeleted() {
+		t.result.Deleted++
+		return
+	}
+	t.result.Active++
+	t.result.ByType[item.Type]++
+	t.registries[item.RegistryName] = true
+	t.sizes = append(t.sizes, item.MetadataSize())
+}
+
+func (t *statsTally) stats() Stats {
+	t.result.Types = len(t.result.ByType)
+	t.result.Registries = len(t.registries)
+	t.result.MetadataSize = sizeStats(t.sizes)
+	return t.result
+}
+
+// sizeStats summarizes sizes, taking the 95th percentile by nearest rank
+func sizeStats(sizes []int) SizeStats {
+	if len(sizes) == 0 {
+		return SizeStats{}
+	}
+	sort.Ints(sizes)
+	total := 0
+	for _, size := range sizes {
+		total += size
+	}
+	rank := (len(sizes)*95 + 99) / 100
+	return SizeStats{
+		Avg: total / len(sizes),
+		P95: sizes[rank-1],
+		Max: sizes[len(sizes)-1],
+	}
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/google/uuid"
+)
+
+// Store is the storage contract the HTTP API depends on. It extends
+// registry.Registry with the item-oriented helpers used by the handlers.
+// Listings return Items in DefaultSort order.
+type Store interface {
+	registry.Registry
+
+	ListByRegistryName(registryName string) []registry.Registerable
+	ListByMetadata(filters map[string]string) []registry.Registerable
+	ListByTag(tags ...string) []registry.Registerable
+	ListTags() []TagCount
+	ListRegistryNames() []RegistryCount
+	// Deprecated: offsets shift as Items are added and removed; use ListPage
+	ListPaginated(limit, offset int) []registry.Registerable
+	ListIncludingDeleted() []registry.Registerable
+	SearchItems(query string) []SearchResult
+	Count(filter CountFilter) int
+
+	CreateItem(item *registry.Item) (*registry.Item, error)
+	CreateItems(items []*registry.Item) []BatchResult
+	GetItem(id string) (*registry.Item, error)
+	UpdateItem(item *registry.Item) (*registry.Item, error)
+	DeleteItem(id string) error
+	RestoreItem(id string) (*registry.Item, error)
+	AddLinks(id, relation string, targets []string) (*registry.Item, error)
+
+	// Purging permanently removes Items, unlike the soft delete of DeleteItem
+	PurgeItem(id string) error
+	PurgeDeleted() (int, error)
+
+	// Context-aware variants record tracing spans as children of ctx
+	CreateItemCtx(ctx context.Context, item *registry.Item) (*registry.Item, error)
+	GetItemCtx(ctx context.Context, id string) (*registry.Item, error)
+	UpdateItemCtx(ctx context.Context, item *registry.Item) (*registry.Item, error)
+	DeleteItemCtx(ctx context.Context, id string) error
+
+	// Cancellable scans stop early and return ctx.Err() once ctx is done
+	ListCtx(ctx context.Context) ([]registry.Registerable, error)
+	ListIncludingDeletedCtx(ctx context.Context) ([]registry.Registerable, error)
+	ListByRegistryNameCtx(ctx context.Context, registryName string) ([]registry.Registerable, error)
+	ListByMetadataCtx(ctx context.Context, filters map[string]string) ([]registry.Registerable, error)
+	ListByTagCtx(ctx context.Context, tags ...string) ([]registry.Registerable, error)
+	// ListUpdatedSinceCtx includes soft-deleted Items, so that clients
+	// syncing incrementally also see deletions
+	ListUpdatedSinceCtx(ctx context.Context, since time.Time) ([]registry.Registerable, error)
+	SearchItemsCtx(ctx context.Context, query string) ([]SearchResult, error)
+
+	// ListPage returns up to limit non-deleted Items ordered by CreatedAt and
+	// then ID, starting after the position encoded by cursor. An empty cursor
+	// starts at the first Item; a malformed one returns ErrInvalidCursor.
+	ListPage(ctx context.Context, limit int, cursor string) (Page, error)
+
+	// Ping reports whether the backend can currently serve requests
+	Ping(ctx context.Context) error
+}
+
+// scanCheckInterval is the number of items a cancellable scan visits between
+// checks of its context
+const scanCheckInterval = 256
+
+var _ Store = (*MemoryStorage)(nil)
+
+// CountFilter narrows Count to the Items matching every non-empty field.
+// Soft-deleted Items are only counted when IncludeDeleted is set.
+type CountFilter struct {
+	Type           string
+	RegistryName   string
+	IncludeDeleted bool
+}
+
+// matches reports whether item passes the filter
+func (f CountFilter) matches(item *registry.Item) bool {
+	return (f.IncludeDeleted || !item.IsDeleted()) &&
+		(f.Type == "" || item.Type == f.Type) &&
+		(f.RegistryName == "" || item.RegistryName == f.RegistryName)
+}
+
+// TagCount is the number of non-deleted items carrying a tag
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// RegistryCount is the number of non-deleted items in a registry
+type RegistryCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// countRegistries tallies the registry names of items, ordered by name
+func countRegistries(items []*registry.Item) []RegistryCount {
+	counts := make(map[string]int)
+	for _, item := range items {
+		counts[item.RegistryName]++
+	}
+
+	result := make([]RegistryCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, RegistryCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result
+}
+
+// countTags tallies the tags of items, ordered by tag name
+func countTags(items []*registry.Item) []TagCount {
+	counts := make(map[string]int)
+	for _, item := range items {
+		for _, tag := range item.Tags {
+			counts[tag]++
+		}
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Tag < result[j].Tag })
+
+	return result
+}
+
+// BatchResult reports the outcome of storing a single item in a batch
+type BatchResult struct {
+	Index   int    `json:"index"`
+	ID      string `json:"id,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// newBatchResult assigns an ID to item when it has none, stores it with
+// register, and records the outcome for the item at index
+func newBatchResult(index int, item *registry.Item, register func(*registry.Item) error) BatchResult {
+	result := BatchResult{Index: index}
+	if item == nil {
+		result.Error = "item must not be null"
+		return result
+	}
+
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+	result.ID = item.ID
+
+	if err := item.Validate(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if err := register(item); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// markDeleted restores the deleted flag on an Item loaded from a persistent
+// backend. SoftDelete stamps UpdatedAt, so the persisted timestamp is kept,
+// and is also taken as the deletion time, since deleting was the last change.
+func markDeleted(item *registry.Item) {
+	updatedAt := item.UpdatedAt
+	item.SoftDelete()
+	item.UpdatedAt = updatedAt
+	item.DeletedAt = updatedAt
+}
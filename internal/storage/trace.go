@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Cdaprod/registry-service/internal/storage")
+
+// startSpan starts a child span of ctx for a storage operation on the item id
+func startSpan(ctx context.Context, name, id string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attribute.String("item.id", id)))
+}
+
+// endSpan records err, if any, on span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceOp records a single span around op for backends that have no finer
+// grained instrumentation
+func traceOp(ctx context.Context, name, id string, op func() error) error {
+	_, span := startSpan(ctx, name, id)
+	err := op()
+	endSpan(span, err)
+	return err
+}
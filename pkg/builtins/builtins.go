@@ -1,59 +1,318 @@
 package builtins
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"plugin"
+	"sync"
 
+	"github.com/Cdaprod/registry-service/internal/reconcile"
 	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/Cdaprod/registry-service/pkg/plugins"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
 )
 
 // BuiltinLoader manages loading and registering built-in plugins
 type BuiltinLoader struct {
 	registry   registry.Registry
 	pluginsDir string
+
+	mu     sync.Mutex
+	loaded map[string]bool
+	// shutdowns holds the Shutdown hooks of loaded plugins in load order
+	shutdowns []shutdownHook
+	// lifecycle calls the hooks of loaded plugins that export a Lifecycle
+	lifecycle *plugins.LifecycleDispatcher
+	// scheduler runs the Reconciler of loaded plugins that export one; nil
+	// ignores them
+	scheduler *reconcile.Scheduler
+
+	logger  *zap.Logger
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// shutdownHook is the Shutdown function exported by the plugin at path
+type shutdownHook struct {
+	path string
+	fn   func() error
 }
 
 // NewBuiltinLoader initializes a new BuiltinLoader with the registry and plugins directory
-func NewBuiltinLoader(reg registry.Registry, pluginsDir string) *BuiltinLoader {
+func NewBuiltinLoader(reg registry.Registry, pluginsDir string, logger *zap.Logger) *BuiltinLoader {
 	return &BuiltinLoader{
 		registry:   reg,
 		pluginsDir: pluginsDir,
+		loaded:     make(map[string]bool),
+		lifecycle:  plugins.NewLifecycleDispatcher(reg, logger),
+		logger:     logger,
+	}
+}
+
+// Lifecycle returns the dispatcher of the Lifecycle hooks of loaded plugins.
+// Start it with the broker item events are published to.
+func (bl *BuiltinLoader) Lifecycle() *plugins.LifecycleDispatcher {
+	return bl.lifecycle
+}
+
+// SetScheduler adds the Reconciler of every plugin loaded from now on to s,
+// under the plugin's name. Call it before LoadAll.
+func (bl *BuiltinLoader) SetScheduler(s *reconcile.Scheduler) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	bl.scheduler = s
+}
+
+// NewBuiltinLoaderWithWatch initializes a BuiltinLoader that also watches the
+// plugins directory and registers any .so file that appears in it until Stop
+// is called. Go cannot unload plugins, so removed or changed files are only
+// logged as warnings and stay registered. A missing plugins directory is not
+// watched.
+func NewBuiltinLoaderWithWatch(reg registry.Registry, pluginsDir string, logger *zap.Logger) (*BuiltinLoader, error) {
+	bl := NewBuiltinLoader(reg, pluginsDir, logger)
+
+	if exists, err := plugins.CheckPluginsDir(pluginsDir); !exists {
+		if err == nil {
+			logger.Info("Plugins directory does not exist; not watching it", zap.String("dir", pluginsDir))
+		}
+		return bl, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugins watcher: %v", err)
 	}
+	if err := watcher.Add(pluginsDir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch plugins directory: %v", err)
+	}
+
+	bl.watcher = watcher
+	bl.done = make(chan struct{})
+	go bl.watch()
+
+	return bl, nil
+}
+
+// ReloadResult reports what a Reload did with each plugin file
+type ReloadResult struct {
+	// Registered lists the plugins registered by this run
+	Registered []string
+	// AlreadyLoaded lists the plugins that were registered before
+	AlreadyLoaded []string
+	// Errors holds the plugins that failed to load
+	Errors plugins.LoadErrors
 }
 
-// LoadAll loads and registers all built-in plugins from the specified directory
+// LoadAll loads and registers all built-in plugins from the specified
+// directory. A plugin that fails to load does not stop the others; failures
+// are returned together as plugins.LoadErrors. A missing plugins directory
+// loads nothing.
 func (bl *BuiltinLoader) LoadAll() error {
-	err := filepath.Walk(bl.pluginsDir, func(path string, info os.FileInfo, err error) error {
+	result, err := bl.Reload()
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return result.Errors
+	}
+	return nil
+}
+
+// Reload registers the plugins in the directory that are not registered yet
+// and reports which ones it registered and which were already loaded. It can
+// be called any number of times. A plugin whose items the registry already
+// holds counts as already loaded. Only a plugins directory that cannot be
+// read is returned as an error.
+func (bl *BuiltinLoader) Reload() (ReloadResult, error) {
+	var result ReloadResult
+	if exists, err := plugins.CheckPluginsDir(bl.pluginsDir); !exists {
+		if err == nil {
+			bl.logger.Info("Plugins directory does not exist; no built-in plugins loaded", zap.String("dir", bl.pluginsDir))
+		}
+		return result, err
+	}
+
+	filepath.Walk(bl.pluginsDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			result.Errors = append(result.Errors, plugins.PluginLoadError{Path: path, Err: err})
+			return nil
 		}
 		if filepath.Ext(path) != ".so" {
 			return nil // Skip non-plugin files
 		}
 
-		p, err := plugin.Open(path)
-		if err != nil {
-			return fmt.Errorf("failed to open plugin: %v", err)
+		registered, err := bl.load(path)
+		switch {
+		case err != nil:
+			result.Errors = append(result.Errors, plugins.PluginLoadError{Path: path, Err: err})
+		case registered:
+			result.Registered = append(result.Registered, path)
+		default:
+			result.AlreadyLoaded = append(result.AlreadyLoaded, path)
 		}
+		return nil
+	})
 
-		symRegister, err := p.Lookup("Register")
-		if err != nil {
-			return fmt.Errorf("failed to find Register function in %v: %v", path, err)
+	return result, nil
+}
+
+// Stop ends the directory watcher, if one is running, and waits for it to exit
+func (bl *BuiltinLoader) Stop() error {
+	if bl.watcher == nil {
+		return nil
+	}
+	err := bl.watcher.Close()
+	<-bl.done
+	return err
+}
+
+// Shutdown calls the Shutdown hook of every loaded plugin that exports one,
+// in the reverse order of loading, so plugins can release their resources.
+// A failing hook is logged and does not stop the others.
+func (bl *BuiltinLoader) Shutdown() error {
+	bl.mu.Lock()
+	hooks := bl.shutdowns
+	bl.shutdowns = nil
+	bl.mu.Unlock()
+
+	failed := 0
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].fn(); err != nil {
+			bl.logger.Error("Plugin failed to shut down", zap.String("path", hooks[i].path), zap.Error(err))
+			failed++
 		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d plugin(s) failed to shut down", failed)
+	}
+	return nil
+}
+
+// load opens the plugin at path and registers it, reporting whether it did.
+// Paths that were already registered are skipped, and so are plugins whose
+// items the registry already holds, as a CentralRegistry does once the
+// service registered them before.
+func (bl *BuiltinLoader) load(path string) (bool, error) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	if bl.loaded[path] {
+		return false, nil
+	}
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open plugin: %v", err)
+	}
+
+	symRegister, err := p.Lookup("Register")
+	if err != nil {
+		return false, fmt.Errorf("failed to find Register function in %v: %v", path, err)
+	}
+
+	registerFunc, err := plugins.AdaptRegister(symRegister)
+	if err != nil {
+		return false, fmt.Errorf("invalid Register function in plugin %v: %v", path, err)
+	}
+
+	registered := true
+	if err := registerFunc(context.Background(), bl.registry); errors.Is(err, registry.ErrAlreadyRegistered) {
+		registered = false
+	} else if err != nil {
+		return false, fmt.Errorf("failed to register built-in plugin: %v", err)
+	}
+
+	if err := plugins.RegisterMetadata(bl.registry, p, path); err != nil {
+		return false, fmt.Errorf("failed to record built-in plugin metadata: %v", err)
+	}
 
-		registerFunc, ok := symRegister.(func(reg registry.Registry) error)
+	// The Shutdown hook is optional
+	if symShutdown, err := p.Lookup("Shutdown"); err == nil {
+		shutdownFunc, ok := symShutdown.(func() error)
 		if !ok {
-			return fmt.Errorf("invalid Register function signature in plugin: %v", path)
+			bl.logger.Warn("Ignoring Shutdown with invalid signature in plugin", zap.String("path", path))
+		} else {
+			bl.shutdowns = append(bl.shutdowns, shutdownHook{path: path, fn: shutdownFunc})
 		}
+	}
 
-		if err := registerFunc(bl.registry); err != nil {
-			return fmt.Errorf("failed to register built-in plugin: %v", err)
+	// So is the Lifecycle, for plugins that react to item changes
+	if symLifecycle, err := p.Lookup("Lifecycle"); err == nil {
+		lifecycle, err := plugins.AdaptLifecycle(symLifecycle)
+		if err != nil {
+			bl.logger.Warn("Ignoring invalid Lifecycle in plugin", zap.String("path", path), zap.Error(err))
+		} else {
+			bl.lifecycle.Add(path, lifecycle)
 		}
+	}
 
-		return nil
-	})
+	// And the Reconciler, for plugins that keep their items in sync with
+	// an external system
+	if symReconciler, err := p.Lookup("Reconciler"); err == nil && bl.scheduler != nil {
+		reconciler, err := plugins.AdaptReconciler(symReconciler)
+		if err != nil {
+			bl.logger.Warn("Ignoring invalid Reconciler in plugin", zap.String("path", path), zap.Error(err))
+		} else {
+			bl.scheduler.Add(plugins.PluginName(p, path), reconciler)
+		}
+	}
 
-	return err
+	bl.loaded[path] = true
+	return registered, nil
+}
+
+// isLoaded reports whether the plugin at path has been registered
+func (bl *BuiltinLoader) isLoaded(path string) bool {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	return bl.loaded[path]
+}
+
+// watch registers plugins as they appear in the plugins directory
+func (bl *BuiltinLoader) watch() {
+	defer close(bl.done)
+
+	for {
+		select {
+		case event, ok := <-bl.watcher.Events:
+			if !ok {
+				return
+			}
+			bl.handleEvent(event)
+		case err, ok := <-bl.watcher.Errors:
+			if !ok {
+				return
+			}
+			bl.logger.Error("Plugins watcher error", zap.Error(err))
+		}
+	}
+}
+
+func (bl *BuiltinLoader) handleEvent(event fsnotify.Event) {
+	if filepath.Ext(event.Name) != ".so" {
+		return
+	}
+
+	if bl.isLoaded(event.Name) {
+		if event.Has(fsnotify.Write) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+			bl.logger.Warn("Plugin changed on disk but cannot be unloaded; restart to apply",
+				zap.String("path", event.Name), zap.String("op", event.Op.String()))
+		}
+		return
+	}
+
+	// A newly created file may still be being written, so a failed load is
+	// retried on the write events that follow
+	if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+		if _, err := bl.load(event.Name); err != nil {
+			bl.logger.Debug("Plugin not loaded yet", zap.String("path", event.Name), zap.Error(err))
+			return
+		}
+		bl.logger.Info("Registered new plugin", zap.String("path", event.Name))
+	}
 }
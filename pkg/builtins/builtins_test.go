@@ -0,0 +1,90 @@
+package builtins
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+
+	"github.com/Cdaprod/registry-service/internal/registry/registrytest"
+	"github.com/Cdaprod/registry-service/pkg/plugins"
+	"go.uber.org/zap"
+)
+
+// buildTestPlugins builds the valid plugin of the plugins package tests into
+// a new directory as valid.so, next to a broken.so that is not a plugin, and
+// returns the directory
+func buildTestPlugins(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building a plugin is slow")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	// Plugins only open in binaries built with the same flags, such as -race
+	args := []string{"build", "-buildmode=plugin", "-o", filepath.Join(dir, "valid.so")}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "-race" && setting.Value == "true" {
+				args = append(args, "-race")
+			}
+		}
+	}
+	out, err := exec.Command(goBin, append(args, "../plugins/testdata/valid")...).CombinedOutput()
+	if err != nil {
+		t.Skipf("cannot build plugins here: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.so"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoadAllContinuesAfterFailure(t *testing.T) {
+	dir := buildTestPlugins(t)
+	reg := registrytest.NewFakeRegistry()
+	bl := NewBuiltinLoader(reg, dir, zap.NewNop())
+
+	err := bl.LoadAll()
+
+	var loadErrs plugins.LoadErrors
+	if !errors.As(err, &loadErrs) {
+		t.Fatalf("LoadAll() = %v, want plugins.LoadErrors", err)
+	}
+	if len(loadErrs) != 1 || loadErrs[0].Path != filepath.Join(dir, "broken.so") {
+		t.Errorf("LoadAll() errors = %v, want only broken.so", loadErrs)
+	}
+	if _, ok := reg.Get("valid"); !ok {
+		t.Error("valid plugin was not registered")
+	}
+
+	// Loading again keeps the valid plugin and reports the broken one again
+	result, err := bl.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.AlreadyLoaded) != 1 || len(result.Registered) != 0 || len(result.Errors) != 1 {
+		t.Errorf("Reload() = %+v, want valid.so already loaded and broken.so failing", result)
+	}
+}
+
+func TestLoadAllMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	reg := registrytest.NewFakeRegistry()
+
+	if err := NewBuiltinLoader(reg, dir, zap.NewNop()).LoadAll(); err != nil {
+		t.Errorf("LoadAll() = %v, want nil", err)
+	}
+	bl, err := NewBuiltinLoaderWithWatch(reg, dir, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewBuiltinLoaderWithWatch() = %v, want nil", err)
+	}
+	if err := bl.Stop(); err != nil {
+		t.Errorf("Stop() = %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+// Package client is a Go client for the registry service REST API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// Item is the registry item exchanged with the API
+type Item = registry.Item
+
+// Default retry settings for requests that fail with a 5xx status
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = 200 * time.Millisecond
+)
+
+// Client calls the /api/v1 endpoints of a registry service
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	// APIKey is sent as X-API-Key when set
+	APIKey string
+	// BearerToken is sent as a bearer Authorization header when set
+	BearerToken string
+
+	// MaxRetries bounds the retries of idempotent requests that fail with a
+	// 5xx status or a transport error; zero disables retrying
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled for each
+	// following one
+	RetryBackoff time.Duration
+}
+
+// NewClient creates a client for the service at baseURL, such as
+// "http://localhost:7777". A nil httpClient uses http.DefaultClient.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:      strings.TrimRight(baseURL, "/") + "/api/v1",
+		httpClient:   httpClient,
+		MaxRetries:   DefaultMaxRetries,
+		RetryBackoff: DefaultRetryBackoff,
+	}
+}
+
+// ListOptions filters and pages ListItems. The zero value lists every
+// non-deleted item.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Sort is one of the API sort fields, such as "name" or "createdAt"
+	Sort string
+	// Order is "asc" or "desc"
+	Order          string
+	Tags           []string
+	Metadata       map[string]string
+	IncludeDeleted bool
+}
+
+// ItemList is one page of ListItems results
+type ItemList struct {
+	Items []*Item
+	// Total is the number of items matching the filters across all pages
+	Total int
+}
+
+// CreateItem registers item and returns the stored item with its ID
+func (c *Client) CreateItem(ctx context.Context, item *Item) (*Item, error) {
+	var created Item
+	if _, err := c.do(ctx, http.MethodPost, "/items", nil, item, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetItem returns the item with the given ID
+func (c *Client) GetItem(ctx context.Context, id string) (*Item, error) {
+	var item Item
+	if _, err := c.do(ctx, http.MethodGet, "/items/"+url.PathEscape(id), nil, nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdateItem replaces the item with item.ID and returns the stored item
+func (c *Client) UpdateItem(ctx context.Context, item *Item) (*Item, error) {
+	var updated Item
+	if _, err := c.do(ctx, http.MethodPut, "/items/"+url.PathEscape(item.ID), nil, item, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteItem soft-deletes the item with the given ID
+func (c *Client) DeleteItem(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/items/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// ListItems lists the items matching opts
+func (c *Client) ListItems(ctx context.Context, opts ListOptions) (*ItemList, error) {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	if opts.Order != "" {
+		query.Set("order", opts.Order)
+	}
+	for _, tag := range opts.Tags {
+		query.Add("tag", tag)
+	}
+	for key, value := range opts.Metadata {
+		query.Set("meta."+key, value)
+	}
+	if opts.IncludeDeleted {
+		query.Set("includeDeleted", "true")
+	}
+
+	var items []*Item
+	header, err := c.do(ctx, http.MethodGet, "/items", query, nil, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &ItemList{Items: items, Total: len(items)}
+	if total, err := strconv.Atoi(header.Get("X-Total-Count")); err == nil {
+		list.Total = total
+	}
+	return list, nil
+}
+
+// ListByRegistry lists the items in the named registry
+func (c *Client) ListByRegistry(ctx context.Context, registryName string) ([]*Item, error) {
+	var items []*Item
+	if _, err := c.do(ctx, http.MethodGet, "/registry/"+url.PathEscape(registryName)+"/list", nil, nil, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// do sends the request, retrying idempotent ones on 5xx responses and
+// transport errors, and decodes a 2xx JSON response into out when it is
+// non-nil. It returns the response headers.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) (http.Header, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	retries := 0
+	if method != http.MethodPost {
+		retries = c.MaxRetries
+	}
+
+	backoff := c.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		header, err := c.send(ctx, method, target, body, out)
+		if err == nil || attempt >= retries || ctx.Err() != nil || !retryable(err) {
+			return header, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send performs a single attempt of the request
+func (c *Client) send(ctx context.Context, method, target string, body []byte, out interface{}) (http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newAPIError(resp)
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// retryable reports whether a failed attempt may succeed when repeated: a
+// 5xx response or a transport error
+func retryable(err error) bool {
+	switch err := err.(type) {
+	case *APIError:
+		return err.StatusCode >= 500
+	case *url.Error:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,446 @@
+// Package config loads the server settings from an optional JSON or YAML
+// file, with environment variables taking precedence over the file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the server settings
+type Config struct {
+	// BindAddress is the host:port the HTTP server listens on
+	BindAddress string `yaml:"bindAddress"`
+	// GRPCAddress is the host:port the gRPC server listens on
+	GRPCAddress string `yaml:"grpcAddress"`
+	// LogLevel is one of debug, info, warn or error
+	LogLevel string `yaml:"logLevel"`
+	// LogFormat is "json" or "console" for colored, human-friendly output
+	LogFormat string `yaml:"logFormat"`
+	// LogSampling thins out repeated JSON log entries
+	LogSampling LogSamplingConfig `yaml:"logSampling"`
+	// LogFile also writes the logs to a rotating file
+	LogFile LogFileConfig `yaml:"logFile"`
+	// StaticDir holds the built web frontend, unless it is embedded with the
+	// embedweb build tag
+	StaticDir string `yaml:"staticDir"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// once the server is told to stop
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+
+	Storage StorageConfig `yaml:"storage"`
+	Plugins PluginsConfig `yaml:"plugins"`
+	CORS    CORSConfig    `yaml:"cors"`
+	Audit   AuditConfig   `yaml:"audit"`
+}
+
+// LogSamplingConfig logs the first Initial entries with the same level and
+// message each second, then every Thereafter-th one. A zero Initial disables
+// sampling.
+type LogSamplingConfig struct {
+	Initial    int `yaml:"initial"`
+	Thereafter int `yaml:"thereafter"`
+}
+
+// LogFileConfig configures the rotating log file, which is disabled while
+// Path is empty. Zero MaxAgeDays or MaxBackups keeps every rotated file.
+type LogFileConfig struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"maxSizeMB"`
+	MaxAgeDays int    `yaml:"maxAgeDays"`
+	MaxBackups int    `yaml:"maxBackups"`
+}
+
+// StorageConfig selects and configures the storage backend
+type StorageConfig struct {
+	// Backend is one of memory, bolt, sqlite, postgres or redis
+	Backend string `yaml:"backend"`
+
+	// HistoryLimit caps the revisions kept per item by the memory backend;
+	// zero keeps every revision
+	HistoryLimit int `yaml:"historyLimit"`
+	// ExpirySweepInterval enables expiry sweeping in the memory backend
+	ExpirySweepInterval time.Duration `yaml:"expirySweepInterval"`
+	// DeletedRetention makes the memory backend purge items soft-deleted
+	// longer ago, on every expiry sweep; zero keeps them until purged
+	DeletedRetention time.Duration `yaml:"deletedRetention"`
+	// Cache fronts the persistent backends with an item cache
+	Cache CacheConfig `yaml:"cache"`
+	// NaturalKey lists the item fields, out of registryName, name and type,
+	// whose combination the memory backend keeps unique; empty allows
+	// duplicates
+	NaturalKey []string `yaml:"naturalKey"`
+	// IndexedMetadataKeys lists the top-level metadata keys the memory
+	// backend indexes, so that filtering on them does not scan every item
+	IndexedMetadataKeys []string `yaml:"indexedMetadataKeys"`
+
+	BoltPath      string `yaml:"boltPath"`
+	SQLiteDSN     string `yaml:"sqliteDSN"`
+	DatabaseURL   string `yaml:"databaseURL"`
+	RedisAddr     string `yaml:"redisAddr"`
+	RedisPassword string `yaml:"redisPassword"`
+	RedisDB       int    `yaml:"redisDB"`
+}
+
+// CacheConfig bounds the LRU cache of item reads. A zero Size disables the
+// cache, and a zero TTL keeps entries until they are evicted or invalidated.
+type CacheConfig struct {
+	Size int           `yaml:"size"`
+	TTL  time.Duration `yaml:"ttl"`
+}
+
+// PluginsConfig configures plugin loading
+type PluginsConfig struct {
+	Dir string `yaml:"dir"`
+	// Loader is "so" for Go plugins or "rpc" for out-of-process plugins
+	Loader string `yaml:"loader"`
+	// Watch hot-reloads Go plugins added to Dir
+	Watch bool `yaml:"watch"`
+	// ReconcileInterval is the time between reconciliations of Go plugins
+	// that export a Reconciler, and ReconcileIntervals overrides it by
+	// plugin name
+	ReconcileInterval  time.Duration            `yaml:"reconcileInterval"`
+	ReconcileIntervals map[string]time.Duration `yaml:"reconcileIntervals"`
+}
+
+// CORSConfig configures cross-origin access to the API
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowedOrigins"`
+	AllowedMethods   []string `yaml:"allowedMethods"`
+	AllowedHeaders   []string `yaml:"allowedHeaders"`
+	AllowCredentials bool     `yaml:"allowCredentials"`
+}
+
+// AuditConfig configures the audit trail of item mutations
+type AuditConfig struct {
+	// Sink is "stdout", "stderr" or a file path the entries are appended
+	// to; empty only keeps the retained entries in memory
+	Sink string `yaml:"sink"`
+	// Retain is the number of recent entries kept for GET /api/v1/audit
+	Retain int `yaml:"retain"`
+}
+
+// Default returns the settings used when neither a file nor the environment
+// sets a value
+func Default() *Config {
+	return &Config{
+		BindAddress: "0.0.0.0:7777",
+		GRPCAddress: "0.0.0.0:7778",
+		LogLevel:    "info",
+		LogFormat:   "json",
+		LogSampling: LogSamplingConfig{
+			Initial:    100,
+			Thereafter: 100,
+		},
+		LogFile: LogFileConfig{
+			MaxSizeMB: 100,
+		},
+		StaticDir:       "./web/build",
+		ShutdownTimeout: 30 * time.Second,
+		Storage: StorageConfig{
+			Backend:      "memory",
+			HistoryLimit: 100,
+			BoltPath:     "registry.db",
+			SQLiteDSN:    "registry.sqlite",
+			RedisAddr:    "localhost:6379",
+			Cache: CacheConfig{
+				Size: 1024,
+				TTL:  30 * time.Second,
+			},
+		},
+		Plugins: PluginsConfig{
+			Dir:               "pkg/plugins/",
+			Loader:            "so",
+			ReconcileInterval: 5 * time.Minute,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "If-Match", "If-None-Match", "X-Lock-Token", "X-Actor", "Idempotency-Key"},
+		},
+		Audit: AuditConfig{
+			Retain: 1000,
+		},
+	}
+}
+
+// Load reads the config file at path over the defaults, then applies the
+// environment overrides and validates the result. JSON files are read as
+// YAML, of which JSON is a subset. An empty path skips the file.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open config file: %w", err)
+		}
+		defer f.Close()
+
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides the settings with the environment variables the server
+// has always read, so existing deployments keep working without a file
+func (c *Config) applyEnv() error {
+	setString(&c.BindAddress, "BIND_ADDRESS")
+	if port := os.Getenv("PORT"); port != "" {
+		c.BindAddress = withPort(c.BindAddress, port)
+	}
+	setString(&c.GRPCAddress, "GRPC_ADDRESS")
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		c.GRPCAddress = withPort(c.GRPCAddress, port)
+	}
+	setString(&c.LogLevel, "LOG_LEVEL")
+	if os.Getenv("APP_ENV") == "development" {
+		c.LogFormat = "console"
+	}
+	setString(&c.LogFormat, "LOG_FORMAT")
+	setString(&c.LogFile.Path, "LOG_FILE")
+	for key, dst := range map[string]*int{
+		"LOG_SAMPLING_INITIAL":    &c.LogSampling.Initial,
+		"LOG_SAMPLING_THEREAFTER": &c.LogSampling.Thereafter,
+		"LOG_MAX_SIZE_MB":         &c.LogFile.MaxSizeMB,
+		"LOG_MAX_AGE_DAYS":        &c.LogFile.MaxAgeDays,
+		"LOG_MAX_BACKUPS":         &c.LogFile.MaxBackups,
+	} {
+		if err := setInt(dst, key); err != nil {
+			return err
+		}
+	}
+	setString(&c.StaticDir, "STATIC_DIR")
+	if value := os.Getenv("SHUTDOWN_TIMEOUT"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %w", err)
+		}
+		c.ShutdownTimeout = d
+	}
+
+	setString(&c.Storage.Backend, "STORAGE_BACKEND")
+	setString(&c.Storage.BoltPath, "BOLT_PATH")
+	setString(&c.Storage.SQLiteDSN, "SQLITE_DSN")
+	setString(&c.Storage.DatabaseURL, "DATABASE_URL")
+	setString(&c.Storage.RedisAddr, "REDIS_ADDR")
+	setString(&c.Storage.RedisPassword, "REDIS_PASSWORD")
+	if err := setInt(&c.Storage.RedisDB, "REDIS_DB"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Storage.HistoryLimit, "HISTORY_LIMIT"); err != nil {
+		return err
+	}
+	if value := os.Getenv("EXPIRY_SWEEP_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid EXPIRY_SWEEP_INTERVAL: %w", err)
+		}
+		c.Storage.ExpirySweepInterval = d
+	}
+	if value := os.Getenv("DELETED_RETENTION"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid DELETED_RETENTION: %w", err)
+		}
+		c.Storage.DeletedRetention = d
+	}
+	setList(&c.Storage.NaturalKey, "NATURAL_KEY")
+	setList(&c.Storage.IndexedMetadataKeys, "INDEXED_METADATA_KEYS")
+	if err := setInt(&c.Storage.Cache.Size, "CACHE_SIZE"); err != nil {
+		return err
+	}
+	if value := os.Getenv("CACHE_TTL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid CACHE_TTL: %w", err)
+		}
+		c.Storage.Cache.TTL = d
+	}
+
+	setString(&c.Plugins.Dir, "PLUGINS_DIR")
+	setString(&c.Plugins.Loader, "PLUGIN_LOADER")
+	if err := setBool(&c.Plugins.Watch, "PLUGINS_WATCH"); err != nil {
+		return err
+	}
+	if value := os.Getenv("RECONCILE_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid RECONCILE_INTERVAL: %w", err)
+		}
+		c.Plugins.ReconcileInterval = d
+	}
+
+	setString(&c.Audit.Sink, "AUDIT_SINK")
+	if err := setInt(&c.Audit.Retain, "AUDIT_RETAIN"); err != nil {
+		return err
+	}
+
+	setList(&c.CORS.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	setList(&c.CORS.AllowedMethods, "CORS_ALLOWED_METHODS")
+	setList(&c.CORS.AllowedHeaders, "CORS_ALLOWED_HEADERS")
+	return setBool(&c.CORS.AllowCredentials, "CORS_ALLOW_CREDENTIALS")
+}
+
+// Validate reports settings the server cannot start with
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.BindAddress); err != nil {
+		return fmt.Errorf("invalid bind address %q: %w", c.BindAddress, err)
+	}
+	if _, _, err := net.SplitHostPort(c.GRPCAddress); err != nil {
+		return fmt.Errorf("invalid gRPC address %q: %w", c.GRPCAddress, err)
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("unknown log level: %s", c.LogLevel)
+	}
+	switch c.LogFormat {
+	case "json", "console":
+	default:
+		return fmt.Errorf("unknown log format: %s", c.LogFormat)
+	}
+	if c.LogSampling.Initial < 0 || c.LogSampling.Thereafter < 0 {
+		return errors.New("log sampling settings must not be negative")
+	}
+	if c.LogFile.MaxSizeMB < 0 || c.LogFile.MaxAgeDays < 0 || c.LogFile.MaxBackups < 0 {
+		return errors.New("log file rotation settings must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		return errors.New("shutdown timeout must be positive")
+	}
+
+	switch c.Storage.Backend {
+	case "memory", "bolt", "sqlite", "redis":
+	case "postgres":
+		if c.Storage.DatabaseURL == "" {
+			return errors.New("DATABASE_URL is required for the postgres storage backend")
+		}
+	default:
+		return fmt.Errorf("unknown storage backend: %s", c.Storage.Backend)
+	}
+	if c.Storage.Cache.Size < 0 || c.Storage.Cache.TTL < 0 {
+		return errors.New("cache settings must not be negative")
+	}
+	if err := storage.ValidateNaturalKey(c.Storage.NaturalKey); err != nil {
+		return err
+	}
+	if len(c.Storage.NaturalKey) > 0 && c.Storage.Backend != "memory" {
+		return fmt.Errorf("the natural key is only supported by the memory storage backend, not %s", c.Storage.Backend)
+	}
+	if c.Storage.ExpirySweepInterval < 0 || c.Storage.DeletedRetention < 0 {
+		return errors.New("expiry sweep interval and deleted retention must not be negative")
+	}
+	if c.Storage.DeletedRetention > 0 && c.Storage.Backend != "memory" {
+		return fmt.Errorf("deleted retention is only supported by the memory storage backend, not %s", c.Storage.Backend)
+	}
+	if err := storage.ValidateIndexedMetadataKeys(c.Storage.IndexedMetadataKeys); err != nil {
+		return err
+	}
+	if len(c.Storage.IndexedMetadataKeys) > 0 && c.Storage.Backend != "memory" {
+		return fmt.Errorf("indexed metadata keys are only supported by the memory storage backend, not %s", c.Storage.Backend)
+	}
+
+	switch c.Plugins.Loader {
+	case "so", "rpc":
+	default:
+		return fmt.Errorf("unknown plugin loader: %s", c.Plugins.Loader)
+	}
+	if c.Plugins.ReconcileInterval <= 0 {
+		return errors.New("the reconcile interval must be positive")
+	}
+	for name, interval := range c.Plugins.ReconcileIntervals {
+		if interval <= 0 {
+			return fmt.Errorf("the reconcile interval of plugin %s must be positive", name)
+		}
+	}
+
+	if c.Audit.Retain < 1 {
+		return errors.New("the audit log must retain at least one entry")
+	}
+
+	if c.CORS.AllowCredentials {
+		for _, origin := range c.CORS.AllowedOrigins {
+			if origin == "*" {
+				return errors.New(`allowing CORS credentials requires listing origins instead of "*"`)
+			}
+		}
+	}
+	return nil
+}
+
+// withPort replaces the port of addr, keeping its host
+func withPort(addr, port string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = "0.0.0.0"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func setString(dst *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+func setInt(dst *int, key string) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, key string) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*dst = b
+	return nil
+}
+
+// setList overrides dst with the comma-separated environment variable key
+// when it lists at least one value
+func setList(dst *[]string, key string) {
+	var list []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	if len(list) > 0 {
+		*dst = list
+	}
+}
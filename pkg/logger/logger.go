@@ -1,21 +1,104 @@
 package logger
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// NewLogger creates a new logger instance
+// Default sampling and rotation settings
+const (
+	DefaultSamplingInitial    = 100
+	DefaultSamplingThereafter = 100
+	DefaultFileMaxSizeMB      = 100
+)
+
+// Options selects the logger level, output format, sampling and file output
+type Options struct {
+	// Level is one of debug, info, warn or error, defaulting to info
+	Level string
+	// Format is "json" (default) or "console" for colored, human-friendly output
+	Format string
+
+	// SamplingInitial entries with the same level and message are logged
+	// each second, then every SamplingThereafter-th one. Sampling applies to
+	// the JSON format only; a zero SamplingInitial disables it.
+	SamplingInitial    int
+	SamplingThereafter int
+
+	// File, when set, receives a copy of the logs and is rotated once it
+	// reaches MaxSizeMB megabytes. Rotated files older than MaxAgeDays days
+	// or beyond the newest MaxBackups are removed; zero keeps them all.
+	File       string
+	MaxSizeMB  int
+	MaxAgeDays int
+	MaxBackups int
+}
+
+// OptionsFromEnv reads the options from LOG_LEVEL, LOG_FORMAT,
+// LOG_SAMPLING_INITIAL, LOG_SAMPLING_THEREAFTER, LOG_FILE, LOG_MAX_SIZE_MB,
+// LOG_MAX_AGE_DAYS and LOG_MAX_BACKUPS. APP_ENV=development selects the
+// console format when LOG_FORMAT is unset.
+func OptionsFromEnv() (Options, error) {
+	opts := Options{
+		Level:              os.Getenv("LOG_LEVEL"),
+		Format:             os.Getenv("LOG_FORMAT"),
+		SamplingInitial:    DefaultSamplingInitial,
+		SamplingThereafter: DefaultSamplingThereafter,
+		File:               os.Getenv("LOG_FILE"),
+		MaxSizeMB:          DefaultFileMaxSizeMB,
+	}
+	if opts.Format == "" && os.Getenv("APP_ENV") == "development" {
+		opts.Format = "console"
+	}
+
+	for key, dst := range map[string]*int{
+		"LOG_SAMPLING_INITIAL":    &opts.SamplingInitial,
+		"LOG_SAMPLING_THEREAFTER": &opts.SamplingThereafter,
+		"LOG_MAX_SIZE_MB":         &opts.MaxSizeMB,
+		"LOG_MAX_AGE_DAYS":        &opts.MaxAgeDays,
+		"LOG_MAX_BACKUPS":         &opts.MaxBackups,
+	} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return Options{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = n
+	}
+	return opts, nil
+}
+
+// NewLogger creates a new logger instance configured from the environment
 func NewLogger() (*zap.Logger, error) {
-	config := zap.NewProductionConfig()
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return New(opts)
+}
+
+// New creates a new logger instance with the given options
+func New(opts Options) (*zap.Logger, error) {
+	var config zap.Config
+	if opts.Format == "console" {
+		config = zap.NewDevelopmentConfig()
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	} else {
+		config = zap.NewProductionConfig()
+	}
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
-	// Set log level based on environment variable
-	logLevel := os.Getenv("LOG_LEVEL")
-	switch logLevel {
+	switch opts.Level {
 	case "debug":
 		config.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
 	case "info":
@@ -28,7 +111,21 @@ func NewLogger() (*zap.Logger, error) {
 		config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
 	}
 
-	logger, err := config.Build()
+	// Sampling wraps the tee built below, so both outputs see the same entries
+	config.Sampling = nil
+	var buildOpts []zap.Option
+	if opts.File != "" {
+		buildOpts = append(buildOpts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, newFileCore(config, opts))
+		}))
+	}
+	if opts.Format != "console" && opts.SamplingInitial > 0 {
+		buildOpts = append(buildOpts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(core, time.Second, opts.SamplingInitial, opts.SamplingThereafter)
+		}))
+	}
+
+	logger, err := config.Build(buildOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -36,6 +133,27 @@ func NewLogger() (*zap.Logger, error) {
 	return logger, nil
 }
 
+// newFileCore writes entries to the rotating opts.File in the configured
+// format, without color codes
+func newFileCore(config zap.Config, opts Options) zapcore.Core {
+	var encoder zapcore.Encoder
+	if config.Encoding == "console" {
+		encoderConfig := config.EncoderConfig
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	} else {
+		encoder = zapcore.NewJSONEncoder(config.EncoderConfig)
+	}
+
+	writer := zapcore.AddSync(&lumberjack.Logger{
+		Filename:   opts.File,
+		MaxSize:    opts.MaxSizeMB,
+		MaxAge:     opts.MaxAgeDays,
+		MaxBackups: opts.MaxBackups,
+	})
+	return zapcore.NewCore(encoder, writer, config.Level)
+}
+
 // GetLogger returns a new logger instance or panics if it can't be created
 func GetLogger() *zap.Logger {
 	logger, err := NewLogger()
@@ -4,13 +4,14 @@ import (
 	"fmt"
 
 	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/Cdaprod/registry-service/pkg/plugins"
 )
 
 // APIPlugin implements the Plugin interface
 type APIPlugin struct{}
 
 func (p *APIPlugin) Register(reg registry.Registry) error {
-	api := &BuiltinAPI{ID: "api", Type: "API", Name: "Generic API"}
+	api := &plugins.BuiltinAPI{ID: "api", Type: plugins.APIItemType, Name: "Generic API", RegistryName: plugins.BuiltinRegistryName}
 	if err := reg.Register(api); err != nil {
 		return fmt.Errorf("failed to register API plugin: %w", err)
 	}
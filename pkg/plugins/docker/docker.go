@@ -4,13 +4,14 @@ import (
 	"fmt"
 
 	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/Cdaprod/registry-service/pkg/plugins"
 )
 
 // DockerPlugin implements the Plugin interface
 type DockerPlugin struct{}
 
 func (p *DockerPlugin) Register(reg registry.Registry) error {
-	dockerAPI := &BuiltinAPI{ID: "docker", Type: "API", Name: "Docker API"}
+	dockerAPI := &plugins.BuiltinAPI{ID: "docker", Type: plugins.APIItemType, Name: "Docker API", RegistryName: plugins.BuiltinRegistryName}
 	if err := reg.Register(dockerAPI); err != nil {
 		return fmt.Errorf("failed to register Docker plugin: %w", err)
 	}
@@ -0,0 +1,46 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PluginLoadError records why a single plugin failed to load
+type PluginLoadError struct {
+	Path string
+	Err  error
+}
+
+func (e PluginLoadError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e PluginLoadError) Unwrap() error {
+	return e.Err
+}
+
+// LoadErrors aggregates the per-plugin failures of a LoadAll run. Plugins
+// that are not listed loaded successfully.
+type LoadErrors []PluginLoadError
+
+func (e LoadErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("failed to load %d plugin(s): %s", len(e), strings.Join(msgs, "; "))
+}
+
+// CheckPluginsDir reports whether dir exists. A missing directory is not an
+// error, since deployments without plugins need not create it; any other
+// failure to stat it is returned as LoadErrors.
+func CheckPluginsDir(dir string) (bool, error) {
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, LoadErrors{{Path: dir, Err: err}}
+	}
+	return true, nil
+}
@@ -4,13 +4,14 @@ import (
 	"fmt"
 
 	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/Cdaprod/registry-service/pkg/plugins"
 )
 
 // GitPlugin implements the Plugin interface
 type GitPlugin struct{}
 
 func (p *GitPlugin) Register(reg registry.Registry) error {
-	gitAPI := &BuiltinAPI{ID: "git", Type: "API", Name: "Git API"}
+	gitAPI := &plugins.BuiltinAPI{ID: "git", Type: plugins.APIItemType, Name: "Git API", RegistryName: plugins.BuiltinRegistryName}
 	if err := reg.Register(gitAPI); err != nil {
 		return fmt.Errorf("failed to register Git plugin: %w", err)
 	}
@@ -0,0 +1,74 @@
+package plugins
+
+import (
+	"net/url"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// APIItemType is the registry item type of BuiltinAPI items
+const APIItemType = "API"
+
+// BuiltinRegistryName is the registry name of the items the builtin plugins
+// register
+const BuiltinRegistryName = "builtin"
+
+// BuiltinAPI describes an API a plugin integrates with, such as Docker or
+// Git. It is a typed kind, so it can also be created and read through the
+// REST API with its endpoint as a top-level field.
+type BuiltinAPI struct {
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	Name         string   `json:"name"`
+	RegistryName string   `json:"registryName"`
+	Tags         []string `json:"tags,omitempty"`
+	// Endpoint is the base URL of the API, if it is reached over HTTP
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+var _ registry.MetadataProvider = (*BuiltinAPI)(nil)
+
+func init() {
+	registry.RegisterKind(APIItemType, func() registry.Registerable { return &BuiltinAPI{} })
+}
+
+// GetID returns the ID of the API
+func (a *BuiltinAPI) GetID() string {
+	return a.ID
+}
+
+// GetType returns the item type, APIItemType for builtin APIs
+func (a *BuiltinAPI) GetType() string {
+	return a.Type
+}
+
+// GetName returns the display name of the API
+func (a *BuiltinAPI) GetName() string {
+	return a.Name
+}
+
+// GetMetadata returns the kind's fields as they are stored in item metadata
+func (a *BuiltinAPI) GetMetadata() map[string]interface{} {
+	if a.Endpoint == "" {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{"endpoint": a.Endpoint}
+}
+
+// GetVersion returns zero, since builtin APIs are not versioned
+func (a *BuiltinAPI) GetVersion() int64 {
+	return 0
+}
+
+// Validate checks that Endpoint, when set, is an absolute URL
+func (a *BuiltinAPI) Validate() error {
+	if a.Endpoint == "" {
+		return nil
+	}
+	if u, err := url.Parse(a.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return &registry.ValidationError{Fields: []registry.FieldError{
+			{Field: "endpoint", Message: "must be an absolute URL"},
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package plugins
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/Cdaprod/registry-service/internal/notify"
+	"github.com/Cdaprod/registry-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// LifecyclePlugin is implemented by plugins that react to item changes after
+// they are loaded, for example to mirror items to an external system. A Go
+// plugin provides one by exporting a Lifecycle variable whose value
+// implements it; plugins without one only register items.
+type LifecyclePlugin interface {
+	// OnItemRegistered is called with the stored item after it was created,
+	// updated or restored
+	OnItemRegistered(item registry.Registerable)
+	// OnItemDeleted is called with the ID of an item after it was deleted or
+	// purged
+	OnItemDeleted(id string)
+}
+
+// AdaptLifecycle returns the LifecyclePlugin provided by the Lifecycle symbol
+// of a plugin. The symbol is a pointer to the exported variable, which may
+// itself implement LifecyclePlugin or hold a value that does.
+func AdaptLifecycle(sym interface{}) (LifecyclePlugin, error) {
+	if lp, ok := sym.(LifecyclePlugin); ok {
+		return lp, nil
+	}
+	if v := reflect.ValueOf(sym); v.Kind() == reflect.Ptr && !v.IsNil() {
+		if lp, ok := v.Elem().Interface().(LifecyclePlugin); ok {
+			return lp, nil
+		}
+	}
+	return nil, fmt.Errorf("Lifecycle of type %T does not implement OnItemRegistered(registry.Registerable) and OnItemDeleted(string)", sym)
+}
+
+// LifecycleDispatcher calls the hooks of LifecyclePlugins for the item events
+// published to a notify.Broker. Hooks run one at a time on the dispatcher's
+// goroutine, so a slow hook delays the others, and events that arrive while
+// the subscription's queue is full are missed.
+type LifecycleDispatcher struct {
+	registry registry.Registry
+	logger   *zap.Logger
+
+	mu    sync.RWMutex
+	hooks []lifecycleHook
+
+	sub  *notify.Subscription
+	done chan struct{}
+}
+
+// lifecycleHook is the LifecyclePlugin of the plugin at path
+type lifecycleHook struct {
+	path   string
+	plugin LifecyclePlugin
+}
+
+// NewLifecycleDispatcher creates a LifecycleDispatcher that looks up
+// registered items in reg
+func NewLifecycleDispatcher(reg registry.Registry, logger *zap.Logger) *LifecycleDispatcher {
+	return &LifecycleDispatcher{registry: reg, logger: logger}
+}
+
+// Add registers the LifecyclePlugin of the plugin at path for subsequent
+// events
+func (d *LifecycleDispatcher) Add(path string, lp LifecyclePlugin) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hooks = append(d.hooks, lifecycleHook{path: path, plugin: lp})
+}
+
+// Len returns the number of registered LifecyclePlugins
+func (d *LifecycleDispatcher) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.hooks)
+}
+
+// Start subscribes to broker and dispatches its events until Stop is called
+func (d *LifecycleDispatcher) Start(broker *notify.Broker) {
+	d.sub = broker.Subscribe(notify.Filter{})
+	d.done = make(chan struct{})
+	go func() {
+		defer close(d.done)
+		for e := range d.sub.Events() {
+			d.dispatch(e)
+		}
+	}()
+}
+
+// Stop ends the subscription and waits for the hook being called, if any
+func (d *LifecycleDispatcher) Stop() {
+	if d.sub == nil {
+		return
+	}
+	d.sub.Close()
+	<-d.done
+	if dropped := d.sub.Dropped(); dropped > 0 {
+		d.logger.Warn("Plugin lifecycle hooks missed events", zap.Int64("dropped", dropped))
+	}
+}
+
+// dispatch calls the hook matching e on every LifecyclePlugin
+func (d *LifecycleDispatcher) dispatch(e notify.Event) {
+	d.mu.RLock()
+	hooks := d.hooks
+	d.mu.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	switch e.Event {
+	case notify.EventItemCreated, notify.EventItemUpdated, notify.EventItemRestored:
+		// The item may have changed or gone since the event, so hooks get
+		// its current state
+		item, ok := d.registry.Get(e.ItemID)
+		if !ok {
+			return
+		}
+		for _, hook := range hooks {
+			d.call(hook, e, func() { hook.plugin.OnItemRegistered(item) })
+		}
+	case notify.EventItemDeleted, notify.EventItemPurged:
+		for _, hook := range hooks {
+			d.call(hook, e, func() { hook.plugin.OnItemDeleted(e.ItemID) })
+		}
+	}
+}
+
+// call runs fn, logging rather than propagating a panic so that one plugin
+// cannot stop the others from being notified
+func (d *LifecycleDispatcher) call(hook lifecycleHook, e notify.Event, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.logger.Error("Plugin lifecycle hook panicked",
+				zap.String("path", hook.path),
+				zap.String("event", e.Event),
+				zap.String("id", e.ItemID),
+				zap.Any("panic", r))
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,84 @@
+package plugins
+
+import (
+	"path/filepath"
+	"plugin"
+	"strings"
+	"time"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// PluginItemType is the registry item type under which loaded plugins are recorded
+const PluginItemType = "plugin"
+
+// pluginRegistryName is the registry name of plugin items
+const pluginRegistryName = "plugins"
+
+// Metadata describes a plugin. Plugins may export it as a PluginMeta variable
+// of this type or of an identical unnamed struct type.
+type Metadata struct {
+	Name    string
+	Version string
+	Author  string
+}
+
+// lookupMetadata reads the optional PluginMeta symbol of p, deriving the name
+// from the plugin's filename when the symbol is absent or leaves it empty
+func lookupMetadata(p *plugin.Plugin, path string) Metadata {
+	var meta Metadata
+	if sym, err := p.Lookup("PluginMeta"); err == nil {
+		switch m := sym.(type) {
+		case *Metadata:
+			meta = *m
+		case *struct{ Name, Version, Author string }:
+			meta = Metadata(*m)
+		}
+	}
+
+	if meta.Name == "" {
+		meta.Name = nameFromPath(path)
+	}
+	return meta
+}
+
+// PluginName returns the name of the plugin p loaded from path: the Name of
+// its PluginMeta, or its filename without the extension
+func PluginName(p *plugin.Plugin, path string) string {
+	return lookupMetadata(p, path).Name
+}
+
+// nameFromPath derives a plugin name from its filename without the extension
+func nameFromPath(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// RegisterMetadata records the plugin loaded from path in reg as an item of
+// type PluginItemType, so active plugins and their versions can be listed
+func RegisterMetadata(reg registry.Registry, p *plugin.Plugin, path string) error {
+	return RegisterPluginItem(reg, lookupMetadata(p, path), path)
+}
+
+// RegisterPluginItem records a plugin described by meta in reg as an item of
+// type PluginItemType, replacing the record of an earlier load. An empty name
+// is derived from the plugin's filename.
+func RegisterPluginItem(reg registry.Registry, meta Metadata, path string) error {
+	if meta.Name == "" {
+		meta.Name = nameFromPath(path)
+	}
+	now := time.Now()
+
+	return registry.Upsert(reg, &registry.Item{
+		ID:           PluginItemType + ":" + meta.Name,
+		Type:         PluginItemType,
+		Name:         meta.Name,
+		RegistryName: pluginRegistryName,
+		Metadata: map[string]interface{}{
+			"version": meta.Version,
+			"author":  meta.Author,
+			"path":    path,
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
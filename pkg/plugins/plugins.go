@@ -1,65 +1,63 @@
 package plugins
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"plugin"
 
 	"github.com/Cdaprod/registry-service/internal/registry"
+	"go.uber.org/zap"
 )
 
 // PluginLoader is responsible for loading and registering plugins
 type PluginLoader struct {
 	registry   registry.Registry
 	pluginsDir string
+	logger     *zap.Logger
 }
 
 // NewPluginLoader creates a new PluginLoader instance
-func NewPluginLoader(reg registry.Registry, pluginsDir string) *PluginLoader {
+func NewPluginLoader(reg registry.Registry, pluginsDir string, logger *zap.Logger) *PluginLoader {
 	return &PluginLoader{
 		registry:   reg,
 		pluginsDir: pluginsDir,
+		logger:     logger,
 	}
 }
 
-// LoadAll dynamically loads all plugins from the specified directory
+// LoadAll dynamically loads all plugins from the specified directory. A plugin
+// that fails to load does not stop the others; failures are returned together
+// as LoadErrors. A missing plugins directory loads nothing.
 func (pl *PluginLoader) LoadAll() error {
-	err := filepath.Walk(pl.pluginsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if filepath.Ext(path) != ".so" {
-			return nil // Skip non-shared object files
-		}
-
-		// Open the plugin
-		p, err := plugin.Open(path)
-		if err != nil {
-			return fmt.Errorf("failed to open plugin: %v", err)
+	if exists, err := CheckPluginsDir(pl.pluginsDir); !exists {
+		if err == nil {
+			pl.logger.Info("Plugins directory does not exist; no plugins loaded", zap.String("dir", pl.pluginsDir))
 		}
+		return err
+	}
 
-		// Look up the Register function
-		symRegister, err := p.Lookup("Register")
+	var errs LoadErrors
+	filepath.Walk(pl.pluginsDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return fmt.Errorf("failed to find Register function in %v: %v", path, err)
+			errs = append(errs, PluginLoadError{Path: path, Err: err})
+			return nil
 		}
-
-		// Assert that the Register function has the correct signature
-		registerFunc, ok := symRegister.(func(reg registry.Registry) error)
-		if !ok {
-			return fmt.Errorf("invalid Register function signature in plugin: %v", path)
+		if filepath.Ext(path) != ".so" {
+			return nil // Skip non-shared object files
 		}
 
-		// Call the Register function to register the plugin with the registry
-		if err := registerFunc(pl.registry); err != nil {
-			return fmt.Errorf("failed to register plugin: %v", err)
+		if err := pl.LoadPlugin(path); err != nil {
+			errs = append(errs, PluginLoadError{Path: path, Err: err})
 		}
-
 		return nil
 	})
 
-	return err
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
 // LoadPlugin dynamically loads a single plugin by file path
@@ -78,14 +76,18 @@ func (pl *PluginLoader) LoadPlugin(pluginPath string) error {
 		return fmt.Errorf("failed to find Register function: %v", err)
 	}
 
-	registerFunc, ok := symRegister.(func(reg registry.Registry) error)
-	if !ok {
-		return fmt.Errorf("invalid Register function signature in plugin: %v", pluginPath)
+	registerFunc, err := AdaptRegister(symRegister)
+	if err != nil {
+		return fmt.Errorf("invalid Register function in plugin %v: %v", pluginPath, err)
 	}
 
-	if err := registerFunc(pl.registry); err != nil {
+	if err := registerFunc(context.Background(), pl.registry); err != nil {
 		return fmt.Errorf("failed to register plugin: %v", err)
 	}
 
+	if err := RegisterMetadata(pl.registry, p, pluginPath); err != nil {
+		return fmt.Errorf("failed to record plugin metadata: %v", err)
+	}
+
 	return nil
 }
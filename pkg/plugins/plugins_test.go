@@ -0,0 +1,92 @@
+package plugins
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+
+	"github.com/Cdaprod/registry-service/internal/registry/registrytest"
+	"go.uber.org/zap"
+)
+
+// buildTestPlugins builds testdata/valid into a new directory as valid.so,
+// next to a broken.so that is not a plugin, and returns the directory
+func buildTestPlugins(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building a plugin is slow")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	// Plugins only open in binaries built with the same flags, such as -race
+	args := []string{"build", "-buildmode=plugin", "-o", filepath.Join(dir, "valid.so")}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "-race" && setting.Value == "true" {
+				args = append(args, "-race")
+			}
+		}
+	}
+	out, err := exec.Command(goBin, append(args, "./testdata/valid")...).CombinedOutput()
+	if err != nil {
+		t.Skipf("cannot build plugins here: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.so"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoadAllContinuesAfterFailure(t *testing.T) {
+	dir := buildTestPlugins(t)
+	reg := registrytest.NewFakeRegistry()
+
+	err := NewPluginLoader(reg, dir, zap.NewNop()).LoadAll()
+
+	var loadErrs LoadErrors
+	if !errors.As(err, &loadErrs) {
+		t.Fatalf("LoadAll() = %v, want LoadErrors", err)
+	}
+	if len(loadErrs) != 1 || loadErrs[0].Path != filepath.Join(dir, "broken.so") {
+		t.Errorf("LoadAll() errors = %v, want only broken.so", loadErrs)
+	}
+	if _, ok := reg.Get("valid"); !ok {
+		t.Error("valid plugin was not registered")
+	}
+}
+
+func TestCheckPluginsDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		dir        string
+		wantExists bool
+		wantErr    bool
+	}{
+		{name: "existing directory", dir: dir, wantExists: true},
+		{name: "missing directory", dir: filepath.Join(dir, "missing")},
+		{name: "unreadable path", dir: filepath.Join(file, "plugins"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists, err := CheckPluginsDir(tt.dir)
+			if exists != tt.wantExists || (err != nil) != tt.wantErr {
+				t.Errorf("CheckPluginsDir() = %v, %v, want %v, error %v", exists, err, tt.wantExists, tt.wantErr)
+			}
+			if err := NewPluginLoader(registrytest.NewFakeRegistry(), tt.dir, zap.NewNop()).LoadAll(); (err != nil) != tt.wantErr {
+				t.Errorf("LoadAll() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package plugins
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Cdaprod/registry-service/internal/reconcile"
+)
+
+// AdaptReconciler returns the reconcile.Reconciler provided by the Reconciler
+// symbol of a plugin. Like Lifecycle, the symbol points to the exported
+// variable, which may itself implement the interface or hold a value that
+// does.
+func AdaptReconciler(sym interface{}) (reconcile.Reconciler, error) {
+	if r, ok := sym.(reconcile.Reconciler); ok {
+		return r, nil
+	}
+	if v := reflect.ValueOf(sym); v.Kind() == reflect.Ptr && !v.IsNil() {
+		if r, ok := v.Elem().Interface().(reconcile.Reconciler); ok {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("Reconciler of type %T does not implement Reconcile(context.Context) ([]*registry.Item, error)", sym)
+}
@@ -0,0 +1,90 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Cdaprod/registry-service/internal/registry"
+)
+
+// RegisterFunc is the form every supported plugin Register function is
+// adapted to
+type RegisterFunc func(ctx context.Context, reg registry.Registry) error
+
+// RegisterSignatures are the Register signatures a plugin may export. Any
+// parameter typed registry.Registry may instead be an interface that
+// registry.Registry satisfies, such as one with only the Register method.
+var RegisterSignatures = []string{
+	"func(registry.Registry) error",
+	"func(context.Context, registry.Registry) error",
+	"func(registry.Registry)",
+	"func(context.Context, registry.Registry)",
+}
+
+var (
+	contextType  = reflect.TypeOf((*context.Context)(nil)).Elem()
+	registryType = reflect.TypeOf((*registry.Registry)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// AdaptRegister wraps the Register symbol of a plugin in a RegisterFunc. The
+// symbol may be a function or, when the plugin exports a variable, a pointer
+// to one. Signatures other than RegisterSignatures are rejected with an
+// error that lists them.
+func AdaptRegister(sym interface{}) (RegisterFunc, error) {
+	switch fn := sym.(type) {
+	case func(registry.Registry) error:
+		return func(_ context.Context, reg registry.Registry) error { return fn(reg) }, nil
+	case func(context.Context, registry.Registry) error:
+		return fn, nil
+	}
+
+	v := reflect.ValueOf(sym)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Func || v.IsNil() || !isRegisterSignature(v.Type()) {
+		return nil, fmt.Errorf("unsupported Register signature %T; expected one of: %s",
+			sym, strings.Join(RegisterSignatures, ", "))
+	}
+
+	return func(ctx context.Context, reg registry.Registry) error {
+		args := make([]reflect.Value, v.Type().NumIn())
+		for i := range args {
+			if v.Type().In(i) == contextType {
+				args[i] = reflect.ValueOf(&ctx).Elem()
+			} else {
+				args[i] = reflect.ValueOf(&reg).Elem().Convert(v.Type().In(i))
+			}
+		}
+
+		out := v.Call(args)
+		if len(out) == 0 || out[0].IsNil() {
+			return nil
+		}
+		return out[0].Interface().(error)
+	}, nil
+}
+
+// isRegisterSignature reports whether t matches one of RegisterSignatures
+func isRegisterSignature(t reflect.Type) bool {
+	if t.IsVariadic() || t.NumOut() > 1 || (t.NumOut() == 1 && t.Out(0) != errorType) {
+		return false
+	}
+
+	switch t.NumIn() {
+	case 1:
+		return acceptsRegistry(t.In(0))
+	case 2:
+		return t.In(0) == contextType && acceptsRegistry(t.In(1))
+	}
+	return false
+}
+
+// acceptsRegistry reports whether a registry.Registry can be passed as a
+// parameter of type t
+func acceptsRegistry(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface && registryType.Implements(t)
+}
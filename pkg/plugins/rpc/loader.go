@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	registrygrpc "github.com/Cdaprod/registry-service/internal/grpc"
+	"github.com/Cdaprod/registry-service/internal/registry"
+	"github.com/Cdaprod/registry-service/pkg/plugins"
+	"github.com/Cdaprod/registry-service/proto/pluginpb"
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"go.uber.org/zap"
+)
+
+// registerTimeout bounds how long a plugin may take to answer Register
+const registerTimeout = 30 * time.Second
+
+// Loader launches plugin binaries and registers the items they return
+type Loader struct {
+	registry   registry.Registry
+	pluginsDir string
+	logger     *zap.Logger
+
+	// pluginLogger receives go-plugin's own logs about plugin processes
+	pluginLogger hclog.Logger
+}
+
+// NewLoader creates a Loader for the executables in pluginsDir
+func NewLoader(reg registry.Registry, pluginsDir string, logger *zap.Logger) *Loader {
+	return &Loader{
+		registry:   reg,
+		pluginsDir: pluginsDir,
+		logger:     logger,
+		pluginLogger: hclog.New(&hclog.LoggerOptions{
+			Name:  "plugin",
+			Level: hclog.Warn,
+		}),
+	}
+}
+
+// LoadAll launches every executable in the plugins directory. A plugin that
+// fails to load does not stop the others; failures are returned together as
+// plugins.LoadErrors. A missing plugins directory loads nothing.
+func (l *Loader) LoadAll() error {
+	if exists, err := plugins.CheckPluginsDir(l.pluginsDir); !exists {
+		if err == nil {
+			l.logger.Info("Plugins directory does not exist; no plugins loaded", zap.String("dir", l.pluginsDir))
+		}
+		return err
+	}
+
+	var errs plugins.LoadErrors
+	filepath.Walk(l.pluginsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			errs = append(errs, plugins.PluginLoadError{Path: path, Err: err})
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			return nil // Skip non-executable files
+		}
+
+		if err := l.LoadPlugin(path); err != nil {
+			errs = append(errs, plugins.PluginLoadError{Path: path, Err: err})
+		}
+		return nil
+	})
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// LoadPlugin launches the plugin binary at path, registers the items it
+// returns from Register, and stops it again
+func (l *Loader) LoadPlugin(path string) error {
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          goplugin.PluginSet{PluginName: &GRPCPlugin{}},
+		Cmd:              exec.Command(path),
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		Logger:           l.pluginLogger,
+	})
+	defer client.Kill()
+
+	conn, err := client.Client()
+	if err != nil {
+		return fmt.Errorf("failed to start plugin: %v", err)
+	}
+
+	raw, err := conn.Dispense(PluginName)
+	if err != nil {
+		return fmt.Errorf("failed to dispense plugin: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), registerTimeout)
+	defer cancel()
+
+	resp, err := raw.(pluginpb.PluginServiceClient).Register(ctx, &pluginpb.RegisterRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to call Register: %v", err)
+	}
+
+	for _, msg := range resp.GetItems() {
+		item, err := registrygrpc.FromProto(msg)
+		if err != nil {
+			return fmt.Errorf("invalid item from plugin: %v", err)
+		}
+		if err := l.registry.Register(item); err != nil {
+			return fmt.Errorf("failed to register item %s: %v", item.ID, err)
+		}
+	}
+
+	meta := resp.GetMeta()
+	return plugins.RegisterPluginItem(l.registry, plugins.Metadata{
+		Name:    meta.GetName(),
+		Version: meta.GetVersion(),
+		Author:  meta.GetAuthor(),
+	}, path)
+}
@@ -0,0 +1,53 @@
+// Package rpc loads out-of-process plugins that speak gRPC through
+// hashicorp/go-plugin. Unlike .so plugins they do not need to be built with
+// the exact toolchain and dependency versions of the registry service.
+package rpc
+
+import (
+	"context"
+
+	"github.com/Cdaprod/registry-service/proto/pluginpb"
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+)
+
+// PluginName is the name under which plugin binaries serve the plugin service
+const PluginName = "registry"
+
+// Handshake is shared by the loader and plugin binaries. It stops the loader
+// from launching arbitrary executables and guards against protocol mismatches.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "REGISTRY_PLUGIN",
+	MagicCookieValue: "registry-service",
+}
+
+// GRPCPlugin adapts a pluginpb.PluginServiceServer to go-plugin
+type GRPCPlugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+
+	// Impl is only set on the plugin side
+	Impl pluginpb.PluginServiceServer
+}
+
+// GRPCServer registers the plugin implementation on the plugin's gRPC server
+func (p *GRPCPlugin) GRPCServer(_ *goplugin.GRPCBroker, s *grpc.Server) error {
+	pluginpb.RegisterPluginServiceServer(s, p.Impl)
+	return nil
+}
+
+// GRPCClient returns a client for the plugin service on the loader side
+func (p *GRPCPlugin) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return pluginpb.NewPluginServiceClient(conn), nil
+}
+
+// Serve runs impl as a plugin; plugin binaries call it from main
+func Serve(impl pluginpb.PluginServiceServer) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins: goplugin.PluginSet{
+			PluginName: &GRPCPlugin{Impl: impl},
+		},
+		GRPCServer: goplugin.DefaultGRPCServer,
+	})
+}
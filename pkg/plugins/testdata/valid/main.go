@@ -0,0 +1,9 @@
+// Command valid is a plugin that registers one item, built by the loader
+// tests
+package main
+
+import "github.com/Cdaprod/registry-service/internal/registry"
+
+func Register(reg registry.Registry) error {
+	return reg.Register(&registry.Item{ID: "valid", Type: "service", Name: "valid", RegistryName: "plugins"})
+}
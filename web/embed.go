@@ -0,0 +1,19 @@
+//go:build embedweb
+
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:build
+var build embed.FS
+
+func init() {
+	assets, err := fs.Sub(build, "build")
+	if err != nil {
+		panic(err)
+	}
+	Assets = assets
+}
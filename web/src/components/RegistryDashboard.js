@@ -42,7 +42,7 @@ const RegistryDashboard = () => {
   };
 
   const renderChart = () => {
-    const data = registries.map(r => ({ name: r, count: items.length }));
+    const data = registries.map(r => ({ name: r.name, count: r.count }));
     return (
       <LineChart width={600} height={300} data={data}>
         <CartesianGrid strokeDasharray="3 3" />
@@ -71,11 +71,11 @@ const RegistryDashboard = () => {
           <ul className="space-y-2">
             {registries.map(registry => (
               <li 
-                key={registry}
+                key={registry.name}
                 className="flex items-center justify-between p-2 bg-gray-100 rounded cursor-pointer hover:bg-gray-200"
-                onClick={() => fetchItems(registry)}
+                onClick={() => fetchItems(registry.name)}
               >
-                <span>{registry}</span>
+                <span>{registry.name} ({registry.count})</span>
                 <CheckCircle className="h-4 w-4 text-green-500" />
               </li>
             ))}
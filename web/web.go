@@ -0,0 +1,9 @@
+// Package web provides the built web frontend to binaries that embed it.
+package web
+
+import "io/fs"
+
+// Assets holds the contents of web/build when the binary is built with the
+// embedweb tag, after building the frontend. It is nil otherwise, and the
+// frontend is served from the configured static directory.
+var Assets fs.FS